@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// runBackup writes an online copy of the database, safe to run while the parser writes
+func runBackup(args []string) error {
+	fs := newFlagSet("backup")
+	dbPath := fs.String("db", defaultDBPath(), "Path to SQLite database file")
+	to := fs.String("to", "", "Backup file path, must not exist")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *to == "" {
+		return fmt.Errorf("-to is required")
+	}
+
+	logger := log.New(os.Stdout, "[ETH-PARSER-DB] ", log.LstdFlags|log.Lshortfile)
+	dbConfig, err := newDBConfig(*dbPath, os.Getenv("DB_JOURNAL_MODE"))
+	if err != nil {
+		return err
+	}
+	dbManager, err := connectDatabase(dbConfig, logger)
+	if err != nil {
+		return err
+	}
+	defer dbManager.Close()
+
+	return dbManager.BackupTo(*to)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/client"
+	"eth-blockchain-parser/pkg/database"
+)
+
+const infuraKeyHelp = `Infura API Key Required!
+
+To use this parser with Infura:
+1. Get your Infura API key from https://infura.io
+2. Set one of these environment variables (checked in this order):
+   - export INFURA_API_KEY="your-key-here"
+   - INFURA_PROJECT_ID, INFURA_KEY, INFURA_ID
+3. Optionally set the network:
+   - export INFURA_NETWORK="mainnet"  (default)
+   - export ETH_NETWORK="sepolia"     (alternative)
+
+Supported networks: mainnet, sepolia, goerli, polygon-mainnet, arbitrum-mainnet
+
+Your Infura "API Key" usually looks like: abc123def456789...`
+
+// defaultDBPath returns DB_PATH from environment or ./blockchain.db
+func defaultDBPath() string {
+	if dbPath := os.Getenv("DB_PATH"); dbPath != "" {
+		return dbPath
+	}
+	return "./blockchain.db"
+}
+
+// newDBConfig returns the database config, journalMode overrides WAL when not empty
+func newDBConfig(dbPath, journalMode string) (*database.Config, error) {
+	dbConfig := database.DefaultConfig(dbPath)
+	if journalMode != "" {
+		if err := dbConfig.SetJournalMode(journalMode); err != nil {
+			return nil, err
+		}
+	}
+	return dbConfig, nil
+}
+
+// openDatabase opens the SQLite database and creates missing tables. A database with every table
+// only gets the migrations and indexes of newer versions.
+// DB_JOURNAL_MODE (DELETE, TRUNCATE, MEMORY, ...) overrides the default WAL journal.
+func openDatabase(dbPath string, logger *log.Logger) (*database.DatabaseManager, error) {
+	logger.Println("DB_PATH", dbPath)
+	dbConfig, err := newDBConfig(dbPath, os.Getenv("DB_JOURNAL_MODE"))
+	if err != nil {
+		return nil, err
+	}
+	dbManager, err := connectDatabase(dbConfig, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := dbManager.DB()
+	if err != nil {
+		dbManager.Close()
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+	schema := database.NewSchema(logger)
+	if err := dbManager.CheckSchema(database.RequiredTables); err != nil {
+		if !errors.Is(err, database.ErrSchemaMissing) {
+			dbManager.Close()
+			return nil, err
+		}
+		logger.Printf("%v, creating tables", err)
+		if err := schema.CreateAllTables(db); err != nil {
+			dbManager.Close()
+			return nil, fmt.Errorf("failed to create tables: %w", err)
+		}
+		return dbManager, nil
+	}
+	if err := schema.Upgrade(db); err != nil {
+		dbManager.Close()
+		return nil, err
+	}
+	return dbManager, nil
+}
+
+// startupRetry returns the retry policy of the initial DB and Infura connections from environment:
+// STARTUP_RETRIES attempts (1 = fail at once) STARTUP_RETRY_INTERVAL apart, doubled after each failure
+func startupRetry() (types.StartupRetry, error) {
+	retry := types.DefaultStartupRetry
+	if value := os.Getenv("STARTUP_RETRIES"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 {
+			return retry, fmt.Errorf("invalid STARTUP_RETRIES %q: must be a positive number", value)
+		}
+		retry.Attempts = attempts
+	}
+	if value := os.Getenv("STARTUP_RETRY_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return retry, fmt.Errorf("invalid STARTUP_RETRY_INTERVAL %q: must be a positive duration like 5s", value)
+		}
+		retry.Interval = interval
+	}
+	return retry, nil
+}
+
+// connectDatabase opens the database, retrying with startupRetry so a slow mount at boot doesn't exit the process
+func connectDatabase(dbConfig *database.Config, logger *log.Logger) (*database.DatabaseManager, error) {
+	retry, err := startupRetry()
+	if err != nil {
+		return nil, err
+	}
+	var dbManager *database.DatabaseManager
+	err = retry.Do(context.Background(), "database connection", func() error {
+		dbManager, err = database.NewDatabaseManager(dbConfig, logger)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return dbManager, nil
+}
+
+// connectInfura creates an Infura client of network, retrying with startupRetry while Infura is unreachable
+func connectInfura(apiKey, network string) (*client.EthClient, error) {
+	retry, err := startupRetry()
+	if err != nil {
+		return nil, err
+	}
+	var ethClient *client.EthClient
+	err = retry.Do(context.Background(), "Infura connection ("+network+")", func() error {
+		ethClient, err = client.NewInfuraClientSimple(apiKey, network)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Infura client for %s: %w", network, err)
+	}
+	return ethClient, nil
+}
+
+// newInfuraClient connects to Infura with the API key and network from environment
+func newInfuraClient() (*client.EthClient, *types.Config, error) {
+	infuraAPIKey, err := client.APIKeyFromEnv()
+	if err != nil {
+		fmt.Println(infuraKeyHelp)
+		return nil, nil, err
+	}
+
+	// Get network from environment variable (defaults to mainnet)
+	network := os.Getenv("INFURA_NETWORK")
+	if network == "" {
+		network = os.Getenv("ETH_NETWORK")
+	}
+	if network == "" {
+		network = "mainnet" // Default to mainnet
+	}
+
+	log.Printf("Using Infura API Key: %s... (network: %s)", infuraAPIKey[:min(8, len(infuraAPIKey))], network)
+
+	ethClient, err := connectInfura(infuraAPIKey, network)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Show connection info
+	info := ethClient.GetInfuraRateLimitInfo()
+	fmt.Printf("Connected to Infura: %+v\n", info)
+
+	return ethClient, types.InfuraConfigSimple(infuraAPIKey, network), nil
+}
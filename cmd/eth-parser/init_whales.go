@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+)
+
+// runInitWhales creates or updates the whale addresses from config in DB, transactions are kept
+func runInitWhales(args []string) error {
+	fs := newFlagSet("init-whales")
+	dbPath := fs.String("db", defaultDBPath(), "Path to SQLite database file")
+	minETH := fs.String("min-eth", "", "per-whale thresholds in ETH, comma separated address=eth pairs, e.g. 0xabc...=1000; an empty eth resets to the global threshold")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config := types.DefaultConfig()
+	thresholds, err := parseWhaleMinETH(*minETH)
+	if err != nil {
+		return fmt.Errorf("invalid -min-eth: %w", err)
+	}
+	for address, value := range config.WhaleMinETH {
+		if _, ok := thresholds[types.NormalizeAddress(address)]; !ok {
+			thresholds[types.NormalizeAddress(address)] = value
+		}
+	}
+
+	logger := log.New(os.Stdout, "[ETH-PARSER-DB] ", log.LstdFlags|log.Lshortfile)
+	dbManager, err := openDatabase(*dbPath, logger)
+	if err != nil {
+		return err
+	}
+	defer dbManager.Close()
+
+	addressRepo := database.NewAddressRepository(dbManager, logger)
+	ctx := context.Background()
+	if err := initWhales(ctx, addressRepo, config.WhalesAddr); err != nil {
+		return fmt.Errorf("failed to upsert whale addresses: %w", err)
+	}
+	fmt.Println("Created or Updated WhaleAddresses OK")
+
+	if err := setWhaleMinETH(ctx, addressRepo, thresholds); err != nil {
+		return err
+	}
+	if len(thresholds) > 0 {
+		fmt.Printf("Set thresholds of %d whales\n", len(thresholds))
+	}
+	return nil
+}
+
+// parseWhaleMinETH parses "addr=eth,addr2=" into thresholds by normalized address, "" for a reset
+func parseWhaleMinETH(value string) (map[string]string, error) {
+	thresholds := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		address, eth, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(address) == "" {
+			return nil, fmt.Errorf("expected address=eth, got %q", pair)
+		}
+		thresholds[types.NormalizeAddress(address)] = strings.TrimSpace(eth)
+	}
+	return thresholds, nil
+}
+
+// setWhaleMinETH applies per-whale thresholds, BatchUpsert keeps the stored ones.
+// An empty threshold resets the whale to the global MinETHValue.
+func setWhaleMinETH(ctx context.Context, ar *database.AddressRepository, thresholds map[string]string) error {
+	addresses := make([]string, 0, len(thresholds))
+	for address := range thresholds {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		var minETH *string
+		if value := thresholds[address]; value != "" {
+			parsed, err := filtering.ParseMinETH(value)
+			if err != nil {
+				return fmt.Errorf("invalid threshold of %s: %w", address, err)
+			}
+			normalized := parsed.String()
+			minETH = &normalized
+		}
+		found, err := ar.SetMinETH(ctx, address, minETH)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("whale %s is not stored, add it to address_names first", address)
+		}
+	}
+	return nil
+}
+
+// initWhales upserts the whale addresses from config: new ones are added, labels of existing ones updated.
+// Existing rows keep their IDs, so stored transactions are preserved and re-running is safe.
+func initWhales(ctx context.Context, ar *database.AddressRepository, whales map[string]string) error {
+	keys := make([]string, 0, len(whales))
+	for k := range whales {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	addrs := make([]*database.WhaleAddress, 0, len(whales))
+	for _, el := range keys {
+		lbl := whales[el]
+		w_addr := database.WhaleAddress{Address: types.NormalizeAddress(el), Label: &lbl}
+		addrs = append(addrs, &w_addr)
+	}
+
+	return ar.BatchUpsert(ctx, addrs)
+}
@@ -0,0 +1,71 @@
+// eth-parser is the single entry point of the project: parsing, the HTTP API
+// and maintenance tasks are subcommands with their own flag sets.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of eth-parser, run gets the arguments after the command name
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{name: "parse", summary: "parse new blocks, save whale transactions to DB and CSV", run: runParse},
+	{name: "parse-multi", summary: "parse new blocks of several networks, each into its own DB and CSV", run: runParseMulti},
+	{name: "serve", summary: "run the HTTP API server", run: runServe},
+	{name: "init-whales", summary: "create whale addresses in DB from config", run: runInitWhales},
+	{name: "test-block", summary: "parse a single block and print it as JSON", run: runTestBlock},
+	{name: "backup", summary: "online backup of the SQLite database (VACUUM INTO)", run: runBackup},
+	{name: "verify", summary: "report block gaps and hash chain breaks in stored data", run: runVerify},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage()
+		return
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		if err := cmd.run(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "eth-parser %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "eth-parser: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: eth-parser <command> [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun 'eth-parser <command> -h' for command flags.\n")
+}
+
+// newFlagSet creates a subcommand flag set that reports errors instead of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("eth-parser "+name, flag.ContinueOnError)
+}
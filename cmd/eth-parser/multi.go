@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/client"
+	"eth-blockchain-parser/pkg/database"
+	"eth-blockchain-parser/pkg/parser"
+)
+
+// runParseMulti parses new blocks of several networks in one process under a single lock file.
+// Every network has its own Infura client, last block file, DB, CSV and NDJSON named with a _<network> suffix.
+func runParseMulti(args []string) error {
+	fs := newFlagSet("parse-multi")
+	networksFlag := fs.String("networks", "", "comma-separated Infura networks, e.g. mainnet,polygon-mainnet")
+	format := fs.String("format", "csv", "whale txs output: csv, or ndjson (one JSON object per line, appended to ndjson_path)")
+	workers := fs.Int("workers", 0, "number of concurrent block workers per network (default: workers from config)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "ndjson" {
+		return fmt.Errorf("invalid -format %q, expected csv or ndjson", *format)
+	}
+	var names []string
+	for _, name := range strings.Split(*networksFlag, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("-networks is required")
+	}
+
+	infuraAPIKey, err := client.APIKeyFromEnv()
+	if err != nil {
+		fmt.Println(infuraKeyHelp)
+		return err
+	}
+
+	release, err := acquireLock(lockFilePath)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	logger := log.New(os.Stdout, "[ETH-PARSER-DB] ", log.LstdFlags|log.Lshortfile)
+	networks := make([]parser.Network, 0, len(names))
+	configs := make(map[string]*types.Config, len(names))
+	csvWriters := make(map[string]*filtering.CSVWriter, len(names))
+	dbs := make(map[string]*database.DatabaseManager, len(names))
+	clientsByName := make(map[string]*client.EthClient, len(names))
+	var clients []*client.EthClient
+	defer func() {
+		for _, ethClient := range clients {
+			ethClient.Close()
+		}
+		for _, dbManager := range dbs {
+			dbManager.Close()
+		}
+	}()
+
+	for _, name := range names {
+		ethClient, err := connectInfura(infuraAPIKey, name)
+		if err != nil {
+			return err
+		}
+		clients = append(clients, ethClient)
+		clientsByName[name] = ethClient
+		config := types.InfuraConfigSimple(infuraAPIKey, name)
+		if *workers > 0 {
+			config.Workers = *workers
+		}
+		config.LastBlockPath = networkPath(config.LastBlockPath, name)
+		config.CsvPath = networkPath(config.CsvPath, name)
+		config.NdjsonPath = networkPath(config.NdjsonPath, name)
+		config.PendingPath = networkPath(config.PendingPath, name)
+		configs[name] = config
+		if *format == "csv" {
+			csvWriter, err := filtering.NewCSVWriter(config.CsvPath)
+			if err != nil {
+				return err
+			}
+			csvWriters[name] = csvWriter
+			defer closeCSVWriter(csvWriter, logger)
+		}
+		networks = append(networks, parser.Network{Name: name, Client: ethClient, Config: config})
+
+		dbManager, err := openDatabase(networkPath(defaultDBPath(), name), logger)
+		if err != nil {
+			return err
+		}
+		dbs[name] = dbManager
+	}
+
+	multi, err := parser.NewMultiParser(networks)
+	if err != nil {
+		return err
+	}
+
+	parseErr := multi.ParseNew(context.Background(), func(ctx context.Context, network string, blocks []*types.ParsedBlock) error {
+		config, err := parser.ApplySettings(ctx, configs[network], database.NewSettingsRepository(dbs[network], logger, 0))
+		if err != nil {
+			return err
+		}
+		return storeParsedBlocks(ctx, dbs[network], logger, config, clientsByName[network], database.NewTransactionRepository(dbs[network], logger), csvWriters[network], blocks, *format)
+	})
+
+	stats := multi.GetStats()
+	for _, name := range multi.Networks() {
+		s := stats.Networks[name]
+		fmt.Printf("[%s] %d blocks, %d transactions, %d logs, %d errors\n",
+			name, s.BlocksParsed, s.TransactionsParsed, s.LogsParsed, s.ErrorsEncountered)
+	}
+	fmt.Printf("Total: %d blocks, %d transactions, %d logs, %d errors\n",
+		stats.BlocksParsed, stats.TransactionsParsed, stats.LogsParsed, stats.ErrorsEncountered)
+	return parseErr
+}
+
+// networkPath adds a _<network> suffix before the file extension, e.g. blockchain.db -> blockchain_mainnet.db
+func networkPath(path, network string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + network + ext
+}
@@ -0,0 +1,682 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/client"
+	"eth-blockchain-parser/pkg/database"
+	"eth-blockchain-parser/pkg/parser"
+	"eth-blockchain-parser/pkg/sink"
+)
+
+func getFileBTime(fname string) (*time.Time, error) {
+	fileInfo, err := os.Stat(fname)
+	if err != nil {
+		return nil, fmt.Errorf("Error stating file: %v\n", err)
+	}
+
+	statT, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil, fmt.Errorf("Could not get syscall.Stat_t from FileInfo.Sys()")
+	}
+	resp := time.Unix(statT.Ctim.Sec, 0)
+	return &resp, nil
+}
+
+// lockFilePath guards against concurrent parse runs, one lock covers all networks of parse-multi
+const lockFilePath = "/tmp/eth_parser.lock"
+
+// acquireLock takes an exclusive lock on path, a lock older than 300 sec is removed to avoid deadlock.
+// The returned func releases the lock and removes the file.
+func acquireLock(path string) (func(), error) {
+	// check lock file, remove it on timeout 300 sec to avoid deadlock
+	ctime, err := getFileBTime(path)
+	if err != nil {
+		fmt.Println("Skipping TS check for lockfile")
+	} else {
+		fmt.Println("Lock file CTIME", path, ctime)
+		now := time.Now()
+		d_seconds := now.Sub(*ctime).Seconds()
+		// TODO: move timeout to config
+		if d_seconds > 300 {
+			fmt.Printf("Reinit lock file. Difference in seconds: %.0f\n", d_seconds)
+			err = os.Remove(path)
+			if err != nil {
+				fmt.Print(err)
+			}
+		}
+	}
+
+	// Open the lock file (create if it doesn't exist)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	release := func() {
+		f.Close()
+		os.Remove(path)
+	}
+
+	// Attempt to acquire an exclusive, non-blocking lock
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err != nil {
+		if err == syscall.EWOULDBLOCK {
+			fmt.Println("Another instance of the script is already running. Exiting.")
+			os.Exit(1) // Exit if lock cannot be acquired
+		}
+		release()
+		return nil, fmt.Errorf("failed to acquire file lock: %w", err)
+	}
+	return release, nil
+}
+
+// touchLock refreshes the lock file timestamps, so a long running -watch daemon isn't taken for a stale lock
+func touchLock(path string) {
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		fmt.Println("Failed to touch lock file:", err)
+	}
+}
+
+// runParse parses blocks from the last parsed one (or -start) to the latest, saving whale transactions to DB and CSV
+func runParse(args []string) error {
+	fs := newFlagSet("parse")
+	startFlag := fs.Uint64("start", 0, "first block to parse (default: block after last_block file, at most max_block_delta behind latest)")
+	endFlag := fs.Uint64("end", 0, "last block to parse (default: latest)")
+	enrich := fs.Bool("enrich", false, "fetch receipts (gas_used/status) for recent whale txs in DB and exit")
+	enrichLimit := fs.Int("enrich-limit", 100, "number of most recent whale txs without receipts to enrich in -enrich mode")
+	reprocessErrors := fs.Bool("reprocess-errors", false, "parse again the txs stored in parse_errors, save the ones that parse now and exit")
+	reprocessLimit := fs.Int("reprocess-limit", 100, "number of stored parse errors to retry in -reprocess-errors mode")
+	csvColumns := fs.String("csv-columns", "", "comma-separated CSV columns in output order (default: "+strings.Join(filtering.DefaultCsvColumns, ",")+"), value_eth/value_grouped give a plain or 12,345 ETH value")
+	format := fs.String("format", "csv", "whale txs output: csv, or ndjson (one JSON object per line, appended to ndjson_path)")
+	sinkKind := fs.String("sink", "sqlite", "where whale txs are stored: sqlite (DB), file (JSON Lines appended to -sink-path, - for stdout) or none")
+	sinkPath := fs.String("sink-path", "", "output file of -sink file, - for stdout")
+	csvRows := fs.String("csv-rows", "", "CSV rows of a whale-to-whale tx: per_side (a FROM and a TO row) or per_tx (one INT row) (default: csv_row_mode from config, per_side)")
+	csvDedup := fs.Bool("csv-dedup", false, "skip whale rows already written to the CSV, tracked in <csv>.idx (safe re-runs over the same blocks)")
+	confirmations := fs.Uint64("confirmations", 0, "write whale txs to CSV/NDJSON only after N confirmations, buffering newer ones in pending_path (default: min_confirmations from config)")
+	maxAlertAge := fs.Duration("max-alert-age", 0, "don't write whale txs of blocks older than this to CSV/NDJSON, they are still stored in the DB (default: max_alert_age from config)")
+	dailyRequestCap := fs.Uint64("daily-request-cap", 0, "max RPC requests per UTC day, counted in the DB across runs; over it parsing stops (default: daily_request_cap from config)")
+	waitForQuota := fs.Bool("wait-for-quota", false, "with -daily-request-cap wait for 00:00 UTC instead of stopping")
+	minETH := fs.String("min-eth", "", "minimum whale tx value in ETH, fractions allowed, e.g. 0.5 (default: min_eth_value from config)")
+	selectors := fs.String("selectors", "", "comma-separated 4-byte method selectors, e.g. 0x7ff36ab5,0x38ed1739: calls are kept regardless of value and whale (default: method_selectors from config)")
+	direction := fs.String("direction", "", "whale side to keep: from (withdrawals), to (deposits) or both (default: whale_direction from config)")
+	skipSelf := fs.Bool("skip-self", false, "skip txs a whale sends to its own address, otherwise stored with transfer type SELF")
+	whaleReceipts := fs.Bool("whale-receipts", false, "fetch receipts (status/gas_used) of matched whale txs of blocks whose receipts were skipped")
+	finalized := fs.Bool("finalized", false, "parse up to the finalized block instead of latest, falls back to latest minus -confirmations when the provider doesn't support it")
+	trackTokens := fs.Bool("tokens", false, "also match whale ERC20 transfers (fetches receipts of every tx for their logs) and output them with ETH txs, asset = token symbol")
+	tokensFile := fs.String("tokens-file", "", "tracked tokens with -tokens, one \"address,symbol,decimals\" per line (default: tokens_path from config, else the built-in USDT/USDC/... list)")
+	tokenMin := fs.String("token-min", "", "min token transfer with -tokens in whole tokens, comma separated symbol=amount pairs, e.g. USDT=1000000,USDC=1000000 (default: token_min_amounts from config)")
+	includeZero := fs.Bool("include-zero", false, "keep zero-value whale txs (contract calls) regardless of the min ETH value")
+	workers := fs.Int("workers", 0, "number of concurrent block workers (default: workers from config)")
+	adaptive := fs.Bool("adaptive", false, "ramp block workers from -min-workers up to -workers, halving on rate limit errors (for backfills)")
+	minWorkers := fs.Int("min-workers", 0, "starting concurrency with -adaptive (default: min_workers from config)")
+	receiptWorkers := fs.Int("receipt-workers", 0, "max concurrent receipt batch calls across block workers (default: receipt_workers from config)")
+	dropUnknownSender := fs.Bool("drop-unknown-sender", false, "drop transactions whose sender can't be recovered instead of keeping them")
+	maxRange := fs.Uint64("max-range", 0, "max blocks in one parse run, a larger -start/-end range fails before parsing (default: max_range_size from config)")
+	timeout := fs.Duration("timeout", 0, "hard deadline of block parsing, a stuck node is abandoned and the blocks parsed so far are saved (default: range_timeout from config)")
+	watch := fs.Bool("watch", false, "run as a daemon: parse new blocks every -interval until SIGTERM/SIGINT instead of once")
+	interval := fs.Duration("interval", 12*time.Second, "poll interval of the chain head in -watch mode")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	release, err := acquireLock(lockFilePath)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	fmt.Println("Lock acquired. Running script...")
+	// Initialize database
+	logger := log.New(os.Stdout, "[ETH-PARSER-DB] ", log.LstdFlags|log.Lshortfile)
+	logger.Println("Initializing database...")
+
+	dbManager, err := openDatabase(defaultDBPath(), logger)
+	if err != nil {
+		return err
+	}
+	defer dbManager.Close()
+
+	ctx := context.Background()
+	// Initialize repositories
+	txRepo := database.NewTransactionRepository(dbManager, logger)
+	txSink, err := sink.New(*sinkKind, *sinkPath, txRepo)
+	if err != nil {
+		return fmt.Errorf("invalid -sink: %w", err)
+	}
+
+	ethClient, config, err := newInfuraClient()
+	if err != nil {
+		return err
+	}
+	defer ethClient.Close()
+
+	if *workers > 0 {
+		config.Workers = *workers
+	}
+	config.AdaptiveWorkers = config.AdaptiveWorkers || *adaptive
+	if *minWorkers > 0 {
+		config.MinWorkers = *minWorkers
+	}
+	if *receiptWorkers > 0 {
+		config.ReceiptWorkers = *receiptWorkers
+	}
+	if *timeout > 0 {
+		config.RangeTimeout = *timeout
+	}
+	config.CsvDedup = config.CsvDedup || *csvDedup
+	config.DropUnknownSender = config.DropUnknownSender || *dropUnknownSender
+	config.SkipSelfTransfers = config.SkipSelfTransfers || *skipSelf
+	config.IncludeZeroValue = config.IncludeZeroValue || *includeZero
+	config.FetchWhaleReceipts = config.FetchWhaleReceipts || *whaleReceipts
+	config.TargetFinalized = config.TargetFinalized || *finalized
+	config.TrackTokens = config.TrackTokens || *trackTokens
+	if *tokensFile != "" {
+		config.TokensPath = *tokensFile
+	}
+	if *tokenMin != "" {
+		config.TokenMinAmounts = make(map[string]string)
+		for _, pair := range strings.Split(*tokenMin, ",") {
+			symbol, amount, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return fmt.Errorf("invalid -token-min: expected symbol=amount, got %q", pair)
+			}
+			config.TokenMinAmounts[symbol] = amount
+		}
+	}
+	if config.TrackTokens {
+		if _, err := filtering.NewTokenFilter(config); err != nil {
+			return fmt.Errorf("invalid token config: %w", err)
+		}
+		// Transfer логи приходят только с receipts
+		config.IncludeLogs = true
+	}
+	if *csvRows != "" {
+		config.CsvRowMode = *csvRows
+	}
+	if *csvColumns != "" {
+		for _, col := range strings.Split(*csvColumns, ",") {
+			config.CsvColumns = append(config.CsvColumns, strings.TrimSpace(col))
+		}
+	}
+	if *confirmations > 0 {
+		config.MinConfirmations = *confirmations
+	}
+	if *maxAlertAge > 0 {
+		config.MaxAlertAge = *maxAlertAge
+	}
+	if *maxRange > 0 {
+		config.MaxRangeSize = *maxRange
+	}
+	if *dailyRequestCap > 0 {
+		config.DailyRequestCap = *dailyRequestCap
+	}
+	config.WaitForQuota = config.WaitForQuota || *waitForQuota
+	ethClient.SetRequestQuota(config.DailyRequestCap, config.WaitForQuota, database.NewMetaRepository(dbManager, logger))
+	if *minETH != "" {
+		if _, err := filtering.ParseMinETH(*minETH); err != nil {
+			return fmt.Errorf("invalid -min-eth: %w", err)
+		}
+		config.MinETHDecimal = *minETH
+	}
+	if *direction != "" {
+		if _, err := filtering.ParseDirection(*direction); err != nil {
+			return fmt.Errorf("invalid -direction: %w", err)
+		}
+		config.WhaleDirection = *direction
+	}
+	if *selectors != "" {
+		config.MethodSelectors = nil
+		for _, value := range strings.Split(*selectors, ",") {
+			if _, err := filtering.ParseMethodSelector(value); err != nil {
+				return fmt.Errorf("invalid -selectors: %w", err)
+			}
+			config.MethodSelectors = append(config.MethodSelectors, strings.TrimSpace(value))
+		}
+	}
+	if *format != "csv" && *format != "ndjson" {
+		return fmt.Errorf("invalid -format %q, expected csv or ndjson", *format)
+	}
+	// thresholds changed with PUT /api/settings, re-read every cycle in -watch mode
+	settings := database.NewSettingsRepository(dbManager, logger, *interval)
+	baseConfig := config
+	tune := func(ctx context.Context) (*types.Config, error) {
+		return tunedConfig(ctx, baseConfig, settings, *minETH, *direction)
+	}
+	if config, err = tune(ctx); err != nil {
+		return err
+	}
+
+	// remove old DB txs records
+	RemoveOldTxs(ctx, txRepo, config.RetentionDays)
+	if err := filtering.ValidateCsvColumns(config.CsvColumns); err != nil {
+		return fmt.Errorf("invalid -csv-columns: %w", err)
+	}
+	if err := filtering.ValidateCsvRowMode(filtering.CsvRowMode(config.CsvRowMode)); err != nil {
+		return fmt.Errorf("invalid -csv-rows: %w", err)
+	}
+
+	if *enrich {
+		fmt.Printf("Enriching up to %d recent whale txs with receipts\n", *enrichLimit)
+		if err := enrichRecentTxs(ctx, ethClient, txRepo, *enrichLimit); err != nil {
+			return fmt.Errorf("failed to enrich transactions: %w", err)
+		}
+		return nil
+	}
+
+	// one handle for the whole run, -watch cycles append through it
+	var csvWriter *filtering.CSVWriter
+	if *format == "csv" {
+		if csvWriter, err = filtering.NewCSVWriter(config.CsvPath); err != nil {
+			return err
+		}
+		defer closeCSVWriter(csvWriter, logger)
+	}
+
+	blockParser := parser.NewParser(ethClient, config)
+
+	if *reprocessErrors {
+		return reprocessParseErrors(ctx, blockParser, dbManager, logger, config, ethClient, txSink, csvWriter, *format, *reprocessLimit)
+	}
+
+	if *watch {
+		if *startFlag != 0 || *endFlag != 0 {
+			return fmt.Errorf("-start and -end can't be used with -watch")
+		}
+		return watchNewBlocks(ctx, ethClient, dbManager, logger, config, tune, txSink, csvWriter, *format, *interval)
+	}
+
+	// Get latest block number, or the finalized one with -finalized
+	latest, err := parser.TargetBlock(ctx, ethClient, config)
+	if err != nil {
+		return err
+	}
+
+	if config.TargetFinalized {
+		fmt.Printf("Target block: %d\n", latest)
+	} else {
+		fmt.Printf("Latest block: %d\n", latest)
+	}
+
+	endBlock := latest
+	if *endFlag != 0 {
+		endBlock = *endFlag
+	}
+	var startBlock uint64
+	if *startFlag != 0 {
+		startBlock = *startFlag
+	} else {
+		// Parse blocks from lastBlock in file
+		// если сервис долго простаивал - парсим только последние config.MaxBlockDelta блоков от latest
+		// иначе долго будем догонять latest block, пропустим актуальные крупные ЕТН транзакции.
+		// last_block после запусков по latest может быть впереди finalized блока - это ошибка, а не повторный парсинг
+		if startBlock, err = parser.StartBlock(filtering.ReadLastBlock(config.LastBlockPath), endBlock, config.MaxBlockDelta); err != nil {
+			return err
+		}
+	}
+	if startBlock > endBlock {
+		return fmt.Errorf("start block %d is after end block %d", startBlock, endBlock)
+	}
+
+	fmt.Printf("Parsing blocks %d to %d...\n", startBlock, endBlock)
+
+	blocks, err := blockParser.ParseBlockRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return fmt.Errorf("failed to parse blocks: %w", err)
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("no blocks parsed in range %d-%d", startBlock, endBlock)
+	}
+
+	// Output summary
+	fmt.Printf("\n=== Parsing Results ===\n")
+	totalTransactions := 0
+	totalLogs := 0
+
+	for _, block := range blocks {
+		totalTransactions += len(block.Transactions)
+		for _, tx := range block.Transactions {
+			if tx.Logs != nil {
+				totalLogs += len(tx.Logs)
+			}
+		}
+		fmt.Printf("Block %d: %d transactions, %d gas used\n",
+			block.Number, len(block.Transactions), block.GasUsed)
+	}
+
+	fmt.Printf("\nTotal: %d blocks, %d transactions, %d logs\n",
+		len(blocks), totalTransactions, totalLogs)
+
+	// Show parsing stats
+	stats := blockParser.GetStats()
+	fmt.Printf("Processing time: %v\n", stats.TotalDuration)
+	fmt.Printf("Block parse time: min %v, avg %v, max %v (block %d), slow blocks: %d\n",
+		stats.MinBlockTime, stats.AvgBlockTime, stats.MaxBlockTime, stats.SlowestBlock, stats.SlowBlocks)
+	fmt.Printf("Transaction types: %s\n", formatTxTypeCounts(stats.TxTypeCounts))
+	fmt.Printf("Unknown senders: %d\n", stats.UnknownSenders)
+	if stats.StoppedByBudget {
+		fmt.Printf("Stopped early by %s, the rest of the range is parsed next run\n", stats.BudgetReason)
+	}
+	if config.AdaptiveWorkers {
+		fmt.Printf("Rate limited fetches: %d, worker limit at end: %d\n", stats.RateLimitHits, stats.WorkerLimit)
+	}
+
+	if config.DumpJsonFile {
+		jsonData, err := json.MarshalIndent(blocks, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		filename := fmt.Sprintf("blocks_%d_%d.json", startBlock, endBlock)
+		if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		fmt.Printf("Results saved to %s\n", filename)
+	}
+
+	lastBlock := blocks[len(blocks)-1].Number
+	fmt.Printf("Last block parsed: %d\n", lastBlock)
+	filtering.WriteLastBlock(config.LastBlockPath, lastBlock)
+
+	return storeParsedBlocks(ctx, dbManager, logger, config, ethClient, txSink, csvWriter, blocks, *format)
+}
+
+// watchNewBlocks parses new blocks every interval until SIGTERM or SIGINT, each cycle like a single parse run.
+// On a signal the cycle in progress is finished before returning, the lock stays held the whole time.
+// Whale txs are filtered with the config returned by tune, so updated settings apply from the next cycle.
+func watchNewBlocks(ctx context.Context, ethClient *client.EthClient, dbManager *database.DatabaseManager, logger *log.Logger, config *types.Config, tune func(context.Context) (*types.Config, error), txSink sink.TransactionSink, csvWriter *filtering.CSVWriter, format string, interval time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	multi, err := parser.NewMultiParser([]parser.Network{{Name: config.InfuraNetwork, Client: ethClient, Config: config}})
+	if err != nil {
+		return err
+	}
+	txRepo := database.NewTransactionRepository(dbManager, logger)
+
+	fmt.Printf("Watching new blocks every %v, stop with SIGTERM\n", interval)
+	err = multi.Watch(ctx, interval, func(ctx context.Context, network string, blocks []*types.ParsedBlock) error {
+		fmt.Printf("Parsed blocks %d to %d\n", blocks[0].Number, blocks[len(blocks)-1].Number)
+		tuned, err := tune(ctx)
+		if err != nil {
+			return err
+		}
+		return storeParsedBlocks(ctx, dbManager, logger, tuned, ethClient, txSink, csvWriter, blocks, format)
+	}, func(error) {
+		touchLock(lockFilePath)
+		tuned, err := tune(ctx)
+		if err != nil {
+			logger.Printf("Failed to load settings: %v", err)
+			return
+		}
+		RemoveOldTxs(ctx, txRepo, tuned.RetentionDays)
+	})
+
+	stats := multi.GetStats()
+	fmt.Printf("Stopped watching: %d blocks, %d transactions, %d errors\n",
+		stats.BlocksParsed, stats.TransactionsParsed, stats.ErrorsEncountered)
+	return err
+}
+
+// storeParsedBlocks saves parsed blocks to DB, writes their whale transactions as CSV (to csvWriter) or
+// NDJSON (format) and stores them in txSink. With FetchWhaleReceipts the whale txs without receipt data
+// get their receipts from receipts first.
+func storeParsedBlocks(ctx context.Context, dbManager *database.DatabaseManager, logger *log.Logger, config *types.Config, receipts parser.ReceiptFetcher, txSink sink.TransactionSink, csvWriter *filtering.CSVWriter, blocks []*types.ParsedBlock, format string) error {
+	addressRepo := database.NewAddressRepository(dbManager, logger)
+	blockRepo := database.NewBlockRepository(dbManager, logger)
+
+	storedBlocks := make([]*database.Block, len(blocks))
+	var parseErrs []*database.ParseError
+	for i, block := range blocks {
+		storedBlocks[i] = database.MapParsedBlockToDatabaseBlock(block)
+		for _, pe := range block.ParseErrors {
+			parseErrs = append(parseErrs, database.MapTxParseError(pe))
+		}
+	}
+	if err := blockRepo.BatchUpsert(ctx, storedBlocks); err != nil {
+		return fmt.Errorf("error inserting blocks to db: %w", err)
+	}
+	// failed txs go to the dead-letter table, retried with -reprocess-errors
+	if err := database.NewParseErrorRepository(dbManager, logger).BatchUpsert(ctx, parseErrs); err != nil {
+		return fmt.Errorf("error inserting parse errors to db: %w", err)
+	}
+
+	labels := database.NewLabelCache(addressRepo, 0)
+	whalesAddrToID, whalesAddrToLabel, err := labels.Mappings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load whale addresses: %w", err)
+	}
+	whaleThresholds, err := labels.Thresholds(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load whale thresholds: %w", err)
+	}
+	filter := filtering.NewWhaleFilter(config).WithWhaleThresholds(whaleThresholds)
+	var tx_filtered []*database.Transaction
+	if config.TrackTokens {
+		tokens, err := filtering.NewTokenFilter(config)
+		if err != nil {
+			return fmt.Errorf("failed to load tracked tokens: %w", err)
+		}
+		// ETH и переводы токенов китов вместе, токены - строки с asset = символ токена
+		tx_filtered = filtering.ActivityTransactions(filtering.ParseWhaleActivity(blocks, whalesAddrToID, filter, tokens))
+	} else {
+		tx_filtered = filtering.ParseWhaleTransactionsWithFilter(blocks, whalesAddrToID, filter)
+	}
+	fmt.Println("TX filtered", tx_filtered)
+	if config.FetchWhaleReceipts {
+		// a failed fetch leaves status/gas_used unknown, -enrich fills them later
+		fetched, err := parser.NewEnricher(receipts, nil).FetchReceipts(ctx, tx_filtered)
+		if err != nil {
+			logger.Printf("Failed to fetch whale tx receipts: %v", err)
+		} else if len(fetched) > 0 {
+			fmt.Printf("Fetched receipts of %d whale txs\n", len(fetched))
+		}
+	}
+
+	// DB gets every whale tx right away, CSV/NDJSON only confirmed ones
+	emitted := tx_filtered
+	var gate *filtering.ConfirmationGate
+	if config.MinConfirmations > 1 && len(blocks) > 0 {
+		if gate, err = filtering.LoadConfirmationGate(config.PendingPath, config.MinConfirmations); err != nil {
+			return err
+		}
+		if dropped := gate.DropReorged(blocks); dropped > 0 {
+			fmt.Printf("Dropped %d pending whale txs of reorged blocks\n", dropped)
+		}
+		gate.Add(tx_filtered)
+		// the last parsed block is the head as far as this run knows, never ahead of the chain
+		emitted = gate.Release(blocks[len(blocks)-1].Number)
+		fmt.Printf("Whale txs: %d confirmed, %d waiting for %d confirmations\n", len(emitted), gate.Pending(), config.MinConfirmations)
+	}
+	// catching up after downtime: old whale txs go to DB only, not to CSV/NDJSON
+	emitted, stale := filtering.DropStale(emitted, config.MaxAlertAge, time.Now())
+	if stale > 0 {
+		fmt.Printf("Skipped %d whale txs older than %v for CSV/NDJSON\n", stale, config.MaxAlertAge)
+	}
+
+	if format == "ndjson" {
+		if err := filtering.AppendNDJSON(config.NdjsonPath, emitted); err != nil {
+			return fmt.Errorf("error appending NDJSON: %w", err)
+		}
+		fmt.Printf("Appended %d whale txs to %s\n", len(emitted), config.NdjsonPath)
+	} else if err := writeWhaleCSV(config, csvWriter, emitted, whalesAddrToLabel); err != nil {
+		return err
+	}
+	// saved only after emitting, so released txs aren't lost on a failed write
+	if gate != nil {
+		if err := gate.Save(config.PendingPath); err != nil {
+			return err
+		}
+	}
+
+	_, toDB := txSink.(*database.TransactionRepository)
+	stored := tx_filtered
+	if toDB {
+		// the DB keeps one row per tx hash, the ETH row of a tx with token transfers
+		stored = filtering.UniqueTxHashes(tx_filtered)
+	}
+	if err := txSink.Store(ctx, stored); err != nil {
+		return fmt.Errorf("error storing whale txs: %w", err)
+	}
+	// logs belong to stored txs, a file or none sink leaves the DB without them
+	if !toDB {
+		return nil
+	}
+	return storeWhaleLogs(ctx, database.NewLogRepository(dbManager, logger), blocks, tx_filtered)
+}
+
+// storeWhaleLogs saves the event logs of whale txs for /api/transactions/{hash}/logs,
+// blocks only carry logs when parsed with IncludeLogs and receipts
+func storeWhaleLogs(ctx context.Context, logRepo *database.LogRepository, blocks []*types.ParsedBlock, txs []*database.Transaction) error {
+	whaleTxs := make(map[string]bool, len(txs))
+	for _, tx := range txs {
+		whaleTxs[tx.TxHash] = true
+	}
+
+	var logs []*database.Log
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if !whaleTxs[tx.Hash] {
+				continue
+			}
+			for _, parsedLog := range tx.Logs {
+				l, err := database.MapParsedLog(parsedLog)
+				if err != nil {
+					return err
+				}
+				logs = append(logs, l)
+			}
+		}
+	}
+	if err := logRepo.BatchInsert(ctx, logs); err != nil {
+		return fmt.Errorf("error inserting logs to db: %w", err)
+	}
+	return nil
+}
+
+// reprocessParseErrors parses the blocks of stored parse errors again. Txs that parse now are saved
+// like in a normal run and removed from parse_errors, still failing ones get one more attempt.
+func reprocessParseErrors(ctx context.Context, blockParser *parser.Parser, dbManager *database.DatabaseManager, logger *log.Logger, config *types.Config, receipts parser.ReceiptFetcher, txSink sink.TransactionSink, csvWriter *filtering.CSVWriter, format string, limit int) error {
+	parseErrRepo := database.NewParseErrorRepository(dbManager, logger)
+	stored, err := parseErrRepo.GetPending(ctx, limit)
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		fmt.Println("No parse errors to reprocess")
+		return nil
+	}
+
+	pending := make([]*types.TxParseError, len(stored))
+	for i, pe := range stored {
+		pending[i] = pe.ToTxParseError()
+	}
+	blocks, fixed, reparseErr := blockParser.ReparseErrors(ctx, pending)
+	if reparseErr != nil {
+		fmt.Printf("Some blocks failed to parse: %v\n", reparseErr)
+	}
+
+	if err := storeParsedBlocks(ctx, dbManager, logger, config, receipts, txSink, csvWriter, blocks, format); err != nil {
+		return err
+	}
+	deleted, err := parseErrRepo.DeleteByHashes(ctx, fixed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reprocessed %d parse errors: %d fixed\n", len(stored), deleted)
+	return nil
+}
+
+// writeWhaleCSV prints whale txs as CSV and appends them to the CSV file, with CsvDedup through its index
+func writeWhaleCSV(config *types.Config, csvWriter *filtering.CSVWriter, txs []*database.Transaction, whalesAddrToLabel map[string]string) error {
+	whale_txn, err := filtering.TransformTxsToCsvColumns(txs, whalesAddrToLabel, config.CsvColumns, filtering.CsvRowMode(config.CsvRowMode))
+	if err != nil {
+		return fmt.Errorf("error formatting CSV: %w", err)
+	}
+	fmt.Println(whale_txn)
+	if config.CsvDedup {
+		written, err := filtering.AppendCSVDedup(config.CsvPath, txs, whalesAddrToLabel, config.CsvColumns, filtering.CsvRowMode(config.CsvRowMode))
+		if err != nil {
+			return fmt.Errorf("error appending CSV: %w", err)
+		}
+		fmt.Printf("Appended %d new CSV rows\n", written)
+	} else if err := csvWriter.WriteRows(whale_txn); err != nil {
+		return fmt.Errorf("error appending CSV: %w", err)
+	}
+	return nil
+}
+
+// closeCSVWriter flushes and closes the CSV file at shutdown, a failure is only logged
+func closeCSVWriter(csvWriter *filtering.CSVWriter, logger *log.Logger) {
+	if err := csvWriter.Close(); err != nil {
+		logger.Printf("Failed to close CSV file: %v", err)
+	}
+}
+
+// formatTxTypeCounts prints tx type counts ordered by type, e.g. "legacy=3 dynamic_fee=10"
+func formatTxTypeCounts(counts map[uint8]uint64) string {
+	txTypes := make([]int, 0, len(counts))
+	for txType := range counts {
+		txTypes = append(txTypes, int(txType))
+	}
+	sort.Ints(txTypes)
+
+	parts := make([]string, 0, len(txTypes))
+	for _, txType := range txTypes {
+		parts = append(parts, fmt.Sprintf("%s=%d", types.TxTypeName(uint8(txType)), counts[uint8(txType)]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// дозаполнить gas_used/status из receipts для последних whale транзакций в БД
+func enrichRecentTxs(ctx context.Context, ethClient *client.EthClient, txRepo *database.TransactionRepository, limit int) error {
+	missing, err := txRepo.GetMissingReceipts(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	updated, err := parser.NewEnricher(ethClient, txRepo).EnrichTransactions(ctx, missing)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Enriched %d of %d txs without receipts\n", updated, len(missing))
+	return nil
+}
+
+// tunedConfig applies the stored settings to config, the -min-eth and -direction flags still win over them
+func tunedConfig(ctx context.Context, config *types.Config, settings parser.Settings, minETH, direction string) (*types.Config, error) {
+	tuned, err := parser.ApplySettings(ctx, config, settings)
+	if err != nil {
+		return nil, err
+	}
+	if minETH != "" {
+		tuned.MinETHDecimal = minETH
+	}
+	if direction != "" {
+		tuned.WhaleDirection = direction
+	}
+	return tuned, nil
+}
+
+// clean old txs (older then retention days) in DB
+func RemoveOldTxs(ctx context.Context, txrepo *database.TransactionRepository, retentionDays int) {
+	now := time.Now()
+	// Extract the hour and minute components
+	hour := now.Hour()
+	minute := now.Minute()
+	// clean txs at ~ 00:30
+	if hour == 0 && minute >= 30 && minute <= 33 {
+		fmt.Printf("Time %d:%d - removing old DB txns\n", hour, minute)
+		txrepo.ClearOldTxns(ctx, retentionDays)
+	}
+}
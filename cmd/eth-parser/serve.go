@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"eth-blockchain-parser/pkg/server"
+)
+
+// runServe runs the HTTP API server over the SQLite database until SIGINT/SIGTERM
+func runServe(args []string) error {
+	fs := newFlagSet("serve")
+	var (
+		dbPath   = fs.String("db", "./blockchain.db", "Path to SQLite database file")
+		journal  = fs.String("journal-mode", os.Getenv("DB_JOURNAL_MODE"), "SQLite journal mode: WAL (default), DELETE, TRUNCATE or MEMORY")
+		port     = fs.String("port", "8015", "HTTP server port")
+		host     = fs.String("host", "localhost", "HTTP server host, IPv4/IPv6 literal or hostname (\"\", 0.0.0.0 or :: bind to all interfaces)")
+		username = fs.String("username", "admin", "Basic auth username")
+		password = fs.String("password", "password123", "Basic auth password")
+
+		readTimeout  = fs.Duration("read-timeout", server.DefaultReadTimeout, "HTTP read timeout")
+		writeTimeout = fs.Duration("write-timeout", server.DefaultWriteTimeout, "HTTP write timeout")
+		idleTimeout  = fs.Duration("idle-timeout", server.DefaultIdleTimeout, "HTTP keep-alive idle timeout")
+		maxPageLimit = fs.Int("max-page-limit", server.DefaultMaxPageLimit, "Maximum page size for paginated endpoints")
+		labelTTL     = fs.Duration("label-cache-ttl", server.DefaultLabelCacheTTL, "How often whale labels are reloaded from DB")
+		txCacheSize  = fs.Int("tx-cache-size", 0, "Cache up to N confirmed GET /api/transactions/{hash} results in memory, 0 disables the cache")
+		txCacheTTL   = fs.Duration("tx-cache-ttl", server.DefaultTxCacheTTL, "How long a cached transaction is served")
+		strictParams = fs.Bool("strict-params", false, "Answer 400 to malformed numeric query params (?limit=abc) instead of using the default")
+
+		enableParse   = fs.Bool("enable-parse", false, "Enable POST /api/parse, blocks are fetched from Infura (INFURA_API_KEY)")
+		maxParseRange = fs.Uint64("max-parse-range", server.DefaultMaxParseRange, "Maximum number of blocks of one POST /api/parse")
+		parseTimeout  = fs.Duration("parse-timeout", server.DefaultParseTimeout, "Timeout of one POST /api/parse, keep below -write-timeout")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Create logger
+	logger := log.New(os.Stdout, "[HTTP-SERVER] ", log.LstdFlags|log.Lshortfile)
+	logger.Println("Starting SQLite HTTP API Server")
+
+	dbConfig, err := newDBConfig(*dbPath, *journal)
+	if err != nil {
+		return err
+	}
+	dbManager, err := connectDatabase(dbConfig, logger)
+	if err != nil {
+		return err
+	}
+	defer dbManager.Close()
+
+	//ctx := context.Background()
+
+	// Test database connection
+	if err := dbManager.Ping(); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	logger.Println("Database connection successful")
+
+	// Server configuration
+	serverConfig := &server.ServerConfig{
+		Port:     *port,
+		Host:     *host,
+		Username: *username,
+		Password: *password,
+
+		ReadTimeout:   *readTimeout,
+		WriteTimeout:  *writeTimeout,
+		IdleTimeout:   *idleTimeout,
+		MaxPageLimit:  *maxPageLimit,
+		LabelCacheTTL: *labelTTL,
+		StrictParams:  *strictParams,
+		TxCacheSize:   *txCacheSize,
+		TxCacheTTL:    *txCacheTTL,
+		MaxParseRange: *maxParseRange,
+		ParseTimeout:  *parseTimeout,
+	}
+
+	// Create HTTP server
+	httpServer := server.NewServer(dbManager, serverConfig, logger)
+	if *enableParse {
+		ethClient, config, err := newInfuraClient()
+		if err != nil {
+			return err
+		}
+		defer ethClient.Close()
+		httpServer.SetBlockClient(ethClient, config)
+		logger.Printf("On-demand parsing enabled: max %d blocks, timeout %v", *maxParseRange, *parseTimeout)
+	}
+
+	// Handle graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		logger.Println("Received shutdown signal, stopping server...")
+		os.Exit(0)
+	}()
+
+	// Start HTTP server
+	logger.Printf("Server configuration:")
+	logger.Printf("  Database: %s", *dbPath)
+	logger.Printf("  Listen: %s", httpServer.Addr())
+	logger.Printf("  Username: %s", *username)
+	logger.Printf("  Password: %s", *password)
+	logger.Printf("  Timeouts: read=%v write=%v idle=%v", *readTimeout, *writeTimeout, *idleTimeout)
+	logger.Printf("  Max page limit: %d", *maxPageLimit)
+
+	if err := httpServer.Start(); err != nil {
+		return fmt.Errorf("HTTP server failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/client"
+	"eth-blockchain-parser/pkg/parser"
+)
+
+// runTestBlock parses one block (latest by default) and prints it as JSON, for checking the RPC connection and parser.
+// With -record the raw node responses are saved as fixtures, with -fixtures the block is replayed from them offline.
+func runTestBlock(args []string) error {
+	fs := newFlagSet("test-block")
+	blockNumber := fs.Uint64("block", 0, "block number to parse (default: latest)")
+	record := fs.String("record", "", "directory to save raw block/receipt responses to as fixtures")
+	fixtures := fs.String("fixtures", "", "directory of recorded fixtures to parse from, without network (default block: latest recorded)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *record != "" && *fixtures != "" {
+		return fmt.Errorf("-record and -fixtures can't be used together")
+	}
+
+	ctx := context.Background()
+	number := *blockNumber
+
+	var source parser.BlockClient
+	var config *types.Config
+	if *fixtures != "" {
+		fixtureClient, err := client.NewFixtureClient(*fixtures)
+		if err != nil {
+			return err
+		}
+		if number == 0 {
+			if number, err = fixtureClient.GetLatestBlockNumber(ctx); err != nil {
+				return fmt.Errorf("failed to get latest recorded block: %w", err)
+			}
+		}
+		source, config = fixtureClient, types.DefaultConfig()
+	} else {
+		ethClient, infuraConfig, err := newInfuraClient()
+		if err != nil {
+			return err
+		}
+		defer ethClient.Close()
+
+		if number == 0 {
+			if number, err = ethClient.GetLatestBlockNumber(ctx); err != nil {
+				return fmt.Errorf("failed to get latest block: %w", err)
+			}
+		}
+		source, config = ethClient, infuraConfig
+		if *record != "" {
+			if source, err = client.NewRecordingClient(ethClient, *record); err != nil {
+				return err
+			}
+		}
+	}
+
+	block, err := parser.NewParser(source, config).ParseSingleBlock(ctx, number)
+	if err != nil {
+		return fmt.Errorf("failed to parse block %d: %w", number, err)
+	}
+
+	jsonData, err := json.MarshalIndent(block, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
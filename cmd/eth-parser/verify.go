@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runVerify prints the integrity report of the stored blocks and fails if gaps or hash chain breaks were found
+func runVerify(args []string) error {
+	fs := newFlagSet("verify")
+	dbPath := fs.String("db", defaultDBPath(), "Path to SQLite database file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	logger := log.New(os.Stderr, "[ETH-PARSER-DB] ", log.LstdFlags|log.Lshortfile)
+	dbManager, err := openDatabase(*dbPath, logger)
+	if err != nil {
+		return err
+	}
+	defer dbManager.Close()
+
+	report, err := dbManager.VerifyIntegrity(context.Background())
+	if err != nil {
+		return err
+	}
+
+	output, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	fmt.Println(string(output))
+
+	if !report.OK() {
+		return fmt.Errorf("integrity check failed: %d gaps (%d missing blocks), %d hash chain breaks",
+			len(report.Gaps), report.MissingBlocks, len(report.ChainBreaks))
+	}
+	return nil
+}
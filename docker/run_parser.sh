@@ -14,4 +14,4 @@ touch /var/log/eth_parser/eth_parser.log
 
 # Run the parser with logging
 echo "$(date): Starting eth parser..." >> /var/log/eth_parser/eth_parser.log
-./infura-parser 2>&1 >> /var/log/eth_parser/eth_parser.log
+./eth-parser parse 2>&1 >> /var/log/eth_parser/eth_parser.log
@@ -8,7 +8,6 @@ if [ -z "$INFURA_API_KEY" ]; then
 fi
 
 # Set default values
-export INFURA_API_KEY=${INFURA_API_KEY:-af0eb78c2b874cb2a2df3515af9182fa}
 export INFURA_NETWORK=${INFURA_NETWORK:-mainnet}
 export DB_PATH=${DB_PATH:-/app/data/blockchain.db}
 export CSV_PATH=${CSV_PATH:-/app/data/whale_txns.csv}
@@ -26,13 +25,14 @@ chmod 755 /app/data
 # Initialize database (SQLite will create file automatically)
 echo "Initializing database bash..."
 touch $DB_PATH
-su-exec appuser ./infura-parser -initw || echo "Database already initialized"
+su-exec appuser ./eth-parser init-whales -db="$DB_PATH" || echo "Database already initialized"
 
 # Create environment file for cron
 cat > /app/cron_env << EOF
 export INFURA_API_KEY="$INFURA_API_KEY"
 export INFURA_NETWORK="$INFURA_NETWORK"
 export DB_PATH="$DB_PATH"
+export DB_JOURNAL_MODE="$DB_JOURNAL_MODE"
 export CSV_PATH="$CSV_PATH"
 export LAST_BLOCK_PATH="$LAST_BLOCK_PATH"
 EOF
@@ -47,7 +47,7 @@ echo "Cron daemon started"
 
 # Start HTTP API server in background as appuser
 echo "Starting HTTP API server..."
-su-exec appuser ./server-run \
+su-exec appuser ./eth-parser serve \
     -db="$DB_PATH" \
     -port="$SERVER_PORT" \
     -host="$SERVER_HOST" \
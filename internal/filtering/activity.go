@@ -0,0 +1,256 @@
+package filtering
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+
+	"github.com/shopspring/decimal"
+)
+
+// TransferEventTopic - topic0 события ERC20 Transfer(address,address,uint256)
+const TransferEventTopic = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+
+// WhaleActivity - перевод ETH или токена с участием кита, общий формат для обоих видов
+type WhaleActivity struct {
+	Asset            string `json:"asset"`           // types.NativeAsset или символ токена
+	Token            string `json:"token,omitempty"` // адрес контракта токена, пусто для ETH
+	TxHash           string `json:"tx_hash"`
+	BlockNumber      int64  `json:"block_number"`
+	TransactionIndex int64  `json:"transaction_index"`
+	LogIndex         *int64 `json:"log_index,omitempty"` // индекс Transfer лога, nil для ETH
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Amount           string `json:"amount"`        // в единицах Asset, округлено до 5 знаков
+	TransferType     string `json:"transfer_type"` // FROM, TO, INT, SELF; пусто для вызовов MethodSelectors без кита
+	WhaleAddressID   *int64 `json:"whale_address_id"`
+
+	// строка транзакции для CSV/NDJSON/sink: whale транзакция для ETH, для токена - транзакция
+	// со сторонами и суммой перевода, Asset/TokenAddress токена
+	tx *database.Transaction
+}
+
+// key - ключ для удаления дублей: транзакция, актив и лог
+func (a *WhaleActivity) key() string {
+	logIndex := int64(-1)
+	if a.LogIndex != nil {
+		logIndex = *a.LogIndex
+	}
+	return fmt.Sprintf("%s/%s/%d", a.TxHash, a.Asset, logIndex)
+}
+
+// TokenFilter - какие токены отслеживать и с какой суммы
+type TokenFilter struct {
+	// отслеживаемые токены, пустой реестр - токены не ищем
+	Tokens types.TokenRegistry
+	// минимальная сумма в целых токенах по символу (USDT: 1000000), токен без порога - любой перевод кита
+	MinAmounts map[string]decimal.Decimal
+}
+
+// NewTokenFilter - фильтр токенов из конфига: реестр из TokensPath (по умолчанию types.DefaultTokens)
+// и пороги TokenMinAmounts в целых токенах. Без TrackTokens - пустой фильтр, токены не ищем.
+func NewTokenFilter(config *types.Config) (TokenFilter, error) {
+	if !config.TrackTokens {
+		return TokenFilter{}, nil
+	}
+	tokens := types.DefaultTokens()
+	if config.TokensPath != "" {
+		var err error
+		if tokens, err = types.TokenRegistryFromFile(config.TokensPath); err != nil {
+			return TokenFilter{}, err
+		}
+	}
+
+	minAmounts := make(map[string]decimal.Decimal, len(config.TokenMinAmounts))
+	for symbol, value := range config.TokenMinAmounts {
+		amount, err := decimal.NewFromString(strings.TrimSpace(value))
+		if err != nil {
+			return TokenFilter{}, fmt.Errorf("invalid %s min amount %q: %w", symbol, value, err)
+		}
+		if amount.IsNegative() {
+			return TokenFilter{}, fmt.Errorf("invalid %s min amount %q: must not be negative", symbol, value)
+		}
+		minAmounts[strings.ToUpper(strings.TrimSpace(symbol))] = amount
+	}
+	return TokenFilter{Tokens: tokens, MinAmounts: minAmounts}, nil
+}
+
+// minRaw - порог токена в минимальных единицах с учетом decimals
+func (f TokenFilter) minRaw(meta types.TokenMeta) *big.Int {
+	return f.MinAmounts[meta.Symbol].Shift(int32(meta.Decimals)).Ceil().BigInt()
+}
+
+// ParseWhaleActivity ищет в одних и тех же блоках whale транзакции в ETH (по filter, как
+// ParseWhaleTransactionsWithFilter) и ERC20 переводы китов (по tokens, из логов транзакций -
+// нужен IncludeLogs). Результат без дублей, по порядку блоков, транзакций и логов, ETH перед токенами.
+func ParseWhaleActivity(blocks []*types.ParsedBlock, whalesAddrsID map[string]string,
+	filter WhaleFilter, tokens TokenFilter) []*WhaleActivity {
+
+	var activity []*WhaleActivity
+	for _, tx := range ParseWhaleTransactionsWithFilter(blocks, whalesAddrsID, filter) {
+		to := ""
+		if tx.ToAddress != nil {
+			to = *tx.ToAddress
+		}
+		activity = append(activity, &WhaleActivity{
+			Asset:            types.NativeAsset,
+			TxHash:           tx.TxHash,
+			BlockNumber:      tx.BlockNumber,
+			TransactionIndex: tx.TransactionIndex,
+			From:             tx.FromAddress,
+			To:               to,
+			Amount:           tx.Value,
+			TransferType:     tx.TransferType,
+			WhaleAddressID:   tx.WhaleAddressID,
+			tx:               tx,
+		})
+	}
+	if len(tokens.Tokens) > 0 {
+		activity = append(activity, parseWhaleTokenTransfers(blocks, whalesAddrsID, filter, tokens)...)
+	}
+
+	seen := make(map[string]bool, len(activity))
+	unique := activity[:0]
+	for _, a := range activity {
+		if key := a.key(); !seen[key] {
+			seen[key] = true
+			unique = append(unique, a)
+		}
+	}
+
+	sort.SliceStable(unique, func(i, j int) bool {
+		a, b := unique[i], unique[j]
+		if a.BlockNumber != b.BlockNumber {
+			return a.BlockNumber < b.BlockNumber
+		}
+		if a.TransactionIndex != b.TransactionIndex {
+			return a.TransactionIndex < b.TransactionIndex
+		}
+		// ETH (без лога) перед токенами
+		if (a.LogIndex == nil) != (b.LogIndex == nil) {
+			return a.LogIndex == nil
+		}
+		return a.LogIndex != nil && *a.LogIndex < *b.LogIndex
+	})
+	return unique
+}
+
+// ActivityTransactions - строки транзакций ParseWhaleActivity для CSV/NDJSON/sink в том же порядке:
+// ETH - whale транзакции, переводы токенов - с Asset/TokenAddress и суммой в токенах
+func ActivityTransactions(activity []*WhaleActivity) []*database.Transaction {
+	txs := make([]*database.Transaction, 0, len(activity))
+	for _, a := range activity {
+		txs = append(txs, a.tx)
+	}
+	return txs
+}
+
+// UniqueTxHashes - первая строка каждой транзакции: в таблице transactions одна строка на tx_hash,
+// у транзакции с ETH и переводами токенов в БД остается ETH строка (ActivityTransactions ставит ее первой)
+func UniqueTxHashes(txs []*database.Transaction) []*database.Transaction {
+	seen := make(map[string]bool, len(txs))
+	unique := make([]*database.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if !seen[tx.TxHash] {
+			seen[tx.TxHash] = true
+			unique = append(unique, tx)
+		}
+	}
+	return unique
+}
+
+// parseWhaleTokenTransfers - ERC20 Transfer логи отслеживаемых токенов, где отправитель или получатель кит.
+// Сторона кита и направление считаются как для ETH, фильтр по газу к токенам не применяется.
+func parseWhaleTokenTransfers(blocks []*types.ParsedBlock, whalesAddrsID map[string]string,
+	filter WhaleFilter, tokens TokenFilter) []*WhaleActivity {
+
+	var res []*WhaleActivity
+	for _, blk := range blocks {
+		for _, txn := range blk.Transactions {
+			for _, lg := range txn.Logs {
+				from, to, amount, ok := decodeTransferLog(lg)
+				if !ok {
+					continue
+				}
+				meta, tracked := tokens.Tokens.Lookup(lg.Address)
+				if !tracked || amount.Cmp(tokens.minRaw(meta)) < 0 {
+					continue
+				}
+
+				whale_id, is_from := whalesAddrsID[from]
+				whale_to_id, is_to := whalesAddrsID[to]
+				tx_dest := ""
+				switch {
+				case is_from && is_to && from == to:
+					tx_dest = "SELF"
+				case is_from && is_to:
+					tx_dest, whale_id = "INT", whale_to_id
+				case is_from:
+					tx_dest = "FROM"
+				case is_to:
+					tx_dest, whale_id = "TO", whale_to_id
+				}
+				if tx_dest == "" || !filter.matchesDirection(tx_dest) {
+					continue
+				}
+
+				value := types.FormatTokenAmount(amount, meta)
+				// перевод токена как отдельная транзакция: участники и актив берутся из лога
+				token := types.NormalizeAddress(lg.Address)
+				transfer := *txn
+				transfer.From, transfer.To = from, &to
+				transfer.Asset, transfer.TokenAddress = meta.Symbol, &token
+				db_tx, err := database.MapParsedTxToDatabaseTx(&transfer, value, tx_dest, whale_id)
+				if err != nil {
+					log.Printf("Skipping %s transfer in tx %s: can't resolve whale ID %q: %v", meta.Symbol, txn.Hash, whale_id, err)
+					continue
+				}
+				filter.stamp(db_tx, blk)
+
+				logIndex := int64(lg.LogIndex)
+				res = append(res, &WhaleActivity{
+					Asset:            meta.Symbol,
+					Token:            token,
+					TxHash:           txn.Hash,
+					BlockNumber:      int64(txn.BlockNumber),
+					TransactionIndex: int64(txn.TransactionIndex),
+					LogIndex:         &logIndex,
+					From:             from,
+					To:               to,
+					Amount:           value,
+					TransferType:     tx_dest,
+					WhaleAddressID:   db_tx.WhaleAddressID,
+					tx:               db_tx,
+				})
+			}
+		}
+	}
+	return res
+}
+
+// decodeTransferLog разбирает ERC20 Transfer: адреса из topics[1..2], сумма из data.
+// ERC721 Transfer (tokenId в topics[3]) и удаленные при реорге логи пропускаются.
+func decodeTransferLog(lg *types.ParsedLog) (from, to string, amount *big.Int, ok bool) {
+	if lg == nil || lg.Removed || len(lg.Topics) != 3 || !strings.EqualFold(lg.Topics[0], TransferEventTopic) {
+		return "", "", nil, false
+	}
+	amount, ok = new(big.Int).SetString(strings.TrimPrefix(lg.Data, "0x"), 16)
+	if !ok {
+		return "", "", nil, false
+	}
+	return topicAddress(lg.Topics[1]), topicAddress(lg.Topics[2]), amount, true
+}
+
+// topicAddress - адрес из 32-байтного topic (последние 20 байт), нормализованный как types.NormalizeAddress
+func topicAddress(topic string) string {
+	topic = strings.TrimPrefix(types.NormalizeAddress(topic), "0x")
+	if len(topic) > 40 {
+		topic = topic[len(topic)-40:]
+	}
+	return "0x" + topic
+}
@@ -0,0 +1,248 @@
+package filtering
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"eth-blockchain-parser/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	activityWhale = "0x1234567890abcdef1234567890abcdef12345678"
+	activityOther = "0x9999999999999999999999999999999999999999"
+	usdtAddress   = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+)
+
+// transferLog builds an ERC20 Transfer log of a raw amount
+func transferLog(token, from, to string, amount int64, logIndex uint) *types.ParsedLog {
+	pad := func(addr string) string { return "0x000000000000000000000000" + addr[2:] }
+	return &types.ParsedLog{
+		Address:  token,
+		Topics:   []string{TransferEventTopic, pad(from), pad(to)},
+		Data:     fmt.Sprintf("%064x", amount),
+		LogIndex: logIndex,
+	}
+}
+
+func activityKeys(activity []*WhaleActivity) []string {
+	keys := make([]string, len(activity))
+	for i, a := range activity {
+		keys[i] = fmt.Sprintf("%s %s %s %s", a.TxHash, a.Asset, a.Amount, a.TransferType)
+	}
+	return keys
+}
+
+// activityBlock - a block with an ETH whale tx, token transfers of the whale and transfers to skip
+func activityBlock() *types.ParsedBlock {
+	return &types.ParsedBlock{
+		Number: 100,
+		Transactions: []*types.ParsedTransaction{
+			// 2 ETH withdrawal by the whale
+			{Hash: "0xeth", BlockNumber: 100, TransactionIndex: 0, From: activityWhale, To: stringPtr(activityOther), Value: big.NewInt(2e18)},
+			// token transfer call: 0 ETH, the whale receives 5000 USDT and sends 10 USDT
+			{Hash: "0xusdt", BlockNumber: 100, TransactionIndex: 1, From: activityOther, To: stringPtr(usdtAddress), Value: big.NewInt(0), Logs: []*types.ParsedLog{
+				transferLog(usdtAddress, activityOther, activityWhale, 5_000_000_000, 3),
+				transferLog(usdtAddress, activityWhale, activityOther, 10_000_000, 4),
+			}},
+			// untracked token and a transfer without whales
+			{Hash: "0xother", BlockNumber: 100, TransactionIndex: 2, From: activityOther, To: stringPtr(activityOther), Value: big.NewInt(0), Logs: []*types.ParsedLog{
+				transferLog("0x1111111111111111111111111111111111111111", activityWhale, activityOther, 1_000_000_000, 5),
+				transferLog(usdtAddress, activityOther, activityOther, 1_000_000_000, 6),
+			}},
+		},
+	}
+}
+
+// TestParseWhaleActivity tests a block with both an ETH whale tx and token whale transfers
+func TestParseWhaleActivity(t *testing.T) {
+	whaleAddressIDs := map[string]string{activityWhale: "1"}
+	block := activityBlock()
+
+	tests := []struct {
+		name     string
+		filter   WhaleFilter
+		tokens   TokenFilter
+		expected []string
+	}{
+		{
+			name:     "ETH only",
+			filter:   WhaleFilter{MinETH: 1},
+			expected: []string{"0xeth ETH 2 FROM"},
+		},
+		{
+			name:     "ETH and every token transfer",
+			filter:   WhaleFilter{MinETH: 1},
+			tokens:   TokenFilter{Tokens: types.DefaultTokens()},
+			expected: []string{"0xeth ETH 2 FROM", "0xusdt USDT 5000 TO", "0xusdt USDT 10 FROM"},
+		},
+		{
+			name:     "Token threshold",
+			filter:   WhaleFilter{MinETH: 1},
+			tokens:   TokenFilter{Tokens: types.DefaultTokens(), MinAmounts: map[string]decimal.Decimal{"USDT": decimal.NewFromInt(1000)}},
+			expected: []string{"0xeth ETH 2 FROM", "0xusdt USDT 5000 TO"},
+		},
+		{
+			name:     "Direction applies to tokens",
+			filter:   WhaleFilter{MinETH: 1, Direction: DirectionTo},
+			tokens:   TokenFilter{Tokens: types.DefaultTokens()},
+			expected: []string{"0xusdt USDT 5000 TO"},
+		},
+		{
+			name:     "ETH threshold above, tokens only",
+			filter:   WhaleFilter{MinETH: 10},
+			tokens:   TokenFilter{Tokens: types.DefaultTokens()},
+			expected: []string{"0xusdt USDT 5000 TO", "0xusdt USDT 10 FROM"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the same block twice, e.g. an overlapping re-run, must not duplicate results
+			activity := ParseWhaleActivity([]*types.ParsedBlock{block, block}, whaleAddressIDs, tt.filter, tt.tokens)
+			if keys := activityKeys(activity); !reflect.DeepEqual(keys, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, keys)
+			}
+			for _, a := range activity {
+				if a.WhaleAddressID == nil || *a.WhaleAddressID != 1 {
+					t.Errorf("Expected whale ID 1 for %s %s, got %v", a.TxHash, a.Asset, a.WhaleAddressID)
+				}
+				if (a.Asset == types.NativeAsset) != (a.LogIndex == nil && a.Token == "") {
+					t.Errorf("Expected log index and token only on token transfers, got %+v", a)
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeTransferLog tests skipping logs that are not ERC20 transfers
+func TestDecodeTransferLog(t *testing.T) {
+	valid := transferLog(usdtAddress, activityWhale, activityOther, 42, 0)
+	nft := transferLog(usdtAddress, activityWhale, activityOther, 42, 0)
+	nft.Topics = append(nft.Topics, "0x01")
+	removed := transferLog(usdtAddress, activityWhale, activityOther, 42, 0)
+	removed.Removed = true
+	approval := transferLog(usdtAddress, activityWhale, activityOther, 42, 0)
+	approval.Topics[0] = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+	prefixed := transferLog(usdtAddress, activityWhale, activityOther, 42, 0)
+	prefixed.Data = "0x" + prefixed.Data
+
+	tests := []struct {
+		name   string
+		log    *types.ParsedLog
+		wantOK bool
+	}{
+		{"ERC20 transfer", valid, true},
+		{"Data with 0x", prefixed, true},
+		{"ERC721 transfer", nft, false},
+		{"Removed by reorg", removed, false},
+		{"Approval", approval, false},
+		{"Nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, amount, ok := decodeTransferLog(tt.log)
+			if ok != tt.wantOK {
+				t.Fatalf("Expected ok %v, got %v", tt.wantOK, ok)
+			}
+			if ok && (from != activityWhale || to != activityOther || amount.Int64() != 42) {
+				t.Errorf("Expected %s -> %s 42, got %s -> %s %v", activityWhale, activityOther, from, to, amount)
+			}
+		})
+	}
+}
+
+// TestActivityTransactions tests the CSV/NDJSON/sink rows of combined ETH and token activity
+func TestActivityTransactions(t *testing.T) {
+	whaleAddressIDs := map[string]string{activityWhale: "1"}
+	activity := ParseWhaleActivity([]*types.ParsedBlock{activityBlock()}, whaleAddressIDs,
+		WhaleFilter{MinETH: 1}, TokenFilter{Tokens: types.DefaultTokens()})
+	txs := ActivityTransactions(activity)
+
+	expected := []string{
+		"0xeth ETH <nil> 2 FROM " + activityWhale + " " + activityOther,
+		"0xusdt USDT " + usdtAddress + " 5000 TO " + activityOther + " " + activityWhale,
+		"0xusdt USDT " + usdtAddress + " 10 FROM " + activityWhale + " " + activityOther,
+	}
+	keys := make([]string, len(txs))
+	for i, tx := range txs {
+		token := "<nil>"
+		if tx.TokenAddress != nil {
+			token = *tx.TokenAddress
+		}
+		keys[i] = fmt.Sprintf("%s %s %s %s %s %s %s", tx.TxHash, tx.Asset, token, tx.Value, tx.TransferType, tx.FromAddress, *tx.ToAddress)
+		if tx.WhaleAddressID == nil || *tx.WhaleAddressID != 1 {
+			t.Errorf("Expected whale ID 1 for %s %s, got %v", tx.TxHash, tx.Asset, tx.WhaleAddressID)
+		}
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("Expected %v, got %v", expected, keys)
+	}
+
+	csv, err := TransformTxsToCsvColumns(txs, map[string]string{activityWhale: "Whale"}, []string{"tx_hash", "value", "asset", "direction"}, CsvRowPerSide)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expectedCsv := "\"0xeth\",\"2 ETH\",\"ETH\",\"FROM\"\n" +
+		"\"0xusdt\",\"5000 USDT\",\"USDT\",\"TO\"\n" +
+		"\"0xusdt\",\"10 USDT\",\"USDT\",\"FROM\"\n"
+	if csv != expectedCsv {
+		t.Errorf("Expected CSV %q, got %q", expectedCsv, csv)
+	}
+
+	// the DB keeps one row per tx hash
+	if unique := UniqueTxHashes(txs); len(unique) != 2 || unique[0] != txs[0] || unique[1] != txs[1] {
+		t.Errorf("Expected the first row of each tx hash, got %d rows", len(unique))
+	}
+}
+
+// TestNewTokenFilter tests loading tracked tokens and thresholds from the config
+func TestNewTokenFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.csv")
+	if err := os.WriteFile(path, []byte("0x2260fac5e5542a773aa44fbcfedf7c193bc2c599,WBTC,8\n"), 0644); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		track      bool
+		path       string
+		minAmounts map[string]string
+		tokens     int
+		minUSDT    string
+		expectErr  bool
+	}{
+		{"Disabled", false, path, map[string]string{"USDT": "x"}, 0, "0", false},
+		{"Default tokens", true, "", nil, len(types.DefaultTokens()), "0", false},
+		{"Token file", true, path, nil, 1, "0", false},
+		{"Thresholds by symbol", true, "", map[string]string{" usdt ": "1000000.5"}, len(types.DefaultTokens()), "1000000.5", false},
+		{"Invalid threshold", true, "", map[string]string{"USDT": "a lot"}, 0, "0", true},
+		{"Negative threshold", true, "", map[string]string{"USDT": "-1"}, 0, "0", true},
+		{"Missing token file", true, filepath.Join(t.TempDir(), "missing.csv"), nil, 0, "0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.TrackTokens = tt.track
+			config.TokensPath = tt.path
+			config.TokenMinAmounts = tt.minAmounts
+
+			filter, err := NewTokenFilter(config)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("Expected error %v, got %v", tt.expectErr, err)
+			}
+			if len(filter.Tokens) != tt.tokens {
+				t.Errorf("Expected %d tokens, got %d", tt.tokens, len(filter.Tokens))
+			}
+			if got := filter.MinAmounts["USDT"].String(); got != tt.minUSDT {
+				t.Errorf("Expected USDT threshold %s, got %s", tt.minUSDT, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package filtering
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+)
+
+// ConfirmationGate придерживает whale транзакции до minConfirmations подтверждений их блока,
+// чтобы не писать в CSV/NDJSON транзакции, которые исчезнут при реорге.
+// Блок head имеет 1 подтверждение, minConfirmations <= 1 пропускает все транзакции сразу.
+type ConfirmationGate struct {
+	minConfirmations uint64
+	pending          map[string]*database.Transaction // tx_hash -> ожидающая подтверждений транзакция
+}
+
+// NewConfirmationGate создает пустой буфер
+func NewConfirmationGate(minConfirmations uint64) *ConfirmationGate {
+	return &ConfirmationGate{
+		minConfirmations: minConfirmations,
+		pending:          make(map[string]*database.Transaction),
+	}
+}
+
+// LoadConfirmationGate создает буфер с транзакциями, сохраненными в NDJSON файл прошлым запуском,
+// отсутствующий файл - пустой буфер
+func LoadConfirmationGate(filename string, minConfirmations uint64) (*ConfirmationGate, error) {
+	g := NewConfirmationGate(minConfirmations)
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return g, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open pending txs file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024) // input_data может быть длинной
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var tx database.Transaction
+		if err := json.Unmarshal(scanner.Bytes(), &tx); err != nil {
+			return nil, fmt.Errorf("failed to decode pending tx: %w", err)
+		}
+		g.pending[tx.TxHash] = &tx
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pending txs file: %w", err)
+	}
+	return g, nil
+}
+
+// Save перезаписывает NDJSON файл текущими ожидающими транзакциями
+func (g *ConfirmationGate) Save(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create pending txs file: %w", err)
+	}
+	if err := WriteTransactionsNDJSON(file, sortByBlock(g.pendingTxs())); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Add добавляет транзакции в буфер, повторно распарсенная транзакция заменяет сохраненную
+func (g *ConfirmationGate) Add(txs []*database.Transaction) {
+	for _, tx := range txs {
+		g.pending[tx.TxHash] = tx
+	}
+}
+
+// DropReorged удаляет из буфера транзакции блоков, которые распарсены заново с другим хэшем -
+// блок заменен реоргом, транзакции из него могут не попасть в новую цепочку.
+// Возвращает число удаленных транзакций.
+func (g *ConfirmationGate) DropReorged(blocks []*types.ParsedBlock) int {
+	hashes := make(map[int64]string, len(blocks))
+	for _, block := range blocks {
+		hashes[int64(block.Number)] = block.Hash
+	}
+
+	dropped := 0
+	for hash, tx := range g.pending {
+		if blockHash, ok := hashes[tx.BlockNumber]; ok && blockHash != tx.BlockHash {
+			delete(g.pending, hash)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// Release возвращает транзакции, набравшие minConfirmations при текущем head, и удаляет их из буфера.
+// Транзакции отсортированы по блоку и индексу в блоке.
+func (g *ConfirmationGate) Release(head uint64) []*database.Transaction {
+	var released []*database.Transaction
+	for hash, tx := range g.pending {
+		if g.confirmed(tx, head) {
+			released = append(released, tx)
+			delete(g.pending, hash)
+		}
+	}
+	return sortByBlock(released)
+}
+
+// Pending - число транзакций, ожидающих подтверждений
+func (g *ConfirmationGate) Pending() int {
+	return len(g.pending)
+}
+
+// confirmed - набрал ли блок транзакции minConfirmations, подтверждения = head - block + 1
+func (g *ConfirmationGate) confirmed(tx *database.Transaction, head uint64) bool {
+	if g.minConfirmations <= 1 {
+		return true
+	}
+	block := uint64(tx.BlockNumber)
+	return head >= block && head-block+1 >= g.minConfirmations
+}
+
+func (g *ConfirmationGate) pendingTxs() []*database.Transaction {
+	txs := make([]*database.Transaction, 0, len(g.pending))
+	for _, tx := range g.pending {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// DropStale убирает транзакции, блок которых старше maxAge на момент now, - при догоняющем парсинге
+// после простоя они уже не нужны как алерты (в БД сохраняются все). Транзакции без времени блока
+// остаются, maxAge <= 0 отключает проверку. Возвращает свежие транзакции и число убранных.
+func DropStale(txs []*database.Transaction, maxAge time.Duration, now time.Time) ([]*database.Transaction, int) {
+	if maxAge <= 0 {
+		return txs, 0
+	}
+	fresh := make([]*database.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if tx.BlockTime != nil && now.Sub(*tx.BlockTime) > maxAge {
+			continue
+		}
+		fresh = append(fresh, tx)
+	}
+	return fresh, len(txs) - len(fresh)
+}
+
+// sortByBlock сортирует транзакции по блоку и индексу в блоке
+func sortByBlock(txs []*database.Transaction) []*database.Transaction {
+	sort.Slice(txs, func(i, j int) bool {
+		if txs[i].BlockNumber != txs[j].BlockNumber {
+			return txs[i].BlockNumber < txs[j].BlockNumber
+		}
+		return txs[i].TransactionIndex < txs[j].TransactionIndex
+	})
+	return txs
+}
@@ -0,0 +1,174 @@
+package filtering
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+)
+
+// whaleTxAt creates a whale tx in the given block
+func whaleTxAt(block int64, index int64) *database.Transaction {
+	return &database.Transaction{
+		TxHash:           fmt.Sprintf("0xtx_%d_%d", block, index),
+		BlockNumber:      block,
+		BlockHash:        fmt.Sprintf("0xblock_%d", block),
+		TransactionIndex: index,
+		Value:            "10",
+	}
+}
+
+func txHashes(txs []*database.Transaction) []string {
+	hashes := make([]string, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.TxHash
+	}
+	return hashes
+}
+
+// TestConfirmationGate tests that whale txs are held back until their block has enough
+// confirmations and released in block order as the head advances
+func TestConfirmationGate(t *testing.T) {
+	gate := NewConfirmationGate(3)
+
+	// head advances one block per run, each run adds the whale txs of its new block
+	steps := []struct {
+		head     uint64
+		added    []*database.Transaction
+		released []string
+		pending  int
+	}{
+		{head: 100, added: []*database.Transaction{whaleTxAt(100, 1), whaleTxAt(100, 0)}, released: []string{}, pending: 2},
+		{head: 101, added: []*database.Transaction{whaleTxAt(101, 0)}, released: []string{}, pending: 3},
+		{head: 102, added: nil, released: []string{"0xtx_100_0", "0xtx_100_1"}, pending: 1},
+		{head: 103, added: []*database.Transaction{whaleTxAt(103, 0)}, released: []string{"0xtx_101_0"}, pending: 1},
+		// head jumps ahead: everything old enough goes at once
+		{head: 110, added: []*database.Transaction{whaleTxAt(110, 0)}, released: []string{"0xtx_103_0"}, pending: 1},
+	}
+
+	for _, step := range steps {
+		t.Run(fmt.Sprintf("Head %d", step.head), func(t *testing.T) {
+			gate.Add(step.added)
+			released := txHashes(gate.Release(step.head))
+			if fmt.Sprint(released) != fmt.Sprint(step.released) {
+				t.Errorf("Expected released %v, got %v", step.released, released)
+			}
+			if gate.Pending() != step.pending {
+				t.Errorf("Expected %d pending, got %d", step.pending, gate.Pending())
+			}
+		})
+	}
+}
+
+// TestConfirmationGateNoWait tests that 0 or 1 confirmations release every tx right away
+func TestConfirmationGateNoWait(t *testing.T) {
+	for _, minConfirmations := range []uint64{0, 1} {
+		gate := NewConfirmationGate(minConfirmations)
+		gate.Add([]*database.Transaction{whaleTxAt(100, 0)})
+		if released := gate.Release(100); len(released) != 1 {
+			t.Errorf("Expected tx released with min %d confirmations, got %d", minConfirmations, len(released))
+		}
+	}
+}
+
+// TestConfirmationGateReorg tests that pending txs of a block parsed again with another hash are dropped
+func TestConfirmationGateReorg(t *testing.T) {
+	gate := NewConfirmationGate(3)
+	gate.Add([]*database.Transaction{whaleTxAt(100, 0), whaleTxAt(101, 0)})
+
+	dropped := gate.DropReorged([]*types.ParsedBlock{
+		{Number: 100, Hash: "0xblock_100"},       // same block, kept
+		{Number: 101, Hash: "0xblock_101_uncle"}, // replaced by a reorg
+	})
+	if dropped != 1 {
+		t.Errorf("Expected 1 dropped tx, got %d", dropped)
+	}
+
+	released := txHashes(gate.Release(105))
+	if fmt.Sprint(released) != fmt.Sprint([]string{"0xtx_100_0"}) {
+		t.Errorf("Expected only 0xtx_100_0 released, got %v", released)
+	}
+}
+
+// TestConfirmationGatePersistence tests that pending txs survive between runs via the pending file
+func TestConfirmationGatePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending.ndjson")
+
+	gate, err := LoadConfirmationGate(path, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error for missing file: %v", err)
+	}
+	gate.Add([]*database.Transaction{whaleTxAt(100, 0), whaleTxAt(101, 0)})
+	if err := gate.Save(path); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	// next run
+	gate, err = LoadConfirmationGate(path, 3)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if gate.Pending() != 2 {
+		t.Fatalf("Expected 2 pending txs loaded, got %d", gate.Pending())
+	}
+	released := gate.Release(102)
+	if len(released) != 1 || released[0].TxHash != "0xtx_100_0" || released[0].Value != "10" {
+		t.Errorf("Expected loaded 0xtx_100_0 released intact, got %+v", released)
+	}
+	if err := gate.Save(path); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	gate, err = LoadConfirmationGate(path, 3)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if gate.Pending() != 1 {
+		t.Errorf("Expected released tx removed from file, got %d pending", gate.Pending())
+	}
+}
+
+// TestDropStale tests that whale txs of old blocks are dropped from CSV/NDJSON output and fresh ones kept
+func TestDropStale(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	at := func(tx *database.Transaction, age time.Duration) *database.Transaction {
+		blockTime := now.Add(-age)
+		tx.BlockTime = &blockTime
+		return tx
+	}
+	old := at(whaleTxAt(100, 0), 3*time.Hour)
+	fresh := at(whaleTxAt(200, 0), time.Minute)
+	noTime := whaleTxAt(150, 0)
+	txs := []*database.Transaction{old, fresh, noTime}
+
+	tests := []struct {
+		name            string
+		maxAge          time.Duration
+		expectedHashes  []string
+		expectedDropped int
+	}{
+		{"Disabled", 0, []string{old.TxHash, fresh.TxHash, noTime.TxHash}, 0},
+		{"Old tx dropped", time.Hour, []string{fresh.TxHash, noTime.TxHash}, 1},
+		{"Both within age", 4 * time.Hour, []string{old.TxHash, fresh.TxHash, noTime.TxHash}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := DropStale(txs, tt.maxAge, now)
+			var hashes []string
+			for _, tx := range kept {
+				hashes = append(hashes, tx.TxHash)
+			}
+			if !reflect.DeepEqual(hashes, tt.expectedHashes) {
+				t.Errorf("Expected %v, got %v", tt.expectedHashes, hashes)
+			}
+			if dropped != tt.expectedDropped {
+				t.Errorf("Expected %d dropped, got %d", tt.expectedDropped, dropped)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package filtering
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// CSVWriter дописывает строки в CSV файл, открытый один раз на весь запуск (или -watch),
+// вместо открытия файла на каждый AppendCSV. Безопасен для одновременной записи из нескольких
+// горутин: строки одного WriteRows пишутся целиком, без перемешивания со строками других вызовов.
+type CSVWriter struct {
+	mu   sync.Mutex
+	file *os.File
+	path string
+}
+
+// NewCSVWriter открывает файл на дозапись, файл создается при отсутствии
+func NewCSVWriter(filename string) (*CSVWriter, error) {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	return &CSVWriter{file: file, path: filename}, nil
+}
+
+// Path - путь к CSV файлу
+func (w *CSVWriter) Path() string {
+	return w.path
+}
+
+// WriteRows дописывает строки CSV (формат TransformTxsToCsvColumns) одной записью в файл,
+// недостающий перевод строки в конце добавляется, чтобы следующий вызов начал новую строку
+func (w *CSVWriter) WriteRows(csv string) error {
+	if csv == "" {
+		return nil
+	}
+	if !strings.HasSuffix(csv, "\n") {
+		csv += "\n"
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return fmt.Errorf("failed to write CSV rows to %s: %w", w.path, os.ErrClosed)
+	}
+	if _, err := w.file.WriteString(csv); err != nil {
+		return fmt.Errorf("failed to write CSV rows to %s: %w", w.path, err)
+	}
+	return nil
+}
+
+// Close сбрасывает записанное на диск и закрывает файл, повторный вызов ничего не делает
+func (w *CSVWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file = nil
+
+	syncErr := file.Sync()
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close CSV file %s: %w", w.path, err)
+	}
+	if syncErr != nil {
+		return fmt.Errorf("failed to flush CSV file %s: %w", w.path, syncErr)
+	}
+	return nil
+}
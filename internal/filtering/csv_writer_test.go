@@ -0,0 +1,115 @@
+package filtering
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestCSVWriterConcurrent tests that concurrent WriteRows calls keep their rows whole and together, run with -race
+func TestCSVWriterConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "whales.csv")
+	writer, err := NewCSVWriter(path)
+	if err != nil {
+		t.Fatalf("Failed to open writer: %v", err)
+	}
+
+	const writers, calls, rowsPerCall = 8, 50, 3
+	// long rows so a split write would show up as a broken line
+	padding := strings.Repeat("x", 512)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for c := 0; c < calls; c++ {
+				csv := ""
+				for r := 0; r < rowsPerCall; r++ {
+					csv += fmt.Sprintf("\"%d\",\"%d\",\"%d\",\"%s\"\n", w, c, r, padding)
+				}
+				if err := writer.WriteRows(csv); err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != writers*calls*rowsPerCall {
+		t.Fatalf("Expected %d lines, got %d", writers*calls*rowsPerCall, len(lines))
+	}
+	for i := 0; i < len(lines); i += rowsPerCall {
+		var w, c int
+		for r := 0; r < rowsPerCall; r++ {
+			var lw, lc, lr int
+			var rest string
+			if _, err := fmt.Sscanf(lines[i+r], "\"%d\",\"%d\",\"%d\",%s", &lw, &lc, &lr, &rest); err != nil || rest != "\""+padding+"\"" {
+				t.Fatalf("Expected a well-formed line %d, got %q (%v)", i+r, lines[i+r], err)
+			}
+			if r == 0 {
+				w, c = lw, lc
+			}
+			if lw != w || lc != c || lr != r {
+				t.Fatalf("Expected row %d of writer %d call %d at line %d, got %q", r, w, c, i+r, lines[i+r])
+			}
+		}
+	}
+}
+
+// TestCSVWriterAppendAndClose tests appending to an existing file, the missing trailing newline and writes after Close
+func TestCSVWriterAppendAndClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "whales.csv")
+	if err := os.WriteFile(path, []byte("\"old\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	writer, err := NewCSVWriter(path)
+	if err != nil {
+		t.Fatalf("Failed to open writer: %v", err)
+	}
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"Row", "\"a\"\n"},
+		{"Empty", ""},
+		{"No trailing newline", "\"b\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := writer.WriteRows(tt.csv); err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Errorf("Expected second Close to be a no-op, got %v", err)
+	}
+	if err := writer.WriteRows("\"c\"\n"); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected os.ErrClosed after Close, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	expected := "\"old\"\n\"a\"\n\"b\"\n"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
@@ -2,12 +2,16 @@ package filtering
 
 import (
 	"bufio"
+	"encoding/hex"
+	"encoding/json"
 	"eth-blockchain-parser/internal/types"
 	"eth-blockchain-parser/pkg/database"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -73,6 +77,8 @@ func ReadLastBlock(filename string) uint64 {
 }
 
 // добавить строки в CSV файл
+//
+// Deprecated: открывает файл на каждый вызов и не защищен от одновременной записи - используйте CSVWriter
 func AppendCSV(filename string, csv string) bool {
 	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
 	if err != nil {
@@ -89,59 +95,380 @@ func AppendCSV(filename string, csv string) bool {
 
 // вывести число ЕТН с 5 знаками, из gwei / 10 ** 18
 func gweiToETH(gwei big.Int) string {
-	str := gwei.String()
-	val, err := decimal.NewFromString(str)
+	return types.FormatUnits(&gwei, types.ETHDecimals, types.ETHValuePrecision)
+}
+
+// WhaleFilter holds the thresholds used when picking whale transactions
+type WhaleFilter struct {
+	// минимальная сумма в ETH, дробная (0.5, 2.5); если 0 - используется MinETH
+	MinValue decimal.Decimal
+	// Deprecated: только целые ETH, оставлено для совместимости - используйте MinValue
+	MinETH uint64
+	// gas price range in wei, nil - no limit
+	MinGasPrice *big.Int
+	MaxGasPrice *big.Int
+	// txs with priority fee (gas price for legacy txs) >= HighPriorityFee are flagged, nil - disabled
+	HighPriorityFee *big.Int
+	// сторона кита: DirectionFrom - только выводы, DirectionTo - только депозиты, "" или DirectionBoth - все
+	Direction string
+	// 4-байтные селекторы методов (a9059cbb) из ParseMethodSelector: вызовы этих методов попадают
+	// в результат при любой сумме, в том числе без кита (пустой TransferType)
+	MethodSelectors []string
+	// свои пороги китов в ETH по адресу в нижнем регистре, вместо MinValue для их транзакций
+	WhaleMinValues map[string]decimal.Decimal
+	// пропускать переводы кита самому себе (TransferType SELF), иначе они сохраняются с пометкой SELF
+	SkipSelfTransfers bool
+	// брать транзакции кита с нулевой суммой (вызовы контрактов) при любом пороге, сумма сохраняется как 0;
+	// ненулевые суммы ниже порога по-прежнему пропускаются
+	IncludeZeroValue bool
+	// источник created_at/updated_at найденных транзакций, nil - types.RealClock
+	Clock types.Clock
+}
+
+// now - текущее время по Clock фильтра
+func (f WhaleFilter) now() time.Time {
+	if f.Clock == nil {
+		return types.RealClock.Now()
+	}
+	return f.Clock.Now()
+}
+
+// stamp заполняет время создания записи по Clock фильтра и время блока транзакции
+func (f WhaleFilter) stamp(tx *database.Transaction, blk *types.ParsedBlock) {
+	now := f.now()
+	tx.CreatedAt, tx.UpdatedAt = now, now
+	if !blk.Timestamp.IsZero() {
+		blockTime := blk.Timestamp
+		tx.BlockTime = &blockTime
+	}
+}
+
+// IsSelfTransfer - перевод на свой же адрес (from == to без учета регистра), для алертов это шум
+func IsSelfTransfer(txn *types.ParsedTransaction) bool {
+	return txn.To != nil && types.NormalizeAddress(txn.From) == types.NormalizeAddress(*txn.To)
+}
+
+// WithWhaleThresholds - копия фильтра с порогами китов из БД (адрес -> "2.5", LabelCache.Thresholds),
+// некорректные пороги пропускаются с предупреждением, для таких китов остается общий порог
+func (f WhaleFilter) WithWhaleThresholds(thresholds map[string]string) WhaleFilter {
+	if len(thresholds) == 0 {
+		return f
+	}
+	f.WhaleMinValues = make(map[string]decimal.Decimal, len(thresholds))
+	for addr, value := range thresholds {
+		minValue, err := ParseMinETH(value)
+		if err != nil {
+			log.Printf("Warning: whale %s: %v, using the global threshold", addr, err)
+			continue
+		}
+		f.WhaleMinValues[types.NormalizeAddress(addr)] = minValue
+	}
+	return f
+}
+
+// ParseMethodSelector проверяет 4-байтный селектор метода ("0xa9059cbb" или "a9059cbb"),
+// возвращает его в нижнем регистре без 0x - в формате ParsedTransaction.InputData
+func ParseMethodSelector(value string) (string, error) {
+	selector := strings.ToLower(strings.TrimSpace(value))
+	selector = strings.TrimPrefix(selector, "0x")
+	if len(selector) != 8 {
+		return "", fmt.Errorf("invalid method selector %q, expected 4 bytes like 0xa9059cbb", value)
+	}
+	if _, err := hex.DecodeString(selector); err != nil {
+		return "", fmt.Errorf("invalid method selector %q: %w", value, err)
+	}
+	return selector, nil
+}
+
+// matchesMethod - начинается ли calldata транзакции с одного из MethodSelectors
+func (f WhaleFilter) matchesMethod(txn *types.ParsedTransaction) bool {
+	if len(f.MethodSelectors) == 0 {
+		return false
+	}
+	input := strings.ToLower(strings.TrimPrefix(txn.InputData, "0x"))
+	for _, selector := range f.MethodSelectors {
+		if strings.HasPrefix(input, selector) {
+			return true
+		}
+	}
+	return false
+}
+
+// направления whale транзакций для WhaleFilter.Direction
+const (
+	DirectionFrom = "from" // кит отправитель (FROM) - вывод с биржи, сигнал накопления
+	DirectionTo   = "to"   // кит получатель (TO) - депозит на биржу, сигнал продажи
+	DirectionBoth = "both"
+)
+
+// ParseDirection проверяет направление из конфига или флага, пустая строка - DirectionBoth
+func ParseDirection(value string) (string, error) {
+	direction := strings.ToLower(strings.TrimSpace(value))
+	switch direction {
+	case "":
+		return DirectionBoth, nil
+	case DirectionFrom, DirectionTo, DirectionBoth:
+		return direction, nil
+	}
+	return "", fmt.Errorf("invalid whale direction %q, expected from, to or both", value)
+}
+
+// matchesDirection - подходит ли сторона транзакции (FROM/TO/INT/SELF) под Direction и SkipSelfTransfers,
+// INT (перевод между китами) и SELF (кит сам себе) - и вывод, и депозит, проходят при любом направлении
+func (f WhaleFilter) matchesDirection(txDest string) bool {
+	if txDest == "SELF" {
+		return !f.SkipSelfTransfers
+	}
+	switch f.Direction {
+	case DirectionFrom:
+		return txDest == "FROM" || txDest == "INT"
+	case DirectionTo:
+		return txDest == "TO" || txDest == "INT"
+	}
+	return true
+}
+
+// keepsZeroValue - транзакция с нулевой суммой, в которой участвует кит, при включенном IncludeZeroValue
+func (f WhaleFilter) keepsZeroValue(txn *types.ParsedTransaction, whalesAddrsID map[string]string) bool {
+	if !f.IncludeZeroValue || (txn.Value != nil && txn.Value.Sign() != 0) {
+		return false
+	}
+	if _, ok := whalesAddrsID[types.NormalizeAddress(txn.From)]; ok {
+		return true
+	}
+	if txn.To == nil {
+		return false
+	}
+	_, ok := whalesAddrsID[types.NormalizeAddress(*txn.To)]
+	return ok
+}
+
+// ParseMinETH разбирает дробный порог в ETH ("0.5", "2.5"), отрицательные значения запрещены
+func ParseMinETH(value string) (decimal.Decimal, error) {
+	minValue, err := decimal.NewFromString(strings.TrimSpace(value))
 	if err != nil {
-		fmt.Println("ERROR ", err)
-		return "0"
+		return decimal.Zero, fmt.Errorf("invalid ETH amount %q: %w", value, err)
+	}
+	if minValue.IsNegative() {
+		return decimal.Zero, fmt.Errorf("invalid ETH amount %q: must not be negative", value)
+	}
+	return minValue, nil
+}
+
+// minValue - порог в ETH: MinValue, либо MinETH для старых вызовов
+func (f WhaleFilter) minValue() decimal.Decimal {
+	if !f.MinValue.IsZero() {
+		return f.MinValue
+	}
+	return decimal.NewFromInt(int64(f.MinETH))
+}
+
+// minWei - порог в wei для сравнения через big.Int без дробей, дробный остаток wei округляется вверх
+func (f WhaleFilter) minWei() *big.Int {
+	return ethToWei(f.minValue())
+}
+
+// whaleMinWei - WhaleMinValues в wei
+func (f WhaleFilter) whaleMinWei() map[string]*big.Int {
+	res := make(map[string]*big.Int, len(f.WhaleMinValues))
+	for addr, minValue := range f.WhaleMinValues {
+		res[addr] = ethToWei(minValue)
+	}
+	return res
+}
+
+func ethToWei(eth decimal.Decimal) *big.Int {
+	return eth.Shift(18).Ceil().BigInt()
+}
+
+// txMinWei - порог транзакции: порог кита-участника (свой или общий minWei), при переводе между
+// китами - меньший из двух; транзакции без китов сравниваются с общим порогом
+func txMinWei(txn *types.ParsedTransaction, whalesAddrsID map[string]string, minWei *big.Int, whaleMinWei map[string]*big.Int) *big.Int {
+	if len(whaleMinWei) == 0 {
+		return minWei
+	}
+	parties := []string{types.NormalizeAddress(txn.From)}
+	if txn.To != nil {
+		parties = append(parties, types.NormalizeAddress(*txn.To))
+	}
+	var res *big.Int
+	for _, addr := range parties {
+		if _, ok := whalesAddrsID[addr]; !ok {
+			continue
+		}
+		threshold, ok := whaleMinWei[addr]
+		if !ok {
+			threshold = minWei
+		}
+		if res == nil || threshold.Cmp(res) < 0 {
+			res = threshold
+		}
+	}
+	if res == nil {
+		return minWei
 	}
-	val = val.Shift(-18)
-	val = val.Round(5)
-	res := fmt.Sprintf("%s", val)
 	return res
 }
 
+// собрать WhaleFilter из конфига, газ в конфиге задается в gwei
+func NewWhaleFilter(config *types.Config) WhaleFilter {
+	var minValue decimal.Decimal
+	if config.MinETHDecimal != "" {
+		parsed, err := ParseMinETH(config.MinETHDecimal)
+		if err != nil {
+			log.Printf("Warning: %v, using min_eth_value %d", err, config.MinETHValue)
+		} else {
+			minValue = parsed
+		}
+	}
+	direction, err := ParseDirection(config.WhaleDirection)
+	if err != nil {
+		log.Printf("Warning: %v, using %s", err, DirectionBoth)
+		direction = DirectionBoth
+	}
+	var selectors []string
+	for _, value := range config.MethodSelectors {
+		selector, err := ParseMethodSelector(value)
+		if err != nil {
+			log.Printf("Warning: %v, skipping", err)
+			continue
+		}
+		selectors = append(selectors, selector)
+	}
+	return WhaleFilter{
+		MinValue:          minValue,
+		MinETH:            config.MinETHValue,
+		MinGasPrice:       gweiToWei(config.MinGasPriceGwei),
+		MaxGasPrice:       gweiToWei(config.MaxGasPriceGwei),
+		HighPriorityFee:   gweiToWei(config.HighPriorityFeeGwei),
+		Direction:         direction,
+		MethodSelectors:   selectors,
+		SkipSelfTransfers: config.SkipSelfTransfers,
+		IncludeZeroValue:  config.IncludeZeroValue,
+	}
+}
+
+// gweiToWei converts a gwei config value to wei, 0 means "not set" and returns nil
+func gweiToWei(gwei uint64) *big.Int {
+	if gwei == 0 {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(gwei), big.NewInt(1000000000))
+}
+
+// gasPriceInRange checks the tx gas price against MinGasPrice/MaxGasPrice
+func (f WhaleFilter) gasPriceInRange(txn *types.ParsedTransaction) bool {
+	if f.MinGasPrice == nil && f.MaxGasPrice == nil {
+		return true
+	}
+	gasPrice := txn.GasPrice
+	if gasPrice == nil {
+		gasPrice = big.NewInt(0)
+	}
+	if f.MinGasPrice != nil && gasPrice.Cmp(f.MinGasPrice) < 0 {
+		return false
+	}
+	if f.MaxGasPrice != nil && gasPrice.Cmp(f.MaxGasPrice) > 0 {
+		return false
+	}
+	return true
+}
+
+// isHighPriority checks MaxPriorityFeePerGas for EIP-1559 txs, GasPrice for legacy ones
+func (f WhaleFilter) isHighPriority(txn *types.ParsedTransaction) bool {
+	if f.HighPriorityFee == nil {
+		return false
+	}
+	fee := txn.MaxPriorityFeePerGas
+	if fee == nil {
+		fee = txn.GasPrice
+	}
+	return fee != nil && fee.Cmp(f.HighPriorityFee) >= 0
+}
+
+// ParseWhaleTransactions - whale транзакции с общим порогом minETH, свои пороги китов задаются
+// через WhaleFilter.WhaleMinValues в ParseWhaleTransactionsWithFilter
 func ParseWhaleTransactions(blocks []*types.ParsedBlock, whalesAddrsID map[string]string,
 	minETH uint64) []*database.Transaction {
+	return ParseWhaleTransactionsWithFilter(blocks, whalesAddrsID, WhaleFilter{MinETH: minETH})
+}
+
+// то же что ParseWhaleTransactions, но с фильтрами по газу и пометкой high-priority транзакций
+func ParseWhaleTransactionsWithFilter(blocks []*types.ParsedBlock, whalesAddrsID map[string]string,
+	filter WhaleFilter) []*database.Transaction {
 
+	minWei := filter.minWei()
+	whaleMinWei := filter.whaleMinWei()
 	fmt.Println("Started parsing WHALE from/to transactions to []")
 	// value 1.12345, from/to, whale_id
 	res := make([]*database.Transaction, 0)
 	for _, blk := range blocks {
+		// блок восстановлен частично - whale транзакции в нем могли быть пропущены
+		if blk.Degraded {
+			log.Printf("Warning: block %d is degraded, whale txs may be missing: %s", blk.Number, blk.DegradedReason)
+		}
 		for _, txn := range blk.Transactions {
-			whale_id, is_from := whalesAddrsID[strings.ToLower(txn.From)]
-			tx_value := gweiToETH(*txn.Value)
-			tx_dest := ""
-			sum_tx, err := strconv.ParseFloat(tx_value, 64)
-			// пропускаем транзакции c value < minETH
-			if err != nil || sum_tx < float64(minETH) {
+			whale_id, is_from := whalesAddrsID[types.NormalizeAddress(txn.From)]
+			whale_addr := txn.From
+			// вызовы отслеживаемых методов берем при любой сумме
+			is_call := filter.matchesMethod(txn)
+			// пропускаем транзакции c value < порога кита (minETH, если своего нет), сравниваем в wei,
+			// строка ETH только для вывода; нулевые суммы китов - при IncludeZeroValue
+			if !is_call && !filter.keepsZeroValue(txn, whalesAddrsID) &&
+				(txn.Value == nil || txn.Value.Cmp(txMinWei(txn, whalesAddrsID, minWei, whaleMinWei)) < 0) {
 				continue
 			}
-			now := time.Now()
-			formattedTime := now.Format("2006-01-02 15:04:05")
+			value := txn.Value
+			if value == nil {
+				value = big.NewInt(0)
+			}
+			tx_value := gweiToETH(*value)
+			tx_dest := ""
 
 			if is_from {
 				tx_dest = "FROM"
 			}
 			// txn.To == nil - при транзакции с созданием контракта, проверка
 			if txn.To != nil {
-				whale_to_id, is_to := whalesAddrsID[strings.ToLower(*txn.To)]
+				whale_to_id, is_to := whalesAddrsID[types.NormalizeAddress(*txn.To)]
 				if is_to {
 					whale_id = whale_to_id
+					whale_addr = *txn.To
 					tx_dest = "TO"
 					if is_from && is_to {
 						tx_dest = "INT"
+						if IsSelfTransfer(txn) {
+							tx_dest = "SELF"
+						}
 					}
 				}
 			}
+			if tx_dest != "" && (!filter.matchesDirection(tx_dest) || !filter.gasPriceInRange(txn)) {
+				if !is_call {
+					continue
+				}
+				// вызов метода не подошел по стороне кита или газу - сохраняем как вызов без кита
+				tx_dest = ""
+			}
+			if tx_dest == "" && is_call {
+				db_tx, _ := database.MapParsedTxToDatabaseTx(txn, tx_value)
+				db_tx.HighPriority = filter.isHighPriority(txn)
+				filter.stamp(db_tx, blk)
+				res = append(res, db_tx)
+				continue
+			}
 			if tx_dest != "" {
 				// map to db.Transaction
 				tx_params := []string{tx_value, tx_dest, whale_id}
 				db_tx, err := database.MapParsedTxToDatabaseTx(txn, tx_params...)
+				// не удалось получить whale_id (метка или пустая строка вместо ID) - не сохраняем транзакцию с битым ID
 				if err != nil {
-					fmt.Println("ERROR mapping tx", txn.Hash)
+					log.Printf("Skipping tx %s: can't resolve whale ID %q for address %s: %v", txn.Hash, whale_id, whale_addr, err)
+					continue
 				}
-				fmt.Println(tx_dest, formattedTime, db_tx, err)
+				db_tx.HighPriority = filter.isHighPriority(txn)
+				filter.stamp(db_tx, blk)
+				fmt.Println(tx_dest, blk.Timestamp.Format("2006-01-02 15:04:05"), db_tx, err)
 				res = append(res, db_tx)
 			}
 		}
@@ -150,24 +477,299 @@ func ParseWhaleTransactions(blocks []*types.ParsedBlock, whalesAddrsID map[strin
 	return res
 }
 
+// DefaultCsvColumns - колонки CSV по умолчанию (исходный формат из 7 колонок)
+var DefaultCsvColumns = []string{"url", "value", "direction", "address", "label", "time", "block"}
+
+// CsvRowMode - сколько строк CSV дает перевод между двумя китами
+type CsvRowMode string
+
+const (
+	// CsvRowPerSide - строка на каждую сторону с китом: перевод кит -> кит дает две строки, FROM и TO,
+	// с адресом и label своей стороны. Режим по умолчанию, исходный формат CSV.
+	CsvRowPerSide CsvRowMode = "per_side"
+	// CsvRowPerTx - одна строка на транзакцию, как в БД: у перевода кит -> кит direction INT
+	// (SELF - самому себе), address - отправитель, label - "отправитель -> получатель"
+	CsvRowPerTx CsvRowMode = "per_tx"
+)
+
+// ValidateCsvRowMode проверяет режим строк CSV, пустой - CsvRowPerSide
+func ValidateCsvRowMode(mode CsvRowMode) error {
+	switch mode {
+	case "", CsvRowPerSide, CsvRowPerTx:
+		return nil
+	}
+	return fmt.Errorf("unknown CSV row mode %q, known modes: %s, %s", mode, CsvRowPerSide, CsvRowPerTx)
+}
+
+// csvRow - одна строка CSV: транзакция и сторона (FROM/TO), по которой найден кит,
+// с CsvRowPerTx у перевода между китами - INT/SELF
+type csvRow struct {
+	tx        *database.Transaction
+	direction string
+	address   string
+	label     string
+	time      string
+}
+
+// csvColumns - известные колонки CSV и их значения
+var csvColumns = map[string]func(r csvRow) string{
+	"url":     func(r csvRow) string { return "https://etherscan.io/tx/" + r.tx.TxHash },
+	"tx_hash": func(r csvRow) string { return r.tx.TxHash },
+	"value":   func(r csvRow) string { return r.tx.Value + " " + txAsset(r.tx) },
+	// value_eth - число без единиц для таблиц, value_grouped - с разделителями тысяч: 12,345.5 ETH
+	"value_eth":     func(r csvRow) string { return r.tx.Value },
+	"value_grouped": func(r csvRow) string { return groupThousands(r.tx.Value) + " " + txAsset(r.tx) },
+	"direction":     func(r csvRow) string { return r.direction },
+	"address":       func(r csvRow) string { return r.address },
+	"label":         func(r csvRow) string { return r.label },
+	"time":          func(r csvRow) string { return r.time },
+	"block":         func(r csvRow) string { return strconv.FormatInt(r.tx.BlockNumber, 10) },
+	"from":          func(r csvRow) string { return r.tx.FromAddress },
+	"to": func(r csvRow) string {
+		if r.tx.ToAddress == nil {
+			return ""
+		}
+		return *r.tx.ToAddress
+	},
+	"gas":       func(r csvRow) string { return strconv.FormatInt(r.tx.Gas, 10) },
+	"gas_price": func(r csvRow) string { return r.tx.GasPrice },
+	"tx_type":   func(r csvRow) string { return strconv.Itoa(r.tx.TxType) },
+	// asset - ETH или символ токена, value у переводов токенов в токенах
+	"asset": func(r csvRow) string { return txAsset(r.tx) },
+}
+
+// txAsset - актив транзакции, ETH для строк без asset (прочитанных из старой БД)
+func txAsset(tx *database.Transaction) string {
+	if tx.Asset == "" {
+		return types.NativeAsset
+	}
+	return tx.Asset
+}
+
+// groupThousands добавляет запятые между тысячами в целую часть числа: 12345.67 -> 12,345.67
+func groupThousands(value string) string {
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign, value = "-", value[1:]
+	}
+	intPart, fraction, hasFraction := strings.Cut(value, ".")
+
+	var b strings.Builder
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFraction {
+		b.WriteString("." + fraction)
+	}
+	return sign + b.String()
+}
+
+// ValidateCsvColumns проверяет, что все колонки известны; пустой список - формат по умолчанию
+func ValidateCsvColumns(columns []string) error {
+	for _, col := range columns {
+		if _, ok := csvColumns[col]; !ok {
+			known := make([]string, 0, len(csvColumns))
+			for name := range csvColumns {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("unknown CSV column %q, known columns: %s", col, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
 // перевод txs в формат CSV - используем результат ParseWhaleTransactions
 func TransformTxsToCsv(txs []*database.Transaction, whalesAddrs map[string]string) string {
+	res, _ := TransformTxsToCsvColumns(txs, whalesAddrs, DefaultCsvColumns, CsvRowPerSide)
+	return res
+}
+
+// TransformTxsToCsvColumns - как TransformTxsToCsv, но с заданным набором и порядком колонок
+// и режимом строк для переводов между китами
+func TransformTxsToCsvColumns(txs []*database.Transaction, whalesAddrs map[string]string, columns []string, mode CsvRowMode) (string, error) {
+	if len(columns) == 0 {
+		columns = DefaultCsvColumns
+	}
+	if err := ValidateCsvColumns(columns); err != nil {
+		return "", err
+	}
+	if err := ValidateCsvRowMode(mode); err != nil {
+		return "", err
+	}
+
 	res := ""
+	for _, row := range csvRows(txs, whalesAddrs, mode) {
+		res += formatCsvRow(row, columns)
+	}
+	return res, nil
+}
+
+// csvClock - время строк CSV для транзакций без времени блока (прочитанных из БД), подменяется в тестах
+var csvClock types.Clock = types.RealClock
+
+// csvRows - строки CSV: по одной на каждую сторону транзакции (FROM/TO), где есть кит,
+// с CsvRowPerTx перевод между китами - одной строкой INT/SELF;
+// колонка time - время блока транзакции, без него - время записи
+func csvRows(txs []*database.Transaction, whalesAddrs map[string]string, mode CsvRowMode) []csvRow {
+	var rows []csvRow
 	for _, tx := range txs {
-		from_name, is_from := whalesAddrs[strings.ToLower(tx.FromAddress)]
-		now := time.Now()
-		formattedTime := now.Format("2006-01-02 15:04:05")
-		if is_from {
-			res += fmt.Sprintf("\"https://etherscan.io/tx/%s\",\"%s ETH\",\"FROM\",\"%s\",\"%s\",\"%s\",\"%d\"\n",
-				tx.TxHash, tx.Value, tx.FromAddress, from_name, formattedTime, tx.BlockNumber)
+		txTime := csvClock.Now()
+		if tx.BlockTime != nil {
+			txTime = *tx.BlockTime
 		}
+		formattedTime := txTime.Format("2006-01-02 15:04:05")
+		from_name, is_from := whalesAddrs[types.NormalizeAddress(tx.FromAddress)]
+		to_name, is_to := "", false
 		if tx.ToAddress != nil {
-			to_name, is_to := whalesAddrs[strings.ToLower(*tx.ToAddress)]
-			if is_to {
-				res += fmt.Sprintf("\"https://etherscan.io/tx/%s\",\"%s ETH\",\"TO\",\"%s\",\"%s\",\"%s\",\"%d\"\n",
-					tx.TxHash, tx.Value, *tx.ToAddress, to_name, formattedTime, tx.BlockNumber)
+			to_name, is_to = whalesAddrs[types.NormalizeAddress(*tx.ToAddress)]
+		}
+		if mode == CsvRowPerTx && is_from && is_to {
+			row := csvRow{tx: tx, direction: "INT", address: tx.FromAddress, label: from_name + " -> " + to_name, time: formattedTime}
+			if types.NormalizeAddress(tx.FromAddress) == types.NormalizeAddress(*tx.ToAddress) {
+				row.direction, row.label = "SELF", from_name
 			}
+			rows = append(rows, row)
+			continue
+		}
+		if is_from {
+			rows = append(rows, csvRow{tx: tx, direction: "FROM", address: tx.FromAddress, label: from_name, time: formattedTime})
+		}
+		if is_to {
+			rows = append(rows, csvRow{tx: tx, direction: "TO", address: *tx.ToAddress, label: to_name, time: formattedTime})
 		}
 	}
-	return res
+	return rows
+}
+
+// csvIndexPath - путь к sidecar индексу уже записанных в CSV строк
+func csvIndexPath(filename string) string {
+	return filename + ".idx"
+}
+
+// key - ключ строки в индексе: хэш транзакции и сторона, у переводов токенов еще символ токена
+func (r csvRow) key() string {
+	if asset := txAsset(r.tx); asset != types.NativeAsset {
+		return r.tx.TxHash + "," + r.direction + "," + asset
+	}
+	return r.tx.TxHash + "," + r.direction
+}
+
+// readCsvIndex читает ключи уже записанных строк, отсутствующий индекс - пустой
+func readCsvIndex(filename string) (map[string]bool, error) {
+	written := make(map[string]bool)
+	file, err := os.Open(csvIndexPath(filename))
+	if os.IsNotExist(err) {
+		return written, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV index: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			written[line] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read CSV index: %w", err)
+	}
+	return written, nil
+}
+
+// AppendCSVDedup дописывает в CSV только строки, которых еще нет в файле - повторный запуск
+// по тем же блокам не дублирует строки. Записанные строки (tx_hash,FROM/TO) хранятся в
+// sidecar индексе <filename>.idx, т.к. набор колонок CSV может не содержать хэш.
+// Возвращает число записанных строк.
+// Ключ строки включает direction, поэтому смена mode между запусками может повторить переводы между китами.
+func AppendCSVDedup(filename string, txs []*database.Transaction, whalesAddrs map[string]string, columns []string, mode CsvRowMode) (int, error) {
+	if len(columns) == 0 {
+		columns = DefaultCsvColumns
+	}
+	if err := ValidateCsvColumns(columns); err != nil {
+		return 0, err
+	}
+	if err := ValidateCsvRowMode(mode); err != nil {
+		return 0, err
+	}
+
+	written, err := readCsvIndex(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	csv := ""
+	index := ""
+	count := 0
+	for _, row := range csvRows(txs, whalesAddrs, mode) {
+		if written[row.key()] {
+			continue
+		}
+		written[row.key()] = true
+		csv += formatCsvRow(row, columns)
+		index += row.key() + "\n"
+		count++
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	if err := appendFile(filename, csv); err != nil {
+		return 0, fmt.Errorf("failed to append CSV: %w", err)
+	}
+	if err := appendFile(csvIndexPath(filename), index); err != nil {
+		return 0, fmt.Errorf("failed to append CSV index: %w", err)
+	}
+	return count, nil
+}
+
+// appendFile дописывает content в конец файла, создавая его при необходимости
+func appendFile(filename string, content string) error {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	return err
+}
+
+// WriteTransactionsNDJSON пишет транзакции в формате JSON Lines - один JSON объект на строку,
+// чтобы внешние инструменты могли обрабатывать записи по одной
+func WriteTransactionsNDJSON(w io.Writer, txs []*database.Transaction) error {
+	encoder := json.NewEncoder(w)
+	for _, tx := range txs {
+		if err := encoder.Encode(tx); err != nil {
+			return fmt.Errorf("failed to encode tx %s: %w", tx.TxHash, err)
+		}
+	}
+	return nil
+}
+
+// AppendNDJSON дописывает транзакции в NDJSON файл, файл создается при отсутствии
+func AppendNDJSON(filename string, txs []*database.Transaction) error {
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open NDJSON file: %w", err)
+	}
+	if err := WriteTransactionsNDJSON(file, txs); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// formatCsvRow - значения колонок в кавычках через запятую
+func formatCsvRow(row csvRow, columns []string) string {
+	fields := make([]string, len(columns))
+	for i, col := range columns {
+		fields[i] = "\"" + csvColumns[col](row) + "\""
+	}
+	return strings.Join(fields, ",") + "\n"
 }
@@ -1,14 +1,20 @@
 package filtering
 
 import (
+	"bytes"
+	"encoding/json"
 	"eth-blockchain-parser/internal/types"
 	"eth-blockchain-parser/pkg/database"
 	"math/big"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 // TestGweiToETH tests the gweiToETH conversion function
@@ -356,8 +362,8 @@ func TestParseWhaleTransactions(t *testing.T) {
 				if tx.Value == "" || tx.Value == "0" {
 					t.Error("Transaction value should not be empty or zero for whale transactions")
 				}
-				if tx.WhaleAddressID == 0 {
-					t.Error("WhaleAddressID should not be zero for whale transactions")
+				if tx.WhaleAddressID == nil {
+					t.Error("WhaleAddressID should be set for whale transactions")
 				}
 				if tx.TransferType == "" {
 					t.Error("TransferType should not be empty for whale transactions")
@@ -409,6 +415,680 @@ func TestParseWhaleTransactionsEdgeCases(t *testing.T) {
 	}
 }
 
+// TestParseWhaleTransactionsGasFilter tests gas price range filtering and high-priority flagging
+func TestParseWhaleTransactionsGasFilter(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	whaleAddressIDs := map[string]string{whale: "1"}
+	gwei := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), big.NewInt(1000000000)) }
+
+	blocks := []*types.ParsedBlock{
+		{
+			Number: 18500000,
+			Transactions: []*types.ParsedTransaction{
+				{
+					Hash:                 "0xlowgas",
+					From:                 whale,
+					To:                   stringPtr("0xregularuser1"),
+					Value:                big.NewInt(2000000000000000000),
+					GasPrice:             gwei(10),
+					MaxPriorityFeePerGas: gwei(1),
+				},
+				{
+					Hash:                 "0xhighgas",
+					From:                 whale,
+					To:                   stringPtr("0xregularuser2"),
+					Value:                big.NewInt(2000000000000000000),
+					GasPrice:             gwei(150),
+					MaxPriorityFeePerGas: gwei(50),
+				},
+				{
+					Hash:     "0xlegacyhighgas",
+					From:     "0xregularuser3",
+					To:       stringPtr(whale),
+					Value:    big.NewInt(2000000000000000000),
+					GasPrice: gwei(80),
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		filter           WhaleFilter
+		expectedHashes   []string
+		expectedPriority map[string]bool
+	}{
+		{
+			name:             "No gas filter",
+			filter:           WhaleFilter{MinETH: 1},
+			expectedHashes:   []string{"0xlowgas", "0xhighgas", "0xlegacyhighgas"},
+			expectedPriority: map[string]bool{},
+		},
+		{
+			name:             "Flag high priority",
+			filter:           WhaleFilter{MinETH: 1, HighPriorityFee: gwei(20)},
+			expectedHashes:   []string{"0xlowgas", "0xhighgas", "0xlegacyhighgas"},
+			expectedPriority: map[string]bool{"0xhighgas": true, "0xlegacyhighgas": true},
+		},
+		{
+			name:             "Min gas price",
+			filter:           WhaleFilter{MinETH: 1, MinGasPrice: gwei(50)},
+			expectedHashes:   []string{"0xhighgas", "0xlegacyhighgas"},
+			expectedPriority: map[string]bool{},
+		},
+		{
+			name:             "Max gas price",
+			filter:           WhaleFilter{MinETH: 1, MaxGasPrice: gwei(100)},
+			expectedHashes:   []string{"0xlowgas", "0xlegacyhighgas"},
+			expectedPriority: map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, tt.filter)
+
+			if len(result) != len(tt.expectedHashes) {
+				t.Fatalf("Expected %d transactions, got %d", len(tt.expectedHashes), len(result))
+			}
+			for i, tx := range result {
+				if tx.TxHash != tt.expectedHashes[i] {
+					t.Errorf("Expected tx %s at %d, got %s", tt.expectedHashes[i], i, tx.TxHash)
+				}
+				if tx.HighPriority != tt.expectedPriority[tx.TxHash] {
+					t.Errorf("Expected HighPriority=%v for %s, got %v",
+						tt.expectedPriority[tx.TxHash], tx.TxHash, tx.HighPriority)
+				}
+			}
+		})
+	}
+}
+
+// TestParseWhaleTransactionsDecimalMin tests fractional ETH thresholds and the uint64 MinETH shim
+func TestParseWhaleTransactionsDecimalMin(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	whaleAddressIDs := map[string]string{whale: "1"}
+	eth := func(value string) *big.Int { return decimal.RequireFromString(value).Shift(18).BigInt() }
+
+	var txs []*types.ParsedTransaction
+	for _, value := range []string{"0.4", "0.5", "1", "2.499999", "2.5", "3"} {
+		txs = append(txs, &types.ParsedTransaction{
+			Hash:  "0x" + value,
+			From:  whale,
+			To:    stringPtr("0xregularuser"),
+			Value: eth(value),
+		})
+	}
+	blocks := []*types.ParsedBlock{{Number: 18500000, Transactions: txs}}
+
+	tests := []struct {
+		name           string
+		filter         WhaleFilter
+		expectedHashes []string
+	}{
+		{
+			name:           "0.5 ETH",
+			filter:         WhaleFilter{MinValue: decimal.RequireFromString("0.5")},
+			expectedHashes: []string{"0x0.5", "0x1", "0x2.499999", "0x2.5", "0x3"},
+		},
+		{
+			name:           "2.5 ETH, 2.499999 is not rounded up",
+			filter:         WhaleFilter{MinValue: decimal.RequireFromString("2.5")},
+			expectedHashes: []string{"0x2.5", "0x3"},
+		},
+		{
+			name:           "uint64 shim",
+			filter:         WhaleFilter{MinETH: 1},
+			expectedHashes: []string{"0x1", "0x2.499999", "0x2.5", "0x3"},
+		},
+		{
+			name:           "MinValue overrides MinETH",
+			filter:         WhaleFilter{MinValue: decimal.RequireFromString("0.5"), MinETH: 3},
+			expectedHashes: []string{"0x0.5", "0x1", "0x2.499999", "0x2.5", "0x3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, tt.filter)
+
+			if len(result) != len(tt.expectedHashes) {
+				t.Fatalf("Expected %d transactions, got %d", len(tt.expectedHashes), len(result))
+			}
+			for i, tx := range result {
+				if tx.TxHash != tt.expectedHashes[i] {
+					t.Errorf("Expected tx %s at %d, got %s", tt.expectedHashes[i], i, tx.TxHash)
+				}
+			}
+		})
+	}
+}
+
+// TestParseWhaleTransactionsWeiThreshold tests the threshold exactly and 1 wei below/above it,
+// differences a float ETH value can't represent
+func TestParseWhaleTransactionsWeiThreshold(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	whaleAddressIDs := map[string]string{whale: "1"}
+	wei := func(value string) *big.Int { return decimal.RequireFromString(value).BigInt() }
+
+	tests := []struct {
+		name     string
+		minValue string
+		values   []string // wei
+		expected []string
+	}{
+		{
+			name:     "1 ETH",
+			minValue: "1",
+			values:   []string{"999999999999999999", "1000000000000000000", "1000000000000000001"},
+			expected: []string{"1000000000000000000", "1000000000000000001"},
+		},
+		{
+			name:     "Large threshold with wei precision",
+			minValue: "1000000.000000000000000001",
+			values:   []string{"1000000000000000000000000", "1000000000000000000000001", "1000000000000000000000002"},
+			expected: []string{"1000000000000000000000001", "1000000000000000000000002"},
+		},
+		{
+			name:     "Fractional wei threshold rounds up",
+			minValue: "0.0000000000000000015",
+			values:   []string{"1", "2"},
+			expected: []string{"2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var txs []*types.ParsedTransaction
+			for _, value := range tt.values {
+				txs = append(txs, &types.ParsedTransaction{
+					Hash:  value,
+					From:  whale,
+					To:    stringPtr("0xregularuser"),
+					Value: wei(value),
+				})
+			}
+			blocks := []*types.ParsedBlock{{Number: 18500000, Transactions: txs}}
+
+			result := ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, WhaleFilter{MinValue: decimal.RequireFromString(tt.minValue)})
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %d transactions, got %d", len(tt.expected), len(result))
+			}
+			for i, tx := range result {
+				if tx.TxHash != tt.expected[i] {
+					t.Errorf("Expected tx %s at %d, got %s", tt.expected[i], i, tx.TxHash)
+				}
+			}
+		})
+	}
+}
+
+// TestParseWhaleTransactionsUnresolvedWhaleID tests that transactions whose whale ID
+// is not a number are skipped instead of being stored without a whale
+func TestParseWhaleTransactionsUnresolvedWhaleID(t *testing.T) {
+	whaleAddressIDs := map[string]string{
+		"0x1111111111111111111111111111111111111111": "1",
+		"0x2222222222222222222222222222222222222222": "Binance Hot Wallet", // label instead of ID
+		"0x3333333333333333333333333333333333333333": "",
+	}
+	value, _ := new(big.Int).SetString("5000000000000000000", 10) // 5 ETH
+
+	blocks := []*types.ParsedBlock{{
+		Number: 18500000,
+		Transactions: []*types.ParsedTransaction{
+			{Hash: "0xvalid", From: "0x1111111111111111111111111111111111111111", To: stringPtr("0xregularuser"), Value: value},
+			{Hash: "0xlabel", From: "0x2222222222222222222222222222222222222222", To: stringPtr("0xregularuser"), Value: value},
+			{Hash: "0xempty", From: "0xregularuser", To: stringPtr("0x3333333333333333333333333333333333333333"), Value: value},
+		},
+	}}
+
+	result := ParseWhaleTransactions(blocks, whaleAddressIDs, 1)
+
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(result))
+	}
+	if result[0].TxHash != "0xvalid" {
+		t.Errorf("Expected tx 0xvalid, got %s", result[0].TxHash)
+	}
+	if result[0].WhaleAddressID == nil || *result[0].WhaleAddressID != 1 {
+		t.Errorf("Expected whale address ID 1, got %v", result[0].WhaleAddressID)
+	}
+}
+
+// TestParseWhaleTransactionsDirection tests keeping only withdrawals (FROM) or deposits (TO)
+func TestParseWhaleTransactionsDirection(t *testing.T) {
+	whaleAddressIDs := map[string]string{
+		"0x1234567890abcdef1234567890abcdef12345678": "1",
+		"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd": "2",
+		"0x9876543210fedcba9876543210fedcba98765432": "3",
+	}
+	// whale to whale transfer on top of the test blocks
+	blocks := append(createTestBlocks(), &types.ParsedBlock{
+		Number: 18500002,
+		Transactions: []*types.ParsedTransaction{{
+			Hash:        "0xhash7",
+			BlockNumber: 18500002,
+			From:        "0x9876543210fedcba9876543210fedcba98765432",
+			To:          stringPtr("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"),
+			Value:       big.NewInt(3000000000000000000), // 3 ETH
+		}},
+	})
+
+	tests := []struct {
+		name           string
+		direction      string
+		expectedHashes []string
+	}{
+		{"Both by default", "", []string{"0xhash1", "0xhash2", "0xhash4", "0xhash5", "0xhash7"}},
+		{"Both", DirectionBoth, []string{"0xhash1", "0xhash2", "0xhash4", "0xhash5", "0xhash7"}},
+		{"Only withdrawals", DirectionFrom, []string{"0xhash1", "0xhash4", "0xhash7"}},
+		{"Only deposits", DirectionTo, []string{"0xhash2", "0xhash5", "0xhash7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, WhaleFilter{MinETH: 1, Direction: tt.direction})
+
+			var hashes []string
+			for _, tx := range result {
+				hashes = append(hashes, tx.TxHash)
+				if tt.direction == DirectionFrom && tx.TransferType == "TO" || tt.direction == DirectionTo && tx.TransferType == "FROM" {
+					t.Errorf("Expected no %s txs with direction %s, got %s", tx.TransferType, tt.direction, tx.TxHash)
+				}
+			}
+			if !reflect.DeepEqual(hashes, tt.expectedHashes) {
+				t.Errorf("Expected %v, got %v", tt.expectedHashes, hashes)
+			}
+		})
+	}
+}
+
+// TestParseWhaleTransactionsWhaleThresholds tests whale-specific thresholds with a fallback to the global one
+func TestParseWhaleTransactionsWhaleThresholds(t *testing.T) {
+	exchange := "0x1234567890abcdef1234567890abcdef12345678"
+	protocol := "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"
+	other := "0x9999999999999999999999999999999999999999"
+	whaleAddressIDs := map[string]string{exchange: "1", protocol: "2"}
+	eth := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18)) }
+	blocks := []*types.ParsedBlock{{
+		Number: 100,
+		Transactions: []*types.ParsedTransaction{
+			{Hash: "0xexchange5", BlockNumber: 100, From: exchange, To: stringPtr(other), Value: eth(5)},
+			{Hash: "0xexchange500", BlockNumber: 100, From: exchange, To: stringPtr(other), Value: eth(500)},
+			{Hash: "0xprotocol5", BlockNumber: 100, From: other, To: stringPtr(protocol), Value: eth(5)},
+			{Hash: "0xint5", BlockNumber: 100, From: exchange, To: stringPtr(protocol), Value: eth(5)},
+		},
+	}}
+
+	tests := []struct {
+		name           string
+		thresholds     map[string]string
+		expectedHashes []string
+	}{
+		{"Global only", nil, []string{"0xexchange5", "0xexchange500", "0xprotocol5", "0xint5"}},
+		{"Exchange at 100 ETH", map[string]string{exchange: "100"}, []string{"0xexchange500", "0xprotocol5", "0xint5"}},
+		{"Both whales own", map[string]string{exchange: "100", protocol: "10"}, []string{"0xexchange500"}},
+		{"Mixed case address", map[string]string{"0x" + strings.ToUpper(exchange[2:]): "100"}, []string{"0xexchange500", "0xprotocol5", "0xint5"}},
+		{"Below global", map[string]string{protocol: "0.5"}, []string{"0xexchange5", "0xexchange500", "0xprotocol5", "0xint5"}},
+		{"Invalid ignored", map[string]string{exchange: "lots"}, []string{"0xexchange5", "0xexchange500", "0xprotocol5", "0xint5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := WhaleFilter{MinETH: 1}.WithWhaleThresholds(tt.thresholds)
+			var hashes []string
+			for _, tx := range ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, filter) {
+				hashes = append(hashes, tx.TxHash)
+			}
+			if !reflect.DeepEqual(hashes, tt.expectedHashes) {
+				t.Errorf("Expected %v, got %v", tt.expectedHashes, hashes)
+			}
+		})
+	}
+}
+
+// TestParseWhaleTransactionsSelfTransfers tests flagging whale self-transfers as SELF and skipping them per config
+func TestParseWhaleTransactionsSelfTransfers(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	other := "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"
+	whaleAddressIDs := map[string]string{whale: "1", other: "2"}
+	blocks := []*types.ParsedBlock{{
+		Number: 100,
+		Transactions: []*types.ParsedTransaction{
+			// the same address in different case is still a self-transfer
+			{Hash: "0xself", BlockNumber: 100, From: whale, To: stringPtr("0x" + strings.ToUpper(whale[2:])), Value: big.NewInt(2e18)},
+			{Hash: "0xint", BlockNumber: 100, From: whale, To: stringPtr(other), Value: big.NewInt(2e18)},
+			{Hash: "0xfrom", BlockNumber: 100, From: whale, To: stringPtr("0x9999999999999999999999999999999999999999"), Value: big.NewInt(2e18)},
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		config   func(c *types.Config)
+		expected []string
+	}{
+		{"Flagged by default", func(c *types.Config) {}, []string{"0xself SELF", "0xint INT", "0xfrom FROM"}},
+		{"Flagged with direction", func(c *types.Config) { c.WhaleDirection = DirectionTo }, []string{"0xself SELF", "0xint INT"}},
+		{"Skipped", func(c *types.Config) { c.SkipSelfTransfers = true }, []string{"0xint INT", "0xfrom FROM"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			tt.config(config)
+			var got []string
+			for _, tx := range ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, NewWhaleFilter(config)) {
+				got = append(got, tx.TxHash+" "+tx.TransferType)
+				if tx.TransferType == "SELF" && (tx.WhaleAddressID == nil || *tx.WhaleAddressID != 1) {
+					t.Errorf("Expected whale ID 1 on the self-transfer, got %v", tx.WhaleAddressID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestParseWhaleTransactionsIncludeZeroValue tests keeping zero-value whale contract calls below the threshold
+func TestParseWhaleTransactionsIncludeZeroValue(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	router := "0x7a250d5630b4cf539739df2c5dacb4c659f2488d"
+	other := "0x9999999999999999999999999999999999999999"
+	whaleAddressIDs := map[string]string{whale: "1"}
+	blocks := []*types.ParsedBlock{{
+		Number: 100,
+		Transactions: []*types.ParsedTransaction{
+			// whale calls a contract without ETH
+			{Hash: "0xcall", BlockNumber: 100, From: whale, To: stringPtr(router), Value: big.NewInt(0), InputData: "095ea7b3"},
+			// nil value is zero too
+			{Hash: "0xcall_nil", BlockNumber: 100, From: whale, To: stringPtr(router), InputData: "095ea7b3"},
+			// small non-zero whale transfer stays below the threshold
+			{Hash: "0xdust", BlockNumber: 100, From: whale, To: stringPtr(other), Value: big.NewInt(1)},
+			// zero-value call without a whale
+			{Hash: "0xother", BlockNumber: 100, From: other, To: stringPtr(router), Value: big.NewInt(0)},
+			{Hash: "0xbig", BlockNumber: 100, From: whale, To: stringPtr(other), Value: big.NewInt(2e18)},
+		},
+	}}
+
+	tests := []struct {
+		name     string
+		config   func(c *types.Config)
+		expected []string
+	}{
+		{"Disabled", func(c *types.Config) {}, []string{"0xbig FROM 2"}},
+		{"Enabled", func(c *types.Config) { c.IncludeZeroValue = true }, []string{"0xcall FROM 0", "0xcall_nil FROM 0", "0xbig FROM 2"}},
+		{"Enabled with direction", func(c *types.Config) {
+			c.IncludeZeroValue = true
+			c.WhaleDirection = DirectionTo
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			tt.config(config)
+			var got []string
+			for _, tx := range ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, NewWhaleFilter(config)) {
+				got = append(got, tx.TxHash+" "+tx.TransferType+" "+tx.Value)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestParseWhaleTransactionsMethodSelectors tests collecting calls of tracked methods regardless of value
+func TestParseWhaleTransactionsMethodSelectors(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	router := "0x7a250d5630b4cf539739df2c5dacb4c659f2488d"
+	other := "0x9999999999999999999999999999999999999999"
+	whaleAddressIDs := map[string]string{whale: "1"}
+	blocks := []*types.ParsedBlock{{
+		Number: 100,
+		Transactions: []*types.ParsedTransaction{
+			// swapExactETHForTokens, 0.1 ETH, no whale
+			{Hash: "0xswap", From: other, To: stringPtr(router), Value: big.NewInt(100000000000000000), InputData: "7ff36ab500000000000000000000000000000000"},
+			// same selector with 0x prefix and upper case, no value
+			{Hash: "0xswap_prefixed", From: other, To: stringPtr(router), InputData: "0x7FF36AB5000000"},
+			// other method, small value
+			{Hash: "0xtransfer", From: other, To: stringPtr(router), Value: big.NewInt(1), InputData: "a9059cbb0000"},
+			// calldata shorter than a selector
+			{Hash: "0xshort", From: other, To: stringPtr(router), Value: big.NewInt(1), InputData: "7ff3"},
+			// plain whale withdrawal above threshold, kept as before
+			{Hash: "0xwhale", From: whale, To: stringPtr(other), Value: big.NewInt(2000000000000000000)},
+			// whale swap below threshold: a tracked call, keeps the whale side
+			{Hash: "0xwhale_swap", From: whale, To: stringPtr(router), Value: big.NewInt(5), InputData: "7ff36ab5"},
+		},
+	}}
+
+	tests := []struct {
+		name           string
+		selectors      []string
+		expectedHashes []string
+	}{
+		{name: "No selectors", selectors: nil, expectedHashes: []string{"0xwhale"}},
+		{name: "Matching selector", selectors: []string{"0x7ff36ab5"}, expectedHashes: []string{"0xswap", "0xswap_prefixed", "0xwhale", "0xwhale_swap"}},
+		{name: "Not matching selector", selectors: []string{"0x38ed1739"}, expectedHashes: []string{"0xwhale"}},
+		{name: "Several selectors", selectors: []string{"38ed1739", "A9059CBB"}, expectedHashes: []string{"0xtransfer", "0xwhale"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.MethodSelectors = tt.selectors
+			result := ParseWhaleTransactionsWithFilter(blocks, whaleAddressIDs, NewWhaleFilter(config))
+
+			var hashes []string
+			for _, tx := range result {
+				hashes = append(hashes, tx.TxHash)
+				switch tx.TxHash {
+				case "0xswap", "0xswap_prefixed", "0xtransfer":
+					if tx.TransferType != "" || tx.WhaleAddressID != nil {
+						t.Errorf("Expected call %s without whale, got %s/%v", tx.TxHash, tx.TransferType, tx.WhaleAddressID)
+					}
+				case "0xwhale_swap":
+					if tx.TransferType != "FROM" || tx.WhaleAddressID == nil || *tx.WhaleAddressID != 1 {
+						t.Errorf("Expected whale swap FROM whale 1, got %s/%v", tx.TransferType, tx.WhaleAddressID)
+					}
+				}
+			}
+			if !reflect.DeepEqual(hashes, tt.expectedHashes) {
+				t.Errorf("Expected %v, got %v", tt.expectedHashes, hashes)
+			}
+		})
+	}
+}
+
+// TestParseMethodSelector tests normalizing 4-byte selectors
+func TestParseMethodSelector(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "0xa9059cbb", expected: "a9059cbb"},
+		{input: " 0XA9059CBB ", expected: "a9059cbb"},
+		{input: "a9059cbb", expected: "a9059cbb"},
+		{input: "0xa9059c", wantErr: true},
+		{input: "0xa9059cbb00", wantErr: true},
+		{input: "0xzz059cbb", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			selector, err := ParseMethodSelector(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %q", selector)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if selector != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, selector)
+			}
+		})
+	}
+}
+
+// TestParseDirection tests validation of the whale direction option
+func TestParseDirection(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "", expected: DirectionBoth},
+		{input: "from", expected: DirectionFrom},
+		{input: " TO ", expected: DirectionTo},
+		{input: "both", expected: DirectionBoth},
+		{input: "in", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			direction, err := ParseDirection(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %s", direction)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if direction != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, direction)
+			}
+		})
+	}
+}
+
+// TestParseMinETH tests parsing of decimal ETH thresholds
+func TestParseMinETH(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{input: "0.5", expected: "0.5"},
+		{input: " 2.5 ", expected: "2.5"},
+		{input: "10", expected: "10"},
+		{input: "-1", wantErr: true},
+		{input: "half", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			value, err := ParseMinETH(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %s", value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if value.String() != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, value)
+			}
+		})
+	}
+}
+
+// TestNewWhaleFilter tests building the filter from gwei config values
+func TestNewWhaleFilter(t *testing.T) {
+	config := types.DefaultConfig()
+	config.MinGasPriceGwei = 2
+	config.HighPriorityFeeGwei = 30
+
+	filter := NewWhaleFilter(config)
+
+	if filter.MinETH != config.MinETHValue {
+		t.Errorf("Expected MinETH %d, got %d", config.MinETHValue, filter.MinETH)
+	}
+	if !filter.MinValue.IsZero() {
+		t.Errorf("Expected MinValue unset without min_eth_decimal, got %s", filter.MinValue)
+	}
+	config.MinETHDecimal = "0.25"
+	if minValue := NewWhaleFilter(config).MinValue; minValue.String() != "0.25" {
+		t.Errorf("Expected MinValue 0.25, got %s", minValue)
+	}
+	if filter.MinGasPrice == nil || filter.MinGasPrice.String() != "2000000000" {
+		t.Errorf("Expected MinGasPrice 2000000000 wei, got %v", filter.MinGasPrice)
+	}
+	if filter.MaxGasPrice != nil {
+		t.Errorf("Expected MaxGasPrice to be disabled, got %v", filter.MaxGasPrice)
+	}
+	if filter.HighPriorityFee == nil || filter.HighPriorityFee.String() != "30000000000" {
+		t.Errorf("Expected HighPriorityFee 30000000000 wei, got %v", filter.HighPriorityFee)
+	}
+	if filter.Direction != DirectionBoth {
+		t.Errorf("Expected direction %s by default, got %s", DirectionBoth, filter.Direction)
+	}
+	config.WhaleDirection = "sideways"
+	if direction := NewWhaleFilter(config).Direction; direction != DirectionBoth {
+		t.Errorf("Expected invalid direction to fall back to %s, got %s", DirectionBoth, direction)
+	}
+}
+
+// TestWhaleTransactionsClock tests created_at from the filter clock and the CSV time column from the block time
+func TestWhaleTransactionsClock(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	blockTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+	now := time.Date(2025, 6, 7, 8, 9, 10, 0, time.Local)
+	blocks := []*types.ParsedBlock{{
+		Number:    100,
+		Timestamp: blockTime,
+		Transactions: []*types.ParsedTransaction{
+			{Hash: "0xwhale", BlockNumber: 100, From: whale, To: stringPtr("0x9999999999999999999999999999999999999999"), Value: big.NewInt(2e18)},
+		},
+	}}
+
+	txs := ParseWhaleTransactionsWithFilter(blocks, map[string]string{whale: "1"}, WhaleFilter{MinETH: 1, Clock: types.NewFakeClock(now)})
+	if len(txs) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(txs))
+	}
+	if !txs[0].CreatedAt.Equal(now) || !txs[0].UpdatedAt.Equal(now) {
+		t.Errorf("Expected created/updated %v, got %v / %v", now, txs[0].CreatedAt, txs[0].UpdatedAt)
+	}
+	if txs[0].BlockTime == nil || !txs[0].BlockTime.Equal(blockTime) {
+		t.Errorf("Expected block time %v, got %v", blockTime, txs[0].BlockTime)
+	}
+
+	oldClock := csvClock
+	csvClock = types.NewFakeClock(now)
+	t.Cleanup(func() { csvClock = oldClock })
+
+	stored := *txs[0]
+	stored.BlockTime = nil
+	tests := []struct {
+		name     string
+		tx       *database.Transaction
+		expected string
+	}{
+		{"Parsed tx uses block time", txs[0], "2024-01-02 03:04:05"},
+		{"Stored tx uses clock", &stored, "2025-06-07 08:09:10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			csv, err := TransformTxsToCsvColumns([]*database.Transaction{tt.tx}, map[string]string{whale: "Whale"}, []string{"tx_hash", "time"}, CsvRowPerSide)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(csv, tt.expected) {
+				t.Errorf("Expected time %s, got %q", tt.expected, csv)
+			}
+		})
+	}
+}
+
 // TestTransformTxsToCsv tests the TransformTxsToCsv function
 func TestTransformTxsToCsv(t *testing.T) {
 	// Create test database transactions
@@ -556,6 +1236,291 @@ func createTestBlocks() []*types.ParsedBlock {
 	}
 }
 
+// TestTransformTxsToCsvColumns tests custom CSV column subsets and ordering
+func TestTransformTxsToCsvColumns(t *testing.T) {
+	whaleNames := map[string]string{
+		"0x1234567890abcdef1234567890abcdef12345678": "Binance",
+	}
+	txs := []*database.Transaction{
+		{
+			TxHash:      "0xhash1",
+			BlockNumber: 18500000,
+			FromAddress: "0x1234567890abcdef1234567890abcdef12345678",
+			ToAddress:   stringPtr("0xregularuser1"),
+			Value:       "2",
+			Gas:         21000,
+			GasPrice:    "30000000000",
+			TxType:      2,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		columns   []string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:     "Subset",
+			columns:  []string{"tx_hash", "value"},
+			expected: "\"0xhash1\",\"2 ETH\"\n",
+		},
+		{
+			name:     "Custom order with gas fields",
+			columns:  []string{"block", "label", "direction", "gas", "gas_price", "tx_type", "to"},
+			expected: "\"18500000\",\"Binance\",\"FROM\",\"21000\",\"30000000000\",\"2\",\"0xregularuser1\"\n",
+		},
+		{
+			name:     "Plain and grouped value",
+			columns:  []string{"value_eth", "value_grouped"},
+			expected: "\"2\",\"2 ETH\"\n",
+		},
+		{
+			name:      "Unknown column",
+			columns:   []string{"value", "color"},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := TransformTxsToCsvColumns(txs, whaleNames, tt.columns, CsvRowPerSide)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for columns %v", tt.columns)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Default layout", func(t *testing.T) {
+		custom, err := TransformTxsToCsvColumns(txs, whaleNames, nil, CsvRowPerSide)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		fields := strings.Split(strings.TrimSpace(custom), ",")
+		if len(fields) != 7 || fields[0] != "\"https://etherscan.io/tx/0xhash1\"" || fields[2] != "\"FROM\"" {
+			t.Errorf("Expected default 7-column layout, got %q", custom)
+		}
+	})
+}
+
+// TestCsvRowModeWhaleToWhale tests the rows of a transfer between two whales under both row modes
+func TestCsvRowModeWhaleToWhale(t *testing.T) {
+	binance := "0x1234567890abcdef1234567890abcdef12345678"
+	kraken := "0x9876543210fedcba9876543210fedcba98765432"
+	whaleNames := map[string]string{binance: "Binance", kraken: "Kraken"}
+	txs := []*database.Transaction{
+		{TxHash: "0xint", BlockNumber: 100, FromAddress: binance, ToAddress: stringPtr(kraken), Value: "50"},
+		{TxHash: "0xself", BlockNumber: 100, FromAddress: binance, ToAddress: stringPtr(binance), Value: "5"},
+		{TxHash: "0xto", BlockNumber: 100, FromAddress: "0xregularuser1", ToAddress: stringPtr(kraken), Value: "2"},
+	}
+	columns := []string{"tx_hash", "direction", "address", "label"}
+
+	tests := []struct {
+		name      string
+		mode      CsvRowMode
+		expected  string
+		expectErr bool
+	}{
+		{
+			name: "Row per side",
+			mode: CsvRowPerSide,
+			expected: "\"0xint\",\"FROM\",\"" + binance + "\",\"Binance\"\n" +
+				"\"0xint\",\"TO\",\"" + kraken + "\",\"Kraken\"\n" +
+				"\"0xself\",\"FROM\",\"" + binance + "\",\"Binance\"\n" +
+				"\"0xself\",\"TO\",\"" + binance + "\",\"Binance\"\n" +
+				"\"0xto\",\"TO\",\"" + kraken + "\",\"Kraken\"\n",
+		},
+		{
+			name:     "Empty mode is row per side",
+			mode:     "",
+			expected: "",
+		},
+		{
+			name: "Row per tx",
+			mode: CsvRowPerTx,
+			expected: "\"0xint\",\"INT\",\"" + binance + "\",\"Binance -> Kraken\"\n" +
+				"\"0xself\",\"SELF\",\"" + binance + "\",\"Binance\"\n" +
+				"\"0xto\",\"TO\",\"" + kraken + "\",\"Kraken\"\n",
+		},
+		{
+			name:      "Unknown mode",
+			mode:      "per_whale",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := TransformTxsToCsvColumns(txs, whaleNames, columns, tt.mode)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error for mode %q", tt.mode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := tt.expected
+			if tt.mode == "" {
+				expected, _ = TransformTxsToCsvColumns(txs, whaleNames, columns, CsvRowPerSide)
+			}
+			if result != expected {
+				t.Errorf("Expected %q, got %q", expected, result)
+			}
+		})
+	}
+
+	t.Run("Dedup per tx", func(t *testing.T) {
+		csvPath := filepath.Join(t.TempDir(), "whales.csv")
+		for run, expected := range []int{3, 0} {
+			written, err := AppendCSVDedup(csvPath, txs, whaleNames, columns, CsvRowPerTx)
+			if err != nil {
+				t.Fatalf("Run %d failed: %v", run+1, err)
+			}
+			if written != expected {
+				t.Errorf("Expected %d rows written on run %d, got %d", expected, run+1, written)
+			}
+		}
+	})
+}
+
+// TestWriteTransactionsNDJSON tests that every line is a separate valid JSON object
+func TestWriteTransactionsNDJSON(t *testing.T) {
+	txs := createTestDatabaseTransactions()
+
+	var buf bytes.Buffer
+	if err := WriteTransactionsNDJSON(&buf, txs); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(txs) {
+		t.Fatalf("Expected %d lines, got %d", len(txs), len(lines))
+	}
+	for i, line := range lines {
+		var tx database.Transaction
+		if err := json.Unmarshal([]byte(line), &tx); err != nil {
+			t.Errorf("Line %d is not valid JSON: %v", i, err)
+			continue
+		}
+		if tx.TxHash != txs[i].TxHash || tx.Value != txs[i].Value {
+			t.Errorf("Expected tx %s with value %s, got %s with %s", txs[i].TxHash, txs[i].Value, tx.TxHash, tx.Value)
+		}
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		var empty bytes.Buffer
+		if err := WriteTransactionsNDJSON(&empty, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if empty.Len() != 0 {
+			t.Errorf("Expected no output, got %q", empty.String())
+		}
+	})
+
+	t.Run("Append", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "whales.ndjson")
+		for i := 0; i < 2; i++ {
+			if err := AppendNDJSON(path, txs); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read file: %v", err)
+		}
+		if count := strings.Count(string(data), "\n"); count != 2*len(txs) {
+			t.Errorf("Expected %d lines after two appends, got %d", 2*len(txs), count)
+		}
+	})
+}
+
+// TestGroupThousands tests thousands grouping of ETH values
+func TestGroupThousands(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0", "0"},
+		{"2", "2"},
+		{"999.5", "999.5"},
+		{"1000", "1,000"},
+		{"12345", "12,345"},
+		{"12345.12345", "12,345.12345"},
+		{"1234567.8", "1,234,567.8"},
+		{"-1234.5", "-1,234.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := groupThousands(tt.input); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestAppendCSVDedup tests that consecutive runs over overlapping transactions don't duplicate rows
+func TestAppendCSVDedup(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "whales.csv")
+	whaleNames := map[string]string{
+		"0x1234567890abcdef1234567890abcdef12345678": "Binance",
+		"0xabcdefabcdefabcdefabcdefabcdefabcdefabcd": "Coinbase",
+	}
+	txs := createTestDatabaseTransactions()
+
+	// first run: hash1 + hash2
+	written, err := AppendCSVDedup(csvPath, txs[:2], whaleNames, nil, CsvRowPerSide)
+	if err != nil {
+		t.Fatalf("First run failed: %v", err)
+	}
+	if written != 2 {
+		t.Errorf("Expected 2 rows written on first run, got %d", written)
+	}
+
+	// second run overlaps: hash2 again + hash5
+	written, err = AppendCSVDedup(csvPath, txs[1:], whaleNames, nil, CsvRowPerSide)
+	if err != nil {
+		t.Fatalf("Second run failed: %v", err)
+	}
+	if written != 1 {
+		t.Errorf("Expected 1 new row on second run, got %d", written)
+	}
+
+	// third run: nothing new
+	written, err = AppendCSVDedup(csvPath, txs, whaleNames, nil, CsvRowPerSide)
+	if err != nil {
+		t.Fatalf("Third run failed: %v", err)
+	}
+	if written != 0 {
+		t.Errorf("Expected no rows on third run, got %d", written)
+	}
+
+	content, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 CSV lines, got %d:\n%s", len(lines), content)
+	}
+	for _, hash := range []string{"0xhash1", "0xhash2", "0xhash5"} {
+		if n := strings.Count(string(content), "/tx/"+hash+"\""); n != 1 {
+			t.Errorf("Expected %s once in CSV, got %d", hash, n)
+		}
+	}
+}
+
 // Helper function to create test database transactions
 func createTestDatabaseTransactions() []*database.Transaction {
 	// Import database package
@@ -567,7 +1532,7 @@ func createTestDatabaseTransactions() []*database.Transaction {
 			ToAddress:      stringPtr("0xregularuser1"),
 			Value:          "2", // 2 ETH in simplified format
 			TransferType:   "FROM",
-			WhaleAddressID: 1,
+			WhaleAddressID: int64Ptr(1),
 		},
 		{
 			TxHash:         "0xhash2",
@@ -576,7 +1541,7 @@ func createTestDatabaseTransactions() []*database.Transaction {
 			ToAddress:      stringPtr("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd"), // Coinbase
 			Value:          "5",                                                     // 5 ETH in simplified format
 			TransferType:   "TO",
-			WhaleAddressID: 2,
+			WhaleAddressID: int64Ptr(2),
 		},
 		{
 			TxHash:         "0xhash5",
@@ -585,7 +1550,7 @@ func createTestDatabaseTransactions() []*database.Transaction {
 			ToAddress:      stringPtr("0x1234567890abcdef1234567890abcdef12345678"), // Binance
 			Value:          "2",                                                     // 2 ETH in simplified format
 			TransferType:   "TO",
-			WhaleAddressID: 1,
+			WhaleAddressID: int64Ptr(1),
 		},
 	}
 }
@@ -639,6 +1604,11 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+// Helper function to create int64 pointer
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
 // Helper function to get absolute difference between floats
 func abs(x float64) float64 {
 	if x < 0 {
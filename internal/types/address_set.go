@@ -0,0 +1,79 @@
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// NormalizeAddress returns the lowercase form of an address, the key used by AddressSet and
+// the whale address maps. Already lowercase addresses are returned without allocation.
+func NormalizeAddress(addr string) string {
+	return strings.ToLower(strings.TrimSpace(addr))
+}
+
+// AddressSet is a set of normalized (lowercase) addresses, checksummed and lowercase
+// forms of the same address match. Build it once and reuse it for every block.
+type AddressSet map[string]struct{}
+
+// NewAddressSet creates a set of the given addresses, empty strings are skipped
+func NewAddressSet(addrs ...string) AddressSet {
+	set := make(AddressSet, len(addrs))
+	for _, addr := range addrs {
+		set.Add(addr)
+	}
+	return set
+}
+
+// AddressSetFromMap creates a set of the keys of an address map, e.g. the whale address to ID mapping
+func AddressSetFromMap[V any](m map[string]V) AddressSet {
+	set := make(AddressSet, len(m))
+	for addr := range m {
+		set.Add(addr)
+	}
+	return set
+}
+
+// AddressSetFromFile reads one address per line, an address may be followed by a comma and
+// a label. Empty lines and lines starting with # are skipped.
+func AddressSetFromFile(path string) (AddressSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open address file: %w", err)
+	}
+	defer file.Close()
+
+	set := make(AddressSet)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addr, _, _ := strings.Cut(line, ",")
+		set.Add(addr)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read address file: %w", err)
+	}
+	return set, nil
+}
+
+// Add adds an address in normalized form, empty addresses are ignored
+func (s AddressSet) Add(addr string) {
+	if addr = NormalizeAddress(addr); addr != "" {
+		s[addr] = struct{}{}
+	}
+}
+
+// Contains reports whether the address is in the set regardless of its case
+func (s AddressSet) Contains(addr string) bool {
+	_, ok := s[NormalizeAddress(addr)]
+	return ok
+}
+
+// Len returns the number of addresses in the set
+func (s AddressSet) Len() int {
+	return len(s)
+}
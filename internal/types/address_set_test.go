@@ -0,0 +1,127 @@
+package types
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// TestAddressSetNormalization tests that checksummed, lowercase and uppercase forms match
+func TestAddressSetNormalization(t *testing.T) {
+	checksummed := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	set := NewAddressSet(checksummed, "", "  0x28C6c06298d514Db089934071355E5743bf21d60 ")
+
+	tests := []struct {
+		name     string
+		addr     string
+		expected bool
+	}{
+		{"Checksummed", checksummed, true},
+		{"Lowercase", strings.ToLower(checksummed), true},
+		{"Uppercase hex", "0x" + strings.ToUpper(checksummed[2:]), true},
+		{"Trimmed on add", "0x28c6c06298d514db089934071355e5743bf21d60", true},
+		{"Unknown", "0x0000000000000000000000000000000000000001", false},
+		{"Empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := set.Contains(tt.addr); result != tt.expected {
+				t.Errorf("Expected Contains(%q) = %v, got %v", tt.addr, tt.expected, result)
+			}
+		})
+	}
+
+	if set.Len() != 2 {
+		t.Errorf("Expected 2 addresses, empty one skipped, got %d", set.Len())
+	}
+	set.Add(strings.ToUpper(checksummed))
+	if set.Len() != 2 {
+		t.Errorf("Expected re-adding in another case to keep 2 addresses, got %d", set.Len())
+	}
+}
+
+// TestAddressSetFromMap tests building a set from whale address mappings
+func TestAddressSetFromMap(t *testing.T) {
+	set := AddressSetFromMap(map[string]string{
+		"0xdAC17F958D2ee523a2206206994597C13D831ec7": "1",
+		"0x28c6c06298d514db089934071355e5743bf21d60": "2",
+	})
+
+	if set.Len() != 2 {
+		t.Errorf("Expected 2 addresses, got %d", set.Len())
+	}
+	if !set.Contains("0xdac17f958d2ee523a2206206994597c13d831ec7") {
+		t.Error("Expected lowercase form of checksummed key to match")
+	}
+}
+
+// TestAddressSetFromFile tests reading addresses with labels, comments and blank lines
+func TestAddressSetFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "addresses.txt")
+	content := "# whales\n0xdAC17F958D2ee523a2206206994597C13D831ec7\n\n0x28C6c06298d514Db089934071355E5743bf21d60,Binance 14\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write address file: %v", err)
+	}
+
+	set, err := AddressSetFromFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if set.Len() != 2 {
+		t.Errorf("Expected 2 addresses, got %d", set.Len())
+	}
+	for _, addr := range []string{"0xdac17f958d2ee523a2206206994597c13d831ec7", "0x28C6C06298D514DB089934071355E5743BF21D60"} {
+		if !set.Contains(addr) {
+			t.Errorf("Expected set to contain %s", addr)
+		}
+	}
+
+	if _, err := AddressSetFromFile(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+// benchmarkAddresses returns n checksummed addresses
+func benchmarkAddresses(n int) []string {
+	addrs := make([]string, n)
+	for i := range addrs {
+		addrs[i] = common.HexToAddress(fmt.Sprintf("0x%040x", i*7919+1)).Hex()
+	}
+	return addrs
+}
+
+// BenchmarkAddressSetContains checks 100 checksummed addresses per block against a prebuilt 10k set
+func BenchmarkAddressSetContains(b *testing.B) {
+	addrs := benchmarkAddresses(10000)
+	set := NewAddressSet(addrs...)
+	lookups := addrs[:100]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, addr := range lookups {
+			set.Contains(addr)
+		}
+	}
+}
+
+// BenchmarkAddressMapPerBlock is the previous approach: the address map is rebuilt for every block
+func BenchmarkAddressMapPerBlock(b *testing.B) {
+	addrs := benchmarkAddresses(10000)
+	lookups := addrs[:100]
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		addressMap := make(map[string]bool)
+		for _, addr := range addrs {
+			addressMap[strings.ToLower(addr)] = true
+		}
+		for _, addr := range lookups {
+			_ = addressMap[strings.ToLower(addr)]
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package types
 
 import (
+	"fmt"
 	"math/big"
 	"time"
 
@@ -17,11 +18,98 @@ type ParsedBlock struct {
 	Miner         string               `json:"miner"`
 	GasLimit      uint64               `json:"gas_limit"`
 	GasUsed       uint64               `json:"gas_used"`
-	BaseFeePerGas *big.Int             `json:"base_fee_per_gas,omitempty"`
+	BaseFeePerGas *big.Int             `json:"base_fee_per_gas,omitempty"` // nil before London, use BaseFeeOrZero for arithmetic
 	Size          uint64               `json:"size"`
 	TxCount       int                  `json:"transaction_count"`
 	Transactions  []*ParsedTransaction `json:"transactions"`
 	UncleCount    int                  `json:"uncle_count"`
+	Withdrawals   []Withdrawal         `json:"withdrawals,omitempty"` // nil before Shanghai
+
+	// Degraded is set when the block was reconstructed and some or all transactions are missing
+	Degraded       bool   `json:"degraded,omitempty"`
+	DegradedReason string `json:"degraded_reason,omitempty"`
+
+	// ParseErrors are the transactions that failed to parse, kept out of Transactions for the dead-letter store
+	ParseErrors []*TxParseError `json:"parse_errors,omitempty"`
+}
+
+// TxParseError is a transaction that failed to parse, stored separately for investigation and reprocessing
+type TxParseError struct {
+	BlockNumber      uint64 `json:"block_number"`
+	TxHash           string `json:"tx_hash"`
+	TransactionIndex uint64 `json:"transaction_index"`
+	Error            string `json:"error"`
+}
+
+// UnknownSender is the From of transactions whose sender could not be recovered
+const UnknownSender = "unknown"
+
+// UnknownTxType marks transactions whose type could not be decoded
+const UnknownTxType uint8 = 255
+
+// TxTypeName returns a readable name of a transaction type
+func TxTypeName(txType uint8) string {
+	switch txType {
+	case types.LegacyTxType:
+		return "legacy"
+	case types.AccessListTxType:
+		return "access_list"
+	case types.DynamicFeeTxType:
+		return "dynamic_fee"
+	case types.BlobTxType:
+		return "blob"
+	case types.SetCodeTxType:
+		return "set_code"
+	case UnknownTxType:
+		return "unknown"
+	}
+	return fmt.Sprintf("type_%d", txType)
+}
+
+// LondonBlockMainnet is the first mainnet block with EIP-1559 base fee, earlier blocks have no BaseFeePerGas
+const LondonBlockMainnet = 12965000
+
+// HasBaseFee reports whether the block has an EIP-1559 base fee (post-London)
+func (b *ParsedBlock) HasBaseFee() bool {
+	return b.BaseFeePerGas != nil
+}
+
+// BaseFeeOrZero returns a copy of the base fee, or 0 for pre-London blocks, safe for arithmetic
+func (b *ParsedBlock) BaseFeeOrZero() *big.Int {
+	if b.BaseFeePerGas == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(b.BaseFeePerGas)
+}
+
+// ShanghaiBlockMainnet is the first mainnet block with validator withdrawals, earlier blocks have no Withdrawals
+const ShanghaiBlockMainnet = 17034870
+
+// Withdrawal is a beacon chain validator withdrawal included in a post-Shanghai block
+type Withdrawal struct {
+	Index          uint64   `json:"index"`
+	ValidatorIndex uint64   `json:"validator_index"`
+	Address        string   `json:"address"`
+	Amount         *big.Int `json:"amount"` // in wei, the node reports Gwei
+}
+
+// NewWithdrawalsFromGeth converts go-ethereum withdrawals, nil stays nil for pre-Shanghai blocks
+func NewWithdrawalsFromGeth(withdrawals types.Withdrawals) []Withdrawal {
+	if withdrawals == nil {
+		return nil
+	}
+
+	gwei := big.NewInt(1e9)
+	parsed := make([]Withdrawal, len(withdrawals))
+	for i, w := range withdrawals {
+		parsed[i] = Withdrawal{
+			Index:          w.Index,
+			ValidatorIndex: w.Validator,
+			Address:        w.Address.Hex(),
+			Amount:         new(big.Int).Mul(new(big.Int).SetUint64(w.Amount), gwei),
+		}
+	}
+	return parsed
 }
 
 // ParsedTransaction represents a parsed Ethereum transaction
@@ -36,16 +124,45 @@ type ParsedTransaction struct {
 	Gas              uint64       `json:"gas"`
 	GasPrice         *big.Int     `json:"gas_price"`
 	GasUsed          uint64       `json:"gas_used"`
-	Status           uint64       `json:"status"` // 1 = success, 0 = failure
+	Status           uint64       `json:"status"`          // 1 = success, 0 = failure, only meaningful with ReceiptFetched
+	ReceiptFetched   bool         `json:"receipt_fetched"` // false: receipts were skipped, GasUsed and Status are unknown
 	InputData        string       `json:"input_data"`
 	Nonce            uint64       `json:"nonce"`
 	Type             uint8        `json:"type"` // Transaction type (0, 1, 2)
 	Logs             []*ParsedLog `json:"logs,omitempty"`
 	ContractAddress  *string      `json:"contract_address,omitempty"`
+	Asset            string       `json:"asset,omitempty"`         // Transferred asset symbol, empty = native ETH
+	TokenAddress     *string      `json:"token_address,omitempty"` // ERC-20 contract of a token transfer
 
 	// EIP-1559 fields
 	MaxFeePerGas         *big.Int `json:"max_fee_per_gas,omitempty"`
 	MaxPriorityFeePerGas *big.Int `json:"max_priority_fee_per_gas,omitempty"`
+
+	// EIP-2930 access list, set for type 1 and type 2+ transactions that carry one
+	AccessList []AccessTuple `json:"access_list,omitempty"`
+}
+
+// AccessTuple is an EIP-2930 access list entry: an address and the storage slots it accesses
+type AccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storage_keys"`
+}
+
+// NewAccessListFromGeth converts a go-ethereum access list, nil if empty
+func NewAccessListFromGeth(accessList types.AccessList) []AccessTuple {
+	if len(accessList) == 0 {
+		return nil
+	}
+
+	tuples := make([]AccessTuple, len(accessList))
+	for i, tuple := range accessList {
+		keys := make([]string, len(tuple.StorageKeys))
+		for j, key := range tuple.StorageKeys {
+			keys[j] = key.Hex()
+		}
+		tuples[i] = AccessTuple{Address: tuple.Address.Hex(), StorageKeys: keys}
+	}
+	return tuples
 }
 
 // ParsedLog represents a parsed Ethereum event log
@@ -85,6 +202,46 @@ type ParsingStats struct {
 	StartTime          time.Time     `json:"start_time"`
 	EndTime            time.Time     `json:"end_time"`
 	TotalDuration      time.Duration `json:"total_duration"`
+	StoppedByBudget    bool          `json:"stopped_by_budget"`       // MaxBlocks/MaxDuration/RangeTimeout/daily request cap stopped the last run early
+	BudgetReason       string        `json:"budget_reason,omitempty"` // "max_blocks", "max_duration", "timeout" or "daily_request_cap"
+
+	// Per-block parse time of successfully parsed blocks
+	MinBlockTime time.Duration `json:"min_block_time"`
+	MaxBlockTime time.Duration `json:"max_block_time"`
+	AvgBlockTime time.Duration `json:"avg_block_time"`
+	SlowestBlock uint64        `json:"slowest_block"`
+	SlowBlocks   uint64        `json:"slow_blocks"` // blocks slower than Config.SlowBlockThreshold
+
+	// Parsed transactions by type: 0 legacy, 1 access list, 2 dynamic fee, 3 blob, 255 unknown
+	TxTypeCounts map[uint8]uint64 `json:"tx_type_counts"`
+
+	// Transactions whose sender could not be recovered (From == UnknownSender), dropped or kept per Config.DropUnknownSender
+	UnknownSenders uint64 `json:"unknown_senders"`
+
+	// Transactions routed to ParsedBlock.ParseErrors
+	TxParseErrors uint64 `json:"tx_parse_errors"`
+
+	// Rate limited block fetches and the concurrency limit at the end of the last run, with Config.AdaptiveWorkers
+	RateLimitHits uint64 `json:"rate_limit_hits"`
+	WorkerLimit   int    `json:"worker_limit,omitempty"`
+
+	totalBlockTime time.Duration
+}
+
+// AddBlockTime adds a parsed block's processing time to the min/max/avg aggregates.
+// Must be called after BlocksParsed was incremented for the block.
+func (s *ParsingStats) AddBlockTime(blockNumber uint64, d time.Duration) {
+	if s.MinBlockTime == 0 || d < s.MinBlockTime {
+		s.MinBlockTime = d
+	}
+	if d > s.MaxBlockTime {
+		s.MaxBlockTime = d
+		s.SlowestBlock = blockNumber
+	}
+	s.totalBlockTime += d
+	if s.BlocksParsed > 0 {
+		s.AvgBlockTime = s.totalBlockTime / time.Duration(s.BlocksParsed)
+	}
 }
 
 // ContractInfo represents smart contract information
@@ -107,21 +264,23 @@ func NewParsedBlockFromGethBlock(gethBlock *types.Block) *ParsedBlock {
 		Miner:         gethBlock.Coinbase().Hex(),
 		GasLimit:      gethBlock.GasLimit(),
 		GasUsed:       gethBlock.GasUsed(),
-		BaseFeePerGas: gethBlock.BaseFee(),
+		BaseFeePerGas: gethBlock.BaseFee(), // nil for pre-London blocks
 		Size:          gethBlock.Size(),
 		TxCount:       len(gethBlock.Transactions()),
 		UncleCount:    len(gethBlock.Uncles()),
+		Withdrawals:   NewWithdrawalsFromGeth(gethBlock.Withdrawals()),
 	}
 }
 
-// парсинг логов - задел на будущее для токен-транзакции
+// NewParsedLogFromGethLog converts a geth log, known events (DefaultEventRegistry) get DecodedEventName and
+// DecodedData with their named fields; a known event with malformed data is left undecoded
 func NewParsedLogFromGethLog(gethLog *types.Log) *ParsedLog {
 	topics := make([]string, len(gethLog.Topics))
 	for i, topic := range gethLog.Topics {
 		topics[i] = topic.Hex()
 	}
 
-	return &ParsedLog{
+	parsed := &ParsedLog{
 		Address:     gethLog.Address.Hex(),
 		Topics:      topics,
 		Data:        common.Bytes2Hex(gethLog.Data),
@@ -132,4 +291,9 @@ func NewParsedLogFromGethLog(gethLog *types.Log) *ParsedLog {
 		LogIndex:    gethLog.Index,
 		Removed:     gethLog.Removed,
 	}
+	if name, fields, ok, err := DefaultEventRegistry.Decode(gethLog.Topics, gethLog.Data); ok && err == nil {
+		parsed.DecodedEventName = name
+		parsed.DecodedData = fields
+	}
+	return parsed
 }
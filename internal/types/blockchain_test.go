@@ -0,0 +1,61 @@
+package types
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TestParsedBlockBaseFee tests base fee handling for pre- and post-London blocks
+func TestParsedBlockBaseFee(t *testing.T) {
+	tests := []struct {
+		name        string
+		number      uint64
+		baseFee     *big.Int
+		wantBaseFee bool
+		wantValue   int64
+	}{
+		{"Pre-London", LondonBlockMainnet - 1, nil, false, 0},
+		{"Post-London", LondonBlockMainnet, big.NewInt(1000000000), true, 1000000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := &types.Header{
+				Number:     new(big.Int).SetUint64(tt.number),
+				Difficulty: big.NewInt(0),
+				BaseFee:    tt.baseFee,
+			}
+			block := NewParsedBlockFromGethBlock(types.NewBlock(header, &types.Body{}, nil, nil))
+
+			if block.HasBaseFee() != tt.wantBaseFee {
+				t.Errorf("Expected HasBaseFee %v, got %v", tt.wantBaseFee, block.HasBaseFee())
+			}
+
+			// arithmetic on the helper must not panic
+			total := new(big.Int).Mul(block.BaseFeeOrZero(), big.NewInt(2))
+			if total.Int64() != 2*tt.wantValue {
+				t.Errorf("Expected doubled base fee %d, got %s", 2*tt.wantValue, total)
+			}
+
+			data, err := json.Marshal(block)
+			if err != nil {
+				t.Fatalf("Failed to marshal block: %v", err)
+			}
+			if strings.Contains(string(data), "base_fee_per_gas") != tt.wantBaseFee {
+				t.Errorf("Unexpected base_fee_per_gas presence in %s", data)
+			}
+		})
+	}
+
+	t.Run("Copy", func(t *testing.T) {
+		block := &ParsedBlock{BaseFeePerGas: big.NewInt(5)}
+		block.BaseFeeOrZero().SetInt64(100)
+		if block.BaseFeePerGas.Int64() != 5 {
+			t.Errorf("BaseFeeOrZero must not expose the block's value, got %s", block.BaseFeePerGas)
+		}
+	})
+}
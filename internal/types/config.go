@@ -25,6 +25,24 @@ type Config struct {
 	Workers        int           `json:"workers" yaml:"workers"`
 	RequestTimeout time.Duration `json:"request_timeout" yaml:"request_timeout"`
 
+	// ParseBlockRange budget, whichever is hit first stops feeding new blocks (0 = no limit)
+	MaxDuration time.Duration `json:"max_duration" yaml:"max_duration"`
+	MaxBlocks   uint64        `json:"max_blocks" yaml:"max_blocks"`
+	// Hard deadline of ParseBlockRange, cancels in-flight calls to a stuck node and returns
+	// the blocks parsed without a gap from the range start (0 = no deadline)
+	RangeTimeout time.Duration `json:"range_timeout" yaml:"range_timeout"`
+	// ParseBlockRange refuses ranges of more than MaxRangeSize blocks, e.g. an accidental -start 0
+	// over the whole chain (0 = no limit). Unlike MaxBlocks nothing is parsed.
+	MaxRangeSize uint64 `json:"max_range_size" yaml:"max_range_size"`
+
+	// Adaptive concurrency (AIMD) for backfills: block fetches start at MinWorkers and ramp up to Workers,
+	// a rate limited fetch halves the concurrency. Disabled runs all Workers at once.
+	AdaptiveWorkers bool `json:"adaptive_workers" yaml:"adaptive_workers"`
+	MinWorkers      int  `json:"min_workers" yaml:"min_workers"`
+
+	// Blocks taking longer than this to parse are logged (0 = disabled)
+	SlowBlockThreshold time.Duration `json:"slow_block_threshold" yaml:"slow_block_threshold"`
+
 	// Output settings
 	OutputFormat string `json:"output_format" yaml:"output_format"` // json, csv, database
 	OutputPath   string `json:"output_path" yaml:"output_path"`
@@ -35,18 +53,77 @@ type Config struct {
 
 	// Filtering options
 	MinETHValue     uint64            `json:"min_eth_value" yaml:"min_eth_value"`
+	MinETHDecimal   string            `json:"min_eth_decimal" yaml:"min_eth_decimal"` // fractional threshold like "0.5", overrides MinETHValue when set
 	WhalesAddr      map[string]string `json:"address_names" yaml:"address_names"`
+	WhaleMinETH     map[string]string `json:"whale_min_eth" yaml:"whale_min_eth"` // per-whale thresholds in ETH applied by init-whales, "" resets to the global one
 	FilterAddresses []string          `json:"filter_addresses" yaml:"filter_addresses"`
 	FilterTopics    []string          `json:"filter_topics" yaml:"filter_topics"`
 	IncludeLogs     bool              `json:"include_logs" yaml:"include_logs"`
+	TrackTokens     bool              `json:"track_tokens" yaml:"track_tokens"`           // whale ERC20 transfers along with ETH txs, needs IncludeLogs
+	TokensPath      string            `json:"tokens_path" yaml:"tokens_path"`             // tracked tokens as "address,symbol,decimals" lines, empty = types.DefaultTokens
+	TokenMinAmounts map[string]string `json:"token_min_amounts" yaml:"token_min_amounts"` // min transfer in whole tokens by symbol, e.g. USDT: "1000000"; none = any whale transfer
 	IncludeTraces   bool              `json:"include_traces" yaml:"include_traces"`
 	CsvPath         string            `json:"csv_path" yaml:"csv_path"`
+	CsvColumns      []string          `json:"csv_columns" yaml:"csv_columns"`   // ordered CSV columns, empty = default layout
+	CsvDedup        bool              `json:"csv_dedup" yaml:"csv_dedup"`       // skip rows already written to CSV (tracked in <csv_path>.idx)
+	CsvRowMode      string            `json:"csv_row_mode" yaml:"csv_row_mode"` // whale-to-whale tx rows: per_side (default, a FROM and a TO row) or per_tx (one INT/SELF row)
+	NdjsonPath      string            `json:"ndjson_path" yaml:"ndjson_path"`   // whale txs as JSON Lines, written with -format ndjson
 	LastBlockPath   string            `json:"last_block_path" yaml:"last_block_path"`
 	MaxBlockDelta   uint64            `json:"max_block_delta" yaml:"max_block_delta"`
 
-	// Receipt processing options
-	MaxTransactionsForReceipts int  `json:"max_transactions_for_receipts" yaml:"max_transactions_for_receipts"`
-	SkipReceiptsOnLargeBlocks  bool `json:"skip_receipts_on_large_blocks" yaml:"skip_receipts_on_large_blocks"`
+	// Whale txs are written to CSV/NDJSON only once their block has MinConfirmations confirmations
+	// (the last parsed block has 1, 0 or 1 = no wait). Until then they are kept in PendingPath between runs.
+	MinConfirmations uint64 `json:"min_confirmations" yaml:"min_confirmations"`
+	PendingPath      string `json:"pending_path" yaml:"pending_path"`
+
+	// TargetFinalized ends the parsed range at the chain's finalized block instead of latest, so stored blocks
+	// are never reorged. Without finalized support the range ends MinConfirmations-1 blocks before latest.
+	TargetFinalized bool `json:"target_finalized" yaml:"target_finalized"`
+
+	// Whale txs whose block is older than MaxAlertAge are stored in the DB but not written to CSV/NDJSON,
+	// so catching up after downtime doesn't emit stale alerts; 0 = no limit
+	MaxAlertAge time.Duration `json:"max_alert_age" yaml:"max_alert_age"`
+
+	// RPC requests are counted per UTC day in the meta table. Over DailyRequestCap (0 = no cap) a call
+	// fails with client.QuotaExceededError, or waits for 00:00 UTC if WaitForQuota is set.
+	DailyRequestCap uint64 `json:"daily_request_cap" yaml:"daily_request_cap"`
+	WaitForQuota    bool   `json:"wait_for_quota" yaml:"wait_for_quota"`
+
+	// Whale gas filters in gwei, 0 = disabled
+	MinGasPriceGwei     uint64 `json:"min_gas_price_gwei" yaml:"min_gas_price_gwei"`         // skip whale txs below this gas price
+	MaxGasPriceGwei     uint64 `json:"max_gas_price_gwei" yaml:"max_gas_price_gwei"`         // skip whale txs above this gas price
+	HighPriorityFeeGwei uint64 `json:"high_priority_fee_gwei" yaml:"high_priority_fee_gwei"` // flag whale txs with priority fee >= this
+
+	// Whale side to keep: "from" (withdrawals), "to" (deposits) or "both"
+	WhaleDirection string `json:"whale_direction" yaml:"whale_direction"`
+
+	// Skip txs a whale sends to its own address (transfer type SELF) instead of storing them
+	SkipSelfTransfers bool `json:"skip_self_transfers" yaml:"skip_self_transfers"`
+
+	// Keep zero-value whale txs (contract calls) below the min ETH value, stored with value 0
+	IncludeZeroValue bool `json:"include_zero_value" yaml:"include_zero_value"`
+
+	// 4-byte method selectors like "0xa9059cbb": txs whose input data starts with one of them are
+	// collected with the whale txs regardless of value, also when no whale is involved
+	MethodSelectors []string `json:"method_selectors" yaml:"method_selectors"`
+
+	// Stored transactions older than this are deleted by the nightly cleanup (0 = keep forever)
+	RetentionDays int `json:"retention_days" yaml:"retention_days"`
+
+	// Drop transactions whose sender could not be recovered instead of keeping them with From "unknown"
+	DropUnknownSender bool `json:"drop_unknown_sender" yaml:"drop_unknown_sender"`
+
+	// Receipt processing options. With SkipReceiptsOnLargeBlocks a block is large when it has more than
+	// MaxTransactionsForReceipts transactions OR used more than MaxGasForReceipts gas (0 = no gas limit),
+	// either limit alone skips receipts. SkipReceiptsOnLargeBlocks false disables both limits.
+	MaxTransactionsForReceipts int    `json:"max_transactions_for_receipts" yaml:"max_transactions_for_receipts"`
+	MaxGasForReceipts          uint64 `json:"max_gas_for_receipts" yaml:"max_gas_for_receipts"`
+	SkipReceiptsOnLargeBlocks  bool   `json:"skip_receipts_on_large_blocks" yaml:"skip_receipts_on_large_blocks"`
+	// Max receipt batch calls in flight across all block workers, tuned separately from Workers (0 = one per worker)
+	ReceiptWorkers int `json:"receipt_workers" yaml:"receipt_workers"`
+	// Fetch receipts of matched whale txs of blocks whose receipts were skipped, one call per whale tx
+	// instead of the whole block, so whale rows get status/gas_used
+	FetchWhaleReceipts bool `json:"fetch_whale_receipts" yaml:"fetch_whale_receipts"`
 }
 
 // DefaultConfig returns a default configuration
@@ -58,18 +135,28 @@ func DefaultConfig() *Config {
 		InfuraNetwork:              "mainnet",
 		BatchSize:                  10, // Smaller batches for Infura
 		Workers:                    5,  // Infura rate limits
+		MinWorkers:                 1,  // AdaptiveWorkers ramp starts here
 		RequestTimeout:             30 * time.Second,
+		RangeTimeout:               10 * time.Minute, // a stuck node must not hold the cron lock forever
+		MaxRangeSize:               100000,           // ~2 weeks of mainnet blocks, raise it for deliberate backfills
 		OutputFormat:               "json",
 		OutputPath:                 "./output",
 		IncludeLogs:                false, // TODO: true для парсинга токен-транзакций
 		IncludeTraces:              false,
 		MaxTransactionsForReceipts: 1,    // Skip receipts for blocks with more than N transactions
+		MaxGasForReceipts:          0,    // Skip receipts for blocks with more than N gas used, 0 = tx count only
 		SkipReceiptsOnLargeBlocks:  true, // Enable skipping receipts for large blocks
+		ReceiptWorkers:             2,    // receipt batches are heavier than block calls
 		MinETHValue:                1,    // signal on TXNs with ETH value >= MinETHValue
+		WhaleDirection:             "both",
+		RetentionDays:              14,
 		WhalesAddr:                 WhaleAddresses(),
 		CsvPath:                    "./whale_txns.csv",
+		NdjsonPath:                 "./whale_txns.ndjson",
 		LastBlockPath:              "./last_block.dat",
+		PendingPath:                "./pending_whales.ndjson",
 		MaxBlockDelta:              100,
+		SlowBlockThreshold:         10 * time.Second,
 		DumpJsonFile:               false,
 	}
 }
@@ -0,0 +1,142 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Event ABIs decoded by DefaultEventRegistry. ERC-20 and ERC-721 Transfer/Approval share a signature
+// (and topic0), ERC-721 indexes the third argument too, so the two are told apart by the topic count.
+const (
+	erc20EventsABI = `[
+		{"type":"event","name":"Transfer","inputs":[
+			{"name":"from","type":"address","indexed":true},
+			{"name":"to","type":"address","indexed":true},
+			{"name":"value","type":"uint256","indexed":false}]},
+		{"type":"event","name":"Approval","inputs":[
+			{"name":"owner","type":"address","indexed":true},
+			{"name":"spender","type":"address","indexed":true},
+			{"name":"value","type":"uint256","indexed":false}]}
+	]`
+	erc721EventsABI = `[
+		{"type":"event","name":"Transfer","inputs":[
+			{"name":"from","type":"address","indexed":true},
+			{"name":"to","type":"address","indexed":true},
+			{"name":"tokenId","type":"uint256","indexed":true}]},
+		{"type":"event","name":"Approval","inputs":[
+			{"name":"owner","type":"address","indexed":true},
+			{"name":"approved","type":"address","indexed":true},
+			{"name":"tokenId","type":"uint256","indexed":true}]}
+	]`
+	wethEventsABI = `[
+		{"type":"event","name":"Deposit","inputs":[
+			{"name":"dst","type":"address","indexed":true},
+			{"name":"wad","type":"uint256","indexed":false}]},
+		{"type":"event","name":"Withdrawal","inputs":[
+			{"name":"src","type":"address","indexed":true},
+			{"name":"wad","type":"uint256","indexed":false}]}
+	]`
+)
+
+// eventKey identifies an event shape: topic0 and the number of topics (topic0 + indexed arguments)
+type eventKey struct {
+	topic0 common.Hash
+	topics int
+}
+
+// registeredEvent is an ABI event with the name it is reported under, e.g. "ERC721.Transfer"
+type registeredEvent struct {
+	name  string
+	event abi.Event
+}
+
+// EventRegistry decodes logs of known events into named fields, keyed by topic0 and topic count
+type EventRegistry struct {
+	events map[eventKey]registeredEvent
+}
+
+// NewEventRegistry creates an empty registry
+func NewEventRegistry() *EventRegistry {
+	return &EventRegistry{events: make(map[eventKey]registeredEvent)}
+}
+
+// Register adds the events of an ABI JSON, reported as "<standard>.<event name>".
+// An event with the same topic0 and topic count as a registered one replaces it.
+func (r *EventRegistry) Register(standard, abiJSON string) error {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return fmt.Errorf("failed to parse %s event ABI: %w", standard, err)
+	}
+	for _, event := range parsed.Events {
+		indexed := 0
+		for _, input := range event.Inputs {
+			if input.Indexed {
+				indexed++
+			}
+		}
+		key := eventKey{topic0: event.ID, topics: 1 + indexed}
+		r.events[key] = registeredEvent{name: standard + "." + event.RawName, event: event}
+	}
+	return nil
+}
+
+// Decode returns the event name and its arguments by ABI name, addresses as checksummed hex and
+// integers as decimal strings. ok is false for unknown events; err is set when a known event
+// has malformed data.
+func (r *EventRegistry) Decode(topics []common.Hash, data []byte) (name string, fields map[string]string, ok bool, err error) {
+	if len(topics) == 0 {
+		return "", nil, false, nil
+	}
+	registered, found := r.events[eventKey{topic0: topics[0], topics: len(topics)}]
+	if !found {
+		return "", nil, false, nil
+	}
+
+	values := make(map[string]interface{})
+	var indexed abi.Arguments
+	for _, input := range registered.event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(values, indexed, topics[1:]); err != nil {
+		return registered.name, nil, true, fmt.Errorf("failed to decode %s topics: %w", registered.name, err)
+	}
+	if err := registered.event.Inputs.NonIndexed().UnpackIntoMap(values, data); err != nil {
+		return registered.name, nil, true, fmt.Errorf("failed to decode %s data: %w", registered.name, err)
+	}
+
+	fields = make(map[string]string, len(values))
+	for argName, value := range values {
+		switch v := value.(type) {
+		case common.Address:
+			fields[argName] = v.Hex()
+		case *big.Int:
+			fields[argName] = v.String()
+		default:
+			fields[argName] = fmt.Sprint(v)
+		}
+	}
+	return registered.name, fields, true, nil
+}
+
+// DefaultEventRegistry decodes ERC-20 Transfer/Approval, ERC-721 Transfer/Approval and WETH Deposit/Withdrawal
+var DefaultEventRegistry = mustEventRegistry(map[string]string{
+	"ERC20":  erc20EventsABI,
+	"ERC721": erc721EventsABI,
+	"WETH":   wethEventsABI,
+})
+
+func mustEventRegistry(abis map[string]string) *EventRegistry {
+	r := NewEventRegistry()
+	for standard, abiJSON := range abis {
+		if err := r.Register(standard, abiJSON); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TestEventRegistryDecode tests decoding of each known event shape from topics and data
+func TestEventRegistryDecode(t *testing.T) {
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	approval := crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+	deposit := crypto.Keccak256Hash([]byte("Deposit(address,uint256)"))
+	withdrawal := crypto.Keccak256Hash([]byte("Withdrawal(address,uint256)"))
+	from := common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678")
+	to := common.HexToAddress("0xabcdefabcdefabcdefabcdefabcdefabcdefabcd")
+	addrTopic := func(a common.Address) common.Hash { return common.BytesToHash(a.Bytes()) }
+	word := func(v int64) []byte { return common.LeftPadBytes(big.NewInt(v).Bytes(), 32) }
+
+	tests := []struct {
+		name           string
+		topics         []common.Hash
+		data           []byte
+		expectedName   string
+		expectedFields map[string]string
+		expectErr      bool
+	}{
+		{
+			name:           "ERC-20 Transfer",
+			topics:         []common.Hash{transfer, addrTopic(from), addrTopic(to)},
+			data:           word(1000000),
+			expectedName:   "ERC20.Transfer",
+			expectedFields: map[string]string{"from": from.Hex(), "to": to.Hex(), "value": "1000000"},
+		},
+		{
+			name:           "ERC-721 Transfer",
+			topics:         []common.Hash{transfer, addrTopic(from), addrTopic(to), common.BigToHash(big.NewInt(42))},
+			expectedName:   "ERC721.Transfer",
+			expectedFields: map[string]string{"from": from.Hex(), "to": to.Hex(), "tokenId": "42"},
+		},
+		{
+			name:           "ERC-20 Approval",
+			topics:         []common.Hash{approval, addrTopic(from), addrTopic(to)},
+			data:           word(5),
+			expectedName:   "ERC20.Approval",
+			expectedFields: map[string]string{"owner": from.Hex(), "spender": to.Hex(), "value": "5"},
+		},
+		{
+			name:           "ERC-721 Approval",
+			topics:         []common.Hash{approval, addrTopic(from), addrTopic(to), common.BigToHash(big.NewInt(7))},
+			expectedName:   "ERC721.Approval",
+			expectedFields: map[string]string{"owner": from.Hex(), "approved": to.Hex(), "tokenId": "7"},
+		},
+		{
+			name:           "WETH Deposit",
+			topics:         []common.Hash{deposit, addrTopic(from)},
+			data:           word(2000000000000000000),
+			expectedName:   "WETH.Deposit",
+			expectedFields: map[string]string{"dst": from.Hex(), "wad": "2000000000000000000"},
+		},
+		{
+			name:           "WETH Withdrawal",
+			topics:         []common.Hash{withdrawal, addrTopic(to)},
+			data:           word(3),
+			expectedName:   "WETH.Withdrawal",
+			expectedFields: map[string]string{"src": to.Hex(), "wad": "3"},
+		},
+		{
+			name:   "Unknown event",
+			topics: []common.Hash{crypto.Keccak256Hash([]byte("Swap(address,uint256,uint256,uint256,uint256,address)"))},
+			data:   word(1),
+		},
+		{
+			name:   "Transfer with unexpected topic count",
+			topics: []common.Hash{transfer, addrTopic(from)},
+			data:   word(1),
+		},
+		{
+			name:   "No topics",
+			topics: nil,
+		},
+		{
+			name:         "ERC-20 Transfer without data",
+			topics:       []common.Hash{transfer, addrTopic(from), addrTopic(to)},
+			expectedName: "ERC20.Transfer",
+			expectErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, fields, ok, err := DefaultEventRegistry.Decode(tt.topics, tt.data)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("Expected error, got fields %v", fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok != (tt.expectedName != "") {
+				t.Fatalf("Expected known event %v, got %v", tt.expectedName != "", ok)
+			}
+			if name != tt.expectedName {
+				t.Errorf("Expected name %q, got %q", tt.expectedName, name)
+			}
+			if ok && !reflect.DeepEqual(fields, tt.expectedFields) {
+				t.Errorf("Expected fields %v, got %v", tt.expectedFields, fields)
+			}
+		})
+	}
+}
+
+// TestNewParsedLogDecoded tests that converted logs carry the decoded event, malformed ones stay undecoded
+func TestNewParsedLogDecoded(t *testing.T) {
+	transfer := crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	from := common.HexToAddress("0x01")
+	to := common.HexToAddress("0x02")
+	topics := []common.Hash{transfer, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())}
+
+	tests := []struct {
+		name         string
+		data         []byte
+		expectedName string
+		expectedData interface{}
+	}{
+		{"Decoded", common.LeftPadBytes([]byte{10}, 32), "ERC20.Transfer", map[string]string{"from": from.Hex(), "to": to.Hex(), "value": "10"}},
+		{"Malformed", []byte{1}, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := NewParsedLogFromGethLog(&types.Log{Topics: topics, Data: tt.data})
+			if parsed.DecodedEventName != tt.expectedName {
+				t.Errorf("Expected event %q, got %q", tt.expectedName, parsed.DecodedEventName)
+			}
+			if !reflect.DeepEqual(parsed.DecodedData, tt.expectedData) {
+				t.Errorf("Expected data %v, got %v", tt.expectedData, parsed.DecodedData)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// MaxStartupRetryInterval caps the backoff between startup connection attempts
+const MaxStartupRetryInterval = 30 * time.Second
+
+// DefaultStartupRetry rides out a few seconds of a slow DB mount or an unreachable provider at boot
+var DefaultStartupRetry = StartupRetry{Attempts: 3, Interval: 2 * time.Second}
+
+// StartupRetry retries the initial connections of a command with exponential backoff,
+// so a transient failure at boot doesn't exit the process
+type StartupRetry struct {
+	Attempts int           // total attempts, <= 1 = no retry
+	Interval time.Duration // wait after the first failure, doubled after each next one up to MaxStartupRetryInterval
+}
+
+// Do calls connect until it succeeds or the attempts are used up and returns the last error.
+// name describes the connection in the logs, ctx cancels the wait between attempts.
+func (r StartupRetry) Do(ctx context.Context, name string, connect func() error) error {
+	attempts := max(r.Attempts, 1)
+	wait := r.Interval
+	for attempt := 1; ; attempt++ {
+		err := connect()
+		if err == nil || attempt >= attempts {
+			if err != nil && attempts > 1 {
+				return fmt.Errorf("%s failed after %d attempts: %w", name, attempts, err)
+			}
+			return err
+		}
+
+		log.Printf("%s failed (attempt %d/%d), retrying in %v: %v", name, attempt, attempts, wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %w (last error: %v)", name, ctx.Err(), err)
+		}
+		wait = min(wait*2, MaxStartupRetryInterval)
+	}
+}
@@ -0,0 +1,81 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestStartupRetry tests that a connect failing N times succeeds on a later attempt
+// and that the last error is returned once the attempts are used up
+func TestStartupRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		attempts      int
+		failures      int
+		expectErr     bool
+		expectedCalls int
+	}{
+		{"succeeds at once", 3, 0, false, 1},
+		{"succeeds after 2 failures", 3, 2, false, 3},
+		{"attempts used up", 3, 5, true, 3},
+		{"no retry", 1, 1, true, 1},
+		{"zero attempts is one attempt", 0, 0, false, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry := StartupRetry{Attempts: tt.attempts, Interval: time.Millisecond}
+			connErr := errors.New("connection refused")
+			calls := 0
+
+			err := retry.Do(context.Background(), "test connection", func() error {
+				calls++
+				if calls <= tt.failures {
+					return fmt.Errorf("dial: %w", connErr)
+				}
+				return nil
+			})
+			if tt.expectErr {
+				if !errors.Is(err, connErr) {
+					t.Errorf("Expected the connect error, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if calls != tt.expectedCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+// TestStartupRetryCanceled tests that a canceled context stops the wait between attempts
+func TestStartupRetryCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	retry := StartupRetry{Attempts: 5, Interval: time.Hour}
+	calls := 0
+
+	done := make(chan error, 1)
+	go func() {
+		done <- retry.Do(ctx, "test connection", func() error {
+			calls++
+			return errors.New("connection refused")
+		})
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("Expected 1 call, got %d", calls)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Do to return after cancel")
+	}
+}
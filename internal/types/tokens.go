@@ -0,0 +1,104 @@
+package types
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// NativeAsset is the asset of native ETH transfers, token transfers carry the token symbol
+const NativeAsset = "ETH"
+
+// ETHDecimals is the number of decimals of wei amounts
+const ETHDecimals = 18
+
+// ETHValuePrecision is the number of fraction digits ETH values are rounded to, as stored in the DB
+const ETHValuePrecision = 5
+
+// TokenAmountPrecision is the number of fraction digits FormatTokenAmount rounds to, same as ETH values
+const TokenAmountPrecision = ETHValuePrecision
+
+// TokenMeta describes an ERC20 token: its ticker and the number of decimals of raw amounts
+type TokenMeta struct {
+	Symbol   string `json:"symbol" yaml:"symbol"`
+	Decimals uint8  `json:"decimals" yaml:"decimals"`
+}
+
+// TokenRegistry maps normalized (lowercase) token contract addresses to their metadata
+type TokenRegistry map[string]TokenMeta
+
+// DefaultTokens returns the registry of the most traded mainnet tokens
+func DefaultTokens() TokenRegistry {
+	return TokenRegistry{
+		"0xdac17f958d2ee523a2206206994597c13d831ec7": {Symbol: "USDT", Decimals: 6},
+		"0xa0b86991c6218b36c1d19d4a2e9eb0ce3606eb48": {Symbol: "USDC", Decimals: 6},
+		"0x6b175474e89094c44da98b954eedeac495271d0f": {Symbol: "DAI", Decimals: 18},
+		"0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2": {Symbol: "WETH", Decimals: 18},
+	}
+}
+
+// TokenRegistryFromFile reads one token per line as "address,symbol,decimals".
+// Empty lines and lines starting with # are skipped.
+func TokenRegistryFromFile(path string) (TokenRegistry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token file: %w", err)
+	}
+	defer file.Close()
+
+	registry := make(TokenRegistry)
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected address,symbol,decimals, got %q", lineNum, line)
+		}
+		decimals, err := strconv.ParseUint(strings.TrimSpace(fields[2]), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid decimals %q: %w", lineNum, fields[2], err)
+		}
+		registry.Add(fields[0], TokenMeta{Symbol: strings.TrimSpace(fields[1]), Decimals: uint8(decimals)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+	return registry, nil
+}
+
+// Add registers a token by its address in normalized form, replacing an existing entry
+func (r TokenRegistry) Add(addr string, meta TokenMeta) {
+	if addr = NormalizeAddress(addr); addr != "" {
+		r[addr] = meta
+	}
+}
+
+// Lookup returns the metadata of a token contract regardless of the address case
+func (r TokenRegistry) Lookup(addr string) (TokenMeta, bool) {
+	meta, ok := r[NormalizeAddress(addr)]
+	return meta, ok
+}
+
+// FormatUnits converts a raw integer amount with the given decimals to human units rounded to round
+// fraction digits, in decimal arithmetic without float loss: 1234567 with 6 decimals, round 5 -> "1.23457".
+// ETH amounts use ETHDecimals, tokens their TokenMeta.Decimals.
+func FormatUnits(raw *big.Int, decimals int, round int32) string {
+	if raw == nil {
+		return "0"
+	}
+	return decimal.NewFromBigInt(raw, -int32(decimals)).Round(round).String()
+}
+
+// FormatTokenAmount converts a raw token amount to whole tokens using meta.Decimals,
+// rounded to TokenAmountPrecision fraction digits, e.g. 1234567 USDT units -> "1.23457"
+func FormatTokenAmount(raw *big.Int, meta TokenMeta) string {
+	return FormatUnits(raw, int(meta.Decimals), TokenAmountPrecision)
+}
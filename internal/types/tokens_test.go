@@ -0,0 +1,151 @@
+package types
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFormatTokenAmount tests applying 6 and 18 decimals with rounding to TokenAmountPrecision digits
+func TestFormatTokenAmount(t *testing.T) {
+	usdt := TokenMeta{Symbol: "USDT", Decimals: 6}
+	weth := TokenMeta{Symbol: "WETH", Decimals: 18}
+	bigAmount, _ := new(big.Int).SetString("123456789012345678901234", 10)
+
+	tests := []struct {
+		name     string
+		raw      *big.Int
+		meta     TokenMeta
+		expected string
+	}{
+		{"6 decimals whole", big.NewInt(1_000_000), usdt, "1"},
+		{"6 decimals fraction", big.NewInt(2_500_000), usdt, "2.5"},
+		{"6 decimals rounded up", big.NewInt(1_234_567), usdt, "1.23457"},
+		{"6 decimals rounded down", big.NewInt(1_234_564), usdt, "1.23456"},
+		{"6 decimals below precision", big.NewInt(4), usdt, "0"},
+		{"6 decimals half rounds up", big.NewInt(5), usdt, "0.00001"},
+		{"18 decimals whole", big.NewInt(3e18), weth, "3"},
+		{"18 decimals rounded up", big.NewInt(1_999_996_000_000_000_000), weth, "2"},
+		{"18 decimals rounded down", big.NewInt(1_234_561_000_000_000_000), weth, "1.23456"},
+		{"18 decimals large", bigAmount, weth, "123456.78901"},
+		{"Zero decimals", big.NewInt(42), TokenMeta{Symbol: "X"}, "42"},
+		{"Nil amount", nil, usdt, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := FormatTokenAmount(tt.raw, tt.meta); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestFormatUnits tests 6-decimal USDC, 8-decimal WBTC and 18-decimal ETH amounts at several roundings
+func TestFormatUnits(t *testing.T) {
+	oneEther, _ := new(big.Int).SetString("1000000000000000000", 10)
+	smallWei, _ := new(big.Int).SetString("123456789012345678", 10)
+
+	tests := []struct {
+		name     string
+		raw      *big.Int
+		decimals int
+		round    int32
+		expected string
+	}{
+		{"USDC whole", big.NewInt(25_000_000), 6, 5, "25"},
+		{"USDC cents", big.NewInt(1_234_560_000), 6, 2, "1234.56"},
+		{"USDC full precision", big.NewInt(1_234_567_890), 6, 6, "1234.56789"},
+		{"USDC rounded", big.NewInt(1_234_567_890), 6, 5, "1234.56789"},
+		{"USDC one unit", big.NewInt(1), 6, 6, "0.000001"},
+		{"USDC one unit rounded away", big.NewInt(1), 6, 5, "0"},
+		{"WBTC whole", big.NewInt(150_000_000), 8, 5, "1.5"},
+		{"WBTC one satoshi", big.NewInt(1), 8, 8, "0.00000001"},
+		{"WBTC rounded up", big.NewInt(123_456_789), 8, 5, "1.23457"},
+		{"WBTC large", big.NewInt(2_100_000_000_000_000), 8, 5, "21000000"},
+		{"ETH whole", oneEther, ETHDecimals, ETHValuePrecision, "1"},
+		{"ETH rounded", smallWei, ETHDecimals, ETHValuePrecision, "0.12346"},
+		{"ETH full precision", smallWei, ETHDecimals, 18, "0.123456789012345678"},
+		{"Nil amount", nil, 6, 5, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := FormatUnits(tt.raw, tt.decimals, tt.round); result != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestTokenRegistry tests the seeded tokens and case-insensitive lookup
+func TestTokenRegistry(t *testing.T) {
+	registry := DefaultTokens()
+
+	tests := []struct {
+		addr     string
+		symbol   string
+		decimals uint8
+	}{
+		{"0xdAC17F958D2ee523a2206206994597C13D831ec7", "USDT", 6},
+		{"0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", "USDC", 6},
+		{"0x6B175474E89094C44Da98b954EedeAC495271d0F", "DAI", 18},
+		{"0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2", "WETH", 18},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			meta, ok := registry.Lookup(tt.addr)
+			if !ok || meta.Symbol != tt.symbol || meta.Decimals != tt.decimals {
+				t.Errorf("Expected %s with %d decimals, got %+v (found %v)", tt.symbol, tt.decimals, meta, ok)
+			}
+		})
+	}
+
+	if _, ok := registry.Lookup("0x0000000000000000000000000000000000000001"); ok {
+		t.Error("Expected unknown token not to be found")
+	}
+}
+
+// TestTokenRegistryFromFile tests loading tokens and rejecting malformed lines
+func TestTokenRegistryFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "tokens.csv")
+	content := "# address,symbol,decimals\n\n0x514910771AF9Ca656af840dff83E8264EcF986CA, LINK, 18\n0x2260fac5e5542a773aa44fbcfedf7c193bc2c599,WBTC,8\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+	registry, err := TokenRegistryFromFile(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(registry) != 2 {
+		t.Fatalf("Expected 2 tokens, got %d", len(registry))
+	}
+	if meta, ok := registry.Lookup("0x514910771af9ca656af840dff83e8264ecf986ca"); !ok || meta.Symbol != "LINK" || meta.Decimals != 18 {
+		t.Errorf("Expected LINK with 18 decimals, got %+v", meta)
+	}
+	if got := FormatTokenAmount(big.NewInt(150_000_000), registry["0x2260fac5e5542a773aa44fbcfedf7c193bc2c599"]); got != "1.5" {
+		t.Errorf("Expected 1.5 WBTC, got %s", got)
+	}
+
+	for name, line := range map[string]string{
+		"Missing decimals": "0x514910771af9ca656af840dff83e8264ecf986ca,LINK\n",
+		"Invalid decimals": "0x514910771af9ca656af840dff83e8264ecf986ca,LINK,300\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			bad := filepath.Join(dir, "bad.csv")
+			if err := os.WriteFile(bad, []byte(line), 0644); err != nil {
+				t.Fatalf("Failed to write token file: %v", err)
+			}
+			if _, err := TokenRegistryFromFile(bad); err == nil {
+				t.Error("Expected error for malformed line")
+			}
+		})
+	}
+
+	if _, err := TokenRegistryFromFile(filepath.Join(dir, "missing.csv")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
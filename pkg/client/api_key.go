@@ -0,0 +1,38 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKeyEnvVars are the environment variables checked for the Infura API key, in precedence order
+var APIKeyEnvVars = []string{
+	"INFURA_API_KEY",
+	"INFURA_PROJECT_ID",
+	"INFURA_KEY",
+	"INFURA_ID",
+}
+
+// apiKeyPlaceholders are template values from docs and .env.example, treated as unset
+var apiKeyPlaceholders = map[string]bool{
+	"YOUR_INFURA_API_KEY_HERE":    true,
+	"your-infura-project-id-here": true,
+}
+
+// ErrAPIKeyNotSet is returned by APIKeyFromEnv when none of APIKeyEnvVars is set
+var ErrAPIKeyNotSet = errors.New("infura API key is not set")
+
+// APIKeyFromEnv returns the Infura API key from the first non-empty variable of APIKeyEnvVars.
+// There is no built-in fallback key: entry points must fail fast when ErrAPIKeyNotSet is returned.
+func APIKeyFromEnv() (string, error) {
+	for _, envVar := range APIKeyEnvVars {
+		key := strings.TrimSpace(os.Getenv(envVar))
+		if key != "" && !apiKeyPlaceholders[key] {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("%w: get a key at https://infura.io and export one of %s",
+		ErrAPIKeyNotSet, strings.Join(APIKeyEnvVars, ", "))
+}
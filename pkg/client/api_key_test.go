@@ -0,0 +1,79 @@
+package client
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestAPIKeyFromEnv tests the env variable precedence order and the unset/placeholder cases
+func TestAPIKeyFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:    "nothing set",
+			env:     map[string]string{},
+			wantErr: true,
+		},
+		{
+			name:     "only INFURA_ID",
+			env:      map[string]string{"INFURA_ID": "id-key"},
+			expected: "id-key",
+		},
+		{
+			name:     "INFURA_KEY wins over INFURA_ID",
+			env:      map[string]string{"INFURA_KEY": "key-key", "INFURA_ID": "id-key"},
+			expected: "key-key",
+		},
+		{
+			name:     "INFURA_PROJECT_ID wins over INFURA_KEY",
+			env:      map[string]string{"INFURA_PROJECT_ID": "project-key", "INFURA_KEY": "key-key"},
+			expected: "project-key",
+		},
+		{
+			name: "INFURA_API_KEY wins over all",
+			env: map[string]string{
+				"INFURA_API_KEY":    "api-key",
+				"INFURA_PROJECT_ID": "project-key",
+				"INFURA_KEY":        "key-key",
+				"INFURA_ID":         "id-key",
+			},
+			expected: "api-key",
+		},
+		{
+			name:     "empty and blank values are skipped",
+			env:      map[string]string{"INFURA_API_KEY": "", "INFURA_PROJECT_ID": "  ", "INFURA_KEY": "key-key"},
+			expected: "key-key",
+		},
+		{
+			name:    "placeholder is treated as unset",
+			env:     map[string]string{"INFURA_API_KEY": "YOUR_INFURA_API_KEY_HERE"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, envVar := range APIKeyEnvVars {
+				t.Setenv(envVar, tt.env[envVar])
+			}
+
+			key, err := APIKeyFromEnv()
+			if tt.wantErr {
+				if !errors.Is(err, ErrAPIKeyNotSet) {
+					t.Errorf("Expected ErrAPIKeyNotSet, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if key != tt.expected {
+				t.Errorf("Expected key %q, got %q", tt.expected, key)
+			}
+		})
+	}
+}
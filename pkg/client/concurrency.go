@@ -0,0 +1,59 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// DefaultMaxConcurrentRequests bounds in-flight RPC requests when ConnectionConfig.MaxConcurrentRequests is 0
+const DefaultMaxConcurrentRequests = 4
+
+// concurrencyLimitTransport is an http.RoundTripper with a counting semaphore: at most cap(slots)
+// requests are in flight, a slot is held until the response body is closed. The ticker rate limiter
+// only spaces request starts, workers released by it together could otherwise all hit the node at once.
+type concurrencyLimitTransport struct {
+	base  http.RoundTripper
+	slots chan struct{}
+}
+
+// newConcurrencyLimitTransport wraps base, http.DefaultTransport is used when base is nil
+func newConcurrencyLimitTransport(base http.RoundTripper, maxInFlight int) *concurrencyLimitTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if maxInFlight <= 0 {
+		maxInFlight = DefaultMaxConcurrentRequests
+	}
+	return &concurrencyLimitTransport{base: base, slots: make(chan struct{}, maxInFlight)}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *concurrencyLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Body == nil {
+		<-t.slots
+		return resp, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() { <-t.slots }}
+	return resp, nil
+}
+
+// releaseOnClose frees the semaphore slot once the response body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowRoundTripper answers eth_blockNumber after a delay and records the peak number of concurrent requests
+type slowRoundTripper struct {
+	delay    time.Duration
+	inFlight atomic.Int64
+	peak     atomic.Int64
+}
+
+func (m *slowRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	for {
+		peak := m.peak.Load()
+		if n <= peak || m.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+
+	time.Sleep(m.delay)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     http.StatusText(http.StatusOK),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`)),
+		Request:    req,
+	}, nil
+}
+
+// TestMaxConcurrentRequests tests that concurrent RPC calls never exceed the configured maximum
+func TestMaxConcurrentRequests(t *testing.T) {
+	const maxInFlight = 3
+
+	rt := &slowRoundTripper{delay: 20 * time.Millisecond}
+	c := newMockedClient(t, newConcurrencyLimitTransport(rt, maxInFlight))
+
+	var wg sync.WaitGroup
+	var failed atomic.Int64
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result string
+			if err := c.rpcClient.CallContext(context.Background(), &result, "eth_blockNumber"); err != nil {
+				failed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := failed.Load(); n != 0 {
+		t.Fatalf("Expected all calls to succeed, %d failed", n)
+	}
+	if peak := rt.peak.Load(); peak > maxInFlight {
+		t.Errorf("Expected at most %d concurrent requests, got %d", maxInFlight, peak)
+	}
+}
+
+// TestConcurrencyLimitContextCancel tests that a request waiting for a slot gives up when its context is done
+func TestConcurrencyLimitContextCancel(t *testing.T) {
+	transport := newConcurrencyLimitTransport(&slowRoundTripper{}, 1)
+	transport.slots <- struct{}{} // the only slot is taken
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "http://node.invalid", nil)
+
+	if _, err := transport.RoundTrip(req); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -3,10 +3,13 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -25,8 +28,18 @@ type EthClient struct {
 	retries        int
 	isInfura       bool
 	infuraConfig   *InfuraConfig
-	rateLimiter    *time.Ticker // Simple rate limiting for Infura
-	batchSizeLimit int          // Maximum batch size for RPC calls
+	rateLimiter    RateLimiter // Paces RPC calls, nil = no limit
+	batchSizeLimit int         // Maximum batch size for RPC calls
+
+	transport     *retryAfterTransport // Captures Retry-After of 429 responses
+	rateLimitHits atomic.Uint64        // rate limit errors seen by executeWithRetry, read by adaptive parsers
+
+	wsURL            string        // WebSocket endpoint for subscriptions, empty if only HTTP is configured
+	wsReconnectDelay time.Duration // Initial delay before resubscribing a dropped subscription
+
+	maxFallbackValueETH uint64 // Fallback txs above this value are rejected, DefaultMaxFallbackValueETH if 0
+
+	quota *requestQuota // Daily request count and cap, nil = not counted
 }
 
 // InfuraConfig holds Infura-specific configuration
@@ -38,6 +51,28 @@ type InfuraConfig struct {
 	WSURL     string
 }
 
+// PartialBlockError is returned together with a reconstructed block when some or all of its
+// transactions could not be decoded (unsupported transaction types). Block is still usable,
+// but callers should not treat it as complete.
+type PartialBlockError struct {
+	BlockNumber uint64
+	Block       *types.Block
+	HeaderOnly  bool  // all transactions were dropped, only the header is available
+	Skipped     int   // number of transactions skipped during reconstruction
+	Reason      error // underlying decode/RPC error, if any
+}
+
+func (e *PartialBlockError) Error() string {
+	if e.HeaderOnly {
+		return fmt.Sprintf("block %d reconstructed from header only: %v", e.BlockNumber, e.Reason)
+	}
+	return fmt.Sprintf("block %d reconstructed with %d transactions skipped", e.BlockNumber, e.Skipped)
+}
+
+func (e *PartialBlockError) Unwrap() error {
+	return e.Reason
+}
+
 // ConnectionConfig holds connection parameters
 type ConnectionConfig struct {
 	NodeURL         string
@@ -48,8 +83,25 @@ type ConnectionConfig struct {
 	InfuraAPIKey    string // This is the Project ID from Infura
 	InfuraAPISecret string // Optional API Secret for paid plans
 	InfuraNetwork   string
+
+	MaxConcurrentRequests int // In-flight HTTP RPC requests limit, DefaultMaxConcurrentRequests if 0
+
+	// RateLimiter paces RPC calls, e.g. NewTokenBucketLimiter for bursts; nil = a TickerLimiter
+	// of one call per 250ms for Infura, no limit otherwise
+	RateLimiter RateLimiter
+
+	// MaxFallbackValueETH rejects manually decoded txs of unsupported types with a larger value,
+	// DefaultMaxFallbackValueETH if 0
+	MaxFallbackValueETH uint64
 }
 
+// DefaultMaxFallbackValueETH caps the value of manually decoded txs: no single real transfer comes
+// near 10M ETH, so a fallback tx worth more comes from a malformed payload
+const DefaultMaxFallbackValueETH = 10_000_000
+
+// ErrImplausibleValue is returned for a fallback transaction whose value fails the sanity check
+var ErrImplausibleValue = errors.New("implausible transaction value")
+
 // NewEthClient creates a new Ethereum client wrapper
 func NewEthClient(config ConnectionConfig) (*EthClient, error) {
 	if config.Timeout == 0 {
@@ -65,6 +117,13 @@ func NewEthClient(config ConnectionConfig) (*EthClient, error) {
 		retries:        config.Retries,
 		isInfura:       config.UseInfura,
 		batchSizeLimit: 5, // Very conservative default for Infura
+		transport:      newRetryAfterTransport(newConcurrencyLimitTransport(nil, config.MaxConcurrentRequests)),
+
+		wsURL:            config.WSNodeURL,
+		wsReconnectDelay: defaultWSReconnectDelay,
+
+		maxFallbackValueETH: config.MaxFallbackValueETH,
+		rateLimiter:         config.RateLimiter,
 	}
 
 	// Setup Infura configuration if enabled
@@ -78,9 +137,12 @@ func NewEthClient(config ConnectionConfig) (*EthClient, error) {
 		}
 		client.infuraConfig = infuraConfig
 		client.nodeURL = infuraConfig.HTTPURL
+		client.wsURL = infuraConfig.WSURL
 
-		// Set up rate limiting for Infura (4 requests per second to be very conservative)
-		client.rateLimiter = time.NewTicker(250 * time.Millisecond)
+		// Set up rate limiting for Infura unless a limiter is configured
+		if client.rateLimiter == nil {
+			client.rateLimiter = NewTickerLimiter(defaultInfuraCallInterval)
+		}
 
 		// Further reduce batch size for Infura
 		client.batchSizeLimit = 6
@@ -100,7 +162,7 @@ func (c *EthClient) connect() error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
 	defer cancel()
 
-	rpcClient, err := rpc.DialContext(ctx, c.nodeURL)
+	rpcClient, err := rpc.DialOptions(ctx, c.nodeURL, rpc.WithHTTPClient(&http.Client{Transport: c.transport}))
 	if err != nil {
 		return fmt.Errorf("failed to connect to RPC: %w", err)
 	}
@@ -109,7 +171,10 @@ func (c *EthClient) connect() error {
 	c.client = ethclient.NewClient(rpcClient)
 
 	// Test the connection with rate limiting
-	c.waitForRateLimit()
+	if err := c.waitForRateLimit(ctx); err != nil {
+		c.rpcClient.Close()
+		return err
+	}
 	if _, err := c.client.NetworkID(ctx); err != nil {
 		c.rpcClient.Close()
 		return fmt.Errorf("failed to verify connection: %w", err)
@@ -121,8 +186,11 @@ func (c *EthClient) connect() error {
 
 // Close closes the connection to the Ethereum node
 func (c *EthClient) Close() {
-	if c.rateLimiter != nil {
-		c.rateLimiter.Stop()
+	if c.quota != nil {
+		c.quota.flush(context.Background())
+	}
+	if stopper, ok := c.rateLimiter.(interface{ Stop() }); ok {
+		stopper.Stop()
 	}
 	if c.rpcClient != nil {
 		c.rpcClient.Close()
@@ -131,7 +199,7 @@ func (c *EthClient) Close() {
 
 // GetLatestBlockNumber returns the latest block number with rate limit handling
 func (c *EthClient) GetLatestBlockNumber(ctx context.Context) (uint64, error) {
-	result, err := c.executeWithRetry(func() (interface{}, error) {
+	result, err := c.executeWithRetry(ctx, func() (interface{}, error) {
 		header, err := c.client.HeaderByNumber(ctx, nil)
 		if err != nil {
 			return nil, err
@@ -146,9 +214,60 @@ func (c *EthClient) GetLatestBlockNumber(ctx context.Context) (uint64, error) {
 	return result.(uint64), nil
 }
 
-// GetBlockByNumber retrieves a block by its number with error handling for unsupported transaction types
+// ErrBlockTagUnsupported is returned by GetFinalizedBlockNumber and GetSafeBlockNumber when the provider
+// doesn't know the tag (pre-merge chains, some L2s and old nodes)
+var ErrBlockTagUnsupported = errors.New("block tag not supported by the provider")
+
+// GetFinalizedBlockNumber returns the latest finalized block number, the block can't be reorged
+// without slashing a third of the validators
+func (c *EthClient) GetFinalizedBlockNumber(ctx context.Context) (uint64, error) {
+	return c.getTaggedBlockNumber(ctx, rpc.FinalizedBlockNumber)
+}
+
+// GetSafeBlockNumber returns the latest safe (justified) block number, unlikely to be reorged
+// but without the guarantee of finalized
+func (c *EthClient) GetSafeBlockNumber(ctx context.Context) (uint64, error) {
+	return c.getTaggedBlockNumber(ctx, rpc.SafeBlockNumber)
+}
+
+// getTaggedBlockNumber returns the number of the block behind a tag, an unsupported tag
+// fails with ErrBlockTagUnsupported without retries
+func (c *EthClient) getTaggedBlockNumber(ctx context.Context, tag rpc.BlockNumber) (uint64, error) {
+	result, err := c.executeWithRetry(ctx, func() (interface{}, error) {
+		header, err := c.client.HeaderByNumber(ctx, big.NewInt(tag.Int64()))
+		if err != nil {
+			if isUnsupportedTagError(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return header.Number.Uint64(), nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	if result == nil {
+		return 0, fmt.Errorf("%s block: %w", tag, ErrBlockTagUnsupported)
+	}
+
+	return result.(uint64), nil
+}
+
+// isUnsupportedTagError reports whether a tagged block lookup failed because the node doesn't know the tag:
+// null result, or an error like "'finalized' tag not supported on pre-merge network"
+func isUnsupportedTagError(err error) bool {
+	if errors.Is(err, ethereum.NotFound) {
+		return true
+	}
+	errorStr := err.Error()
+	return strings.Contains(errorStr, "not supported") || strings.Contains(errorStr, "block not found")
+}
+
+// GetBlockByNumber retrieves a block by its number with error handling for unsupported transaction types.
+// If the block had to be reconstructed, it is returned together with a *PartialBlockError.
 func (c *EthClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
-	result, err := c.executeWithRetry(func() (interface{}, error) {
+	result, err := c.executeWithRetry(ctx, func() (interface{}, error) {
 		// First try the standard method
 		block, err := c.client.BlockByNumber(ctx, big.NewInt(int64(blockNumber)))
 		if err == nil {
@@ -158,7 +277,13 @@ func (c *EthClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*
 		// If we get a "transaction type not supported" error, try to reconstruct the block
 		if strings.Contains(err.Error(), "transaction type not supported") {
 			log.Printf("Block %d contains unsupported transaction types, attempting to reconstruct with supported transactions", blockNumber)
-			return c.getBlockWithFilteredTransactions(ctx, blockNumber)
+			block, err := c.getBlockWithFilteredTransactions(ctx, blockNumber)
+			// a degraded block is a result, not a failure - don't retry it
+			var partial *PartialBlockError
+			if errors.As(err, &partial) {
+				return partial, nil
+			}
+			return block, err
 		}
 
 		return nil, err
@@ -168,18 +293,25 @@ func (c *EthClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*
 		return nil, err
 	}
 
+	if partial, ok := result.(*PartialBlockError); ok {
+		return partial.Block, partial
+	}
 	return result.(*types.Block), nil
 }
 
 // GetBlockByHash retrieves a block by its hash
 func (c *EthClient) GetBlockByHash(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
-	c.waitForRateLimit()
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	return c.client.BlockByHash(ctx, blockHash)
 }
 
 // GetTransactionReceipt retrieves transaction receipt
 func (c *EthClient) GetTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
-	c.waitForRateLimit()
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	return c.client.TransactionReceipt(ctx, txHash)
 }
 
@@ -212,7 +344,7 @@ func (c *EthClient) GetTransactionReceiptsBatch(ctx context.Context, txHashes []
 
 // getReceiptsBatchOptimized tries to get receipts in an optimized batch with better error handling
 func (c *EthClient) getReceiptsBatchOptimized(ctx context.Context, txHashes []common.Hash) ([]*types.Receipt, error) {
-	result, err := c.executeWithRetry(func() (interface{}, error) {
+	result, err := c.executeWithRetry(ctx, func() (interface{}, error) {
 		receipts := make([]*types.Receipt, len(txHashes))
 
 		// Create batch request with proper initialization
@@ -290,7 +422,9 @@ func (c *EthClient) getReceiptsInChunks(ctx context.Context, txHashes []common.H
 
 		// Apply rate limiting between chunks
 		if i > 0 {
-			c.waitForRateLimit()
+			if err := c.waitForRateLimit(ctx); err != nil {
+				return nil, err
+			}
 		}
 
 		// Try batch first, then fall back to individual for this chunk
@@ -352,7 +486,10 @@ func (c *EthClient) getReceiptsIndividually(ctx context.Context, txHashes []comm
 	receipts := make([]*types.Receipt, len(txHashes))
 
 	for i, txHash := range txHashes {
-		c.waitForRateLimit() // Apply rate limiting for individual calls
+		// Apply rate limiting for individual calls
+		if err := c.waitForRateLimit(ctx); err != nil {
+			return nil, err
+		}
 
 		receipt, err := c.client.TransactionReceipt(ctx, txHash)
 		if err != nil {
@@ -372,27 +509,102 @@ func (c *EthClient) getReceiptsIndividually(ctx context.Context, txHashes []comm
 	return receipts, nil
 }
 
-// GetLogs retrieves event logs based on filter criteria
+// GetLogs retrieves event logs based on filter criteria. A query refused for exceeding the
+// provider's result cap is split in halves by block range until every part fits; a single block
+// over the cap, or a query without an explicit block range, fails with ErrLogResultCap.
 func (c *EthClient) GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
-	c.waitForRateLimit()
-	return c.client.FilterLogs(ctx, query)
+	result, err := c.executeWithRetry(ctx, func() (interface{}, error) {
+		logs, err := c.client.FilterLogs(ctx, query)
+		// the cap depends on the range, not a transient failure - don't retry it as a rate limit
+		if isLogResultCapError(err) {
+			return &logResultCapError{err: err}, nil
+		}
+		return logs, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	capped, ok := result.(*logResultCapError)
+	if !ok {
+		return result.([]types.Log), nil
+	}
+
+	from, to := query.FromBlock, query.ToBlock
+	if query.BlockHash != nil || from == nil || to == nil || from.Sign() < 0 || to.Sign() < 0 || to.Cmp(from) <= 0 {
+		return nil, fmt.Errorf("%w: %v", ErrLogResultCap, capped.err)
+	}
+
+	mid := new(big.Int).Add(from, to)
+	mid.Rsh(mid, 1)
+	log.Printf("Logs of blocks %s-%s exceed the provider result cap, splitting at block %s", from, to, mid)
+
+	lower, upper := query, query
+	lower.ToBlock = mid
+	upper.FromBlock = new(big.Int).Add(mid, big.NewInt(1))
+
+	logs, err := c.GetLogs(ctx, lower)
+	if err != nil {
+		return nil, err
+	}
+	upperLogs, err := c.GetLogs(ctx, upper)
+	if err != nil {
+		return nil, err
+	}
+	return append(logs, upperLogs...), nil
+}
+
+// ErrLogResultCap is returned by GetLogs when the provider refuses a log query for returning too
+// many results (Infura: "query returned more than 10000 results") and it can't be split further
+var ErrLogResultCap = errors.New("log query exceeds provider result cap")
+
+// logResultCapError marks a capped FilterLogs attempt, passed through executeWithRetry as a result
+type logResultCapError struct {
+	err error
+}
+
+// logResultCapMessages are provider errors for log queries returning too many results
+var logResultCapMessages = []string{
+	"query returned more than",   // Infura, geth
+	"log response size exceeded", // Alchemy
+	"exceeds max results",
+}
+
+// isLogResultCapError checks if the error is a provider refusing a too broad log query
+func isLogResultCapError(err error) bool {
+	if err == nil {
+		return false
+	}
+	errorStr := strings.ToLower(err.Error())
+	for _, msg := range logResultCapMessages {
+		if strings.Contains(errorStr, msg) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetNetworkID returns the network/chain ID
 func (c *EthClient) GetNetworkID(ctx context.Context) (*big.Int, error) {
-	c.waitForRateLimit()
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	return c.client.NetworkID(ctx)
 }
 
 // GetBalance returns the balance of an account at a specific block
 func (c *EthClient) GetBalance(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
-	c.waitForRateLimit()
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	return c.client.BalanceAt(ctx, account, blockNumber)
 }
 
 // GetCode returns the contract code at a specific address and block
 func (c *EthClient) GetCode(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
-	c.waitForRateLimit()
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 	return c.client.CodeAt(ctx, contract, blockNumber)
 }
 
@@ -409,7 +621,7 @@ func (c *EthClient) Reconnect() error {
 }
 
 // executeWithRetry executes a function with automatic retry on connection errors
-func (c *EthClient) executeWithRetry(fn func() (interface{}, error)) (interface{}, error) {
+func (c *EthClient) executeWithRetry(ctx context.Context, fn func() (interface{}, error)) (interface{}, error) {
 	var result interface{}
 	var err error
 
@@ -428,7 +640,9 @@ func (c *EthClient) executeWithRetry(fn func() (interface{}, error)) (interface{
 		}
 
 		// Apply rate limiting for Infura
-		c.waitForRateLimit()
+		if err := c.waitForRateLimit(ctx); err != nil {
+			return nil, err
+		}
 
 		result, err = fn()
 		if err == nil {
@@ -437,7 +651,8 @@ func (c *EthClient) executeWithRetry(fn func() (interface{}, error)) (interface{
 
 		// Check for rate limit errors and handle them specially
 		if c.isRateLimitError(err) {
-			waitTime := c.calculateRateLimitBackoff(attempt)
+			c.rateLimitHits.Add(1)
+			waitTime := c.rateLimitWait(attempt)
 			log.Printf("Rate limit exceeded, waiting %v before retry (attempt %d/%d)", waitTime, attempt+1, c.retries+1)
 			time.Sleep(waitTime)
 			continue
@@ -451,6 +666,16 @@ func (c *EthClient) executeWithRetry(fn func() (interface{}, error)) (interface{
 
 // isRateLimitError checks if the error is a rate limit error
 func (c *EthClient) isRateLimitError(err error) bool {
+	return IsRateLimitError(err)
+}
+
+// RateLimitHits returns how many rate limit errors the client got so far, including retried ones
+func (c *EthClient) RateLimitHits() uint64 {
+	return c.rateLimitHits.Load()
+}
+
+// IsRateLimitError reports whether err is a provider rate limit error (HTTP 429 or a limit message)
+func IsRateLimitError(err error) bool {
 	if err == nil {
 		return false
 	}
@@ -461,6 +686,17 @@ func (c *EthClient) isRateLimitError(err error) bool {
 		strings.Contains(errorStr, "exceeded")
 }
 
+// rateLimitWait returns how long to wait after a rate limit error: the server's
+// Retry-After when it sent one, exponential backoff otherwise
+func (c *EthClient) rateLimitWait(attempt int) time.Duration {
+	if c.transport != nil {
+		if wait := c.transport.takeRetryAfter(); wait > 0 {
+			return wait
+		}
+	}
+	return c.calculateRateLimitBackoff(attempt)
+}
+
 // calculateRateLimitBackoff calculates exponential backoff for rate limit errors
 func (c *EthClient) calculateRateLimitBackoff(attempt int) time.Duration {
 	// Start with 1 second, double each attempt, max 60 seconds
@@ -493,23 +729,35 @@ func buildInfuraWSURL(network, projectID, apiKey string) string {
 	return baseURL
 }
 
-// waitForRateLimit implements rate limiting for Infura requests
-func (c *EthClient) waitForRateLimit() {
-	if c.isInfura && c.rateLimiter != nil {
-		<-c.rateLimiter.C
+// waitForRateLimit counts an RPC call against the daily quota and waits for the rate limiter before it,
+// the error is a *QuotaExceededError or the ctx error
+func (c *EthClient) waitForRateLimit(ctx context.Context) error {
+	if c.quota != nil {
+		if err := c.quota.take(ctx); err != nil {
+			return err
+		}
+	}
+	if c.rateLimiter == nil {
+		return nil
 	}
+	if err := c.rateLimiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limiter wait: %w", err)
+	}
+	return nil
 }
 
 // getBlockWithFilteredTransactions attempts to get block data using raw RPC calls to handle unsupported transaction types
 func (c *EthClient) getBlockWithFilteredTransactions(ctx context.Context, blockNumber uint64) (*types.Block, error) {
-	c.waitForRateLimit()
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 
 	// Use raw RPC call to get block with transactions, but with error recovery
 	var result map[string]interface{}
 	err := c.rpcClient.CallContext(ctx, &result, "eth_getBlockByNumber", fmt.Sprintf("0x%x", blockNumber), true)
 	if err != nil {
 		log.Printf("Raw RPC call failed for block %d: %v", blockNumber, err)
-		return c.getBlockWithHeaderOnly(ctx, blockNumber)
+		return c.getBlockWithHeaderOnly(ctx, blockNumber, err)
 	}
 
 	if result == nil {
@@ -520,7 +768,7 @@ func (c *EthClient) getBlockWithFilteredTransactions(ctx context.Context, blockN
 	header, err := c.parseBlockHeader(result)
 	if err != nil {
 		log.Printf("Failed to parse block header for block %d: %v", blockNumber, err)
-		return c.getBlockWithHeaderOnly(ctx, blockNumber)
+		return c.getBlockWithHeaderOnly(ctx, blockNumber, err)
 	}
 
 	// Extract transactions with error handling
@@ -528,26 +776,33 @@ func (c *EthClient) getBlockWithFilteredTransactions(ctx context.Context, blockN
 
 	log.Printf("Successfully parsed block %d with %d transactions (%d skipped due to unsupported types)", blockNumber, len(txs), skipped)
 
-	// Create block with the parsed transactions
-	emptyUncles := make([]*types.Header, 0)
-	emptyWithdrawals := make([]*types.Withdrawal, 0)
+	// Withdrawals are only present after Shanghai, nil keeps pre-Shanghai blocks without them
+	withdrawals, err := parseBlockWithdrawals(result)
+	if err != nil {
+		log.Printf("Failed to parse withdrawals for block %d: %v", blockNumber, err)
+	}
 
-	// Create body for the new block structure
-	body := &types.Body{
+	// Create block with the parsed transactions. The header hashes are kept as returned
+	// by the node, so the block hash stays the same with skipped transactions.
+	body := types.Body{
 		Transactions: txs,
-		Uncles:       emptyUncles,
-		Withdrawals:  emptyWithdrawals,
+		Uncles:       make([]*types.Header, 0),
+		Withdrawals:  withdrawals,
 	}
+	block := types.NewBlockWithHeader(header).WithBody(body)
 
-	// Create block with empty receipts and nil hasher (receipts will be computed later)
-	block := types.NewBlock(header, body, nil, nil)
-
+	if skipped > 0 {
+		return block, &PartialBlockError{BlockNumber: blockNumber, Block: block, Skipped: skipped}
+	}
 	return block, nil
 }
 
-// getBlockWithHeaderOnly creates a block with only header info when transaction parsing fails
-func (c *EthClient) getBlockWithHeaderOnly(ctx context.Context, blockNumber uint64) (*types.Block, error) {
-	c.waitForRateLimit()
+// getBlockWithHeaderOnly creates a block with only header info when transaction parsing fails.
+// The block is returned with a *PartialBlockError carrying the reason.
+func (c *EthClient) getBlockWithHeaderOnly(ctx context.Context, blockNumber uint64, reason error) (*types.Block, error) {
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
 
 	// Get the block header
 	header, err := c.client.HeaderByNumber(ctx, big.NewInt(int64(blockNumber)))
@@ -573,7 +828,7 @@ func (c *EthClient) getBlockWithHeaderOnly(ctx context.Context, blockNumber uint
 
 	log.Printf("Created fallback block %d with header only (transactions skipped due to unsupported types)", blockNumber)
 
-	return block, nil
+	return block, &PartialBlockError{BlockNumber: blockNumber, Block: block, HeaderOnly: true, Reason: reason}
 }
 
 // NewInfuraClient creates a new Ethereum client specifically for Infura
@@ -650,6 +905,24 @@ func (c *EthClient) parseBlockHeader(result map[string]interface{}) (*types.Head
 	return &header, nil
 }
 
+// parseBlockWithdrawals parses the withdrawals array of a raw RPC block, nil if the block has none (pre-Shanghai)
+func parseBlockWithdrawals(result map[string]interface{}) ([]*types.Withdrawal, error) {
+	raw, ok := result["withdrawals"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+
+	jsonData, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal withdrawals: %w", err)
+	}
+	withdrawals := make([]*types.Withdrawal, 0)
+	if err := json.Unmarshal(jsonData, &withdrawals); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal withdrawals: %w", err)
+	}
+	return withdrawals, nil
+}
+
 // parseBlockTransactions parses transactions from raw RPC response, skipping unsupported types
 func (c *EthClient) parseBlockTransactions(result map[string]interface{}, blockNumber uint64) ([]*types.Transaction, int) {
 	txsData, ok := result["transactions"].([]interface{})
@@ -704,7 +977,30 @@ func (c *EthClient) parseTransaction(txMap map[string]interface{}) (*types.Trans
 	return &tx, nil
 }
 
-// createFallbackTransaction creates a basic transaction object for unsupported transaction types
+// maxFallbackValue returns the fallback tx value cap in wei
+func (c *EthClient) maxFallbackValue() *big.Int {
+	eth := c.maxFallbackValueETH
+	if eth == 0 {
+		eth = DefaultMaxFallbackValueETH
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(eth), big.NewInt(1e18))
+}
+
+// parseHexQuantity parses a 0x-prefixed hex quantity of a fallback tx field, "" is zero
+func parseHexQuantity(field, value string) (*big.Int, error) {
+	n := new(big.Int)
+	if value == "" {
+		return n, nil
+	}
+	if _, ok := n.SetString(strings.TrimPrefix(value, "0x"), 16); !ok || n.Sign() < 0 {
+		return nil, fmt.Errorf("invalid %s %q: %w", field, value, ErrImplausibleValue)
+	}
+	return n, nil
+}
+
+// createFallbackTransaction creates a basic transaction object for unsupported transaction types.
+// Fields are decoded by hand, so a malformed value or one above the MaxFallbackValueETH cap is
+// rejected (logged as an anomaly) instead of turning into a giant whale tx.
 func (c *EthClient) createFallbackTransaction(txMap map[string]interface{}) (*types.Transaction, error) {
 	// Extract basic fields that are common to all transaction types
 	hash, _ := txMap["hash"].(string)
@@ -716,25 +1012,30 @@ func (c *EthClient) createFallbackTransaction(txMap map[string]interface{}) (*ty
 	nonce, _ := txMap["nonce"].(string)
 
 	// Convert hex strings to appropriate types
-	nonceBig := new(big.Int)
-	gasBig := new(big.Int)
-	gasPriceBig := new(big.Int)
-	valueBig := new(big.Int)
-
-	if nonce != "" {
-		nonceBig.SetString(strings.TrimPrefix(nonce, "0x"), 16)
+	reject := func(err error) (*types.Transaction, error) {
+		log.Printf("Anomaly: rejecting fallback transaction %s: %v", hash, err)
+		return nil, err
 	}
-
-	if gas != "" {
-		gasBig.SetString(strings.TrimPrefix(gas, "0x"), 16)
+	nonceBig, err := parseHexQuantity("nonce", nonce)
+	if err != nil {
+		return reject(err)
 	}
-
-	if gasPrice != "" {
-		gasPriceBig.SetString(strings.TrimPrefix(gasPrice, "0x"), 16)
+	gasBig, err := parseHexQuantity("gas", gas)
+	if err != nil {
+		return reject(err)
+	}
+	gasPriceBig, err := parseHexQuantity("gasPrice", gasPrice)
+	if err != nil {
+		return reject(err)
+	}
+	valueBig, err := parseHexQuantity("value", value)
+	if err != nil {
+		return reject(err)
 	}
 
-	if value != "" {
-		valueBig.SetString(strings.TrimPrefix(value, "0x"), 16)
+	if maxValue := c.maxFallbackValue(); valueBig.Cmp(maxValue) > 0 {
+		log.Printf("Anomaly: rejecting fallback transaction %s: value %s wei exceeds the %s wei cap", hash, valueBig, maxValue)
+		return nil, fmt.Errorf("value %s wei of fallback transaction %s: %w", valueBig, hash, ErrImplausibleValue)
 	}
 
 	// Create a legacy transaction (type 0) as fallback
@@ -764,7 +1065,7 @@ func (c *EthClient) createFallbackTransaction(txMap map[string]interface{}) (*ty
 			}
 			return "CONTRACT_CREATION"
 		}(),
-		fmt.Sprintf("%.6f", float64(valueBig.Uint64())/1e18))
+		new(big.Float).Quo(new(big.Float).SetInt(valueBig), big.NewFloat(1e18)).Text('f', 6))
 
 	return tx, nil
 }
@@ -0,0 +1,324 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TestParseBlockWithdrawals tests withdrawals extraction from a raw eth_getBlockByNumber result
+func TestParseBlockWithdrawals(t *testing.T) {
+	tests := []struct {
+		name      string
+		block     string
+		wantNil   bool
+		wantCount int
+	}{
+		{"pre-Shanghai block", `{"number":"0x1"}`, true, 0},
+		{"null withdrawals", `{"number":"0x1","withdrawals":null}`, true, 0},
+		{"empty withdrawals", `{"number":"0x1","withdrawals":[]}`, false, 0},
+		{"two withdrawals", `{"number":"0x1","withdrawals":[
+			{"index":"0x10","validatorIndex":"0x20","address":"0x00000000219ab540356cbb839cbe05303d7705fa","amount":"0x3b9aca00"},
+			{"index":"0x11","validatorIndex":"0x21","address":"0x00000000219ab540356cbb839cbe05303d7705fa","amount":"0x1"}]}`, false, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.block), &result); err != nil {
+				t.Fatalf("Invalid test block: %v", err)
+			}
+
+			withdrawals, err := parseBlockWithdrawals(result)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (withdrawals == nil) != tt.wantNil {
+				t.Errorf("Expected nil=%v, got %v", tt.wantNil, withdrawals)
+			}
+			if len(withdrawals) != tt.wantCount {
+				t.Errorf("Expected %d withdrawals, got %d", tt.wantCount, len(withdrawals))
+			}
+		})
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		result := map[string]interface{}{"withdrawals": []interface{}{map[string]interface{}{
+			"index": "0x10", "validatorIndex": "0x20",
+			"address": "0x00000000219ab540356cbb839cbe05303d7705fa", "amount": "0x3b9aca00",
+		}}}
+		withdrawals, err := parseBlockWithdrawals(result)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		w := withdrawals[0]
+		if w.Index != 16 || w.Validator != 32 || w.Amount != 1e9 {
+			t.Errorf("Expected index 16, validator 32, amount 1e9, got %d, %d, %d", w.Index, w.Validator, w.Amount)
+		}
+		if w.Address != common.HexToAddress("0x00000000219ab540356cbb839cbe05303d7705fa") {
+			t.Errorf("Expected deposit contract address, got %s", w.Address.Hex())
+		}
+	})
+
+	t.Run("invalid withdrawals", func(t *testing.T) {
+		if _, err := parseBlockWithdrawals(map[string]interface{}{"withdrawals": "bad"}); err == nil {
+			t.Error("Expected error for invalid withdrawals")
+		}
+	})
+}
+
+// mockLogsService serves eth_getLogs with one log per block and refuses ranges wider than
+// maxBlocks the way Infura refuses queries over its result cap
+type mockLogsService struct {
+	maxBlocks uint64
+	mu        sync.Mutex
+	ranges    [][2]uint64
+}
+
+func (s *mockLogsService) GetLogs(crit map[string]interface{}) ([]*types.Log, error) {
+	from, err := hexutil.DecodeUint64(crit["fromBlock"].(string))
+	if err != nil {
+		return nil, err
+	}
+	to, err := hexutil.DecodeUint64(crit["toBlock"].(string))
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.ranges = append(s.ranges, [2]uint64{from, to})
+	s.mu.Unlock()
+
+	if to-from+1 > s.maxBlocks {
+		return nil, fmt.Errorf("query returned more than 10000 results. Try with this block range [0x%x, 0x%x].", from, from+s.maxBlocks-1)
+	}
+	logs := []*types.Log{}
+	for n := from; n <= to; n++ {
+		logs = append(logs, &types.Log{
+			Address:     common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7"),
+			Topics:      []common.Hash{},
+			Data:        []byte{},
+			BlockNumber: n,
+			TxHash:      common.BigToHash(new(big.Int).SetUint64(n)),
+			BlockHash:   common.BigToHash(new(big.Int).SetUint64(n)),
+		})
+	}
+	return logs, nil
+}
+
+// newMockLogsClient starts an HTTP JSON-RPC server with the logs service
+func newMockLogsClient(t *testing.T, service *mockLogsService) *EthClient {
+	t.Helper()
+	return newMockRPCClient(t, service)
+}
+
+// newMockRPCClient creates a client of a test server serving service's methods in the eth namespace
+func newMockRPCClient(t *testing.T, service interface{}) *EthClient {
+	t.Helper()
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("eth", service); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	ts := httptest.NewServer(rpcServer)
+
+	rpcClient, err := rpc.Dial(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to dial mock server: %v", err)
+	}
+	t.Cleanup(func() {
+		rpcClient.Close()
+		ts.Close()
+		rpcServer.Stop()
+	})
+	return &EthClient{rpcClient: rpcClient, client: ethclient.NewClient(rpcClient), timeout: 5 * time.Second}
+}
+
+// TestGetLogsSplitsCappedRange tests that a range over the result cap is subdivided until every part fits
+func TestGetLogsSplitsCappedRange(t *testing.T) {
+	tests := []struct {
+		name          string
+		from, to      uint64
+		maxBlocks     uint64
+		expectedCalls int
+	}{
+		{"Within cap", 100, 103, 4, 1},
+		{"Split once", 100, 107, 4, 3},
+		{"Split twice, uneven", 100, 110, 3, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockLogsService{maxBlocks: tt.maxBlocks}
+			c := newMockLogsClient(t, service)
+
+			logs, err := c.GetLogs(context.Background(), ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(tt.from),
+				ToBlock:   new(big.Int).SetUint64(tt.to),
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if uint64(len(logs)) != tt.to-tt.from+1 {
+				t.Fatalf("Expected %d logs, got %d", tt.to-tt.from+1, len(logs))
+			}
+			for i, l := range logs {
+				if l.BlockNumber != tt.from+uint64(i) {
+					t.Errorf("Expected log %d from block %d, got %d", i, tt.from+uint64(i), l.BlockNumber)
+				}
+			}
+			if len(service.ranges) != tt.expectedCalls {
+				t.Errorf("Expected %d eth_getLogs calls, got %d: %v", tt.expectedCalls, len(service.ranges), service.ranges)
+			}
+		})
+	}
+}
+
+// TestGetLogsCapNotSplittable tests that a capped query that can't be narrowed fails with ErrLogResultCap
+func TestGetLogsCapNotSplittable(t *testing.T) {
+	service := &mockLogsService{maxBlocks: 0}
+	c := newMockLogsClient(t, service)
+
+	_, err := c.GetLogs(context.Background(), ethereum.FilterQuery{FromBlock: big.NewInt(100), ToBlock: big.NewInt(101)})
+	if !errors.Is(err, ErrLogResultCap) {
+		t.Fatalf("Expected ErrLogResultCap, got %v", err)
+	}
+	// 100-101, then 100-100 fails without trying 101-101
+	if len(service.ranges) != 2 {
+		t.Errorf("Expected 2 eth_getLogs calls without retries, got %d: %v", len(service.ranges), service.ranges)
+	}
+}
+
+// TestIsLogResultCapError tests provider error detection
+func TestIsLogResultCapError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{errors.New("query returned more than 10000 results"), true},
+		{errors.New("Log response size exceeded. You can make eth_getLogs requests with up to a 2K block range"), true},
+		{errors.New("429 Too Many Requests"), false},
+		{errors.New("daily request count exceeded, request rate limited"), false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.err), func(t *testing.T) {
+			if result := isLogResultCapError(tt.err); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+// TestCreateFallbackTransaction tests that malformed or implausibly large fallback tx values are rejected
+func TestCreateFallbackTransaction(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxETH      uint64
+		value       string
+		gas         string
+		expectErr   bool
+		expectValue string
+	}{
+		{"Valid value", 0, "0x1bc16d674ec80000", "0x5208", false, "2000000000000000000"},
+		{"Empty value", 0, "", "0x5208", false, "0"},
+		{"Above uint64 but under cap", 0, "0x3635c9adc5dea00000", "0x5208", false, "1000000000000000000000"},
+		{"Malformed value", 0, "0xzz", "0x5208", true, ""},
+		{"Malformed gas", 0, "0x1", "gas", true, ""},
+		{"Above default cap", 0, "0xffffffffffffffffffffffffffffffff", "0x5208", true, ""},
+		{"Above custom cap", 100, "0x3635c9adc5dea00000", "0x5208", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &EthClient{maxFallbackValueETH: tt.maxETH}
+			tx, err := c.createFallbackTransaction(map[string]interface{}{
+				"hash":     "0xabc",
+				"from":     "0x0000000000000000000000000000000000000001",
+				"to":       "0x0000000000000000000000000000000000000002",
+				"value":    tt.value,
+				"gas":      tt.gas,
+				"gasPrice": "0x3b9aca00",
+				"nonce":    "0x1",
+			})
+			if tt.expectErr {
+				if !errors.Is(err, ErrImplausibleValue) {
+					t.Errorf("Expected ErrImplausibleValue, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tx.Value().String() != tt.expectValue {
+				t.Errorf("Expected value %s, got %s", tt.expectValue, tx.Value())
+			}
+		})
+	}
+}
+
+// mockTagService serves eth_getBlockByNumber for block tags, a tag missing from heads
+// fails like a pre-merge node
+type mockTagService struct {
+	heads map[string]uint64
+}
+
+func (s *mockTagService) GetBlockByNumber(tag string, fullTx bool) (*types.Header, error) {
+	number, ok := s.heads[tag]
+	if !ok {
+		return nil, fmt.Errorf("'%s' tag not supported on pre-merge network", tag)
+	}
+	return &types.Header{Number: new(big.Int).SetUint64(number), Difficulty: big.NewInt(0)}, nil
+}
+
+// TestGetTaggedBlockNumber tests the finalized and safe block lookups and an unsupported tag
+func TestGetTaggedBlockNumber(t *testing.T) {
+	tests := []struct {
+		name        string
+		heads       map[string]uint64
+		expected    [2]uint64 // finalized, safe
+		unsupported bool
+	}{
+		{"finalized and safe", map[string]uint64{"finalized": 100, "safe": 132}, [2]uint64{100, 132}, false},
+		{"pre-merge node", map[string]uint64{}, [2]uint64{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newMockRPCClient(t, &mockTagService{heads: tt.heads})
+
+			finalized, err := c.GetFinalizedBlockNumber(context.Background())
+			if tt.unsupported {
+				if !errors.Is(err, ErrBlockTagUnsupported) {
+					t.Fatalf("Expected ErrBlockTagUnsupported, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			safe, err := c.GetSafeBlockNumber(context.Background())
+			if tt.unsupported {
+				if !errors.Is(err, ErrBlockTagUnsupported) {
+					t.Fatalf("Expected ErrBlockTagUnsupported, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if got := [2]uint64{finalized, safe}; got != tt.expected {
+				t.Errorf("Expected finalized/safe %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
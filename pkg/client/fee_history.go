@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// FeeHistory is the eth_feeHistory result split per block, used to compare whale gas prices with the market
+type FeeHistory struct {
+	OldestBlock uint64            `json:"oldest_block"`
+	Percentiles []float64         `json:"reward_percentiles"`
+	Blocks      []FeeHistoryBlock `json:"blocks"`
+	NextBaseFee *big.Int          `json:"next_base_fee,omitempty"` // base fee of the block after the newest one
+}
+
+// FeeHistoryBlock holds the fee market data of one block
+type FeeHistoryBlock struct {
+	Number       uint64     `json:"number"`
+	BaseFee      *big.Int   `json:"base_fee"`
+	GasUsedRatio float64    `json:"gas_used_ratio"`
+	Rewards      []*big.Int `json:"rewards,omitempty"` // priority fee at each of Percentiles
+}
+
+// GetFeeHistory returns base fees, gas used ratios and priority fee percentiles of blockCount
+// blocks up to newestBlock (nil for latest), rewardPercentiles are ascending values in [0, 100]
+func (c *EthClient) GetFeeHistory(ctx context.Context, blockCount uint64, newestBlock *big.Int, rewardPercentiles []float64) (*FeeHistory, error) {
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	history, err := c.client.FeeHistory(ctx, blockCount, newestBlock, rewardPercentiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fee history: %w", err)
+	}
+	return newFeeHistory(history, rewardPercentiles), nil
+}
+
+// newFeeHistory converts an eth_feeHistory result. The node returns one base fee more
+// than blocks (for the next block), it goes to NextBaseFee.
+func newFeeHistory(history *ethereum.FeeHistory, percentiles []float64) *FeeHistory {
+	parsed := &FeeHistory{Percentiles: percentiles}
+	if history.OldestBlock != nil {
+		parsed.OldestBlock = history.OldestBlock.Uint64()
+	}
+
+	parsed.Blocks = make([]FeeHistoryBlock, len(history.GasUsedRatio))
+	for i, ratio := range history.GasUsedRatio {
+		block := FeeHistoryBlock{Number: parsed.OldestBlock + uint64(i), GasUsedRatio: ratio}
+		if i < len(history.BaseFee) {
+			block.BaseFee = history.BaseFee[i]
+		}
+		if i < len(history.Reward) {
+			block.Rewards = history.Reward[i]
+		}
+		parsed.Blocks[i] = block
+	}
+	if len(history.BaseFee) > len(history.GasUsedRatio) {
+		parsed.NextBaseFee = history.BaseFee[len(history.GasUsedRatio)]
+	}
+	return parsed
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// mockFeeHistoryService serves eth_feeHistory with a fixed payload for 2 blocks from 0x100
+type mockFeeHistoryService struct {
+	blockCount  string
+	newestBlock string
+	percentiles []float64
+}
+
+func (s *mockFeeHistoryService) FeeHistory(blockCount string, newestBlock string, percentiles []float64) json.RawMessage {
+	s.blockCount, s.newestBlock, s.percentiles = blockCount, newestBlock, percentiles
+	return json.RawMessage(`{
+		"oldestBlock": "0x100",
+		"baseFeePerGas": ["0x3b9aca00", "0x77359400", "0x6fc23ac0"],
+		"gasUsedRatio": [0.5, 0.9],
+		"reward": [["0x1", "0x3b9aca00"], ["0x2", "0x77359400"]]
+	}`)
+}
+
+// newFeeHistoryClient starts an HTTP JSON-RPC server with the mock service
+func newFeeHistoryClient(t *testing.T) (*EthClient, *mockFeeHistoryService) {
+	t.Helper()
+	service := &mockFeeHistoryService{}
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("eth", service); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	ts := httptest.NewServer(rpcServer)
+
+	rpcClient, err := rpc.Dial(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to dial mock server: %v", err)
+	}
+	t.Cleanup(func() {
+		rpcClient.Close()
+		ts.Close()
+		rpcServer.Stop()
+	})
+	return &EthClient{rpcClient: rpcClient, client: ethclient.NewClient(rpcClient), timeout: 5 * time.Second}, service
+}
+
+// TestGetFeeHistory tests that the eth_feeHistory payload is split per block
+func TestGetFeeHistory(t *testing.T) {
+	c, service := newFeeHistoryClient(t)
+
+	history, err := c.GetFeeHistory(context.Background(), 2, big.NewInt(0x101), []float64{10, 90})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if service.blockCount != "0x2" || service.newestBlock != "0x101" {
+		t.Errorf("Expected request for 0x2 blocks up to 0x101, got %s up to %s", service.blockCount, service.newestBlock)
+	}
+	if !reflect.DeepEqual(service.percentiles, []float64{10, 90}) {
+		t.Errorf("Expected percentiles [10 90], got %v", service.percentiles)
+	}
+
+	if history.OldestBlock != 256 {
+		t.Errorf("Expected oldest block 256, got %d", history.OldestBlock)
+	}
+	if len(history.Blocks) != 2 {
+		t.Fatalf("Expected 2 blocks, got %d", len(history.Blocks))
+	}
+
+	tests := []struct {
+		number       uint64
+		baseFee      int64
+		gasUsedRatio float64
+		rewards      []int64
+	}{
+		{256, 1e9, 0.5, []int64{1, 1e9}},
+		{257, 2e9, 0.9, []int64{2, 2e9}},
+	}
+	for i, tt := range tests {
+		block := history.Blocks[i]
+		if block.Number != tt.number {
+			t.Errorf("Expected block %d, got %d", tt.number, block.Number)
+		}
+		if block.BaseFee.Int64() != tt.baseFee {
+			t.Errorf("Expected base fee %d in block %d, got %s", tt.baseFee, tt.number, block.BaseFee)
+		}
+		if block.GasUsedRatio != tt.gasUsedRatio {
+			t.Errorf("Expected gas used ratio %v in block %d, got %v", tt.gasUsedRatio, tt.number, block.GasUsedRatio)
+		}
+		if len(block.Rewards) != len(tt.rewards) {
+			t.Fatalf("Expected %d rewards in block %d, got %d", len(tt.rewards), tt.number, len(block.Rewards))
+		}
+		for j, reward := range tt.rewards {
+			if block.Rewards[j].Int64() != reward {
+				t.Errorf("Expected reward %d at p%v in block %d, got %s", reward, history.Percentiles[j], tt.number, block.Rewards[j])
+			}
+		}
+	}
+
+	if history.NextBaseFee == nil || history.NextBaseFee.Int64() != 1875000000 {
+		t.Errorf("Expected next base fee 1875000000, got %v", history.NextBaseFee)
+	}
+}
+
+// TestGetFeeHistoryLatest tests that a nil newest block requests the latest block
+func TestGetFeeHistoryLatest(t *testing.T) {
+	c, service := newFeeHistoryClient(t)
+
+	if _, err := c.GetFeeHistory(context.Background(), 2, nil, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if service.newestBlock != "latest" {
+		t.Errorf("Expected newest block latest, got %s", service.newestBlock)
+	}
+}
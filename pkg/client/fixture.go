@@ -0,0 +1,319 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// BlockSource is what the parser reads blocks from: EthClient for a node, FixtureClient for recorded fixtures
+type BlockSource interface {
+	GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error)
+	GetBlockByHash(ctx context.Context, blockHash common.Hash) (*types.Block, error)
+	GetTransactionReceiptsBatch(ctx context.Context, txHashes []common.Hash) ([]*types.Receipt, error)
+	GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error)
+}
+
+var (
+	_ BlockSource = (*EthClient)(nil)
+	_ BlockSource = (*RecordingClient)(nil)
+	_ BlockSource = (*FixtureClient)(nil)
+)
+
+// ErrFixtureNotFound is returned by FixtureClient for blocks and receipts that were not recorded
+var ErrFixtureNotFound = errors.New("fixture not found")
+
+// Fixture files in a fixture directory: raw JSON-RPC results as returned by the node
+func blockFixturePath(dir string, number uint64) string {
+	return filepath.Join(dir, fmt.Sprintf("block_%d.json", number))
+}
+
+func receiptFixturePath(dir string, txHash common.Hash) string {
+	return filepath.Join(dir, fmt.Sprintf("receipt_%s.json", strings.ToLower(txHash.Hex())))
+}
+
+// RecordingClient wraps an EthClient and saves the raw eth_getBlockByNumber/eth_getBlockByHash and
+// eth_getTransactionReceipt results to a fixture directory for FixtureClient. GetLogs is passed
+// through without recording, FixtureClient answers it from the recorded receipts.
+type RecordingClient struct {
+	client *EthClient
+	dir    string
+}
+
+// NewRecordingClient creates a recording client writing fixtures to dir, the directory is created if missing
+func NewRecordingClient(client *EthClient, dir string) (*RecordingClient, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	return &RecordingClient{client: client, dir: dir}, nil
+}
+
+// Close closes the wrapped client
+func (r *RecordingClient) Close() {
+	r.client.Close()
+}
+
+// GetBlockByNumber fetches the raw block with full transactions and records it
+func (r *RecordingClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	return r.recordBlock(ctx, "eth_getBlockByNumber", fmt.Sprintf("0x%x", blockNumber))
+}
+
+// GetBlockByHash fetches the raw block with full transactions and records it under its number
+func (r *RecordingClient) GetBlockByHash(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	return r.recordBlock(ctx, "eth_getBlockByHash", blockHash.Hex())
+}
+
+// recordBlock calls a get-block method, saves the raw result and decodes it
+func (r *RecordingClient) recordBlock(ctx context.Context, method string, arg string) (*types.Block, error) {
+	if err := r.client.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	if err := r.client.rpcClient.CallContext(ctx, &raw, method, arg, true); err != nil {
+		return nil, fmt.Errorf("failed to get block %s: %w", arg, err)
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, fmt.Errorf("block %s not found", arg)
+	}
+
+	block, err := decodeRawBlock(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(blockFixturePath(r.dir, block.NumberU64()), raw, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write block fixture: %w", err)
+	}
+	return block, nil
+}
+
+// GetTransactionReceiptsBatch fetches raw receipts in one batch call and records them, missing receipts are nil
+func (r *RecordingClient) GetTransactionReceiptsBatch(ctx context.Context, txHashes []common.Hash) ([]*types.Receipt, error) {
+	if len(txHashes) == 0 {
+		return []*types.Receipt{}, nil
+	}
+
+	raws := make([]json.RawMessage, len(txHashes))
+	batch := make([]rpc.BatchElem, len(txHashes))
+	for i, hash := range txHashes {
+		batch[i] = rpc.BatchElem{Method: "eth_getTransactionReceipt", Args: []interface{}{hash}, Result: &raws[i]}
+	}
+	if err := r.client.waitForRateLimit(ctx); err != nil {
+		return nil, err
+	}
+	if err := r.client.rpcClient.BatchCallContext(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to get transaction receipts: %w", err)
+	}
+
+	receipts := make([]*types.Receipt, len(txHashes))
+	for i, hash := range txHashes {
+		if batch[i].Error != nil {
+			return nil, fmt.Errorf("failed to get receipt %s: %w", hash.Hex(), batch[i].Error)
+		}
+		receipt, err := decodeRawReceipt(raws[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode receipt %s: %w", hash.Hex(), err)
+		}
+		if receipt == nil {
+			continue
+		}
+		if err := os.WriteFile(receiptFixturePath(r.dir, hash), raws[i], 0644); err != nil {
+			return nil, fmt.Errorf("failed to write receipt fixture: %w", err)
+		}
+		receipts[i] = receipt
+	}
+	return receipts, nil
+}
+
+// GetLogs is passed through to the wrapped client
+func (r *RecordingClient) GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	return r.client.GetLogs(ctx, query)
+}
+
+// FixtureClient replays blocks and receipts recorded by RecordingClient without network access
+type FixtureClient struct {
+	dir string
+}
+
+// NewFixtureClient creates a client reading fixtures from dir
+func NewFixtureClient(dir string) (*FixtureClient, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fixture directory: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("fixture path %s is not a directory", dir)
+	}
+	return &FixtureClient{dir: dir}, nil
+}
+
+// GetLatestBlockNumber returns the highest recorded block number
+func (f *FixtureClient) GetLatestBlockNumber(ctx context.Context) (uint64, error) {
+	numbers, err := f.blockNumbers()
+	if err != nil {
+		return 0, err
+	}
+	if len(numbers) == 0 {
+		return 0, fmt.Errorf("no recorded blocks in %s: %w", f.dir, ErrFixtureNotFound)
+	}
+	latest := numbers[0]
+	for _, n := range numbers[1:] {
+		latest = max(latest, n)
+	}
+	return latest, nil
+}
+
+// GetBlockByNumber decodes a recorded block
+func (f *FixtureClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*types.Block, error) {
+	raw, err := os.ReadFile(blockFixturePath(f.dir, blockNumber))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("block %d: %w", blockNumber, ErrFixtureNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read block fixture: %w", err)
+	}
+	return decodeRawBlock(raw)
+}
+
+// GetBlockByHash searches the recorded blocks for the hash
+func (f *FixtureClient) GetBlockByHash(ctx context.Context, blockHash common.Hash) (*types.Block, error) {
+	numbers, err := f.blockNumbers()
+	if err != nil {
+		return nil, err
+	}
+	for _, number := range numbers {
+		block, err := f.GetBlockByNumber(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		if block.Hash() == blockHash {
+			return block, nil
+		}
+	}
+	return nil, fmt.Errorf("block %s: %w", blockHash.Hex(), ErrFixtureNotFound)
+}
+
+// GetTransactionReceiptsBatch returns recorded receipts, not recorded ones are nil like missing receipts of a node
+func (f *FixtureClient) GetTransactionReceiptsBatch(ctx context.Context, txHashes []common.Hash) ([]*types.Receipt, error) {
+	receipts := make([]*types.Receipt, len(txHashes))
+	for i, hash := range txHashes {
+		receipt, err := f.receipt(hash)
+		if err != nil && !errors.Is(err, ErrFixtureNotFound) {
+			return nil, err
+		}
+		receipts[i] = receipt
+	}
+	return receipts, nil
+}
+
+// GetLogs returns the logs of recorded receipts in the recorded blocks matching the query range and addresses.
+// Topic filters are not supported.
+func (f *FixtureClient) GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	if len(query.Topics) > 0 {
+		return nil, fmt.Errorf("fixture client does not support topic filters")
+	}
+	numbers, err := f.blockNumbers()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make(map[common.Address]bool, len(query.Addresses))
+	for _, addr := range query.Addresses {
+		addresses[addr] = true
+	}
+
+	var logs []types.Log
+	for _, number := range numbers {
+		if (query.FromBlock != nil && new(big.Int).SetUint64(number).Cmp(query.FromBlock) < 0) ||
+			(query.ToBlock != nil && new(big.Int).SetUint64(number).Cmp(query.ToBlock) > 0) {
+			continue
+		}
+		block, err := f.GetBlockByNumber(ctx, number)
+		if err != nil {
+			return nil, err
+		}
+		for _, tx := range block.Transactions() {
+			receipt, err := f.receipt(tx.Hash())
+			if errors.Is(err, ErrFixtureNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range receipt.Logs {
+				if len(addresses) == 0 || addresses[l.Address] {
+					logs = append(logs, *l)
+				}
+			}
+		}
+	}
+	return logs, nil
+}
+
+// receipt decodes a recorded receipt
+func (f *FixtureClient) receipt(txHash common.Hash) (*types.Receipt, error) {
+	raw, err := os.ReadFile(receiptFixturePath(f.dir, txHash))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("receipt %s: %w", txHash.Hex(), ErrFixtureNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt fixture: %w", err)
+	}
+	return decodeRawReceipt(raw)
+}
+
+// blockNumbers lists the recorded block numbers
+func (f *FixtureClient) blockNumbers() ([]uint64, error) {
+	files, err := filepath.Glob(filepath.Join(f.dir, "block_*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list block fixtures: %w", err)
+	}
+	numbers := make([]uint64, 0, len(files))
+	for _, file := range files {
+		var number uint64
+		if _, err := fmt.Sscanf(filepath.Base(file), "block_%d.json", &number); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers, nil
+}
+
+// decodeRawBlock decodes a raw eth_getBlockBy* result with full transactions. The header is kept as
+// returned by the node, so the block hash matches; uncles are not included (only their hashes are returned).
+func decodeRawBlock(raw json.RawMessage) (*types.Block, error) {
+	var header types.Header
+	if err := header.UnmarshalJSON(raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block header: %w", err)
+	}
+	var body struct {
+		Transactions []*types.Transaction `json:"transactions"`
+		Withdrawals  []*types.Withdrawal  `json:"withdrawals"`
+	}
+	if err := json.Unmarshal(raw, &body); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal block %d body: %w", header.Number.Uint64(), err)
+	}
+	return types.NewBlockWithHeader(&header).WithBody(types.Body{
+		Transactions: body.Transactions,
+		Withdrawals:  body.Withdrawals,
+	}), nil
+}
+
+// decodeRawReceipt decodes a raw eth_getTransactionReceipt result, nil for a null result
+func decodeRawReceipt(raw json.RawMessage) (*types.Receipt, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var receipt types.Receipt
+	if err := receipt.UnmarshalJSON(raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal receipt: %w", err)
+	}
+	return &receipt, nil
+}
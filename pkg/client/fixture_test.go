@@ -0,0 +1,236 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// mockBlockService serves one raw block and the receipt of its transaction
+type mockBlockService struct {
+	block   json.RawMessage
+	number  string
+	receipt json.RawMessage
+	txHash  common.Hash
+	calls   atomic.Int64
+}
+
+func (s *mockBlockService) GetBlockByNumber(number string, full bool) json.RawMessage {
+	s.calls.Add(1)
+	if number != s.number {
+		return json.RawMessage("null")
+	}
+	return s.block
+}
+
+func (s *mockBlockService) GetTransactionReceipt(hash common.Hash) json.RawMessage {
+	s.calls.Add(1)
+	if hash != s.txHash {
+		return json.RawMessage("null")
+	}
+	return s.receipt
+}
+
+// newMockBlockService builds the raw RPC payloads of a post-Shanghai block with one signed transaction
+func newMockBlockService(t *testing.T) (*mockBlockService, *types.Block) {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	chainID := big.NewInt(1)
+	to := common.HexToAddress("0x00000000219ab540356cbb839cbe05303d7705fa")
+	tx, err := types.SignNewTx(key, types.LatestSignerForChainID(chainID), &types.DynamicFeeTx{
+		ChainID: chainID, Nonce: 7, Gas: 21000, GasTipCap: big.NewInt(2e9), GasFeeCap: big.NewInt(30e9), To: &to, Value: big.NewInt(1e18),
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	withdrawalsHash := types.EmptyWithdrawalsHash
+	header := &types.Header{
+		ParentHash:      common.HexToHash("0x01"),
+		UncleHash:       types.EmptyUncleHash,
+		Root:            common.HexToHash("0x02"),
+		TxHash:          common.HexToHash("0x03"),
+		ReceiptHash:     types.EmptyReceiptsHash,
+		Difficulty:      big.NewInt(0),
+		Number:          big.NewInt(20000000),
+		GasLimit:        30000000,
+		GasUsed:         21000,
+		Time:            1717000000,
+		BaseFee:         big.NewInt(10e9),
+		WithdrawalsHash: &withdrawalsHash,
+	}
+	block := types.NewBlockWithHeader(header).WithBody(types.Body{
+		Transactions: []*types.Transaction{tx},
+		Withdrawals:  []*types.Withdrawal{{Index: 1, Validator: 2, Address: to, Amount: 32}},
+	})
+
+	headerJSON, err := header.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Failed to marshal header: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(headerJSON, &fields); err != nil {
+		t.Fatalf("Failed to decode header: %v", err)
+	}
+	fields["transactions"] = block.Transactions()
+	fields["withdrawals"] = block.Withdrawals()
+	fields["uncles"] = []string{}
+	rawBlock, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("Failed to marshal block: %v", err)
+	}
+
+	receipt := &types.Receipt{
+		Type:              types.DynamicFeeTxType,
+		Status:            types.ReceiptStatusSuccessful,
+		CumulativeGasUsed: 21000,
+		GasUsed:           21000,
+		TxHash:            tx.Hash(),
+		BlockNumber:       header.Number,
+		BlockHash:         block.Hash(),
+		Logs: []*types.Log{{
+			Address:     to,
+			Topics:      []common.Hash{common.HexToHash("0xaa")},
+			Data:        hexutil.MustDecode("0x01"),
+			BlockNumber: header.Number.Uint64(),
+			TxHash:      tx.Hash(),
+			BlockHash:   block.Hash(),
+		}},
+	}
+	rawReceipt, err := receipt.MarshalJSON()
+	if err != nil {
+		t.Fatalf("Failed to marshal receipt: %v", err)
+	}
+
+	return &mockBlockService{
+		block:   rawBlock,
+		number:  hexutil.EncodeUint64(header.Number.Uint64()),
+		receipt: rawReceipt,
+		txHash:  tx.Hash(),
+	}, block
+}
+
+// newMockBlockClient starts an HTTP JSON-RPC server with the block service
+func newMockBlockClient(t *testing.T, service *mockBlockService) *EthClient {
+	t.Helper()
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("eth", service); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	ts := httptest.NewServer(rpcServer)
+
+	rpcClient, err := rpc.Dial(ts.URL)
+	if err != nil {
+		t.Fatalf("Failed to dial mock server: %v", err)
+	}
+	t.Cleanup(func() {
+		rpcClient.Close()
+		ts.Close()
+		rpcServer.Stop()
+	})
+	return &EthClient{rpcClient: rpcClient, client: ethclient.NewClient(rpcClient), timeout: 5 * time.Second}
+}
+
+// TestFixtureRoundTrip records a block and its receipt, then replays them without the server
+func TestFixtureRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	service, want := newMockBlockService(t)
+	dir := filepath.Join(t.TempDir(), "fixtures")
+
+	recorder, err := NewRecordingClient(newMockBlockClient(t, service), dir)
+	if err != nil {
+		t.Fatalf("Failed to create recording client: %v", err)
+	}
+	recorded, err := recorder.GetBlockByNumber(ctx, want.NumberU64())
+	if err != nil {
+		t.Fatalf("Failed to record block: %v", err)
+	}
+	if recorded.Hash() != want.Hash() {
+		t.Errorf("Expected recorded block hash %s, got %s", want.Hash().Hex(), recorded.Hash().Hex())
+	}
+	unknown := common.HexToHash("0xdead")
+	if _, err := recorder.GetTransactionReceiptsBatch(ctx, []common.Hash{service.txHash, unknown}); err != nil {
+		t.Fatalf("Failed to record receipts: %v", err)
+	}
+	calls := service.calls.Load()
+
+	replay, err := NewFixtureClient(dir)
+	if err != nil {
+		t.Fatalf("Failed to create fixture client: %v", err)
+	}
+
+	t.Run("Block", func(t *testing.T) {
+		block, err := replay.GetBlockByNumber(ctx, want.NumberU64())
+		if err != nil {
+			t.Fatalf("Failed to replay block: %v", err)
+		}
+		if block.Hash() != want.Hash() {
+			t.Errorf("Expected block hash %s, got %s", want.Hash().Hex(), block.Hash().Hex())
+		}
+		if len(block.Transactions()) != 1 || block.Transactions()[0].Hash() != service.txHash {
+			t.Errorf("Expected the recorded transaction, got %d transactions", len(block.Transactions()))
+		}
+		if len(block.Withdrawals()) != 1 || block.Withdrawals()[0].Amount != 32 {
+			t.Errorf("Expected the recorded withdrawal, got %v", block.Withdrawals())
+		}
+
+		byHash, err := replay.GetBlockByHash(ctx, want.Hash())
+		if err != nil || byHash.NumberU64() != want.NumberU64() {
+			t.Errorf("Expected block %d by hash, got %v", want.NumberU64(), err)
+		}
+		latest, err := replay.GetLatestBlockNumber(ctx)
+		if err != nil || latest != want.NumberU64() {
+			t.Errorf("Expected latest recorded block %d, got %d (%v)", want.NumberU64(), latest, err)
+		}
+	})
+
+	t.Run("Receipts", func(t *testing.T) {
+		receipts, err := replay.GetTransactionReceiptsBatch(ctx, []common.Hash{service.txHash, unknown})
+		if err != nil {
+			t.Fatalf("Failed to replay receipts: %v", err)
+		}
+		if receipts[0] == nil || receipts[0].GasUsed != 21000 || receipts[0].Status != types.ReceiptStatusSuccessful {
+			t.Errorf("Expected recorded receipt, got %+v", receipts[0])
+		}
+		if receipts[1] != nil {
+			t.Errorf("Expected nil receipt for unrecorded tx, got %+v", receipts[1])
+		}
+	})
+
+	t.Run("Logs", func(t *testing.T) {
+		logs, err := replay.GetLogs(ctx, ethereum.FilterQuery{FromBlock: want.Number(), ToBlock: want.Number()})
+		if err != nil {
+			t.Fatalf("Failed to replay logs: %v", err)
+		}
+		if len(logs) != 1 || logs[0].TxHash != service.txHash {
+			t.Errorf("Expected 1 log of the recorded tx, got %d", len(logs))
+		}
+	})
+
+	t.Run("Not recorded", func(t *testing.T) {
+		if _, err := replay.GetBlockByNumber(ctx, want.NumberU64()+1); !errors.Is(err, ErrFixtureNotFound) {
+			t.Errorf("Expected ErrFixtureNotFound, got %v", err)
+		}
+	})
+
+	if service.calls.Load() != calls {
+		t.Errorf("Expected no RPC calls during replay, got %d", service.calls.Load()-calls)
+	}
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const (
+	// defaultBlockInterval is the mainnet slot time, used until block intervals are observed
+	defaultBlockInterval = 12 * time.Second
+	// blockIntervalAlpha is the EMA weight of the newest observed interval
+	blockIntervalAlpha = 0.2
+	// headPollMargin is how long after the expected block time the head is polled, blocks need time to propagate
+	headPollMargin = time.Second
+	// minHeadPollInterval bounds polling when a block is late
+	minHeadPollInterval = 500 * time.Millisecond
+)
+
+// blockIntervalEstimator keeps an exponential moving average of the block interval
+// from observed head timestamps and predicts when the next block is due
+type blockIntervalEstimator struct {
+	alpha      float64
+	interval   time.Duration
+	minPoll    time.Duration
+	lastNumber uint64
+	lastTime   time.Time
+}
+
+// newBlockIntervalEstimator creates an estimator starting from the initial interval
+func newBlockIntervalEstimator(initial time.Duration, alpha float64) *blockIntervalEstimator {
+	return &blockIntervalEstimator{alpha: alpha, interval: initial, minPoll: minHeadPollInterval}
+}
+
+// observe records a head and reports whether it is new. When several blocks were skipped
+// the elapsed time is spread evenly over them.
+func (e *blockIntervalEstimator) observe(number uint64, blockTime time.Time) bool {
+	if number <= e.lastNumber && !e.lastTime.IsZero() {
+		return false
+	}
+
+	if !e.lastTime.IsZero() {
+		if elapsed := blockTime.Sub(e.lastTime); elapsed > 0 {
+			sample := elapsed / time.Duration(number-e.lastNumber)
+			e.interval = time.Duration(e.alpha*float64(sample) + (1-e.alpha)*float64(e.interval))
+		}
+	}
+	e.lastNumber = number
+	e.lastTime = blockTime
+	return true
+}
+
+// nextPoll returns how long to wait from now until just after the expected next block,
+// a late block is polled every interval/6 (at least minPoll)
+func (e *blockIntervalEstimator) nextPoll(now time.Time) time.Duration {
+	minWait := e.interval / 6
+	if minWait < e.minPoll {
+		minWait = e.minPoll
+	}
+	if e.lastTime.IsZero() {
+		return minWait
+	}
+
+	wait := e.lastTime.Add(e.interval + headPollMargin).Sub(now)
+	if wait < minWait {
+		return minWait
+	}
+	return wait
+}
+
+// WatchHead emits the latest block number each time a new head is seen. Instead of polling at a
+// fixed interval it estimates the block interval from head timestamps and polls just after the
+// next block is expected. Skipped heads are not emitted one by one, only the newest number.
+// The channel is closed when ctx is cancelled.
+func (c *EthClient) WatchHead(ctx context.Context) <-chan uint64 {
+	return watchHead(ctx, c.latestHead, newBlockIntervalEstimator(defaultBlockInterval, blockIntervalAlpha))
+}
+
+// latestHead returns the number and timestamp of the latest block
+func (c *EthClient) latestHead(ctx context.Context) (uint64, time.Time, error) {
+	if err := c.waitForRateLimit(ctx); err != nil {
+		return 0, time.Time{}, err
+	}
+	header, err := c.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return header.Number.Uint64(), time.Unix(int64(header.Time), 0), nil
+}
+
+// watchHead is the WatchHead loop over a head source
+func watchHead(ctx context.Context, head func(ctx context.Context) (uint64, time.Time, error), estimator *blockIntervalEstimator) <-chan uint64 {
+	out := make(chan uint64)
+
+	go func() {
+		defer close(out)
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			}
+
+			number, blockTime, err := head(ctx)
+			if err != nil {
+				log.Printf("Failed to get latest head: %v", err)
+			} else if estimator.observe(number, blockTime) {
+				select {
+				case out <- number:
+				case <-ctx.Done():
+					return
+				}
+			}
+			timer.Reset(estimator.nextPoll(time.Now()))
+		}
+	}()
+
+	return out
+}
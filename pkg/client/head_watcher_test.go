@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestBlockIntervalEstimator drives the estimator with a sequence of block times
+func TestBlockIntervalEstimator(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := newBlockIntervalEstimator(12*time.Second, 0.5)
+
+	steps := []struct {
+		name         string
+		number       uint64
+		offset       time.Duration // block time relative to base
+		wantNew      bool
+		wantInterval time.Duration
+	}{
+		{"first head keeps initial interval", 100, 0, true, 12 * time.Second},
+		{"same head is not new", 100, 0, false, 12 * time.Second},
+		{"12s block", 101, 12 * time.Second, true, 12 * time.Second},
+		{"faster 8s block", 102, 20 * time.Second, true, 10 * time.Second},
+		{"two blocks in 24s count as 12s each", 104, 44 * time.Second, true, 11 * time.Second},
+		{"older head is ignored", 103, 32 * time.Second, false, 11 * time.Second},
+		{"slow 15s block", 105, 59 * time.Second, true, 13 * time.Second},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if isNew := e.observe(step.number, base.Add(step.offset)); isNew != step.wantNew {
+				t.Errorf("Expected new=%v, got %v", step.wantNew, isNew)
+			}
+			if e.interval != step.wantInterval {
+				t.Errorf("Expected interval %v, got %v", step.wantInterval, e.interval)
+			}
+		})
+	}
+}
+
+// TestBlockIntervalEstimatorNextPoll tests that the poll is scheduled just after the expected next block
+func TestBlockIntervalEstimatorNextPoll(t *testing.T) {
+	blockTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := newBlockIntervalEstimator(12*time.Second, 0.2)
+
+	if wait := e.nextPoll(blockTime); wait != 2*time.Second {
+		t.Errorf("Expected minimum wait 2s before any head, got %v", wait)
+	}
+
+	e.observe(100, blockTime)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Duration
+	}{
+		{"right after the block", blockTime.Add(time.Second), 12 * time.Second},
+		{"mid interval", blockTime.Add(8 * time.Second), 5 * time.Second},
+		{"block is late", blockTime.Add(20 * time.Second), 2 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if wait := e.nextPoll(tt.now); wait != tt.want {
+				t.Errorf("Expected wait %v, got %v", tt.want, wait)
+			}
+		})
+	}
+}
+
+// TestWatchHead tests that only new heads are emitted and the channel is closed on cancel
+func TestWatchHead(t *testing.T) {
+	heads := []uint64{10, 10, 11, 13}
+	calls := 0
+	head := func(ctx context.Context) (uint64, time.Time, error) {
+		n := heads[min(calls, len(heads)-1)]
+		calls++
+		// block times in the past, so every poll is scheduled after the minimum wait
+		return n, time.Now().Add(-time.Minute), nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	estimator := newBlockIntervalEstimator(time.Millisecond, 0.2)
+	estimator.minPoll = time.Millisecond
+	out := watchHead(ctx, head, estimator)
+
+	for _, want := range []uint64{10, 11, 13} {
+		select {
+		case got := <-out:
+			if got != want {
+				t.Errorf("Expected head %d, got %d", want, got)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out waiting for head %d", want)
+		}
+	}
+
+	cancel()
+	for range out {
+	}
+}
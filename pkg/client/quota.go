@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// quotaSaveEvery is how many requests are counted between writes to the RequestCounterStore,
+// a crash loses at most that many requests of the day's count
+const quotaSaveEvery = 50
+
+// ErrQuotaExceeded is matched by errors.Is on a *QuotaExceededError
+var ErrQuotaExceeded = errors.New("daily request quota exceeded")
+
+// QuotaExceededError is returned instead of an RPC call once the daily request cap is used up
+type QuotaExceededError struct {
+	Day     string    // UTC day of the count, "2006-01-02"
+	Used    uint64    // requests sent that day
+	Cap     uint64    // configured daily cap
+	ResetAt time.Time // start of the next UTC day
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("daily request quota exceeded: %d of %d requests used on %s, resets at %s",
+		e.Used, e.Cap, e.Day, e.ResetAt.Format(time.RFC3339))
+}
+
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// RequestCounterStore persists the daily request count across restarts,
+// implemented by database.MetaRepository
+type RequestCounterStore interface {
+	// LoadRequestCount returns the stored count of day, 0 if none is stored
+	LoadRequestCount(ctx context.Context, day string) (uint64, error)
+	SaveRequestCount(ctx context.Context, day string, count uint64) error
+}
+
+// requestQuota counts RPC requests per UTC day (Infura resets its daily credits at 00:00 UTC)
+// and enforces the daily cap
+type requestQuota struct {
+	cap          uint64 // 0 = count only
+	waitForReset bool   // block until the next UTC day instead of returning QuotaExceededError
+	store        RequestCounterStore
+	now          func() time.Time
+
+	mu      sync.Mutex
+	day     string
+	count   uint64
+	unsaved uint64
+	loaded  bool
+}
+
+// take counts one request, the count is loaded from the store on the first request of a day
+func (q *requestQuota) take(ctx context.Context) error {
+	for {
+		wait, err := q.reserve(ctx)
+		if err != nil || wait == 0 {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve counts a request and returns 0, or returns how long until the quota resets when waitForReset is set
+func (q *requestQuota) reserve(ctx context.Context) (time.Duration, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now().UTC()
+	day := now.Format("2006-01-02")
+	if day != q.day || !q.loaded {
+		if q.day != "" && q.day != day {
+			q.flushLocked(ctx)
+		}
+		q.day, q.count, q.unsaved, q.loaded = day, 0, 0, true
+		if q.store != nil {
+			count, err := q.store.LoadRequestCount(ctx, day)
+			if err != nil {
+				log.Printf("Failed to load request count for %s, counting from 0: %v", day, err)
+			} else {
+				q.count = count
+			}
+		}
+	}
+
+	if q.cap > 0 && q.count >= q.cap {
+		resetAt := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+		if q.waitForReset {
+			return resetAt.Sub(now), nil
+		}
+		return 0, &QuotaExceededError{Day: day, Used: q.count, Cap: q.cap, ResetAt: resetAt}
+	}
+
+	q.count++
+	q.unsaved++
+	if q.unsaved >= quotaSaveEvery {
+		q.flushLocked(ctx)
+	}
+	return 0, nil
+}
+
+// flush writes the unsaved part of the count to the store
+func (q *requestQuota) flush(ctx context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.flushLocked(ctx)
+}
+
+func (q *requestQuota) flushLocked(ctx context.Context) {
+	if q.store == nil || q.unsaved == 0 {
+		return
+	}
+	if err := q.store.SaveRequestCount(ctx, q.day, q.count); err != nil {
+		log.Printf("Failed to save request count for %s: %v", q.day, err)
+		return
+	}
+	q.unsaved = 0
+}
+
+// used returns the day and the count of requests sent on it
+func (q *requestQuota) used() (string, uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.day, q.count
+}
+
+// SetRequestQuota counts RPC requests per UTC day in store (nil = in memory only) and caps them at
+// dailyCap (0 = no cap). A call over the cap fails with *QuotaExceededError, or blocks until
+// 00:00 UTC when waitForReset is set. Call before the client is shared between goroutines.
+func (c *EthClient) SetRequestQuota(dailyCap uint64, waitForReset bool, store RequestCounterStore) {
+	c.quota = &requestQuota{cap: dailyCap, waitForReset: waitForReset, store: store, now: time.Now}
+}
+
+// RequestsToday returns the UTC day and the number of RPC requests counted on it,
+// zero values if no quota is set
+func (c *EthClient) RequestsToday() (string, uint64) {
+	if c.quota == nil {
+		return "", 0
+	}
+	return c.quota.used()
+}
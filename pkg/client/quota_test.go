@@ -0,0 +1,106 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// memoryCounterStore is a RequestCounterStore keeping the counts in a map
+type memoryCounterStore struct {
+	counts map[string]uint64
+	saves  int
+}
+
+func (s *memoryCounterStore) LoadRequestCount(ctx context.Context, day string) (uint64, error) {
+	return s.counts[day], nil
+}
+
+func (s *memoryCounterStore) SaveRequestCount(ctx context.Context, day string, count uint64) error {
+	s.counts[day] = count
+	s.saves++
+	return nil
+}
+
+// TestRequestQuotaCap tests that the count picks up the stored value, crossing the cap fails
+// with QuotaExceededError without an RPC call, and the next UTC day starts from 0
+func TestRequestQuotaCap(t *testing.T) {
+	now := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	store := &memoryCounterStore{counts: map[string]uint64{"2024-01-01": 8}}
+	service := &mockLogsService{maxBlocks: 100}
+	c := newMockLogsClient(t, service)
+	c.SetRequestQuota(10, false, store)
+	c.quota.now = func() time.Time { return now }
+
+	query := func() error {
+		_, err := c.GetLogs(context.Background(), ethereum.FilterQuery{FromBlock: big.NewInt(1), ToBlock: big.NewInt(2)})
+		return err
+	}
+
+	steps := []struct {
+		name          string
+		advance       time.Duration
+		expectedErr   bool
+		expectedCount uint64
+		expectedCalls int
+	}{
+		{"Request 9", 0, false, 9, 1},
+		{"Request 10", 0, false, 10, 2},
+		{"Over the cap", 0, true, 10, 2},
+		{"Still over the cap", 30 * time.Minute, true, 10, 2},
+		{"Next UTC day", 30 * time.Minute, false, 1, 3},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		err := query()
+		if step.expectedErr {
+			var quotaErr *QuotaExceededError
+			if !errors.As(err, &quotaErr) || !errors.Is(err, ErrQuotaExceeded) {
+				t.Fatalf("%s: expected QuotaExceededError, got %v", step.name, err)
+			}
+			if quotaErr.Used != 10 || quotaErr.Cap != 10 || !quotaErr.ResetAt.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("%s: unexpected error fields %+v", step.name, quotaErr)
+			}
+		} else if err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+		if _, count := c.RequestsToday(); count != step.expectedCount {
+			t.Errorf("%s: expected count %d, got %d", step.name, step.expectedCount, count)
+		}
+		if len(service.ranges) != step.expectedCalls {
+			t.Errorf("%s: expected %d RPC calls, got %d", step.name, step.expectedCalls, len(service.ranges))
+		}
+	}
+
+	// the previous day is saved on rollover, today's count on Close
+	if store.counts["2024-01-01"] != 10 {
+		t.Errorf("Expected 10 requests saved for 2024-01-01, got %d", store.counts["2024-01-01"])
+	}
+	c.Close()
+	if store.counts["2024-01-02"] != 1 {
+		t.Errorf("Expected 1 request saved for 2024-01-02, got %d", store.counts["2024-01-02"])
+	}
+}
+
+// TestRequestQuotaWaitForReset tests that with waitForReset a call over the cap waits for 00:00 UTC
+func TestRequestQuotaWaitForReset(t *testing.T) {
+	now := time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC)
+	q := &requestQuota{cap: 1, waitForReset: true, now: func() time.Time { return now }}
+
+	if wait, err := q.reserve(context.Background()); err != nil || wait != 0 {
+		t.Fatalf("Expected the first request right away, got %v %v", wait, err)
+	}
+	if wait, err := q.reserve(context.Background()); err != nil || wait != time.Minute {
+		t.Errorf("Expected a wait of 1m until reset, got %v %v", wait, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := q.take(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultInfuraCallInterval paces Infura calls to 4 requests per second to be very conservative
+const defaultInfuraCallInterval = 250 * time.Millisecond
+
+// MinTokenBucketRate is the lowest refill rate of a TokenBucketLimiter, one token per 1000s.
+// A zero or negative rate would make the wait for the next token infinite and Wait spin.
+const MinTokenBucketRate = 0.001
+
+// RateLimiter paces RPC calls: Wait blocks until the next call may start, or returns the ctx error
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TickerLimiter allows one call per tick, idle ticks are dropped so there is no burst after a pause.
+// It is the default limiter of Infura clients.
+type TickerLimiter struct {
+	ticker *time.Ticker
+}
+
+// NewTickerLimiter creates a limiter allowing one call per interval
+func NewTickerLimiter(interval time.Duration) *TickerLimiter {
+	return &TickerLimiter{ticker: time.NewTicker(interval)}
+}
+
+// Wait blocks until the next tick
+func (l *TickerLimiter) Wait(ctx context.Context) error {
+	select {
+	case <-l.ticker.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stop releases the ticker, called by EthClient.Close
+func (l *TickerLimiter) Stop() {
+	l.ticker.Stop()
+}
+
+// TokenBucketLimiter allows bursts of up to burst calls and refills rate tokens per second,
+// so short spikes (a receipt batch) go out at once while the average stays at rate
+type TokenBucketLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucketLimiter creates a full bucket of burst tokens refilled at rate per second,
+// burst < 1 is raised to 1, rate below MinTokenBucketRate (including <= 0) is raised to it
+func NewTokenBucketLimiter(rate float64, burst int) *TokenBucketLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	if !(rate >= MinTokenBucketRate) { // also catches NaN
+		rate = MinTokenBucketRate
+	}
+	return &TokenBucketLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		now:    time.Now,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait takes a token, sleeping until one is refilled if the bucket is empty
+func (l *TokenBucketLimiter) Wait(ctx context.Context) error {
+	for {
+		wait := l.reserve()
+		if wait == 0 {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long until the next token is available
+func (l *TokenBucketLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// recordingLimiter counts Wait calls and fails them with err
+type recordingLimiter struct {
+	mu    sync.Mutex
+	calls int
+	err   error
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls++
+	return l.err
+}
+
+// TestRateLimiterInjected tests that every RPC call waits for the configured limiter and a failed wait skips the call
+func TestRateLimiterInjected(t *testing.T) {
+	tests := []struct {
+		name          string
+		limiterErr    error
+		expectedCalls int
+		expectErr     bool
+	}{
+		// 1-40 is split into 1-20 and 21-40, three RPC calls
+		{"Waits before each call", nil, 3, false},
+		{"Canceled wait", context.Canceled, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockLogsService{maxBlocks: 20}
+			c := newMockLogsClient(t, service)
+			limiter := &recordingLimiter{err: tt.limiterErr}
+			c.rateLimiter = limiter
+
+			_, err := c.GetLogs(context.Background(), ethereum.FilterQuery{FromBlock: big.NewInt(1), ToBlock: big.NewInt(40)})
+			if tt.expectErr {
+				if !errors.Is(err, tt.limiterErr) {
+					t.Errorf("Expected %v, got %v", tt.limiterErr, err)
+				}
+				if len(service.ranges) != 0 {
+					t.Errorf("Expected no RPC calls after a failed wait, got %v", service.ranges)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if limiter.calls != tt.expectedCalls {
+				t.Errorf("Expected %d waits, got %d", tt.expectedCalls, limiter.calls)
+			}
+		})
+	}
+}
+
+// TestTokenBucketLimiter tests the burst, refill and the wait for the next token on a fake clock
+func TestTokenBucketLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewTokenBucketLimiter(2, 3)
+	l.now = func() time.Time { return now }
+	l.last = now
+
+	steps := []struct {
+		name     string
+		advance  time.Duration
+		expected time.Duration
+	}{
+		{"Burst 1", 0, 0},
+		{"Burst 2", 0, 0},
+		{"Burst 3", 0, 0},
+		{"Empty bucket", 0, 500 * time.Millisecond},
+		{"Half refilled", 250 * time.Millisecond, 250 * time.Millisecond},
+		{"Refilled", 250 * time.Millisecond, 0},
+		{"Refill capped at burst", time.Hour, 0},
+		{"Burst after refill 2", 0, 0},
+		{"Burst after refill 3", 0, 0},
+		{"Empty again", 0, 500 * time.Millisecond},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if wait := l.reserve(); wait != step.expected {
+			t.Errorf("%s: expected wait %v, got %v", step.name, step.expected, wait)
+		}
+	}
+}
+
+// TestTokenBucketLimiterMinRate tests that a zero, negative or tiny rate waits a finite time for the next token
+func TestTokenBucketLimiterMinRate(t *testing.T) {
+	expected := time.Duration(1 / MinTokenBucketRate * float64(time.Second))
+
+	tests := []struct {
+		name string
+		rate float64
+	}{
+		{"Zero rate", 0},
+		{"Negative rate", -5},
+		{"Below minimum", MinTokenBucketRate / 10},
+		{"NaN rate", math.NaN()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			l := NewTokenBucketLimiter(tt.rate, 1)
+			l.now = func() time.Time { return now }
+			l.last = now
+
+			if wait := l.reserve(); wait != 0 {
+				t.Fatalf("Expected the first token right away, got %v", wait)
+			}
+			if wait := l.reserve(); wait != expected {
+				t.Errorf("Expected wait %v, got %v", expected, wait)
+			}
+		})
+	}
+}
+
+// TestRateLimiterCanceled tests that Wait returns the ctx error instead of blocking
+func TestRateLimiterCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ticker := NewTickerLimiter(time.Hour)
+	defer ticker.Stop()
+	bucket := NewTokenBucketLimiter(0.001, 1)
+	if err := bucket.Wait(ctx); err != nil {
+		t.Fatalf("Expected the first token right away, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		limiter RateLimiter
+	}{
+		{"Ticker", ticker},
+		{"Token bucket", bucket},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limiter.Wait(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetryAfter caps the server-advised wait so a bad header can't stall the parser
+const maxRetryAfter = 5 * time.Minute
+
+// retryAfterTransport is an http.RoundTripper that remembers the Retry-After header
+// of the last 429 response, the RPC client itself only exposes the status code
+type retryAfterTransport struct {
+	base http.RoundTripper
+
+	mu         sync.Mutex
+	retryAfter time.Duration
+}
+
+// newRetryAfterTransport wraps base, http.DefaultTransport is used when base is nil
+func newRetryAfterTransport(base http.RoundTripper) *retryAfterTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryAfterTransport{base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			t.mu.Lock()
+			t.retryAfter = wait
+			t.mu.Unlock()
+		}
+	}
+	return resp, nil
+}
+
+// takeRetryAfter returns the last advised wait and resets it, zero if none was received
+func (t *retryAfterTransport) takeRetryAfter() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	wait := t.retryAfter
+	t.retryAfter = 0
+	return wait
+}
+
+// parseRetryAfter parses a Retry-After value: delay in seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		wait = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		wait = date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+	} else {
+		return 0, false
+	}
+
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait, true
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// mockRoundTripper answers every request with a fixed status and headers
+type mockRoundTripper struct {
+	status int
+	header http.Header
+}
+
+func (m *mockRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: m.status,
+		Status:     http.StatusText(m.status),
+		Header:     m.header,
+		Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"rate limit"}}`)),
+		Request:    req,
+	}, nil
+}
+
+// newMockedClient creates an EthClient whose RPC requests go through the mock round tripper
+func newMockedClient(t *testing.T, rt http.RoundTripper) *EthClient {
+	t.Helper()
+
+	c := &EthClient{transport: newRetryAfterTransport(rt)}
+	rpcClient, err := rpc.DialOptions(context.Background(), "http://node.invalid", rpc.WithHTTPClient(&http.Client{Transport: c.transport}))
+	if err != nil {
+		t.Fatalf("Failed to create RPC client: %v", err)
+	}
+	t.Cleanup(rpcClient.Close)
+	c.rpcClient = rpcClient
+	return c
+}
+
+// TestRateLimitWaitRetryAfter tests that a 429 Retry-After header overrides the backoff
+func TestRateLimitWaitRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+	}{
+		{"Seconds", http.Header{"Retry-After": []string{"7"}}, 7 * time.Second},
+		{"Capped", http.Header{"Retry-After": []string{"3600"}}, maxRetryAfter},
+		{"Missing header falls back to backoff", http.Header{}, 4 * time.Second},
+		{"Invalid header falls back to backoff", http.Header{"Retry-After": []string{"soon"}}, 4 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newMockedClient(t, &mockRoundTripper{status: http.StatusTooManyRequests, header: tt.header})
+
+			var result string
+			err := c.rpcClient.CallContext(context.Background(), &result, "eth_blockNumber")
+			if !c.isRateLimitError(err) {
+				t.Fatalf("Expected rate limit error, got %v", err)
+			}
+
+			if wait := c.rateLimitWait(2); wait != tt.want {
+				t.Errorf("Expected wait %v, got %v", tt.want, wait)
+			}
+			// the advice is used once, the next wait is plain backoff again
+			if wait := c.rateLimitWait(0); wait != time.Second {
+				t.Errorf("Expected backoff of 1s after Retry-After was used, got %v", wait)
+			}
+		})
+	}
+}
+
+// TestParseRetryAfter tests both Retry-After formats
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{"-1", 0, false},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0, true},
+		{"tomorrow", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.value, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("Expected %v (%v), got %v (%v)", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrNoWebSocket is returned by SubscribeLogs when the client has no WebSocket URL configured
+var ErrNoWebSocket = errors.New("log subscriptions require a WebSocket URL (ConnectionConfig.WSNodeURL), only HTTP is configured")
+
+const (
+	defaultWSReconnectDelay = time.Second
+	maxWSReconnectDelay     = 30 * time.Second
+)
+
+// SubscribeLogs subscribes to logs matching query over the WebSocket connection (eth_subscribe "logs").
+// When the subscription drops it reconnects with exponential backoff and resubscribes; logs emitted
+// while disconnected are not replayed, use GetLogs to backfill if gaps matter.
+// The returned channel is closed when ctx is cancelled.
+func (c *EthClient) SubscribeLogs(ctx context.Context, query ethereum.FilterQuery) (<-chan types.Log, error) {
+	if c.wsURL == "" {
+		return nil, ErrNoWebSocket
+	}
+
+	// the first subscription is synchronous so that a bad URL or filter is reported to the caller
+	wsClient, sub, logs, err := c.subscribeLogs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.Log)
+	go c.forwardLogs(ctx, query, wsClient, sub, logs, out)
+	return out, nil
+}
+
+// subscribeLogs dials the WebSocket endpoint and starts a logs subscription
+func (c *EthClient) subscribeLogs(ctx context.Context, query ethereum.FilterQuery) (*rpc.Client, ethereum.Subscription, chan types.Log, error) {
+	dialCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	wsClient, err := rpc.DialContext(dialCtx, c.wsURL)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+
+	if err := c.waitForRateLimit(ctx); err != nil {
+		wsClient.Close()
+		return nil, nil, nil, err
+	}
+	logs := make(chan types.Log, 128)
+	sub, err := ethclient.NewClient(wsClient).SubscribeFilterLogs(dialCtx, query, logs)
+	if err != nil {
+		wsClient.Close()
+		return nil, nil, nil, fmt.Errorf("failed to subscribe to logs: %w", err)
+	}
+	return wsClient, sub, logs, nil
+}
+
+// forwardLogs copies subscription logs to out until ctx is done, resubscribing on errors
+func (c *EthClient) forwardLogs(ctx context.Context, query ethereum.FilterQuery, wsClient *rpc.Client, sub ethereum.Subscription, logs chan types.Log, out chan<- types.Log) {
+	defer close(out)
+
+	for {
+		dropped := false
+		for !dropped {
+			select {
+			case <-ctx.Done():
+				sub.Unsubscribe()
+				wsClient.Close()
+				return
+			case err := <-sub.Err():
+				log.Printf("Log subscription dropped: %v", err)
+				dropped = true
+			case l := <-logs:
+				select {
+				case out <- l:
+				case <-ctx.Done():
+					sub.Unsubscribe()
+					wsClient.Close()
+					return
+				}
+			}
+		}
+		sub.Unsubscribe()
+		wsClient.Close()
+
+		var err error
+		wsClient, sub, logs, err = c.resubscribeLogs(ctx, query)
+		if err != nil {
+			// only fails when ctx is done
+			return
+		}
+	}
+}
+
+// resubscribeLogs retries subscribeLogs with exponential backoff until it succeeds or ctx is done
+func (c *EthClient) resubscribeLogs(ctx context.Context, query ethereum.FilterQuery) (*rpc.Client, ethereum.Subscription, chan types.Log, error) {
+	delay := c.wsReconnectDelay
+	if delay <= 0 {
+		delay = defaultWSReconnectDelay
+	}
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		wsClient, sub, logs, err := c.subscribeLogs(ctx, query)
+		if err == nil {
+			log.Printf("Log subscription restored after %d attempt(s)", attempt)
+			return wsClient, sub, logs, nil
+		}
+		log.Printf("Failed to resubscribe to logs (attempt %d): %v", attempt, err)
+
+		delay *= 2
+		if delay > maxWSReconnectDelay {
+			delay = maxWSReconnectDelay
+		}
+	}
+}
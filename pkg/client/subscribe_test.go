@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// mockEthService serves eth_subscribe("logs"), each subscription gets one log with BlockNumber = subscription number
+type mockEthService struct {
+	subscriptions atomic.Int64
+}
+
+func (s *mockEthService) Logs(ctx context.Context, crit map[string]interface{}) (*rpc.Subscription, error) {
+	notifier, ok := rpc.NotifierFromContext(ctx)
+	if !ok {
+		return nil, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	n := s.subscriptions.Add(1)
+
+	go notifier.Notify(sub.ID, &types.Log{
+		Address:     common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7"),
+		Topics:      []common.Hash{common.HexToHash("0x01")},
+		Data:        []byte{},
+		BlockNumber: uint64(n),
+		TxHash:      common.HexToHash("0x02"),
+	})
+	return sub, nil
+}
+
+// hijackTracker records WebSocket connections so the test can drop them,
+// httptest.Server.CloseClientConnections doesn't track hijacked connections
+type hijackTracker struct {
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+type trackingWriter struct {
+	http.ResponseWriter
+	tracker *hijackTracker
+}
+
+func (w *trackingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		w.tracker.mu.Lock()
+		w.tracker.conns = append(w.tracker.conns, conn)
+		w.tracker.mu.Unlock()
+	}
+	return conn, rw, err
+}
+
+// closeAll drops all WebSocket connections
+func (h *hijackTracker) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, conn := range h.conns {
+		conn.Close()
+	}
+	h.conns = nil
+}
+
+// newMockWSServer starts a WebSocket JSON-RPC server with the mock eth service
+func newMockWSServer(t *testing.T) (*hijackTracker, *mockEthService, string) {
+	t.Helper()
+	service := &mockEthService{}
+	rpcServer := rpc.NewServer()
+	if err := rpcServer.RegisterName("eth", service); err != nil {
+		t.Fatalf("Failed to register service: %v", err)
+	}
+	tracker := &hijackTracker{}
+	wsHandler := rpcServer.WebsocketHandler([]string{"*"})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wsHandler.ServeHTTP(&trackingWriter{ResponseWriter: w, tracker: tracker}, r)
+	}))
+	t.Cleanup(func() {
+		tracker.closeAll()
+		ts.Close()
+		rpcServer.Stop()
+	})
+	return tracker, service, "ws" + strings.TrimPrefix(ts.URL, "http")
+}
+
+// receiveLog waits for one log or fails the test
+func receiveLog(t *testing.T, logs <-chan types.Log) types.Log {
+	t.Helper()
+	select {
+	case l, ok := <-logs:
+		if !ok {
+			t.Fatal("Log channel closed unexpectedly")
+		}
+		return l
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for log")
+	}
+	return types.Log{}
+}
+
+// TestSubscribeLogsHTTPOnly tests that a client without WebSocket URL returns ErrNoWebSocket
+func TestSubscribeLogsHTTPOnly(t *testing.T) {
+	c := &EthClient{nodeURL: "https://mainnet.infura.io/v3/key", timeout: time.Second}
+
+	_, err := c.SubscribeLogs(context.Background(), ethereum.FilterQuery{})
+	if !errors.Is(err, ErrNoWebSocket) {
+		t.Errorf("Expected ErrNoWebSocket, got %v", err)
+	}
+}
+
+// TestSubscribeLogsResubscribe tests that logs are delivered and the subscription is restored after a drop
+func TestSubscribeLogsResubscribe(t *testing.T) {
+	conns, service, wsURL := newMockWSServer(t)
+	c := &EthClient{wsURL: wsURL, timeout: 5 * time.Second, wsReconnectDelay: 10 * time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logs, err := c.SubscribeLogs(ctx, ethereum.FilterQuery{
+		Addresses: []common.Address{common.HexToAddress("0xdac17f958d2ee523a2206206994597c13d831ec7")},
+	})
+	if err != nil {
+		t.Fatalf("SubscribeLogs failed: %v", err)
+	}
+
+	if l := receiveLog(t, logs); l.BlockNumber != 1 {
+		t.Errorf("Expected log of subscription 1, got block %d", l.BlockNumber)
+	}
+
+	// drop the connection, the client should reconnect and resubscribe
+	conns.closeAll()
+
+	if l := receiveLog(t, logs); l.BlockNumber != 2 {
+		t.Errorf("Expected log of subscription 2, got block %d", l.BlockNumber)
+	}
+	if n := service.subscriptions.Load(); n != 2 {
+		t.Errorf("Expected 2 subscriptions, got %d", n)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-logs:
+		if ok {
+			t.Error("Expected channel to be closed after cancel")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Channel not closed after cancel")
+	}
+}
+
+// TestSubscribeLogsDialError tests that an unreachable WebSocket endpoint is reported by SubscribeLogs
+func TestSubscribeLogsDialError(t *testing.T) {
+	ts := httptest.NewServer(nil)
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+	ts.Close()
+
+	c := &EthClient{wsURL: wsURL, timeout: time.Second}
+	if _, err := c.SubscribeLogs(context.Background(), ethereum.FilterQuery{}); err == nil {
+		t.Error("Expected error for unreachable WebSocket endpoint")
+	}
+}
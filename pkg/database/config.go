@@ -3,10 +3,19 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"eth-blockchain-parser/internal/types"
+
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -19,10 +28,29 @@ type Config struct {
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
 	PragmaSettings  map[string]string
+
+	// ExtraDSNParams are appended to the connection string as is (escaped), e.g. _loc=auto or mode=rwc
+	ExtraDSNParams map[string]string
+
+	// MaxSQLVariables caps the bound parameters of one statement, batch inserts are split into
+	// statements under it within the same transaction (0 = DefaultMaxSQLVariables)
+	MaxSQLVariables int
+
+	// CheckpointOnClose runs PRAGMA wal_checkpoint(TRUNCATE) in Close, so a shutdown leaves the data in the
+	// main file and an empty -wal even if another process keeps the database open (no-op without WAL)
+	CheckpointOnClose bool
 }
 
-// DefaultConfig returns a production-ready configuration
+// DefaultMaxSQLVariables is SQLite's historical SQLITE_MAX_VARIABLE_NUMBER, safe for every build
+// (newer builds allow 32766, with ~20 columns per row that is still only ~1600 rows)
+const DefaultMaxSQLVariables = 999
+
+// DefaultConfig returns a production-ready configuration.
+// Passing ":memory:" returns InMemoryConfig instead.
 func DefaultConfig(dbPath string) *Config {
+	if dbPath == ":memory:" {
+		return InMemoryConfig()
+	}
 	return &Config{
 		DatabasePath:    dbPath,
 		MaxOpenConns:    25,
@@ -40,14 +68,61 @@ func DefaultConfig(dbPath string) *Config {
 			"auto_vacuum":        "INCREMENTAL", // Incremental auto-vacuum
 			"wal_autocheckpoint": "1000",        // Checkpoint after 1000 WAL frames
 		},
+		CheckpointOnClose: true,
+	}
+}
+
+// SetJournalMode overrides the journal_mode pragma after validating it against SQLite's modes.
+//
+// WAL (default) lets readers work while the parser writes, but keeps -wal/-shm files next to the
+// database: backups must copy all three files and the directory must be writable even for readers.
+// DELETE and TRUNCATE keep a single database file, a write blocks readers until it commits
+// (TRUNCATE is a bit faster on some filesystems). MEMORY keeps the rollback journal in memory,
+// a crash during a write can corrupt the database. PERSIST and OFF are accepted but not recommended.
+func (c *Config) SetJournalMode(mode string) error {
+	if err := pragmaValidators["journal_mode"](mode); err != nil {
+		return fmt.Errorf("invalid journal mode %q: %w", mode, err)
+	}
+	if c.PragmaSettings == nil {
+		c.PragmaSettings = make(map[string]string)
+	}
+	c.PragmaSettings["journal_mode"] = strings.ToUpper(mode)
+	return nil
+}
+
+// inMemoryCounter gives every in-memory database its own name so tests don't share state
+var inMemoryCounter int64
+
+// InMemoryConfig returns a configuration for a shared-cache in-memory database (useful for testing).
+// A single pooled connection that never expires keeps the schema visible to every query
+// and prevents the database from being dropped when the last connection closes.
+func InMemoryConfig() *Config {
+	name := fmt.Sprintf("file:memdb%d?mode=memory&cache=shared", atomic.AddInt64(&inMemoryCounter, 1))
+	return &Config{
+		DatabasePath:    name,
+		MaxOpenConns:    1,
+		MaxIdleConns:    1,
+		ConnMaxLifetime: 0,
+		ConnMaxIdleTime: 0,
+		PragmaSettings: map[string]string{
+			"synchronous":  "OFF",
+			"foreign_keys": "ON",
+			"temp_store":   "MEMORY",
+		},
 	}
 }
 
+// IsInMemory reports whether the config points to an in-memory database
+func (c *Config) IsInMemory() bool {
+	return c.DatabasePath == ":memory:" || strings.Contains(c.DatabasePath, "mode=memory")
+}
+
 // DatabaseManager handles SQLite connection with auto-reconnection
 type DatabaseManager struct {
 	db     *sqlx.DB
 	config *Config
 	logger *log.Logger
+	clock  types.Clock // created_at/updated_at of written rows and cache ages of the repositories
 }
 
 // NewDatabaseManager creates a new database manager with auto-reconnection
@@ -59,6 +134,7 @@ func NewDatabaseManager(config *Config, logger *log.Logger) (*DatabaseManager, e
 	dm := &DatabaseManager{
 		config: config,
 		logger: logger,
+		clock:  types.RealClock,
 	}
 
 	if err := dm.connect(); err != nil {
@@ -68,20 +144,126 @@ func NewDatabaseManager(config *Config, logger *log.Logger) (*DatabaseManager, e
 	return dm, nil
 }
 
+// pragmaValidators checks known pragma values, unknown pragmas only get a generic check
+var pragmaValidators = map[string]func(string) error{
+	"journal_mode":       oneOf("DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"),
+	"synchronous":        oneOf("OFF", "NORMAL", "FULL", "EXTRA", "0", "1", "2", "3"),
+	"cache_size":         isInteger,
+	"foreign_keys":       oneOf("ON", "OFF", "TRUE", "FALSE", "YES", "NO", "1", "0"),
+	"temp_store":         oneOf("DEFAULT", "FILE", "MEMORY", "0", "1", "2"),
+	"mmap_size":          isNonNegativeInteger,
+	"page_size":          isPageSize,
+	"auto_vacuum":        oneOf("NONE", "FULL", "INCREMENTAL", "0", "1", "2"),
+	"wal_autocheckpoint": isInteger,
+	"busy_timeout":       isNonNegativeInteger,
+}
+
+// oneOf returns a validator accepting only the given values (case-insensitive)
+func oneOf(allowed ...string) func(string) error {
+	return func(value string) error {
+		for _, a := range allowed {
+			if strings.EqualFold(value, a) {
+				return nil
+			}
+		}
+		return fmt.Errorf("expected one of %s", strings.Join(allowed, ", "))
+	}
+}
+
+func isInteger(value string) error {
+	if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+		return fmt.Errorf("expected an integer")
+	}
+	return nil
+}
+
+func isNonNegativeInteger(value string) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("expected a non-negative integer")
+	}
+	return nil
+}
+
+// isPageSize accepts a power of two between 512 and 65536, as SQLite requires
+func isPageSize(value string) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 512 || n > 65536 || n&(n-1) != 0 {
+		return fmt.Errorf("expected a power of two between 512 and 65536")
+	}
+	return nil
+}
+
+// ValidatePragmas checks pragma names and values before they are put into the connection string
+func (c *Config) ValidatePragmas() error {
+	for key, value := range c.PragmaSettings {
+		if key == "" || strings.IndexFunc(key, func(r rune) bool {
+			return !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+		}) >= 0 {
+			return fmt.Errorf("invalid pragma name %q", key)
+		}
+		if value == "" {
+			return fmt.Errorf("invalid value for pragma %s: value is empty", key)
+		}
+		if validate, ok := pragmaValidators[strings.ToLower(key)]; ok {
+			if err := validate(value); err != nil {
+				return fmt.Errorf("invalid value %q for pragma %s: %w", value, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ConnectionString builds the SQLite DSN with escaped pragma values in a stable order
+func (c *Config) ConnectionString() (string, error) {
+	if err := c.ValidatePragmas(); err != nil {
+		return "", err
+	}
+
+	keys := make([]string, 0, len(c.PragmaSettings))
+	for key := range c.PragmaSettings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(keys)+len(c.ExtraDSNParams))
+	for _, key := range keys {
+		params = append(params, fmt.Sprintf("_pragma=%s=%s", key, url.QueryEscape(c.PragmaSettings[key])))
+	}
+
+	extraKeys := make([]string, 0, len(c.ExtraDSNParams))
+	for key := range c.ExtraDSNParams {
+		extraKeys = append(extraKeys, key)
+	}
+	sort.Strings(extraKeys)
+	for _, key := range extraKeys {
+		params = append(params, fmt.Sprintf("%s=%s", url.QueryEscape(key), url.QueryEscape(c.ExtraDSNParams[key])))
+	}
+	if len(params) == 0 {
+		return c.DatabasePath, nil
+	}
+	separator := "?"
+	if strings.Contains(c.DatabasePath, "?") {
+		separator = "&"
+	}
+	return c.DatabasePath + separator + strings.Join(params, "&"), nil
+}
+
 // connect establishes a connection to SQLite database
 func (dm *DatabaseManager) connect() error {
-	// Build connection string with pragmas
-	connStr := dm.config.DatabasePath + "?"
-	for key, value := range dm.config.PragmaSettings {
-		connStr += fmt.Sprintf("_pragma=%s=%s&", key, value)
-	}
-	// Remove trailing &
-	if len(connStr) > 0 && connStr[len(connStr)-1] == '&' {
-		connStr = connStr[:len(connStr)-1]
+	connStr, err := dm.config.ConnectionString()
+	if err != nil {
+		return fmt.Errorf("invalid database configuration: %w", err)
 	}
 
-	db, err := sqlx.Connect("sqlite3", connStr)
+	connector, err := newPragmaConnector(connStr)
 	if err != nil {
+		return fmt.Errorf("invalid database configuration: %w", err)
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(connector), "sqlite3")
+	if err := db.Ping(); err != nil {
+		db.Close()
 		return fmt.Errorf("failed to connect to SQLite database: %w", err)
 	}
 
@@ -97,6 +279,16 @@ func (dm *DatabaseManager) connect() error {
 	return nil
 }
 
+// SetClock replaces the clock of the repositories over this manager, for deterministic tests
+func (dm *DatabaseManager) SetClock(clock types.Clock) {
+	dm.clock = clock
+}
+
+// now returns the current time of the manager's clock
+func (dm *DatabaseManager) now() time.Time {
+	return dm.clock.Now()
+}
+
 // DB returns the database connection with health check
 func (dm *DatabaseManager) DB() (*sqlx.DB, error) {
 	if err := dm.Ping(); err != nil {
@@ -121,15 +313,104 @@ func (dm *DatabaseManager) Ping() error {
 	return dm.db.PingContext(ctx)
 }
 
-// Close closes the database connection
+// ErrSchemaMissing is returned by CheckSchema when required tables don't exist
+var ErrSchemaMissing = errors.New("database schema missing")
+
+// CheckSchema verifies that the expected tables exist (e.g. RequiredTables), unlike Ping it fails on
+// a new or foreign database file. The error lists the missing tables and wraps ErrSchemaMissing.
+func (dm *DatabaseManager) CheckSchema(expected []string) error {
+	if dm.db == nil {
+		return fmt.Errorf("database connection is nil")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var tables []string
+	if err := dm.db.SelectContext(ctx, &tables, "SELECT name FROM sqlite_master WHERE type = 'table'"); err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+	existing := make(map[string]bool, len(tables))
+	for _, name := range tables {
+		existing[name] = true
+	}
+
+	var missing []string
+	for _, name := range expected {
+		if !existing[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: no tables %s", ErrSchemaMissing, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Close closes the database connection, with Config.CheckpointOnClose flushing the WAL first.
+// A failed checkpoint is only logged, the WAL is replayed on the next open anyway.
 func (dm *DatabaseManager) Close() error {
 	if dm.db != nil {
+		if dm.config.CheckpointOnClose {
+			dm.checkpoint()
+		}
 		dm.logger.Println("Closing database connection")
 		return dm.db.Close()
 	}
 	return nil
 }
 
+// checkpoint moves the WAL content into the main database file and truncates the -wal file
+func (dm *DatabaseManager) checkpoint() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// busy = 1 when readers or writers of other connections kept the checkpoint from completing
+	var busy, logFrames, checkpointed int
+	row := dm.db.QueryRowContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)")
+	if err := row.Scan(&busy, &logFrames, &checkpointed); err != nil {
+		dm.logger.Printf("WAL checkpoint on close failed: %v", err)
+		return
+	}
+	if busy != 0 {
+		dm.logger.Printf("WAL checkpoint on close incomplete: %d of %d frames checkpointed", checkpointed, logFrames)
+	}
+}
+
+// BackupTo writes a consistent copy of the database to path with VACUUM INTO. It is an online
+// backup: it reads in a single transaction, so it is safe while the parser writes (in WAL mode
+// writers are not blocked). The copy is compacted and has no -wal file. path must not exist.
+func (dm *DatabaseManager) BackupTo(path string) error {
+	if path == "" {
+		return fmt.Errorf("backup path is empty")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup target %s already exists", path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to check backup target: %w", err)
+	}
+
+	db, err := dm.DB()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return fmt.Errorf("failed to back up database to %s: %w", path, err)
+	}
+	dm.logger.Printf("Database backed up to %s in %v", path, time.Since(start))
+	return nil
+}
+
+// maxSQLVariables returns the configured bound parameter limit per statement
+func (dm *DatabaseManager) maxSQLVariables() int {
+	if dm.config.MaxSQLVariables > 0 {
+		return dm.config.MaxSQLVariables
+	}
+	return DefaultMaxSQLVariables
+}
+
 // RunInTransaction executes a function within a database transaction
 func (dm *DatabaseManager) RunInTransaction(fn func(*sqlx.Tx) error) error {
 	db, err := dm.DB()
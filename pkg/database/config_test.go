@@ -0,0 +1,441 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidatePragmas tests pragma value validation
+func TestValidatePragmas(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		expectErr bool
+	}{
+		{name: "Valid cache size", key: "cache_size", value: "-64000", expectErr: false},
+		{name: "Non-numeric cache size", key: "cache_size", value: "lots", expectErr: true},
+		{name: "Valid journal mode", key: "journal_mode", value: "wal", expectErr: false},
+		{name: "Unknown journal mode", key: "journal_mode", value: "FAST", expectErr: true},
+		{name: "Page size not power of two", key: "page_size", value: "5000", expectErr: true},
+		{name: "Negative mmap size", key: "mmap_size", value: "-1", expectErr: true},
+		{name: "Empty value", key: "synchronous", value: "", expectErr: true},
+		{name: "Bad pragma name", key: "cache_size&x", value: "1", expectErr: true},
+		{name: "Unknown pragma passes", key: "recursive_triggers", value: "ON", expectErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig("test.db")
+			config.PragmaSettings = map[string]string{tt.key: tt.value}
+
+			err := config.ValidatePragmas()
+			if tt.expectErr && err == nil {
+				t.Fatalf("Expected error for %s=%q, got nil", tt.key, tt.value)
+			}
+			if !tt.expectErr && err != nil {
+				t.Fatalf("Unexpected error for %s=%q: %v", tt.key, tt.value, err)
+			}
+		})
+	}
+}
+
+// TestDefaultConfigPragmasValid makes sure the shipped defaults pass validation
+func TestDefaultConfigPragmasValid(t *testing.T) {
+	if err := DefaultConfig("test.db").ValidatePragmas(); err != nil {
+		t.Fatalf("Default pragmas should be valid: %v", err)
+	}
+}
+
+// TestNewDatabaseManagerBadPragma tests that a bad pragma is reported by name
+func TestNewDatabaseManagerBadPragma(t *testing.T) {
+	config := DefaultConfig(t.TempDir() + "/test.db")
+	config.PragmaSettings["cache_size"] = "sixty-four"
+
+	_, err := NewDatabaseManager(config, nil)
+	if err == nil {
+		t.Fatal("Expected error for non-numeric cache_size, got nil")
+	}
+	if !strings.Contains(err.Error(), "cache_size") {
+		t.Errorf("Expected error to name the bad pragma, got: %v", err)
+	}
+}
+
+// TestConnectionStringEscaping tests that pragma values are URL-escaped and ordered
+func TestConnectionStringEscaping(t *testing.T) {
+	config := DefaultConfig("test.db")
+	config.PragmaSettings = map[string]string{
+		"synchronous":  "NORMAL",
+		"journal_mode": "WAL",
+		"custom":       "a&b c",
+	}
+
+	connStr, err := config.ConnectionString()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "test.db?_pragma=custom=a%26b+c&_pragma=journal_mode=WAL&_pragma=synchronous=NORMAL"
+	if connStr != expected {
+		t.Errorf("Expected %s, got %s", expected, connStr)
+	}
+}
+
+// TestConnectionStringExtraParams tests that extra DSN params are appended escaped after the pragmas
+func TestConnectionStringExtraParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		pragmas  map[string]string
+		extra    map[string]string
+		expected string
+	}{
+		{
+			name:     "With pragmas",
+			path:     "test.db",
+			pragmas:  map[string]string{"synchronous": "NORMAL"},
+			extra:    map[string]string{"mode": "rwc", "_loc": "auto"},
+			expected: "test.db?_pragma=synchronous=NORMAL&_loc=auto&mode=rwc",
+		},
+		{
+			name:     "Only extra params",
+			path:     "test.db",
+			extra:    map[string]string{"_loc": "Europe/Moscow"},
+			expected: "test.db?_loc=Europe%2FMoscow",
+		},
+		{
+			name:     "Path with query",
+			path:     "file:test.db?cache=shared",
+			extra:    map[string]string{"mode": "rwc"},
+			expected: "file:test.db?cache=shared&mode=rwc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{DatabasePath: tt.path, PragmaSettings: tt.pragmas, ExtraDSNParams: tt.extra}
+			connStr, err := config.ConnectionString()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if connStr != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, connStr)
+			}
+		})
+	}
+}
+
+// TestExtraDSNParamsReachDriver tests that an extra param is applied by the driver
+func TestExtraDSNParamsReachDriver(t *testing.T) {
+	config := DefaultConfig("file:" + filepath.Join(t.TempDir(), "ro.db"))
+	config.ExtraDSNParams = map[string]string{"mode": "ro"}
+
+	// read-only mode (honored for file: URIs) can't create a missing database file
+	if dm, err := NewDatabaseManager(config, nil); err == nil {
+		dm.Close()
+		t.Fatal("Expected error opening a missing database with mode=ro")
+	}
+}
+
+// newTestDatabase creates an in-memory database with the full schema
+func newTestDatabase(t *testing.T) *DatabaseManager {
+	t.Helper()
+
+	dm, err := NewDatabaseManager(InMemoryConfig(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if err := NewSchema(nil).CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	return dm
+}
+
+// TestInMemoryDatabase tests that the schema and data are visible across queries in memory
+func TestInMemoryDatabase(t *testing.T) {
+	dm := newTestDatabase(t)
+	ctx := context.Background()
+
+	label := "Binance 7"
+	addrRepo := NewAddressRepository(dm, nil)
+	if err := addrRepo.BatchInsert(ctx, []*WhaleAddress{{Address: "0xbe0eb53f46cd790cd13851d5eff43d12404d33e8", Label: &label}}); err != nil {
+		t.Fatalf("Failed to insert whale address: %v", err)
+	}
+
+	txRepo := NewTransactionRepository(dm, nil)
+	tx := &Transaction{
+		TxHash:         "0xabc",
+		BlockNumber:    100,
+		FromAddress:    "0xbe0eb53f46cd790cd13851d5eff43d12404d33e8",
+		WhaleAddressID: int64Ptr(1),
+		TransferType:   "FROM",
+		Value:          "1.5",
+	}
+	if err := txRepo.BatchInsert(ctx, []*Transaction{tx}); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	got, err := txRepo.GetByHash(ctx, "0xabc")
+	if err != nil {
+		t.Fatalf("Failed to read transaction: %v", err)
+	}
+	if got == nil || got.Value != "1.5" || got.TransferType != "FROM" {
+		t.Fatalf("Unexpected transaction read back: %+v", got)
+	}
+}
+
+// TestInMemoryConfigsAreIsolated tests that two in-memory configs don't share tables
+func TestInMemoryConfigsAreIsolated(t *testing.T) {
+	newTestDatabase(t)
+
+	dm, err := NewDatabaseManager(DefaultConfig(":memory:"), nil)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer dm.Close()
+
+	if !dm.config.IsInMemory() {
+		t.Error("Expected DefaultConfig(\":memory:\") to be in-memory")
+	}
+
+	info, err := NewSchema(nil).GetTableInfo(dm.db)
+	if err != nil {
+		t.Fatalf("Failed to get table info: %v", err)
+	}
+	if tables, _ := info["tables"].([]string); len(tables) != 0 {
+		t.Errorf("Expected empty database, got tables %v", tables)
+	}
+}
+
+// TestCheckSchema tests the required tables check on a full, a partial and an empty database
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name          string
+		setup         func(t *testing.T) *DatabaseManager
+		expectMissing string
+	}{
+		{"all tables", newTestDatabase, ""},
+		{"missing tables", func(t *testing.T) *DatabaseManager {
+			dm := newTestDatabase(t)
+			if _, err := dm.db.Exec("DROP TABLE settings; DROP TABLE meta"); err != nil {
+				t.Fatalf("Failed to drop tables: %v", err)
+			}
+			return dm
+		}, "settings, meta"},
+		{"empty database", func(t *testing.T) *DatabaseManager {
+			dm, err := NewDatabaseManager(InMemoryConfig(), nil)
+			if err != nil {
+				t.Fatalf("Failed to create in-memory database: %v", err)
+			}
+			t.Cleanup(func() { dm.Close() })
+			return dm
+		}, strings.Join(RequiredTables, ", ")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm := tt.setup(t)
+
+			err := dm.CheckSchema(RequiredTables)
+			if tt.expectMissing == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSchemaMissing) {
+				t.Fatalf("Expected ErrSchemaMissing, got %v", err)
+			}
+			if !strings.HasSuffix(err.Error(), "no tables "+tt.expectMissing) {
+				t.Errorf("Expected missing tables %s, got %q", tt.expectMissing, err)
+			}
+		})
+	}
+}
+
+// TestJournalMode tests that the configured journal mode reaches SQLite and DELETE leaves no -wal file
+func TestJournalMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string // empty keeps the default
+		want    string
+		wantWAL bool
+	}{
+		{name: "Default WAL", want: "wal", wantWAL: true},
+		{name: "DELETE", mode: "DELETE", want: "delete"},
+		{name: "TRUNCATE lowercase", mode: "truncate", want: "truncate"},
+		{name: "MEMORY", mode: "MEMORY", want: "memory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "journal.db")
+			config := DefaultConfig(path)
+			if tt.mode != "" {
+				if err := config.SetJournalMode(tt.mode); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+
+			dm, err := NewDatabaseManager(config, nil)
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			defer dm.Close()
+
+			if _, err := dm.db.Exec("CREATE TABLE t (v INTEGER); INSERT INTO t VALUES (1)"); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+
+			var mode string
+			if err := dm.db.Get(&mode, "PRAGMA journal_mode"); err != nil {
+				t.Fatalf("Failed to read journal mode: %v", err)
+			}
+			if mode != tt.want {
+				t.Errorf("Expected journal mode %s, got %s", tt.want, mode)
+			}
+
+			_, err = os.Stat(path + "-wal")
+			if hasWAL := err == nil; hasWAL != tt.wantWAL {
+				t.Errorf("Expected -wal file exists=%v, got %v", tt.wantWAL, hasWAL)
+			}
+		})
+	}
+}
+
+// TestCheckpointOnClose tests that Close leaves an empty -wal file while another connection keeps it open
+func TestCheckpointOnClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		enabled   bool
+		wantEmpty bool
+	}{
+		{name: "Enabled", enabled: true, wantEmpty: true},
+		{name: "Disabled", enabled: false, wantEmpty: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "checkpoint.db")
+			config := DefaultConfig(path)
+			config.CheckpointOnClose = tt.enabled
+
+			// the last connection to close checkpoints by itself, a second process keeps the WAL alive
+			other, err := NewDatabaseManager(DefaultConfig(path), nil)
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			other.config.CheckpointOnClose = false
+			defer other.Close()
+
+			dm, err := NewDatabaseManager(config, nil)
+			if err != nil {
+				t.Fatalf("Failed to open database: %v", err)
+			}
+			if _, err := dm.db.Exec("CREATE TABLE t (v INTEGER); INSERT INTO t VALUES (1), (2), (3)"); err != nil {
+				t.Fatalf("Failed to write: %v", err)
+			}
+			// a read maps the WAL index, an idle connection alone doesn't count as a user of the WAL
+			var count int
+			if err := other.db.Get(&count, "SELECT COUNT(*) FROM t"); err != nil {
+				t.Fatalf("Failed to read: %v", err)
+			}
+			if err := dm.Close(); err != nil {
+				t.Fatalf("Failed to close: %v", err)
+			}
+
+			var size int64
+			if info, err := os.Stat(path + "-wal"); err == nil {
+				size = info.Size()
+			} else if !os.IsNotExist(err) {
+				t.Fatalf("Failed to stat -wal: %v", err)
+			}
+			if (size == 0) != tt.wantEmpty {
+				t.Errorf("Expected empty -wal %v, got %d bytes", tt.wantEmpty, size)
+			}
+
+			if err := other.db.Get(&count, "SELECT COUNT(*) FROM t"); err != nil || count != 3 {
+				t.Errorf("Expected 3 rows, got %d (%v)", count, err)
+			}
+		})
+	}
+}
+
+// TestSetJournalModeInvalid tests that unknown journal modes are rejected
+func TestSetJournalModeInvalid(t *testing.T) {
+	config := DefaultConfig("test.db")
+	if err := config.SetJournalMode("FAST"); err == nil {
+		t.Error("Expected error for unknown journal mode")
+	}
+	if config.PragmaSettings["journal_mode"] != "WAL" {
+		t.Errorf("Expected journal mode to stay WAL, got %s", config.PragmaSettings["journal_mode"])
+	}
+}
+
+// TestPragmasApplied tests that pragmas are set on every pooled connection, not only in the DSN
+func TestPragmasApplied(t *testing.T) {
+	dm, err := NewDatabaseManager(DefaultConfig(filepath.Join(t.TempDir(), "pragma.db")), nil)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer dm.Close()
+
+	// hold one connection so the query below runs on a second one
+	conn, err := dm.db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	var foreignKeys int
+	if err := dm.db.Get(&foreignKeys, "PRAGMA foreign_keys"); err != nil {
+		t.Fatalf("Failed to read pragma: %v", err)
+	}
+	if foreignKeys != 1 {
+		t.Errorf("Expected foreign_keys=1, got %d", foreignKeys)
+	}
+}
+
+// TestBackupTo tests that a backup of a populated database has the same row counts
+func TestBackupTo(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 4)
+
+	path := filepath.Join(t.TempDir(), "backup.db")
+	if err := dm.BackupTo(path); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	backup, err := NewDatabaseManager(DefaultConfig(path), nil)
+	if err != nil {
+		t.Fatalf("Failed to open backup: %v", err)
+	}
+	defer backup.Close()
+
+	for _, table := range []string{"transactions", "whale_addresses"} {
+		var want, got int
+		if err := dm.db.Get(&want, "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Fatalf("Failed to count %s: %v", table, err)
+		}
+		if err := backup.db.Get(&got, "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Fatalf("Failed to count %s in backup: %v", table, err)
+		}
+		if want == 0 || got != want {
+			t.Errorf("Expected %d rows in %s, got %d", want, table, got)
+		}
+	}
+
+	if err := dm.BackupTo(path); err == nil {
+		t.Error("Expected error when the backup target exists")
+	}
+}
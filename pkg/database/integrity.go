@@ -0,0 +1,101 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// BlockGap is a range of block numbers missing from the stored data, both ends inclusive
+type BlockGap struct {
+	Start int64 `json:"start" db:"gap_start"`
+	End   int64 `json:"end" db:"gap_end"`
+}
+
+// ChainBreak is a stored block whose parent_hash doesn't match the hash of the previous block
+type ChainBreak struct {
+	BlockNumber  int64  `json:"block_number" db:"number"`
+	ParentHash   string `json:"parent_hash" db:"parent_hash"`
+	PreviousHash string `json:"previous_hash" db:"previous_hash"`
+}
+
+// IntegrityReport is the result of VerifyIntegrity
+type IntegrityReport struct {
+	// Source is the table the block numbers were read from: "blocks" or "transactions"
+	Source        string       `json:"source"`
+	FirstBlock    int64        `json:"first_block"`
+	LastBlock     int64        `json:"last_block"`
+	StoredBlocks  int64        `json:"stored_blocks"`
+	MissingBlocks int64        `json:"missing_blocks"`
+	Gaps          []BlockGap   `json:"gaps"`
+	HashChecked   bool         `json:"hash_checked"` // parent hashes are only checked with a blocks table
+	ChainBreaks   []ChainBreak `json:"chain_breaks"`
+}
+
+// OK reports whether no gaps and no hash chain breaks were found
+func (r *IntegrityReport) OK() bool {
+	return len(r.Gaps) == 0 && len(r.ChainBreaks) == 0
+}
+
+// VerifyIntegrity scans the stored block numbers and reports the gaps in the covered range.
+// Block numbers come from the blocks table (number, hash, parent_hash) when it has rows, otherwise
+// from the distinct block numbers of transactions - only whale transactions are stored, so there
+// gaps are expected between blocks without whale activity. With a blocks table the parent_hash of
+// each block is also checked against the hash of the previous block.
+func (dm *DatabaseManager) VerifyIntegrity(ctx context.Context) (*IntegrityReport, error) {
+	db, err := dm.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// databases parsed before blocks were stored have an empty blocks table, fall back to transactions
+	var storedBlocks int
+	if err := db.GetContext(ctx, &storedBlocks, "SELECT EXISTS (SELECT 1 FROM blocks)"); err != nil {
+		return nil, fmt.Errorf("failed to check blocks table: %w", err)
+	}
+
+	report := &IntegrityReport{Source: "transactions", Gaps: []BlockGap{}, ChainBreaks: []ChainBreak{}}
+	numbers := "SELECT DISTINCT block_number AS number FROM transactions"
+	if storedBlocks > 0 {
+		report.Source = "blocks"
+		report.HashChecked = true
+		numbers = "SELECT DISTINCT number FROM blocks"
+	}
+
+	var summary struct {
+		First  *int64 `db:"first_block"`
+		Last   *int64 `db:"last_block"`
+		Stored int64  `db:"stored_blocks"`
+	}
+	query := "SELECT MIN(number) AS first_block, MAX(number) AS last_block, COUNT(*) AS stored_blocks FROM (" + numbers + ")"
+	if err := db.GetContext(ctx, &summary, query); err != nil {
+		return nil, fmt.Errorf("failed to read block range: %w", err)
+	}
+	if summary.First == nil {
+		return report, nil
+	}
+	report.FirstBlock = *summary.First
+	report.LastBlock = *summary.Last
+	report.StoredBlocks = summary.Stored
+	report.MissingBlocks = report.LastBlock - report.FirstBlock + 1 - report.StoredBlocks
+
+	gapsQuery := `
+	SELECT prev + 1 AS gap_start, number - 1 AS gap_end FROM (
+		SELECT number, LAG(number) OVER (ORDER BY number) AS prev FROM (` + numbers + `)
+	) WHERE number - prev > 1 ORDER BY number`
+	if err := db.SelectContext(ctx, &report.Gaps, gapsQuery); err != nil {
+		return nil, fmt.Errorf("failed to find block gaps: %w", err)
+	}
+
+	if report.HashChecked {
+		breaksQuery := `
+		SELECT b.number, b.parent_hash, p.hash AS previous_hash
+		FROM blocks b JOIN blocks p ON p.number = b.number - 1
+		WHERE LOWER(b.parent_hash) != LOWER(p.hash)
+		ORDER BY b.number`
+		if err := db.SelectContext(ctx, &report.ChainBreaks, breaksQuery); err != nil {
+			return nil, fmt.Errorf("failed to check block hash chain: %w", err)
+		}
+	}
+
+	return report, nil
+}
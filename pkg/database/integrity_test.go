@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// insertBlockNumbers stores one transaction per block number
+func insertBlockNumbers(t *testing.T, dm *DatabaseManager, numbers ...int64) {
+	t.Helper()
+	var txs []*Transaction
+	for _, number := range numbers {
+		txs = append(txs, &Transaction{
+			TxHash:      fmt.Sprintf("0x%d", number),
+			BlockNumber: number,
+			FromAddress: "0x1",
+			Value:       "1",
+		})
+	}
+	if err := NewTransactionRepository(dm, nil).BatchInsert(context.Background(), txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+}
+
+// TestVerifyIntegrityTransactions tests gap detection over transaction block numbers
+func TestVerifyIntegrityTransactions(t *testing.T) {
+	t.Run("Empty database", func(t *testing.T) {
+		report, err := newTestDatabase(t).VerifyIntegrity(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !report.OK() || report.StoredBlocks != 0 {
+			t.Errorf("Expected empty OK report, got %+v", report)
+		}
+	})
+
+	t.Run("Gapped blocks", func(t *testing.T) {
+		dm := newTestDatabase(t)
+		insertBlockNumbers(t, dm, 100, 101, 101, 104, 105, 110)
+
+		report, err := dm.VerifyIntegrity(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if report.Source != "transactions" || report.HashChecked {
+			t.Errorf("Expected transactions source without hash check, got %s, %v", report.Source, report.HashChecked)
+		}
+		if report.FirstBlock != 100 || report.LastBlock != 110 {
+			t.Errorf("Expected range 100-110, got %d-%d", report.FirstBlock, report.LastBlock)
+		}
+		if report.StoredBlocks != 5 || report.MissingBlocks != 6 {
+			t.Errorf("Expected 5 stored and 6 missing blocks, got %d and %d", report.StoredBlocks, report.MissingBlocks)
+		}
+		expected := []BlockGap{{Start: 102, End: 103}, {Start: 106, End: 109}}
+		if !reflect.DeepEqual(report.Gaps, expected) {
+			t.Errorf("Expected gaps %v, got %v", expected, report.Gaps)
+		}
+		if report.OK() {
+			t.Error("Expected report with gaps not to be OK")
+		}
+	})
+}
+
+// TestVerifyIntegrityBlocks tests gap and parent hash checks over a blocks table
+func TestVerifyIntegrityBlocks(t *testing.T) {
+	dm := newTestDatabase(t)
+	// transactions alone would report every missing block as a gap
+	insertBlockNumbers(t, dm, 1, 6)
+	blocks := []struct {
+		number           int64
+		hash, parentHash string
+	}{
+		{1, "0xa1", "0xa0"},
+		{2, "0xa2", "0xA1"}, // hashes are compared case-insensitively
+		{3, "0xa3", "0xbad"},
+		{5, "0xa5", "0xa4"}, // block 4 missing, nothing to compare with
+		{6, "0xa6", "0xa5"},
+	}
+	var stored []*Block
+	for _, b := range blocks {
+		stored = append(stored, &Block{Number: b.number, Hash: b.hash, ParentHash: b.parentHash, Timestamp: time.Unix(b.number, 0)})
+	}
+	if err := NewBlockRepository(dm, nil).BatchUpsert(context.Background(), stored); err != nil {
+		t.Fatalf("Failed to insert blocks: %v", err)
+	}
+
+	report, err := dm.VerifyIntegrity(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Source != "blocks" || !report.HashChecked {
+		t.Errorf("Expected blocks source with hash check, got %s, %v", report.Source, report.HashChecked)
+	}
+	if expected := []BlockGap{{Start: 4, End: 4}}; !reflect.DeepEqual(report.Gaps, expected) {
+		t.Errorf("Expected gaps %v, got %v", expected, report.Gaps)
+	}
+	expected := []ChainBreak{{BlockNumber: 3, ParentHash: "0xbad", PreviousHash: "0xa2"}}
+	if !reflect.DeepEqual(report.ChainBreaks, expected) {
+		t.Errorf("Expected chain breaks %v, got %v", expected, report.ChainBreaks)
+	}
+}
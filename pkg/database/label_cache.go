@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+)
+
+// LabelCache keeps the whale address -> ID, address -> label and address -> min ETH maps in memory.
+// The maps are reloaded from DB every TTL by Start, and on the next lookup after Invalidate.
+// Writes through the AddressRepository the cache was created with invalidate it automatically.
+type LabelCache struct {
+	repo *AddressRepository
+	ttl  time.Duration
+
+	mu       sync.RWMutex
+	ids      map[string]string
+	labels   map[string]string
+	minETH   map[string]string
+	loaded   bool
+	loadedAt time.Time
+	// generation is bumped by Invalidate, a refresh started before an invalidation doesn't mark the maps fresh
+	generation uint64
+}
+
+// NewLabelCache creates a cache over the repository's whale addresses, ttl <= 0 disables background refresh
+func NewLabelCache(ar *AddressRepository, ttl time.Duration) *LabelCache {
+	c := &LabelCache{repo: ar, ttl: ttl}
+	ar.labelCaches = append(ar.labelCaches, c)
+	return c
+}
+
+// Start reloads the maps every TTL until ctx is done, a failed refresh keeps the previous maps
+func (c *LabelCache) Start(ctx context.Context) {
+	if c.ttl <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(c.ttl)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Refresh(ctx); err != nil {
+					c.repo.logger.Printf("Failed to refresh whale labels: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Refresh reloads the maps from DB
+func (c *LabelCache) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	generation := c.generation
+	c.mu.RUnlock()
+
+	mappings, err := c.repo.GetAddrMappings(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load whale labels: %w", err)
+	}
+
+	c.mu.Lock()
+	c.ids, c.labels, c.minETH = *mappings[0], *mappings[1], *mappings[2]
+	c.loaded = generation == c.generation
+	c.loadedAt = c.repo.dm.now()
+	c.mu.Unlock()
+	return nil
+}
+
+// Invalidate drops the loaded maps, the next lookup reloads them from DB
+func (c *LabelCache) Invalidate() {
+	c.mu.Lock()
+	c.loaded = false
+	c.generation++
+	c.mu.Unlock()
+}
+
+// Mappings returns the address -> ID and address -> label maps, loading them if needed.
+// The maps are shared with other callers and must not be modified.
+func (c *LabelCache) Mappings(ctx context.Context) (ids, labels map[string]string, err error) {
+	c.mu.RLock()
+	ids, labels, loaded := c.ids, c.labels, c.loaded
+	c.mu.RUnlock()
+	if loaded {
+		return ids, labels, nil
+	}
+
+	if err := c.Refresh(ctx); err != nil {
+		return nil, nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ids, c.labels, nil
+}
+
+// Thresholds returns the address -> min ETH map of whales with their own threshold, loading it if needed.
+// The map is shared with other callers and must not be modified.
+func (c *LabelCache) Thresholds(ctx context.Context) (map[string]string, error) {
+	if _, _, err := c.Mappings(ctx); err != nil {
+		return nil, err
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.minETH, nil
+}
+
+// Label returns the label of a whale address regardless of the address case
+func (c *LabelCache) Label(ctx context.Context, addr string) (string, bool, error) {
+	_, labels, err := c.Mappings(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	label, ok := labels[types.NormalizeAddress(addr)]
+	return label, ok, nil
+}
+
+// LoadedAt returns when the maps were last loaded, zero if never
+func (c *LabelCache) LoadedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.loadedAt
+}
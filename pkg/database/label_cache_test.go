@@ -0,0 +1,113 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestLabelCacheInvalidate tests that lookups are served from memory until a write or Invalidate
+func TestLabelCacheInvalidate(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 1)
+	addrRepo := NewAddressRepository(dm, nil)
+	cache := NewLabelCache(addrRepo, 0)
+	ctx := context.Background()
+	whale := "0x0000000000000000000000000000000000000001"
+
+	label, ok, err := cache.Label(ctx, whale)
+	if err != nil || !ok || label != "Whale 1" {
+		t.Fatalf("Expected Whale 1, got %q (found %v, err %v)", label, ok, err)
+	}
+
+	// a write bypassing the repository is not seen until Invalidate
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if _, err := db.Exec("UPDATE whale_addresses SET label = 'Direct' WHERE address = ?", whale); err != nil {
+		t.Fatalf("Failed to update label: %v", err)
+	}
+	if label, _, _ := cache.Label(ctx, whale); label != "Whale 1" {
+		t.Errorf("Expected cached Whale 1, got %s", label)
+	}
+	cache.Invalidate()
+	if label, _, _ := cache.Label(ctx, whale); label != "Direct" {
+		t.Errorf("Expected Direct after Invalidate, got %s", label)
+	}
+
+	// writes through the repository invalidate the cache
+	tests := []struct {
+		name  string
+		write func() error
+		addr  string
+		want  string
+	}{
+		{"BatchUpsert", func() error {
+			label := "Upserted"
+			return addrRepo.BatchUpsert(ctx, []*WhaleAddress{{Address: whale, Label: &label}})
+		}, whale, "Upserted"},
+		{"BatchInsert", func() error {
+			label := "New whale"
+			return addrRepo.BatchInsert(ctx, []*WhaleAddress{{Address: "0xnew", Label: &label}})
+		}, "0xNEW", "New whale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			label, ok, err := cache.Label(ctx, tt.addr)
+			if err != nil || !ok || label != tt.want {
+				t.Errorf("Expected %s, got %q (found %v, err %v)", tt.want, label, ok, err)
+			}
+		})
+	}
+
+	ids, _, err := cache.Mappings(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ids[whale] != "1" {
+		t.Errorf("Expected whale ID 1, got %s", ids[whale])
+	}
+}
+
+// TestLabelCacheRefresh tests that the background refresh picks up changes made by other processes
+func TestLabelCacheRefresh(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 1)
+	cache := NewLabelCache(NewAddressRepository(dm, nil), 20*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	whale := "0x0000000000000000000000000000000000000002"
+
+	if label, _, _ := cache.Label(ctx, whale); label != "Whale 2" {
+		t.Fatalf("Expected Whale 2, got %s", label)
+	}
+	loadedAt := cache.LoadedAt()
+	cache.Start(ctx)
+
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if _, err := db.Exec("UPDATE whale_addresses SET label = 'Refreshed' WHERE address = ?", whale); err != nil {
+		t.Fatalf("Failed to update label: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		label, _, _ := cache.Label(ctx, whale)
+		if label == "Refreshed" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected Refreshed after background refresh, got %s", label)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !cache.LoadedAt().After(loadedAt) {
+		t.Errorf("Expected LoadedAt to advance past %v, got %v", loadedAt, cache.LoadedAt())
+	}
+}
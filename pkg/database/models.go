@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"eth-blockchain-parser/internal/types"
 	"fmt"
 	"strconv"
@@ -18,20 +19,39 @@ type Transaction struct {
 	TransactionIndex int64     `json:"transaction_index" db:"transaction_index"`
 	FromAddress      string    `json:"from_address" db:"from_address"`
 	ToAddress        *string   `json:"to_address" db:"to_address"`             // Nullable for contract creation
-	WhaleAddressID   int64     `json:"whale_address_id" db:"whale_address_id"` // Foreign key - required field
+	WhaleAddressID   *int64    `json:"whale_address_id" db:"whale_address_id"` // Foreign key, nil for non-whale transactions
 	TransferType     string    `json:"transfer_type" db:"transfer_type"`       // Required field with default ''
 	Value            string    `json:"value" db:"value"`                       // Store as string, DB has DECIMAL(10,5) with default '0'
 	Gas              int64     `json:"gas" db:"gas"`
-	GasPrice         string    `json:"gas_price" db:"gas_price"` // Default '0'
-	GasUsed          *int64    `json:"gas_used" db:"gas_used"`   // Nullable if not yet mined
-	Status           *int      `json:"status" db:"status"`       // Nullable, 0=failed, 1=success
+	GasPrice         string    `json:"gas_price" db:"gas_price"`             // Default '0'
+	GasUsed          *int64    `json:"gas_used" db:"gas_used"`               // Nullable if not yet mined
+	Status           *int      `json:"status" db:"status"`                   // Nullable, 0=failed, 1=success
+	ReceiptFetched   bool      `json:"receipt_fetched" db:"receipt_fetched"` // false: gas_used/status unknown, not a failed tx
 	Nonce            int64     `json:"nonce" db:"nonce"`
 	InputData        *string   `json:"input_data" db:"input_data"`             // BLOB field
 	TxType           int       `json:"tx_type" db:"tx_type"`                   // Default 0
 	MaxFeePerGas     *string   `json:"max_fee_per_gas" db:"max_fee_per_gas"`   // EIP-1559, nullable
 	MaxPriorityFee   *string   `json:"max_priority_fee" db:"max_priority_fee"` // EIP-1559, nullable
+	ContractAddress  *string   `json:"contract_address" db:"contract_address"` // Created contract, set only for contract creations with a receipt
+	Asset            string    `json:"asset" db:"asset"`                       // Transferred asset symbol, types.NativeAsset for ETH transfers
+	TokenAddress     *string   `json:"token_address" db:"token_address"`       // ERC-20 contract of a token transfer, nil for ETH
 	CreatedAt        time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+
+	// Set by whale filtering, not persisted
+	HighPriority bool       `json:"high_priority" db:"-"`
+	BlockTime    *time.Time `json:"block_time,omitempty" db:"-"` // timestamp of the block, nil for rows read from DB
+}
+
+// normalizeAddresses stores all addresses of the transaction in types.NormalizeAddress form,
+// the form every address lookup queries with
+func (t *Transaction) normalizeAddresses() {
+	t.FromAddress = types.NormalizeAddress(t.FromAddress)
+	for _, addr := range []*string{t.ToAddress, t.ContractAddress, t.TokenAddress} {
+		if addr != nil {
+			*addr = types.NormalizeAddress(*addr)
+		}
+	}
 }
 
 // SetDefaults sets default values for required fields
@@ -48,11 +68,10 @@ func (t *Transaction) SetDefaults() {
 	if t.GasPrice == "" {
 		t.GasPrice = "0"
 	}
-	if t.WhaleAddressID == 0 {
-		// Set to 1 as default whale address ID
-		// This should be handled by the mapper function
-		t.WhaleAddressID = 1
+	if t.Asset == "" {
+		t.Asset = types.NativeAsset
 	}
+	// WhaleAddressID is left nil for non-whale transactions, it is set by the mapper
 }
 
 // MapParsedTxToDatabaseTx converts a types.ParsedTransaction to database.Transaction
@@ -85,41 +104,46 @@ func MapParsedTxToDatabaseTx(parsedTx *types.ParsedTransaction, params ...string
 		maxPriorityFee = &maxPriorityFeeStr
 	}
 
-	// Handle nullable fields
+	// Receipt fields stay NULL when the receipt was not fetched, status 0 means a failed tx only with a receipt
 	var gasUsed *int64
-	if parsedTx.GasUsed > 0 {
+	var status *int
+	if parsedTx.ReceiptFetched {
 		gasUsedVal := int64(parsedTx.GasUsed)
 		gasUsed = &gasUsedVal
-	}
-
-	var status *int
-	if parsedTx.Status == 1 || parsedTx.Status == 0 {
 		statusVal := int(parsedTx.Status)
 		status = &statusVal
 	}
 
+	// Never store the "unknown" placeholder as an address
+	from := parsedTx.From
+	if from == types.UnknownSender {
+		from = ""
+	}
+
 	// Create the database transaction
 	tx := &Transaction{
 		TxHash:           parsedTx.Hash,
 		BlockNumber:      int64(parsedTx.BlockNumber),
 		BlockHash:        parsedTx.BlockHash,
 		TransactionIndex: int64(parsedTx.TransactionIndex),
-		FromAddress:      parsedTx.From,
+		FromAddress:      from,
 		ToAddress:        parsedTx.To, // This is already *string
-		WhaleAddressID:   0,
+		WhaleAddressID:   nil,
 		TransferType:     "", // Default empty string
 		Value:            value,
 		Gas:              int64(parsedTx.Gas),
 		GasPrice:         gasPrice,
 		GasUsed:          gasUsed,
 		Status:           status,
+		ReceiptFetched:   parsedTx.ReceiptFetched,
 		Nonce:            int64(parsedTx.Nonce),
 		InputData:        &parsedTx.InputData,
 		TxType:           int(parsedTx.Type),
 		MaxFeePerGas:     maxFeePerGas,
 		MaxPriorityFee:   maxPriorityFee,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		ContractAddress:  parsedTx.ContractAddress,
+		Asset:            parsedTx.Asset,
+		TokenAddress:     parsedTx.TokenAddress,
 	}
 	// value 1.12345, from/to, whale_id, from/to_addr
 	for i, prm := range params {
@@ -133,28 +157,222 @@ func MapParsedTxToDatabaseTx(parsedTx *types.ParsedTransaction, params ...string
 			if err != nil {
 				return tx, fmt.Errorf("Error converting %s to int", prm)
 			}
-			tx.WhaleAddressID = int64(whaleAddressID)
+			whaleID := int64(whaleAddressID)
+			tx.WhaleAddressID = &whaleID
 		case 3:
 		}
 	}
 
 	// Set defaults for required fields
 	tx.SetDefaults()
-	fmt.Println("MAPPED", tx.Value, tx.TransferType, tx.WhaleAddressID)
+	fmt.Println("MAPPED", tx.Value, tx.TransferType)
 
 	return tx, nil
 }
 
+// Block holds header data of a parsed block, stored for every block (not only with whale activity)
+type Block struct {
+	Number        int64     `json:"number" db:"number"`
+	Hash          string    `json:"hash" db:"hash"`
+	ParentHash    string    `json:"parent_hash" db:"parent_hash"`
+	Timestamp     time.Time `json:"timestamp" db:"timestamp"`
+	GasUsed       int64     `json:"gas_used" db:"gas_used"`
+	GasLimit      int64     `json:"gas_limit" db:"gas_limit"`
+	GasUsedRatio  float64   `json:"gas_used_ratio" db:"gas_used_ratio"`     // gas_used/gas_limit, 0..1
+	BaseFeePerGas *string   `json:"base_fee_per_gas" db:"base_fee_per_gas"` // wei, nil before London
+	TxCount       int       `json:"transaction_count" db:"tx_count"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// MapParsedBlockToDatabaseBlock converts a types.ParsedBlock to database.Block
+func MapParsedBlockToDatabaseBlock(parsedBlock *types.ParsedBlock) *Block {
+	var baseFee *string
+	if parsedBlock.HasBaseFee() {
+		baseFeeStr := parsedBlock.BaseFeePerGas.String()
+		baseFee = &baseFeeStr
+	}
+
+	return &Block{
+		Number:        int64(parsedBlock.Number),
+		Hash:          parsedBlock.Hash,
+		ParentHash:    parsedBlock.ParentHash,
+		Timestamp:     parsedBlock.Timestamp,
+		GasUsed:       int64(parsedBlock.GasUsed),
+		GasLimit:      int64(parsedBlock.GasLimit),
+		GasUsedRatio:  GasUsedRatio(parsedBlock.GasUsed, parsedBlock.GasLimit),
+		BaseFeePerGas: baseFee,
+		TxCount:       parsedBlock.TxCount,
+	}
+}
+
+// ParseError is a dead-letter record of a transaction that failed to parse
+type ParseError struct {
+	ID               int64     `json:"id" db:"id"`
+	TxHash           string    `json:"tx_hash" db:"tx_hash"`
+	BlockNumber      int64     `json:"block_number" db:"block_number"`
+	TransactionIndex int64     `json:"transaction_index" db:"transaction_index"`
+	Error            string    `json:"error" db:"error"`
+	Attempts         int       `json:"attempts" db:"attempts"` // failed parses, grows with every reprocessing
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MapTxParseError converts a types.TxParseError to database.ParseError
+func MapTxParseError(pe *types.TxParseError) *ParseError {
+	return &ParseError{
+		TxHash:           pe.TxHash,
+		BlockNumber:      int64(pe.BlockNumber),
+		TransactionIndex: int64(pe.TransactionIndex),
+		Error:            pe.Error,
+		Attempts:         1,
+	}
+}
+
+// ToTxParseError converts a stored record back for Parser.ReparseErrors
+func (pe *ParseError) ToTxParseError() *types.TxParseError {
+	return &types.TxParseError{
+		BlockNumber:      uint64(pe.BlockNumber),
+		TxHash:           pe.TxHash,
+		TransactionIndex: uint64(pe.TransactionIndex),
+		Error:            pe.Error,
+	}
+}
+
+// GasUsedRatio returns gasUsed/gasLimit, 0 for a zero gas limit
+func GasUsedRatio(gasUsed, gasLimit uint64) float64 {
+	if gasLimit == 0 {
+		return 0
+	}
+	return float64(gasUsed) / float64(gasLimit)
+}
+
+// AddressSummary holds aggregated activity of an address over stored transactions
+type AddressSummary struct {
+	Address        string `json:"address" db:"-"`
+	TxCount        int64  `json:"tx_count" db:"tx_count"`
+	FirstSeenBlock *int64 `json:"first_seen_block" db:"first_seen_block"` // nil if no transactions
+	LastSeenBlock  *int64 `json:"last_seen_block" db:"last_seen_block"`   // nil if no transactions
+	TotalSentETH   string `json:"total_sent_eth" db:"-"`
+	TotalRecvETH   string `json:"total_received_eth" db:"-"`
+	Label          string `json:"label,omitempty" db:"-"` // whale label, empty for non-whale addresses
+}
+
 // Address represents an Ethereum address with metadata
 type WhaleAddress struct {
 	ID        int64     `json:"id" db:"id"`
 	Address   string    `json:"address" db:"address"`
 	Label     *string   `json:"label" db:"label"` // Optional human-readable label
 	IsWatched bool      `json:"is_watched" db:"is_watched"`
+	MinETH    *string   `json:"min_eth" db:"min_eth"` // Optional whale threshold in ETH, nil uses the global one
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Log is a stored event log of a transaction, known events have EventName and DecodedData
+type Log struct {
+	ID          int64       `json:"id" db:"id"`
+	TxHash      string      `json:"tx_hash" db:"tx_hash"`
+	LogIndex    int64       `json:"log_index" db:"log_index"`
+	BlockNumber int64       `json:"block_number" db:"block_number"`
+	Address     string      `json:"address" db:"address"` // emitting contract, lowercase
+	Topics      JSONStrings `json:"topics" db:"topics"`
+	Data        string      `json:"data" db:"data"`                 // hex without 0x
+	EventName   *string     `json:"event_name" db:"event_name"`     // nil for events not in DefaultEventRegistry
+	DecodedData JSONValue   `json:"decoded_data" db:"decoded_data"` // named event fields, null if not decoded
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+}
+
+// MapParsedLog converts a types.ParsedLog to database.Log
+func MapParsedLog(parsedLog *types.ParsedLog) (*Log, error) {
+	l := &Log{
+		TxHash:      parsedLog.TxHash,
+		LogIndex:    int64(parsedLog.LogIndex),
+		BlockNumber: int64(parsedLog.BlockNumber),
+		Address:     types.NormalizeAddress(parsedLog.Address),
+		Topics:      JSONStrings(parsedLog.Topics),
+		Data:        parsedLog.Data,
+	}
+	if parsedLog.DecodedEventName != "" {
+		name := parsedLog.DecodedEventName
+		l.EventName = &name
+	}
+	if parsedLog.DecodedData != nil {
+		decoded, err := json.Marshal(parsedLog.DecodedData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode decoded data of log %d of %s: %w", parsedLog.LogIndex, parsedLog.TxHash, err)
+		}
+		l.DecodedData = decoded
+	}
+	return l, nil
+}
+
+// JSONStrings is a string slice stored as a JSON array
+type JSONStrings []string
+
+func (js *JSONStrings) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*js = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("cannot scan %T into JSONStrings", value)
+	}
+	return json.Unmarshal(raw, (*[]string)(js))
+}
+
+func (js JSONStrings) Value() (driver.Value, error) {
+	if js == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal([]string(js))
+	return string(raw), err
+}
+
+// JSONValue is a JSON document stored as TEXT, empty is NULL and encodes as null
+type JSONValue json.RawMessage
+
+func (jv *JSONValue) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*jv = nil
+	case string:
+		*jv = JSONValue(v)
+	case []byte:
+		*jv = append(JSONValue(nil), v...)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONValue", value)
+	}
+	return nil
+}
+
+func (jv JSONValue) Value() (driver.Value, error) {
+	if len(jv) == 0 {
+		return nil, nil
+	}
+	return string(jv), nil
+}
+
+func (jv JSONValue) MarshalJSON() ([]byte, error) {
+	if len(jv) == 0 {
+		return []byte("null"), nil
+	}
+	return jv, nil
+}
+
+func (jv *JSONValue) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*jv = nil
+		return nil
+	}
+	*jv = append(JSONValue(nil), data...)
+	return nil
+}
+
 // Custom scanner for handling nullable string slices (topics)
 type NullableStringSlice []string
 
@@ -195,7 +413,11 @@ func (ns NullableStringSlice) Value() (driver.Value, error) {
 var TableNames = struct {
 	Transactions   string
 	WhaleAddresses string
+	Blocks         string
+	ParseErrors    string
 }{
 	Transactions:   "transactions",
 	WhaleAddresses: "whale_addresses",
+	Blocks:         "blocks",
+	ParseErrors:    "parse_errors",
 }
@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// pragmaConnector opens mattn/go-sqlite3 connections and runs the _pragma=name=value
+// parameters of the DSN on each of them. The driver ignores _pragma itself, and most
+// pragmas (synchronous, foreign_keys, ...) are per connection, so they have to be set
+// on every connection the pool opens.
+type pragmaConnector struct {
+	dsn     string
+	pragmas [][2]string // name, value in DSN order
+	driver  *sqlite3.SQLiteDriver
+}
+
+// newPragmaConnector parses the _pragma parameters of a DSN built by Config.ConnectionString
+func newPragmaConnector(dsn string) (*pragmaConnector, error) {
+	c := &pragmaConnector{dsn: dsn, driver: &sqlite3.SQLiteDriver{}}
+
+	_, query, found := strings.Cut(dsn, "?")
+	if !found {
+		return c, nil
+	}
+	for _, param := range strings.Split(query, "&") {
+		key, value, _ := strings.Cut(param, "=")
+		if key != "_pragma" {
+			continue
+		}
+		pragma, err := url.QueryUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pragma %q: %w", value, err)
+		}
+		name, pragmaValue, ok := strings.Cut(pragma, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid pragma %q: expected name=value", pragma)
+		}
+		c.pragmas = append(c.pragmas, [2]string{name, pragmaValue})
+	}
+	return c, nil
+}
+
+// Connect implements driver.Connector
+func (c *pragmaConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	execer := conn.(driver.ExecerContext)
+	for _, pragma := range c.pragmas {
+		// names are checked by ValidatePragmas, values are quoted as string literals
+		stmt := fmt.Sprintf("PRAGMA %s = '%s'", pragma[0], strings.ReplaceAll(pragma[1], "'", "''"))
+		if _, err := execer.ExecContext(ctx, stmt, nil); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to set pragma %s: %w", pragma[0], err)
+		}
+	}
+	return conn, nil
+}
+
+// Driver implements driver.Connector
+func (c *pragmaConnector) Driver() driver.Driver {
+	return c.driver
+}
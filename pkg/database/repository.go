@@ -3,13 +3,20 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"eth-blockchain-parser/internal/types"
+
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
 )
 
 // Repository provides database operations with auto-reconnection
@@ -29,6 +36,40 @@ func NewRepository(dm *DatabaseManager, logger *log.Logger) *Repository {
 	}
 }
 
+// namedExecBatch runs a multi-row named statement in chunks of rows whose bound parameters stay
+// under maxVars, all chunks within tx. A single row with more parameters than maxVars is sent alone.
+func namedExecBatch[T any](ctx context.Context, tx *sqlx.Tx, query string, rows []T, maxVars int) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	_, args, err := sqlx.Named(query, rows[0])
+	if err != nil {
+		return fmt.Errorf("failed to bind query: %w", err)
+	}
+	chunkSize := max(1, maxVars/max(1, len(args)))
+
+	for start := 0; start < len(rows); start += chunkSize {
+		end := min(start+chunkSize, len(rows))
+		if _, err := tx.NamedExecContext(ctx, query, rows[start:end]); err != nil {
+			return fmt.Errorf("rows %d-%d: %w", start, end-1, err)
+		}
+	}
+	return nil
+}
+
+// ErrDuplicate is returned by single-row inserts when a row with the same unique key (tx hash,
+// whale address) is already stored, callers can treat it as a no-op
+var ErrDuplicate = errors.New("duplicate record")
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE or PRIMARY KEY constraint failure
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
 // TransactionRepository handles transaction-related database operations
 type TransactionRepository struct {
 	*Repository
@@ -41,28 +82,36 @@ func NewTransactionRepository(dm *DatabaseManager, logger *log.Logger) *Transact
 	}
 }
 
-// Insert inserts a new transaction
+// Insert inserts a new transaction, ErrDuplicate if its hash is already stored
 func (tr *TransactionRepository) Insert(ctx context.Context, tx *Transaction) error {
 	db, err := tr.dm.DB()
 	if err != nil {
 		return fmt.Errorf("failed to get database connection: %w", err)
 	}
 
-	tx.CreatedAt = time.Now()
-	tx.UpdatedAt = time.Now()
+	now := tr.dm.now()
+	tx.CreatedAt = now
+	tx.UpdatedAt = now
+	tx.normalizeAddresses()
+	if tx.Asset == "" {
+		tx.Asset = types.NativeAsset
+	}
 
 	query := `
 		INSERT INTO transactions (
-			tx_hash, block_number, transaction_index, from_address, to_address,
-			value, gas, gas_price, gas_used, status, nonce, input_data, tx_type,
-			max_fee_per_gas, max_priority_fee, created_at, updated_at
+			tx_hash, block_number, block_hash, transaction_index, from_address, to_address,
+			value, gas, gas_price, gas_used, status, receipt_fetched, nonce, input_data, tx_type, transfer_type,
+			max_fee_per_gas, max_priority_fee, contract_address, asset, token_address, created_at, updated_at, whale_address_id
 		) VALUES (
-			:tx_hash, :block_number, :transaction_index, :from_address, :to_address,
-			:value, :gas, :gas_price, :gas_used, :status, :nonce, :input_data, :tx_type,
-			:max_fee_per_gas, :max_priority_fee, :created_at, :updated_at
+			:tx_hash, :block_number, :block_hash, :transaction_index, :from_address, :to_address,
+			:value, :gas, :gas_price, :gas_used, :status, :receipt_fetched, :nonce, :input_data, :tx_type, :transfer_type,
+			:max_fee_per_gas, :max_priority_fee, :contract_address, :asset, :token_address, :created_at, :updated_at, :whale_address_id
 		)`
 
 	result, err := db.NamedExecContext(ctx, query, tx)
+	if isUniqueViolation(err) {
+		return fmt.Errorf("%w: transaction %s", ErrDuplicate, tx.TxHash)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to insert transaction: %w", err)
 	}
@@ -98,6 +147,27 @@ func (tr *TransactionRepository) GetByHash(ctx context.Context, txHash string) (
 	return &tx, nil
 }
 
+// GetByContractAddress retrieves the transaction that created a contract, nil if it isn't stored
+func (tr *TransactionRepository) GetByContractAddress(ctx context.Context, contractAddress string) (*Transaction, error) {
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	var tx Transaction
+	query := "SELECT * FROM transactions WHERE contract_address = ? LIMIT 1"
+
+	err = db.GetContext(ctx, &tx, query, types.NormalizeAddress(contractAddress))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get creation transaction of contract %s: %w", contractAddress, err)
+	}
+
+	return &tx, nil
+}
+
 // GetByAddress retrieves transactions for a specific address (from or to)
 func (tr *TransactionRepository) GetByAddress(ctx context.Context, address string, limit int, offset int) ([]*Transaction, error) {
 	db, err := tr.dm.DB()
@@ -112,6 +182,7 @@ func (tr *TransactionRepository) GetByAddress(ctx context.Context, address strin
 		LIMIT ? OFFSET ?`
 
 	var transactions []*Transaction
+	address = types.NormalizeAddress(address)
 	err = db.SelectContext(ctx, &transactions, query, address, address, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get transactions for address %s: %w", address, err)
@@ -141,19 +212,418 @@ func (tr *TransactionRepository) GetByBlockNumber(ctx context.Context, blockNumb
 	return transactions, nil
 }
 
-// clear old txns
-func (tr *TransactionRepository) ClearOldTxns(ctx context.Context) error {
+// GetRecent retrieves the most recent transactions
+func (tr *TransactionRepository) GetRecent(ctx context.Context, limit int) ([]*Transaction, error) {
 	db, err := tr.dm.DB()
 	if err != nil {
-		return fmt.Errorf("failed to get database connection: %w", err)
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
 	}
-	// TODO: move 14 days to config
-	query := "DELETE FROM transactions where created_at <= datetime('now', '-14 days')"
-	_, err2 := db.Exec(query)
-	if err2 != nil {
-		return fmt.Errorf("failed to clear old txs: %w", err2)
+
+	query := `
+		SELECT * FROM transactions 
+		ORDER BY block_number DESC, transaction_index DESC 
+		LIMIT ?`
+
+	var transactions []*Transaction
+	err = db.SelectContext(ctx, &transactions, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent transactions: %w", err)
 	}
-	return nil
+
+	return transactions, nil
+}
+
+// getMissingReceiptsQuery is served by the partial idx_transactions_missing_receipts, which only holds
+// rows without receipt data, so the enrich pass doesn't scan enriched history
+const getMissingReceiptsQuery = `
+		SELECT * FROM transactions
+		WHERE receipt_fetched = FALSE
+		ORDER BY block_number DESC, transaction_index DESC
+		LIMIT ?`
+
+// GetMissingReceipts retrieves the most recent transactions stored without receipt data (gas_used and
+// status NULL, receipt_fetched false), the targets of the enrich pass
+func (tr *TransactionRepository) GetMissingReceipts(ctx context.Context, limit int) ([]*Transaction, error) {
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	transactions := []*Transaction{}
+	if err := db.SelectContext(ctx, &transactions, getMissingReceiptsQuery, limit); err != nil {
+		return nil, fmt.Errorf("failed to get transactions missing receipts: %w", err)
+	}
+
+	return transactions, nil
+}
+
+// UpdateReceiptData updates gas_used, status, receipt_fetched and contract_address (if set) of stored transactions,
+// matched by tx_hash
+func (tr *TransactionRepository) UpdateReceiptData(ctx context.Context, transactions []*Transaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	return tr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		query := `
+			UPDATE transactions 
+			SET gas_used = :gas_used, status = :status, receipt_fetched = :receipt_fetched,
+				contract_address = COALESCE(:contract_address, contract_address), updated_at = :updated_at 
+			WHERE tx_hash = :tx_hash`
+
+		now := tr.dm.now()
+		for _, transaction := range transactions {
+			transaction.UpdatedAt = now
+			transaction.normalizeAddresses()
+			if _, err := tx.NamedExecContext(ctx, query, transaction); err != nil {
+				return fmt.Errorf("failed to update receipt data for %s: %w", transaction.TxHash, err)
+			}
+		}
+
+		tr.logger.Printf("Updated receipt data for %d transactions", len(transactions))
+		return nil
+	})
+}
+
+// GetAddressSummary aggregates first/last seen block, sent/received ETH and tx count for an address.
+// Sends and receives are summed separately by from_address/to_address.
+func (tr *TransactionRepository) GetAddressSummary(ctx context.Context, address string) (*AddressSummary, error) {
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query := `
+		SELECT 
+			COUNT(*) AS tx_count,
+			MIN(block_number) AS first_seen_block,
+			MAX(block_number) AS last_seen_block,
+			TOTAL(CASE WHEN from_address = ? AND asset = ? THEN CAST(value AS REAL) END) AS total_sent,
+			TOTAL(CASE WHEN to_address = ? AND asset = ? THEN CAST(value AS REAL) END) AS total_received
+		FROM transactions 
+		WHERE from_address = ? OR to_address = ?`
+
+	var row struct {
+		AddressSummary
+		TotalSent     float64 `db:"total_sent"`
+		TotalReceived float64 `db:"total_received"`
+	}
+	address = types.NormalizeAddress(address)
+	// tx count and blocks cover token transfers too, the totals are ETH only
+	err = db.GetContext(ctx, &row, query, address, types.NativeAsset, address, types.NativeAsset, address, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get summary for address %s: %w", address, err)
+	}
+
+	summary := row.AddressSummary
+	summary.Address = address
+	// values are stored with 5 decimals, round away float noise from SUM
+	summary.TotalSentETH = decimal.NewFromFloat(row.TotalSent).Round(5).String()
+	summary.TotalRecvETH = decimal.NewFromFloat(row.TotalReceived).Round(5).String()
+
+	return &summary, nil
+}
+
+// MaxWhaleIDsPerQuery caps the number of whale IDs accepted by GetByWhaleIDs
+const MaxWhaleIDsPerQuery = 100
+
+// GetByWhaleIDs retrieves transactions belonging to any of the given whale addresses
+func (tr *TransactionRepository) GetByWhaleIDs(ctx context.Context, ids []int64, limit int, offset int) ([]*Transaction, error) {
+	if len(ids) == 0 {
+		return []*Transaction{}, nil
+	}
+	if len(ids) > MaxWhaleIDsPerQuery {
+		return nil, fmt.Errorf("too many whale IDs: %d (max %d)", len(ids), MaxWhaleIDsPerQuery)
+	}
+
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT * FROM transactions 
+		WHERE whale_address_id IN (?) 
+		ORDER BY block_number DESC, transaction_index DESC 
+		LIMIT ? OFFSET ?`, ids, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build whale IDs query: %w", err)
+	}
+
+	transactions := []*Transaction{}
+	err = db.SelectContext(ctx, &transactions, db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions for whale IDs %v: %w", ids, err)
+	}
+
+	return transactions, nil
+}
+
+// CountByWhaleIDs returns the number of transactions belonging to any of the given whale addresses
+func (tr *TransactionRepository) CountByWhaleIDs(ctx context.Context, ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if len(ids) > MaxWhaleIDsPerQuery {
+		return 0, fmt.Errorf("too many whale IDs: %d (max %d)", len(ids), MaxWhaleIDsPerQuery)
+	}
+
+	db, err := tr.dm.DB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query, args, err := sqlx.In("SELECT COUNT(*) FROM transactions WHERE whale_address_id IN (?)", ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build whale IDs query: %w", err)
+	}
+
+	var total int
+	if err := db.GetContext(ctx, &total, db.Rebind(query), args...); err != nil {
+		return 0, fmt.Errorf("failed to count transactions for whale IDs %v: %w", ids, err)
+	}
+
+	return total, nil
+}
+
+// TransferTypes are the transfer_type values of whale transactions: the whale is the sender,
+// the receiver, both sides are whales, or a whale sends to its own address
+var TransferTypes = []string{"FROM", "TO", "INT", "SELF"}
+
+// getByTransferTypeQuery is served by idx_transactions_type_block without a sort step
+const getByTransferTypeQuery = `
+		SELECT * FROM transactions 
+		WHERE transfer_type = ? 
+		ORDER BY block_number DESC, transaction_index DESC 
+		LIMIT ? OFFSET ?`
+
+// GetByTransferType retrieves transactions of one transfer type (FROM, TO, INT), newest first
+func (tr *TransactionRepository) GetByTransferType(ctx context.Context, transferType string, limit int, offset int) ([]*Transaction, error) {
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	transactions := []*Transaction{}
+	err = db.SelectContext(ctx, &transactions, getByTransferTypeQuery, transferType, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions for transfer type %s: %w", transferType, err)
+	}
+
+	return transactions, nil
+}
+
+// CountByTransferType returns the number of transactions of one transfer type
+func (tr *TransactionRepository) CountByTransferType(ctx context.Context, transferType string) (int, error) {
+	db, err := tr.dm.DB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	var total int
+	if err := db.GetContext(ctx, &total, "SELECT COUNT(*) FROM transactions WHERE transfer_type = ?", transferType); err != nil {
+		return 0, fmt.Errorf("failed to count transactions for transfer type %s: %w", transferType, err)
+	}
+
+	return total, nil
+}
+
+// clear txns stored more than days ago, days <= 0 keeps everything
+func (tr *TransactionRepository) ClearOldTxns(ctx context.Context, days int) error {
+	if days <= 0 {
+		return nil
+	}
+	age := fmt.Sprintf("-%d days", days)
+	// logs are stored with their transactions, so they age out together
+	return tr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM transactions where created_at <= datetime('now', ?)", age); err != nil {
+			return fmt.Errorf("failed to clear old txs: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM logs where created_at <= datetime('now', ?)", age); err != nil {
+			return fmt.Errorf("failed to clear old logs: %w", err)
+		}
+		return nil
+	})
+}
+
+// CountByTxType returns the number of stored transactions per transaction type
+func (tr *TransactionRepository) CountByTxType(ctx context.Context) (map[uint8]uint64, error) {
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	var rows []struct {
+		TxType int    `db:"tx_type"`
+		Count  uint64 `db:"count"`
+	}
+	if err := db.SelectContext(ctx, &rows, "SELECT tx_type, COUNT(*) AS count FROM transactions GROUP BY tx_type"); err != nil {
+		return nil, fmt.Errorf("failed to count transactions by type: %w", err)
+	}
+
+	counts := make(map[uint8]uint64, len(rows))
+	for _, row := range rows {
+		counts[uint8(row.TxType)] = row.Count
+	}
+	return counts, nil
+}
+
+// ErrInvalidBuckets is returned by ValueHistogram for empty or not ascending bucket boundaries
+var ErrInvalidBuckets = errors.New("invalid histogram buckets")
+
+// DefaultValueHistogramBuckets are the ETH bucket boundaries of ValueHistogram: <1, 1-10, 10-100, 100-1000, 1000+
+var DefaultValueHistogramBuckets = []float64{1, 10, 100, 1000}
+
+// ValueHistogramLabels returns the bucket labels for ascending boundaries, e.g. [1 10] gives "<1", "1-10", "10+"
+func ValueHistogramLabels(buckets []float64) []string {
+	if len(buckets) == 0 {
+		return nil
+	}
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+
+	labels := make([]string, 0, len(buckets)+1)
+	labels = append(labels, "<"+format(buckets[0]))
+	for i := 1; i < len(buckets); i++ {
+		labels = append(labels, format(buckets[i-1])+"-"+format(buckets[i]))
+	}
+	return append(labels, format(buckets[len(buckets)-1])+"+")
+}
+
+// ValueHistogram counts whale transactions by ETH value into buckets split by the ascending
+// boundaries, a bucket includes its lower bound. Every label of ValueHistogramLabels is present.
+func (tr *TransactionRepository) ValueHistogram(ctx context.Context, buckets []float64) (map[string]int, error) {
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("%w: at least one bucket boundary is required", ErrInvalidBuckets)
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return nil, fmt.Errorf("%w: boundaries must be ascending, got %v after %v", ErrInvalidBuckets, buckets[i], buckets[i-1])
+		}
+	}
+
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	// CASE WHEN v < b0 THEN 0 WHEN v < b1 THEN 1 ... ELSE n END
+	var caseSQL strings.Builder
+	args := make([]interface{}, 0, len(buckets))
+	caseSQL.WriteString("CASE")
+	for i, boundary := range buckets {
+		fmt.Fprintf(&caseSQL, " WHEN v < ? THEN %d", i)
+		args = append(args, boundary)
+	}
+	fmt.Fprintf(&caseSQL, " ELSE %d END", len(buckets))
+
+	query := fmt.Sprintf(`
+		SELECT %s AS bucket, COUNT(*) AS count
+		FROM (SELECT CAST(value AS REAL) AS v FROM transactions WHERE whale_address_id IS NOT NULL AND asset = ?)
+		GROUP BY bucket`, caseSQL.String())
+	// values of token transfers are in tokens, not ETH
+	args = append(args, types.NativeAsset)
+
+	var rows []struct {
+		Bucket int `db:"bucket"`
+		Count  int `db:"count"`
+	}
+	if err := db.SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to build value histogram: %w", err)
+	}
+
+	labels := ValueHistogramLabels(buckets)
+	histogram := make(map[string]int, len(labels))
+	for _, label := range labels {
+		histogram[label] = 0
+	}
+	for _, row := range rows {
+		histogram[labels[row.Bucket]] = row.Count
+	}
+	return histogram, nil
+}
+
+// GetTopByValue returns the n largest ETH transactions by value in blocks fromBlock..toBlock (inclusive),
+// ties newest first, token transfers are skipped. Values are compared as numbers, so 100 ranks above 99.5.
+func (tr *TransactionRepository) GetTopByValue(ctx context.Context, fromBlock, toBlock int64, n int) ([]*Transaction, error) {
+	db, err := tr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query := `
+		SELECT * FROM transactions 
+		WHERE block_number BETWEEN ? AND ? AND asset = ?
+		ORDER BY CAST(value AS REAL) DESC, block_number DESC, transaction_index DESC 
+		LIMIT ?`
+
+	transactions := []*Transaction{}
+	if err := db.SelectContext(ctx, &transactions, query, fromBlock, toBlock, types.NativeAsset, n); err != nil {
+		return nil, fmt.Errorf("failed to get top transactions in blocks %d-%d: %w", fromBlock, toBlock, err)
+	}
+	return transactions, nil
+}
+
+// DeleteByHash deletes a transaction by hash and returns the number of deleted rows
+func (tr *TransactionRepository) DeleteByHash(ctx context.Context, hash string) (int64, error) {
+	var deleted int64
+	err := tr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE tx_hash = ?", hash)
+		if err != nil {
+			return fmt.Errorf("failed to delete transaction %s: %w", hash, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM logs WHERE tx_hash = ?", hash); err != nil {
+			return fmt.Errorf("failed to delete logs of transaction %s: %w", hash, err)
+		}
+		if deleted, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get deleted rows count: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// DeleteByBlockRange deletes transactions in blocks from..to (inclusive) and returns the number of deleted rows.
+// Used to drop transactions of reorged blocks.
+func (tr *TransactionRepository) DeleteByBlockRange(ctx context.Context, from, to int64) (int64, error) {
+	if from > to {
+		return 0, fmt.Errorf("invalid block range: from %d > to %d", from, to)
+	}
+
+	var deleted int64
+	err := tr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		var err error
+		deleted, err = deleteBlockRangeTxs(ctx, tx, from, to)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	tr.logger.Printf("Deleted %d transactions in blocks %d-%d", deleted, from, to)
+	return deleted, nil
+}
+
+// deleteBlockRangeTxs deletes the transactions and logs of blocks from..to in tx, returns the number of deleted transactions
+func deleteBlockRangeTxs(ctx context.Context, tx *sqlx.Tx, from, to int64) (int64, error) {
+	result, err := tx.ExecContext(ctx, "DELETE FROM transactions WHERE block_number BETWEEN ? AND ?", from, to)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete transactions in blocks %d-%d: %w", from, to, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM logs WHERE block_number BETWEEN ? AND ?", from, to); err != nil {
+		return 0, fmt.Errorf("failed to delete logs in blocks %d-%d: %w", from, to, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get deleted rows count: %w", err)
+	}
+	return deleted, nil
+}
+
+// Store saves whale transactions with BatchInsert, the SQLite implementation of sink.TransactionSink
+func (tr *TransactionRepository) Store(ctx context.Context, transactions []*Transaction) error {
+	return tr.BatchInsert(ctx, transactions)
 }
 
 // BatchInsert inserts multiple transactions in a transaction
@@ -166,23 +636,27 @@ func (tr *TransactionRepository) BatchInsert(ctx context.Context, transactions [
 		query := `
 			INSERT OR REPLACE INTO transactions (
 				tx_hash, block_number, block_hash, transaction_index, from_address, to_address,
-				value, gas, gas_price, gas_used, status, nonce, input_data, tx_type, transfer_type,
-				max_fee_per_gas, max_priority_fee, created_at, updated_at, whale_address_id
+				value, gas, gas_price, gas_used, status, receipt_fetched, nonce, input_data, tx_type, transfer_type,
+				max_fee_per_gas, max_priority_fee, contract_address, asset, token_address, created_at, updated_at, whale_address_id
 			) VALUES (
 				:tx_hash, :block_number, :block_hash, :transaction_index, :from_address, :to_address,
-				:value, :gas, :gas_price, :gas_used, :status, :nonce, :input_data, :tx_type, :transfer_type,
-				:max_fee_per_gas, :max_priority_fee, :created_at, :updated_at, :whale_address_id
+				:value, :gas, :gas_price, :gas_used, :status, :receipt_fetched, :nonce, :input_data, :tx_type, :transfer_type,
+				:max_fee_per_gas, :max_priority_fee, :contract_address, :asset, :token_address, :created_at, :updated_at, :whale_address_id
 			)`
 
-		now := time.Now()
+		now := tr.dm.now()
 		for _, transaction := range transactions {
 			if transaction.CreatedAt.IsZero() {
 				transaction.CreatedAt = now
 			}
 			transaction.UpdatedAt = now
+			transaction.normalizeAddresses()
+			if transaction.Asset == "" {
+				transaction.Asset = types.NativeAsset
+			}
 		}
 
-		_, err := tx.NamedExecContext(ctx, query, transactions)
+		err := namedExecBatch(ctx, tx, query, transactions, tr.dm.maxSQLVariables())
 		if err != nil {
 			return fmt.Errorf("failed to batch insert transactions: %w", err)
 		}
@@ -192,9 +666,240 @@ func (tr *TransactionRepository) BatchInsert(ctx context.Context, transactions [
 	})
 }
 
+// BlockRepository handles block-related database operations
+type BlockRepository struct {
+	*Repository
+}
+
+// NewBlockRepository creates a new block repository
+func NewBlockRepository(dm *DatabaseManager, logger *log.Logger) *BlockRepository {
+	return &BlockRepository{
+		Repository: NewRepository(dm, logger),
+	}
+}
+
+// BatchUpsert inserts blocks in a transaction, a re-parsed block (e.g. after a reorg) replaces the stored one
+func (br *BlockRepository) BatchUpsert(ctx context.Context, blocks []*Block) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+
+	return br.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		query := `
+			INSERT OR REPLACE INTO blocks (
+				number, hash, parent_hash, timestamp, gas_used, gas_limit,
+				gas_used_ratio, base_fee_per_gas, tx_count, created_at
+			) VALUES (
+				:number, :hash, :parent_hash, :timestamp, :gas_used, :gas_limit,
+				:gas_used_ratio, :base_fee_per_gas, :tx_count, :created_at
+			)`
+
+		now := br.dm.now()
+		for _, block := range blocks {
+			if block.CreatedAt.IsZero() {
+				block.CreatedAt = now
+			}
+		}
+
+		if err := namedExecBatch(ctx, tx, query, blocks, br.dm.maxSQLVariables()); err != nil {
+			return fmt.Errorf("failed to batch insert blocks: %w", err)
+		}
+
+		br.logger.Printf("Batch inserted %d blocks", len(blocks))
+		return nil
+	})
+}
+
+// GetRecent retrieves stored blocks, newest first
+func (br *BlockRepository) GetRecent(ctx context.Context, limit int, offset int) ([]*Block, error) {
+	db, err := br.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	var blocks []*Block
+	err = db.SelectContext(ctx, &blocks, "SELECT * FROM blocks ORDER BY number DESC LIMIT ? OFFSET ?", limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent blocks: %w", err)
+	}
+
+	return blocks, nil
+}
+
+// Count returns the number of stored blocks
+func (br *BlockRepository) Count(ctx context.Context) (int, error) {
+	db, err := br.dm.DB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	var count int
+	if err := db.GetContext(ctx, &count, "SELECT COUNT(*) FROM blocks"); err != nil {
+		return 0, fmt.Errorf("failed to count blocks: %w", err)
+	}
+	return count, nil
+}
+
+// Delete deletes a stored block with its transactions and logs in one DB transaction.
+// Returns the number of deleted transactions and whether the block itself was stored.
+func (br *BlockRepository) Delete(ctx context.Context, number int64) (int64, bool, error) {
+	var deletedTxs, deletedBlocks int64
+	err := br.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		var err error
+		if deletedTxs, err = deleteBlockRangeTxs(ctx, tx, number, number); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, "DELETE FROM blocks WHERE number = ?", number)
+		if err != nil {
+			return fmt.Errorf("failed to delete block %d: %w", number, err)
+		}
+		if deletedBlocks, err = result.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to get deleted rows count: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	br.logger.Printf("Deleted block %d with %d transactions", number, deletedTxs)
+	return deletedTxs, deletedBlocks > 0, nil
+}
+
+// LogRepository handles event log operations
+type LogRepository struct {
+	*Repository
+}
+
+// NewLogRepository creates a new event log repository
+func NewLogRepository(dm *DatabaseManager, logger *log.Logger) *LogRepository {
+	return &LogRepository{
+		Repository: NewRepository(dm, logger),
+	}
+}
+
+// BatchInsert stores event logs, a log already stored (same tx hash and log index) is kept
+func (lr *LogRepository) BatchInsert(ctx context.Context, logs []*Log) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	return lr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		query := `
+			INSERT INTO logs (
+				tx_hash, log_index, block_number, address, topics, data, event_name, decoded_data
+			) VALUES (
+				:tx_hash, :log_index, :block_number, :address, :topics, :data, :event_name, :decoded_data
+			)
+			ON CONFLICT(tx_hash, log_index) DO NOTHING`
+
+		if err := namedExecBatch(ctx, tx, query, logs, lr.dm.maxSQLVariables()); err != nil {
+			return fmt.Errorf("failed to batch insert logs: %w", err)
+		}
+
+		lr.logger.Printf("Batch inserted %d logs", len(logs))
+		return nil
+	})
+}
+
+// GetByTxHash retrieves the logs of a transaction in log index order, empty if none are stored
+func (lr *LogRepository) GetByTxHash(ctx context.Context, txHash string) ([]*Log, error) {
+	db, err := lr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	logs := []*Log{}
+	query := "SELECT * FROM logs WHERE tx_hash = ? ORDER BY log_index"
+	if err := db.SelectContext(ctx, &logs, query, strings.ToLower(txHash)); err != nil {
+		return nil, fmt.Errorf("failed to get logs of transaction %s: %w", txHash, err)
+	}
+	return logs, nil
+}
+
+// ParseErrorRepository handles the dead-letter store of transactions that failed to parse
+type ParseErrorRepository struct {
+	*Repository
+}
+
+// NewParseErrorRepository creates a new parse error repository
+func NewParseErrorRepository(dm *DatabaseManager, logger *log.Logger) *ParseErrorRepository {
+	return &ParseErrorRepository{
+		Repository: NewRepository(dm, logger),
+	}
+}
+
+// BatchUpsert stores failed transactions, a transaction failing again gets the new error and one more attempt
+func (pr *ParseErrorRepository) BatchUpsert(ctx context.Context, parseErrs []*ParseError) error {
+	if len(parseErrs) == 0 {
+		return nil
+	}
+
+	return pr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		query := `
+			INSERT INTO parse_errors (
+				tx_hash, block_number, transaction_index, error, attempts
+			) VALUES (
+				:tx_hash, :block_number, :transaction_index, :error, :attempts
+			)
+			ON CONFLICT(tx_hash) DO UPDATE SET
+				error = excluded.error,
+				attempts = parse_errors.attempts + 1,
+				updated_at = CURRENT_TIMESTAMP`
+
+		if err := namedExecBatch(ctx, tx, query, parseErrs, pr.dm.maxSQLVariables()); err != nil {
+			return fmt.Errorf("failed to batch upsert parse errors: %w", err)
+		}
+
+		pr.logger.Printf("Batch upserted %d parse errors", len(parseErrs))
+		return nil
+	})
+}
+
+// GetPending retrieves stored parse errors, oldest blocks first
+func (pr *ParseErrorRepository) GetPending(ctx context.Context, limit int) ([]*ParseError, error) {
+	db, err := pr.dm.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query := "SELECT * FROM parse_errors ORDER BY block_number, transaction_index LIMIT ?"
+
+	var parseErrs []*ParseError
+	if err := db.SelectContext(ctx, &parseErrs, query, limit); err != nil {
+		return nil, fmt.Errorf("failed to get parse errors: %w", err)
+	}
+	return parseErrs, nil
+}
+
+// DeleteByHashes removes reprocessed transactions from the store, returns the number of deleted rows
+func (pr *ParseErrorRepository) DeleteByHashes(ctx context.Context, hashes []string) (int64, error) {
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+	db, err := pr.dm.DB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query, args, err := sqlx.In("DELETE FROM parse_errors WHERE tx_hash IN (?)", hashes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build delete query: %w", err)
+	}
+	result, err := db.ExecContext(ctx, db.Rebind(query), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete parse errors: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // AddressRepository handles address-related database operations
 type AddressRepository struct {
 	*Repository
+	labelCaches []*LabelCache // invalidated after writes, see NewLabelCache
+
+	// StrictChecksum makes Search reject mixed-case addresses with a wrong EIP-55 checksum
+	// instead of matching them case-insensitively
+	StrictChecksum bool
 }
 
 // NewAddressRepository creates a new address repository
@@ -204,6 +909,8 @@ func NewAddressRepository(dm *DatabaseManager, logger *log.Logger) *AddressRepos
 	}
 }
 
+// DeleteAll removes all whale addresses. Transactions reference them with ON DELETE CASCADE,
+// so this also deletes the whole transaction history.
 func (ar *AddressRepository) DeleteAll(ctx context.Context) error {
 	db, err := ar.dm.DB()
 	if err != nil {
@@ -214,31 +921,80 @@ func (ar *AddressRepository) DeleteAll(ctx context.Context) error {
 	if err2 != nil {
 		return fmt.Errorf("failed to insert address: %w", err2)
 	}
+	ar.invalidateLabels()
 	return nil
 }
 
+// invalidateLabels drops the maps of the label caches over this repository after a write
+func (ar *AddressRepository) invalidateLabels() {
+	for _, c := range ar.labelCaches {
+		c.Invalidate()
+	}
+}
+
+// Insert adds a single whale address, sets its timestamps and returns the new ID (also set on addr).
+// The address is stored lowercase, an already existing address is an error.
+func (ar *AddressRepository) Insert(ctx context.Context, addr *WhaleAddress) (int64, error) {
+	db, err := ar.dm.DB()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	now := ar.dm.now()
+	if addr.CreatedAt.IsZero() {
+		addr.CreatedAt = now
+	}
+	addr.UpdatedAt = now
+	addr.Address = types.NormalizeAddress(addr.Address)
+
+	query := `
+		INSERT INTO whale_addresses (
+			address, label, min_eth, created_at, updated_at
+		) VALUES (
+			:address, :label, :min_eth, :created_at, :updated_at
+		)`
+
+	result, err := db.NamedExecContext(ctx, query, addr)
+	if isUniqueViolation(err) {
+		return 0, fmt.Errorf("%w: address %s", ErrDuplicate, addr.Address)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert address %s: %w", addr.Address, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	addr.ID = id
+	ar.invalidateLabels()
+
+	ar.logger.Printf("Inserted address %s", addr.Address)
+	return id, nil
+}
+
 func (ar *AddressRepository) BatchInsert(ctx context.Context, addrs []*WhaleAddress) error {
 	if len(addrs) == 0 {
 		return nil
 	}
 
-	return ar.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+	err := ar.dm.RunInTransaction(func(tx *sqlx.Tx) error {
 		query := `
 			INSERT OR REPLACE INTO whale_addresses (
-				address, label
+				address, label, min_eth
 			) VALUES (
-				:address, :label
+				:address, :label, :min_eth
 			)`
 
-		now := time.Now()
+		now := ar.dm.now()
 		for _, transaction := range addrs {
 			if transaction.CreatedAt.IsZero() {
 				transaction.CreatedAt = now
 			}
 			transaction.UpdatedAt = now
+			transaction.Address = types.NormalizeAddress(transaction.Address)
 		}
 
-		_, err := tx.NamedExecContext(ctx, query, addrs)
+		err := namedExecBatch(ctx, tx, query, addrs, ar.dm.maxSQLVariables())
 		if err != nil {
 			return fmt.Errorf("failed to batch insert addresses: %w", err)
 		}
@@ -246,6 +1002,46 @@ func (ar *AddressRepository) BatchInsert(ctx context.Context, addrs []*WhaleAddr
 		ar.logger.Printf("Batch inserted %d addresses", len(addrs))
 		return nil
 	})
+	if err == nil {
+		ar.invalidateLabels()
+	}
+	return err
+}
+
+// BatchUpsert inserts new addresses and updates labels of existing ones in place.
+// Unlike INSERT OR REPLACE the row IDs are kept, so transactions referencing them survive.
+// Thresholds of existing whales are kept too, they are changed with SetMinETH only.
+func (ar *AddressRepository) BatchUpsert(ctx context.Context, addrs []*WhaleAddress) error {
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	err := ar.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		query := `
+			INSERT INTO whale_addresses (
+				address, label, min_eth
+			) VALUES (
+				:address, :label, :min_eth
+			)
+			ON CONFLICT(address) DO UPDATE SET
+				label = excluded.label,
+				updated_at = CURRENT_TIMESTAMP`
+
+		for _, addr := range addrs {
+			addr.Address = types.NormalizeAddress(addr.Address)
+		}
+
+		if err := namedExecBatch(ctx, tx, query, addrs, ar.dm.maxSQLVariables()); err != nil {
+			return fmt.Errorf("failed to batch upsert addresses: %w", err)
+		}
+
+		ar.logger.Printf("Batch upserted %d addresses", len(addrs))
+		return nil
+	})
+	if err == nil {
+		ar.invalidateLabels()
+	}
+	return err
 }
 
 // GetWatched retrieves all watched whale_addresses
@@ -258,7 +1054,7 @@ func (ar *AddressRepository) GetIdByAddress(ctx context.Context, addr string) ([
 	query := "SELECT * FROM whale_addresses WHERE address = ?"
 
 	var addresses []*WhaleAddress
-	err = db.SelectContext(ctx, &addresses, query, strings.ToLower(addr))
+	err = db.SelectContext(ctx, &addresses, query, types.NormalizeAddress(addr))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get watched addresses: %w", err)
 	}
@@ -290,14 +1086,44 @@ func (ar *AddressRepository) GetAddrMappings(ctx context.Context) ([]*map[string
 	}
 	addr_to_id := map[string]string{}
 	addr_to_label := map[string]string{}
+	addr_to_min_eth := map[string]string{}
 	for _, addr := range addrs {
-		addr_to_id[strings.ToLower(addr.Address)] = strconv.Itoa(int(addr.ID))
-		addr_to_label[strings.ToLower(addr.Address)] = *addr.Label
+		key := types.NormalizeAddress(addr.Address)
+		addr_to_id[key] = strconv.Itoa(int(addr.ID))
+		if addr.Label != nil {
+			addr_to_label[key] = *addr.Label
+		}
+		if addr.MinETH != nil {
+			addr_to_min_eth[key] = *addr.MinETH
+		}
 	}
-	resp := []*map[string]string{&addr_to_id, &addr_to_label}
+	resp := []*map[string]string{&addr_to_id, &addr_to_label, &addr_to_min_eth}
 	return resp, nil
 }
 
+// SetMinETH sets the whale threshold of an address, nil resets it to the global one.
+// Returns false if the address is not stored.
+func (ar *AddressRepository) SetMinETH(ctx context.Context, address string, minETH *string) (bool, error) {
+	db, err := ar.dm.DB()
+	if err != nil {
+		return false, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query := "UPDATE whale_addresses SET min_eth = ?, updated_at = CURRENT_TIMESTAMP WHERE address = ?"
+	result, err := db.ExecContext(ctx, query, minETH, types.NormalizeAddress(address))
+	if err != nil {
+		return false, fmt.Errorf("failed to set min ETH of %s: %w", address, err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if updated > 0 {
+		ar.invalidateLabels()
+	}
+	return updated > 0, nil
+}
+
 // GetWatched retrieves all watched whale_addresses
 func (ar *AddressRepository) GetWatched(ctx context.Context) ([]*WhaleAddress, error) {
 	db, err := ar.dm.DB()
@@ -316,13 +1142,37 @@ func (ar *AddressRepository) GetWatched(ctx context.Context) ([]*WhaleAddress, e
 	return addresses, nil
 }
 
-// Search searches for addresses by label or address
+// searchExactAddressQuery is served by the whale_addresses address index, unlike the LIKE scan
+const searchExactAddressQuery = `
+		SELECT * FROM whale_addresses 
+		WHERE address = ? 
+		LIMIT ?`
+
+// ErrInvalidChecksum is returned by Search in StrictChecksum mode for a mixed-case address
+// that doesn't match its EIP-55 checksum, usually a typo
+var ErrInvalidChecksum = errors.New("invalid EIP-55 address checksum")
+
+// Search finds whale addresses by a search term. A full 0x address (any case) is looked up by
+// equality on the indexed column, anything else is matched as a substring of addresses and labels.
 func (ar *AddressRepository) Search(ctx context.Context, searchTerm string, limit int) ([]*WhaleAddress, error) {
 	db, err := ar.dm.DB()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database connection: %w", err)
 	}
 
+	var addresses []*WhaleAddress
+	term := strings.TrimSpace(searchTerm)
+	if isFullAddress(term) {
+		if ar.StrictChecksum && !validChecksum(term) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidChecksum, term)
+		}
+		err = db.SelectContext(ctx, &addresses, searchExactAddressQuery, types.NormalizeAddress(term), limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search address %s: %w", term, err)
+		}
+		return addresses, nil
+	}
+
 	searchPattern := "%" + strings.ToLower(searchTerm) + "%"
 	query := `
 		SELECT * FROM whale_addresses 
@@ -331,7 +1181,6 @@ func (ar *AddressRepository) Search(ctx context.Context, searchTerm string, limi
 		ORDER BY created_at DESC 
 		LIMIT ?`
 
-	var addresses []*WhaleAddress
 	err = db.SelectContext(ctx, &addresses, query, searchPattern, searchPattern, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search addresses: %w", err)
@@ -339,3 +1188,237 @@ func (ar *AddressRepository) Search(ctx context.Context, searchTerm string, limi
 
 	return addresses, nil
 }
+
+// isFullAddress reports whether s is 0x followed by exactly 40 hex characters
+func isFullAddress(s string) bool {
+	return len(s) == 42 && strings.HasPrefix(s, "0x") && common.IsHexAddress(s)
+}
+
+// validChecksum reports whether a full address is all lowercase, all uppercase (no checksum)
+// or mixed case matching its EIP-55 checksum
+func validChecksum(addr string) bool {
+	hexPart := addr[2:]
+	if hexPart == strings.ToLower(hexPart) || hexPart == strings.ToUpper(hexPart) {
+		return true
+	}
+	return common.HexToAddress(addr).Hex() == addr
+}
+
+// Setting is a runtime-tunable value stored in the settings table
+type Setting struct {
+	Key       string    `json:"key" db:"key"`
+	Value     string    `json:"value" db:"value"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SettingsRepository reads and writes the settings table. All settings are cached in memory,
+// writes through the repository invalidate the cache, settings written by other processes
+// show up after at most TTL.
+type SettingsRepository struct {
+	*Repository
+	ttl time.Duration
+
+	mu       sync.RWMutex
+	values   map[string]string
+	loadedAt time.Time
+	// generation is bumped by writes, a load started before a write doesn't fill the cache
+	generation uint64
+}
+
+// NewSettingsRepository creates a settings repository, ttl <= 0 keeps the cache until the next write
+func NewSettingsRepository(dm *DatabaseManager, logger *log.Logger, ttl time.Duration) *SettingsRepository {
+	return &SettingsRepository{
+		Repository: NewRepository(dm, logger),
+		ttl:        ttl,
+	}
+}
+
+// All returns a copy of all stored settings, loading them if the cache is empty or expired
+func (sr *SettingsRepository) All(ctx context.Context) (map[string]string, error) {
+	sr.mu.RLock()
+	values, loadedAt, generation := sr.values, sr.loadedAt, sr.generation
+	sr.mu.RUnlock()
+
+	if values == nil || (sr.ttl > 0 && sr.dm.now().Sub(loadedAt) > sr.ttl) {
+		db, err := sr.dm.DB()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get database connection: %w", err)
+		}
+		var settings []*Setting
+		if err := db.SelectContext(ctx, &settings, "SELECT key, value, updated_at FROM settings"); err != nil {
+			return nil, fmt.Errorf("failed to load settings: %w", err)
+		}
+		values = make(map[string]string, len(settings))
+		for _, setting := range settings {
+			values[setting.Key] = setting.Value
+		}
+		sr.mu.Lock()
+		if generation == sr.generation {
+			sr.values, sr.loadedAt = values, sr.dm.now()
+		}
+		sr.mu.Unlock()
+	}
+
+	result := make(map[string]string, len(values))
+	for key, value := range values {
+		result[key] = value
+	}
+	return result, nil
+}
+
+// GetString returns a setting, false if it is not stored
+func (sr *SettingsRepository) GetString(ctx context.Context, key string) (string, bool, error) {
+	values, err := sr.All(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := values[key]
+	return value, ok, nil
+}
+
+// GetInt returns an integer setting, false if it is not stored
+func (sr *SettingsRepository) GetInt(ctx context.Context, key string) (int64, bool, error) {
+	value, ok, err := sr.GetString(ctx, key)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("setting %s is not an integer: %w", key, err)
+	}
+	return n, true, nil
+}
+
+// GetDecimal returns a decimal setting, false if it is not stored
+func (sr *SettingsRepository) GetDecimal(ctx context.Context, key string) (decimal.Decimal, bool, error) {
+	value, ok, err := sr.GetString(ctx, key)
+	if err != nil || !ok {
+		return decimal.Zero, false, err
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, false, fmt.Errorf("setting %s is not a number: %w", key, err)
+	}
+	return d, true, nil
+}
+
+// SetString stores a setting
+func (sr *SettingsRepository) SetString(ctx context.Context, key, value string) error {
+	return sr.SetAll(ctx, map[string]string{key: value})
+}
+
+// SetInt stores an integer setting
+func (sr *SettingsRepository) SetInt(ctx context.Context, key string, value int64) error {
+	return sr.SetString(ctx, key, strconv.FormatInt(value, 10))
+}
+
+// SetDecimal stores a decimal setting
+func (sr *SettingsRepository) SetDecimal(ctx context.Context, key string, value decimal.Decimal) error {
+	return sr.SetString(ctx, key, value.String())
+}
+
+// SetAll stores several settings in one transaction, an empty value deletes the setting
+// so readers fall back to their default
+func (sr *SettingsRepository) SetAll(ctx context.Context, values map[string]string) error {
+	err := sr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		for key, value := range values {
+			if value == "" {
+				if _, err := tx.ExecContext(ctx, "DELETE FROM settings WHERE key = ?", key); err != nil {
+					return fmt.Errorf("failed to delete setting %s: %w", key, err)
+				}
+				continue
+			}
+			query := `
+				INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
+				ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+			if _, err := tx.ExecContext(ctx, query, key, value, sr.dm.now()); err != nil {
+				return fmt.Errorf("failed to store setting %s: %w", key, err)
+			}
+		}
+		return nil
+	})
+
+	sr.mu.Lock()
+	sr.values = nil
+	sr.generation++
+	sr.mu.Unlock()
+	return err
+}
+
+// requestCountKeyPrefix prefixes the meta key of a day's RPC request count, e.g. "rpc_requests:2024-01-02"
+const requestCountKeyPrefix = "rpc_requests:"
+
+// MetaRepository reads and writes the meta table of internal state, uncached
+type MetaRepository struct {
+	*Repository
+}
+
+// NewMetaRepository creates a meta repository
+func NewMetaRepository(dm *DatabaseManager, logger *log.Logger) *MetaRepository {
+	return &MetaRepository{Repository: NewRepository(dm, logger)}
+}
+
+// Get returns a meta value, false if it is not stored
+func (mr *MetaRepository) Get(ctx context.Context, key string) (string, bool, error) {
+	db, err := mr.dm.DB()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	var value string
+	err = db.GetContext(ctx, &value, "SELECT value FROM meta WHERE key = ?", key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get meta %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Set stores a meta value
+func (mr *MetaRepository) Set(ctx context.Context, key, value string) error {
+	db, err := mr.dm.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	query := `
+		INSERT INTO meta (key, value, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+	if _, err := db.ExecContext(ctx, query, key, value, mr.dm.now()); err != nil {
+		return fmt.Errorf("failed to store meta %s: %w", key, err)
+	}
+	return nil
+}
+
+// LoadRequestCount returns the RPC request count of a UTC day, 0 if none is stored.
+// Implements client.RequestCounterStore.
+func (mr *MetaRepository) LoadRequestCount(ctx context.Context, day string) (uint64, error) {
+	value, ok, err := mr.Get(ctx, requestCountKeyPrefix+day)
+	if err != nil || !ok {
+		return 0, err
+	}
+	count, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("request count of %s is not an integer: %w", day, err)
+	}
+	return count, nil
+}
+
+// SaveRequestCount stores the RPC request count of a UTC day and drops the counts of other days
+func (mr *MetaRepository) SaveRequestCount(ctx context.Context, day string, count uint64) error {
+	key := requestCountKeyPrefix + day
+	return mr.dm.RunInTransaction(func(tx *sqlx.Tx) error {
+		query := `
+			INSERT INTO meta (key, value, updated_at) VALUES (?, ?, ?)
+			ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`
+		if _, err := tx.ExecContext(ctx, query, key, strconv.FormatUint(count, 10), mr.dm.now()); err != nil {
+			return fmt.Errorf("failed to store request count of %s: %w", day, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM meta WHERE key LIKE ? AND key != ?", requestCountKeyPrefix+"%", key); err != nil {
+			return fmt.Errorf("failed to delete old request counts: %w", err)
+		}
+		return nil
+	})
+}
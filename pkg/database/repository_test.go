@@ -0,0 +1,1474 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+
+	"github.com/shopspring/decimal"
+)
+
+// seedTransactions inserts three whale addresses and one transaction per whale per block
+func seedTransactions(t *testing.T, dm *DatabaseManager, blocks int) {
+	t.Helper()
+	ctx := context.Background()
+
+	addrs := make([]*WhaleAddress, 0, 3)
+	for i := 1; i <= 3; i++ {
+		label := fmt.Sprintf("Whale %d", i)
+		addrs = append(addrs, &WhaleAddress{Address: fmt.Sprintf("0x%040d", i), Label: &label})
+	}
+	if err := NewAddressRepository(dm, nil).BatchInsert(ctx, addrs); err != nil {
+		t.Fatalf("Failed to insert whale addresses: %v", err)
+	}
+
+	var txs []*Transaction
+	for block := 1; block <= blocks; block++ {
+		for whale := 1; whale <= 3; whale++ {
+			txs = append(txs, &Transaction{
+				TxHash:           fmt.Sprintf("0x%d_%d", block, whale),
+				BlockNumber:      int64(block),
+				TransactionIndex: int64(whale),
+				FromAddress:      fmt.Sprintf("0x%040d", whale),
+				WhaleAddressID:   int64Ptr(int64(whale)),
+				TransferType:     "FROM",
+				Value:            "1",
+			})
+		}
+	}
+	if err := NewTransactionRepository(dm, nil).BatchInsert(ctx, txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+}
+
+// TestGetByWhaleIDs tests fetching transactions for one or more whale IDs
+func TestGetByWhaleIDs(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 4)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		ids           []int64
+		expectedCount int
+	}{
+		{name: "Single ID", ids: []int64{2}, expectedCount: 4},
+		{name: "Multiple IDs", ids: []int64{1, 3}, expectedCount: 8},
+		{name: "Unknown ID", ids: []int64{42}, expectedCount: 0},
+		{name: "Empty IDs", ids: []int64{}, expectedCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs, err := txRepo.GetByWhaleIDs(ctx, tt.ids, 100, 0)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if txs == nil {
+				t.Fatal("Expected empty slice, got nil")
+			}
+			if len(txs) != tt.expectedCount {
+				t.Fatalf("Expected %d transactions, got %d", tt.expectedCount, len(txs))
+			}
+
+			allowed := make(map[int64]bool)
+			for _, id := range tt.ids {
+				allowed[id] = true
+			}
+			for i, tx := range txs {
+				if tx.WhaleAddressID == nil || !allowed[*tx.WhaleAddressID] {
+					t.Errorf("Unexpected whale ID %v in results", tx.WhaleAddressID)
+				}
+				if i > 0 && txs[i-1].BlockNumber < tx.BlockNumber {
+					t.Errorf("Results not ordered by block desc: %d before %d", txs[i-1].BlockNumber, tx.BlockNumber)
+				}
+			}
+
+			total, err := txRepo.CountByWhaleIDs(ctx, tt.ids)
+			if err != nil {
+				t.Fatalf("Unexpected count error: %v", err)
+			}
+			if total != tt.expectedCount {
+				t.Errorf("Expected count %d, got %d", tt.expectedCount, total)
+			}
+		})
+	}
+}
+
+// TestGetByWhaleIDsPagination tests limit/offset with whale IDs
+func TestGetByWhaleIDsPagination(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 4)
+	txRepo := NewTransactionRepository(dm, nil)
+
+	txs, err := txRepo.GetByWhaleIDs(context.Background(), []int64{1, 2}, 3, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(txs) != 3 {
+		t.Fatalf("Expected 3 transactions, got %d", len(txs))
+	}
+	if txs[0].BlockNumber != 3 {
+		t.Errorf("Expected page to start at block 3, got %d", txs[0].BlockNumber)
+	}
+}
+
+// TestGetByWhaleIDsTooMany tests the cap on the number of IDs
+func TestGetByWhaleIDsTooMany(t *testing.T) {
+	dm := newTestDatabase(t)
+	txRepo := NewTransactionRepository(dm, nil)
+
+	ids := make([]int64, MaxWhaleIDsPerQuery+1)
+	for i := range ids {
+		ids[i] = int64(i + 1)
+	}
+
+	if _, err := txRepo.GetByWhaleIDs(context.Background(), ids, 10, 0); err == nil {
+		t.Error("Expected error for too many IDs, got nil")
+	}
+}
+
+// TestGetAddressSummary tests sent/received totals and first/last seen blocks
+func TestGetAddressSummary(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 0)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	addr := "0xaaaa"
+	other := "0xbbbb"
+	txs := []*Transaction{
+		{TxHash: "0x1", BlockNumber: 10, FromAddress: addr, ToAddress: &other, WhaleAddressID: int64Ptr(1), Value: "1.1"},
+		{TxHash: "0x2", BlockNumber: 20, FromAddress: addr, ToAddress: &other, WhaleAddressID: int64Ptr(1), Value: "2.2"},
+		{TxHash: "0x3", BlockNumber: 15, FromAddress: other, ToAddress: &addr, WhaleAddressID: int64Ptr(1), Value: "0.70001"},
+		{TxHash: "0x4", BlockNumber: 30, FromAddress: other, ToAddress: stringPtr("0xcccc"), WhaleAddressID: int64Ptr(1), Value: "99"},
+		// token transfer: counted as a transaction, but not in the ETH totals
+		{TxHash: "0x5", BlockNumber: 25, FromAddress: addr, ToAddress: &other, WhaleAddressID: int64Ptr(1), Value: "1000000", Asset: "USDC"},
+	}
+	if err := txRepo.BatchInsert(ctx, txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		address       string
+		expectedCount int64
+		expectedFirst int64
+		expectedLast  int64
+		expectedSent  string
+		expectedRecv  string
+	}{
+		{name: "Sender and receiver", address: addr, expectedCount: 4, expectedFirst: 10, expectedLast: 25, expectedSent: "3.3", expectedRecv: "0.70001"},
+		{name: "Mostly sender", address: other, expectedCount: 5, expectedFirst: 10, expectedLast: 30, expectedSent: "99.70001", expectedRecv: "3.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := txRepo.GetAddressSummary(ctx, tt.address)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if summary.TxCount != tt.expectedCount {
+				t.Errorf("Expected tx count %d, got %d", tt.expectedCount, summary.TxCount)
+			}
+			if summary.FirstSeenBlock == nil || *summary.FirstSeenBlock != tt.expectedFirst {
+				t.Errorf("Expected first seen %d, got %v", tt.expectedFirst, summary.FirstSeenBlock)
+			}
+			if summary.LastSeenBlock == nil || *summary.LastSeenBlock != tt.expectedLast {
+				t.Errorf("Expected last seen %d, got %v", tt.expectedLast, summary.LastSeenBlock)
+			}
+			if summary.TotalSentETH != tt.expectedSent {
+				t.Errorf("Expected sent %s, got %s", tt.expectedSent, summary.TotalSentETH)
+			}
+			if summary.TotalRecvETH != tt.expectedRecv {
+				t.Errorf("Expected received %s, got %s", tt.expectedRecv, summary.TotalRecvETH)
+			}
+		})
+	}
+}
+
+// TestGetAddressSummaryEmpty tests the empty summary for unknown addresses
+func TestGetAddressSummaryEmpty(t *testing.T) {
+	dm := newTestDatabase(t)
+	txRepo := NewTransactionRepository(dm, nil)
+
+	summary, err := txRepo.GetAddressSummary(context.Background(), "0xnobody")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if summary.TxCount != 0 || summary.FirstSeenBlock != nil || summary.LastSeenBlock != nil {
+		t.Errorf("Expected empty summary, got %+v", summary)
+	}
+	if summary.TotalSentETH != "0" || summary.TotalRecvETH != "0" {
+		t.Errorf("Expected zero totals, got sent=%s received=%s", summary.TotalSentETH, summary.TotalRecvETH)
+	}
+}
+
+// TestDeleteByHash tests deleting a transaction by hash
+func TestDeleteByHash(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 2)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		hash string
+		want int64
+	}{
+		{"Existing", "0x1_1", 1},
+		{"Already deleted", "0x1_1", 0},
+		{"Nonexistent", "0xmissing", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleted, err := txRepo.DeleteByHash(ctx, tt.hash)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if deleted != tt.want {
+				t.Errorf("Expected %d deleted, got %d", tt.want, deleted)
+			}
+		})
+	}
+
+	if tx, err := txRepo.GetByHash(ctx, "0x1_2"); err != nil || tx == nil {
+		t.Errorf("Expected other transactions to be kept, got %v, %v", tx, err)
+	}
+}
+
+// TestTransactionInsertDuplicate tests that inserting a stored tx hash again returns ErrDuplicate and keeps the row
+func TestTransactionInsertDuplicate(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 0)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	tx := &Transaction{TxHash: "0xdup", BlockNumber: 1, BlockHash: "0xb1", FromAddress: "0x01", Value: "1", WhaleAddressID: int64Ptr(2), TransferType: "FROM"}
+	if err := txRepo.Insert(ctx, tx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	again := &Transaction{TxHash: "0xdup", BlockNumber: 2, FromAddress: "0x02", Value: "2"}
+	if err := txRepo.Insert(ctx, again); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("Expected ErrDuplicate, got %v", err)
+	}
+
+	stored, err := txRepo.GetByHash(ctx, "0xdup")
+	if err != nil || stored == nil {
+		t.Fatalf("Expected stored transaction, got %v, %v", stored, err)
+	}
+	if stored.BlockNumber != 1 || stored.Value != "1" {
+		t.Errorf("Expected the first insert kept, got block %d value %s", stored.BlockNumber, stored.Value)
+	}
+	if stored.BlockHash != "0xb1" {
+		t.Errorf("Expected block hash 0xb1, got %q", stored.BlockHash)
+	}
+	if stored.WhaleAddressID == nil || *stored.WhaleAddressID != 2 {
+		t.Errorf("Expected whale address ID 2, got %v", stored.WhaleAddressID)
+	}
+	if stored.TransferType != "FROM" {
+		t.Errorf("Expected transfer type FROM, got %q", stored.TransferType)
+	}
+}
+
+// TestContractCreationRoundTrip tests that the created contract address survives mapping, storing and lookup
+func TestContractCreationRoundTrip(t *testing.T) {
+	dm := newTestDatabase(t)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	contract := "0x00000000000000000000000000000000000000c1"
+	creation, err := MapParsedTxToDatabaseTx(&types.ParsedTransaction{
+		Hash:            "0xcreate",
+		BlockNumber:     10,
+		From:            "0x01",
+		Value:           big.NewInt(0),
+		ReceiptFetched:  true,
+		Status:          1,
+		ContractAddress: &contract,
+	})
+	if err != nil {
+		t.Fatalf("Failed to map transaction: %v", err)
+	}
+	call, err := MapParsedTxToDatabaseTx(&types.ParsedTransaction{Hash: "0xcall", BlockNumber: 11, From: "0x01", To: &contract, Value: big.NewInt(1)})
+	if err != nil {
+		t.Fatalf("Failed to map transaction: %v", err)
+	}
+	if err := txRepo.BatchInsert(ctx, []*Transaction{creation, call}); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		address string
+		want    string // expected tx hash, "" for not found
+	}{
+		{"Created contract", contract, "0xcreate"},
+		{"Unknown contract", "0x00000000000000000000000000000000000000c2", ""},
+		{"Sender is not a contract", "0x01", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := txRepo.GetByContractAddress(ctx, tt.address)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.want == "" {
+				if tx != nil {
+					t.Errorf("Expected no transaction, got %s", tx.TxHash)
+				}
+				return
+			}
+			if tx == nil {
+				t.Fatalf("Expected transaction %s, got nil", tt.want)
+			}
+			if tx.TxHash != tt.want || tx.ToAddress != nil || tx.ContractAddress == nil || *tx.ContractAddress != contract {
+				t.Errorf("Expected creation %s of %s, got %s to %v contract %v", tt.want, contract, tx.TxHash, tx.ToAddress, tx.ContractAddress)
+			}
+		})
+	}
+}
+
+// TestAddressBatchUpsert tests that re-running the whale upsert keeps IDs and the transactions referencing them
+func TestAddressBatchUpsert(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 2)
+	addrRepo := NewAddressRepository(dm, nil)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	before, err := addrRepo.GetIdByAddress(ctx, fmt.Sprintf("0x%040d", 2))
+	if err != nil || len(before) != 1 {
+		t.Fatalf("Expected seeded whale, got %v, %v", before, err)
+	}
+
+	// same whales as seeded with a renamed one, plus a new whale
+	var addrs []*WhaleAddress
+	for i := 1; i <= 4; i++ {
+		label := fmt.Sprintf("Whale %d", i)
+		if i == 2 {
+			label = "Renamed whale"
+		}
+		addrs = append(addrs, &WhaleAddress{Address: fmt.Sprintf("0x%040d", i), Label: &label})
+	}
+	for run := 1; run <= 2; run++ {
+		if err := addrRepo.BatchUpsert(ctx, addrs); err != nil {
+			t.Fatalf("Unexpected error on run %d: %v", run, err)
+		}
+	}
+
+	count, err := txRepo.CountByWhaleIDs(ctx, []int64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	if count != 6 {
+		t.Errorf("Expected 6 transactions to survive, got %d", count)
+	}
+
+	after, err := addrRepo.GetIdByAddress(ctx, fmt.Sprintf("0x%040d", 2))
+	if err != nil || len(after) != 1 {
+		t.Fatalf("Expected upserted whale, got %v, %v", after, err)
+	}
+	if after[0].ID != before[0].ID {
+		t.Errorf("Expected ID %d to be kept, got %d", before[0].ID, after[0].ID)
+	}
+	if after[0].Label == nil || *after[0].Label != "Renamed whale" {
+		t.Errorf("Expected label Renamed whale, got %v", deref(after[0].Label))
+	}
+
+	watched, err := addrRepo.GetWatched(ctx)
+	if err != nil {
+		t.Fatalf("Failed to get whales: %v", err)
+	}
+	if len(watched) != 4 {
+		t.Errorf("Expected 4 whales, got %d", len(watched))
+	}
+}
+
+// TestAddressInsert tests inserting a single whale address with timestamps and the returned ID
+func TestAddressInsert(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 1)
+	addrRepo := NewAddressRepository(dm, nil)
+	ctx := context.Background()
+
+	label := "New whale"
+	addr := &WhaleAddress{Address: "0xAbCdEf0000000000000000000000000000000042", Label: &label}
+	before := time.Now()
+	id, err := addrRepo.Insert(ctx, addr)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if id != 4 || addr.ID != id {
+		t.Errorf("Expected ID 4 after 3 seeded whales set on the address, got %d (address ID %d)", id, addr.ID)
+	}
+	if addr.CreatedAt.Before(before) || !addr.UpdatedAt.Equal(addr.CreatedAt) {
+		t.Errorf("Expected created_at = updated_at set at insert, got %v / %v", addr.CreatedAt, addr.UpdatedAt)
+	}
+
+	stored, err := addrRepo.GetIdByAddress(ctx, "0xabcdef0000000000000000000000000000000042")
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Expected inserted whale by lowercase address, got %v, %v", stored, err)
+	}
+	if stored[0].ID != id {
+		t.Errorf("Expected stored ID %d, got %d", id, stored[0].ID)
+	}
+	if stored[0].CreatedAt.Unix() != addr.CreatedAt.Unix() || stored[0].UpdatedAt.Unix() != addr.UpdatedAt.Unix() {
+		t.Errorf("Expected stored timestamps %v, got %v / %v", addr.CreatedAt, stored[0].CreatedAt, stored[0].UpdatedAt)
+	}
+	if !stored[0].IsWatched {
+		t.Error("Expected inserted whale to be watched")
+	}
+
+	if _, err := addrRepo.Insert(ctx, &WhaleAddress{Address: addr.Address}); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for duplicate address, got %v", err)
+	}
+}
+
+// TestAddressSetMinETH tests storing whale thresholds, their loading through the label cache and keeping them on upsert
+func TestAddressSetMinETH(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 1)
+	addrRepo := NewAddressRepository(dm, nil)
+	cache := NewLabelCache(addrRepo, 0)
+	ctx := context.Background()
+	whale := "0x0000000000000000000000000000000000000001"
+	hundred := "100"
+
+	tests := []struct {
+		name        string
+		write       func() (bool, error)
+		wantUpdated bool
+		want        map[string]string
+	}{
+		{"Set", func() (bool, error) { return addrRepo.SetMinETH(ctx, strings.ToUpper(whale), &hundred) }, true,
+			map[string]string{whale: "100"}},
+		{"Kept on upsert", func() (bool, error) {
+			label := "Relabeled"
+			return true, addrRepo.BatchUpsert(ctx, []*WhaleAddress{{Address: whale, Label: &label}})
+		}, true, map[string]string{whale: "100"}},
+		{"Unknown address", func() (bool, error) { return addrRepo.SetMinETH(ctx, "0xmissing", &hundred) }, false,
+			map[string]string{whale: "100"}},
+		{"Reset", func() (bool, error) { return addrRepo.SetMinETH(ctx, whale, nil) }, true, map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := tt.write()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("Expected updated %v, got %v", tt.wantUpdated, updated)
+			}
+			thresholds, err := cache.Thresholds(ctx)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(thresholds, tt.want) {
+				t.Errorf("Expected thresholds %v, got %v", tt.want, thresholds)
+			}
+		})
+	}
+
+	// set on insert
+	minETH := "2.5"
+	id, err := addrRepo.Insert(ctx, &WhaleAddress{Address: "0xprotocol", MinETH: &minETH})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stored, err := addrRepo.GetIdByAddress(ctx, "0xprotocol")
+	if err != nil || len(stored) != 1 || stored[0].ID != id || stored[0].MinETH == nil || *stored[0].MinETH != minETH {
+		t.Errorf("Expected whale %d with min ETH %s, got %v (%v)", id, minETH, stored, err)
+	}
+}
+
+// TestAddressSearch tests the exact address path and the substring fallback for labels and partial addresses
+func TestAddressSearch(t *testing.T) {
+	dm := newTestDatabase(t)
+	addrRepo := NewAddressRepository(dm, nil)
+	ctx := context.Background()
+
+	const checksummed = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	lower := strings.ToLower(checksummed)
+	labels := map[string]string{
+		lower: "Exchange hot wallet",
+		"0x0000000000000000000000000000000000000001": "Mentions " + lower, // LIKE would match the address here
+		"0x0000000000000000000000000000000000000002": "Exchange cold wallet",
+	}
+	for addr, label := range labels {
+		label := label
+		if _, err := addrRepo.Insert(ctx, &WhaleAddress{Address: addr, Label: &label}); err != nil {
+			t.Fatalf("Failed to insert whale: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		term     string
+		strict   bool
+		expected []string
+		wantErr  error
+	}{
+		{name: "Checksummed address", term: checksummed, expected: []string{lower}},
+		{name: "Lowercase address", term: lower, expected: []string{lower}},
+		{name: "Bad checksum matches without strict mode", term: "0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", expected: []string{lower}},
+		{name: "Bad checksum in strict mode", term: "0x5aaeb6053F3E94C9b9A09f33669435E7Ef1BeAed", strict: true, wantErr: ErrInvalidChecksum},
+		{name: "Checksummed address in strict mode", term: checksummed, strict: true, expected: []string{lower}},
+		{name: "Lowercase address in strict mode", term: lower, strict: true, expected: []string{lower}},
+		{name: "Unknown address", term: "0x00000000000000000000000000000000000000ff", expected: []string{}},
+		{name: "Label substring", term: "exchange", expected: []string{lower, "0x0000000000000000000000000000000000000002"}},
+		{name: "Partial address", term: "0x5aaeb6", expected: []string{lower, "0x0000000000000000000000000000000000000001"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addrRepo.StrictChecksum = tt.strict
+			found, err := addrRepo.Search(ctx, tt.term, 10)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := make(map[string]bool)
+			for _, addr := range found {
+				got[addr.Address] = true
+			}
+			if len(found) != len(tt.expected) {
+				t.Fatalf("Expected %d addresses, got %d: %v", len(tt.expected), len(found), got)
+			}
+			for _, addr := range tt.expected {
+				if !got[addr] {
+					t.Errorf("Expected %s in results, got %v", addr, got)
+				}
+			}
+		})
+	}
+
+	plan := explainQueryPlan(t, dm, searchExactAddressQuery, lower, 10)
+	if !strings.Contains(plan, "USING INDEX") && !strings.Contains(plan, "USING COVERING INDEX") {
+		t.Errorf("Expected exact address search to use an index, got plan:\n%s", plan)
+	}
+}
+
+// TestParseErrorRepository tests storing, re-failing and deleting dead-letter transactions
+func TestParseErrorRepository(t *testing.T) {
+	dm := newTestDatabase(t)
+	repo := NewParseErrorRepository(dm, nil)
+	ctx := context.Background()
+
+	first := []*ParseError{
+		MapTxParseError(&types.TxParseError{BlockNumber: 20, TxHash: "0xb", TransactionIndex: 3, Error: "panic: nil log"}),
+		MapTxParseError(&types.TxParseError{BlockNumber: 10, TxHash: "0xa", TransactionIndex: 1, Error: "bad signature"}),
+	}
+	if err := repo.BatchUpsert(ctx, first); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	again := []*ParseError{MapTxParseError(&types.TxParseError{BlockNumber: 20, TxHash: "0xb", TransactionIndex: 3, Error: "panic: still nil"})}
+	if err := repo.BatchUpsert(ctx, again); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	pending, err := repo.GetPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected 2 parse errors, got %d", len(pending))
+	}
+	if pending[0].TxHash != "0xa" || pending[0].Attempts != 1 {
+		t.Errorf("Expected 0xa first with 1 attempt, got %+v", pending[0])
+	}
+	if pending[1].Error != "panic: still nil" || pending[1].Attempts != 2 {
+		t.Errorf("Expected updated error with 2 attempts, got %+v", pending[1])
+	}
+	if pe := pending[1].ToTxParseError(); pe.BlockNumber != 20 || pe.TransactionIndex != 3 || pe.TxHash != "0xb" {
+		t.Errorf("Unexpected converted parse error: %+v", pe)
+	}
+
+	deleted, err := repo.DeleteByHashes(ctx, []string{"0xa", "0xmissing"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected 1 deleted, got %d", deleted)
+	}
+	if pending, _ := repo.GetPending(ctx, 10); len(pending) != 1 || pending[0].TxHash != "0xb" {
+		t.Errorf("Expected only 0xb left, got %v", pending)
+	}
+}
+
+// TestBatchInsertChunked tests that a batch with more bound parameters than SQLite allows in one
+// statement (32766) is split into statements and every row lands
+func TestBatchInsertChunked(t *testing.T) {
+	tests := []struct {
+		name            string
+		maxSQLVariables int
+	}{
+		{name: "Default limit", maxSQLVariables: 0},
+		{name: "Small limit", maxSQLVariables: 100},
+		{name: "Limit below one row", maxSQLVariables: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm := newTestDatabase(t)
+			dm.config.MaxSQLVariables = tt.maxSQLVariables
+			// 3 transactions per block, ~20 parameters each: 6000 rows, far over one statement
+			seedTransactions(t, dm, 2000)
+
+			count, err := NewTransactionRepository(dm, nil).CountByWhaleIDs(context.Background(), []int64{1, 2, 3})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if count != 6000 {
+				t.Errorf("Expected 6000 transactions, got %d", count)
+			}
+		})
+	}
+}
+
+// TestGetTopByValue tests numeric ordering of values with different magnitudes, where text order would differ
+func TestGetTopByValue(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 0)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	values := map[string]int64{ // value -> block
+		"9":           10,
+		"10":          10,
+		"0.5":         11,
+		"100000":      11,
+		"1234.56789":  12,
+		"99.99999":    12,
+		"2.5":         13,
+		"10000000.25": 20, // outside most ranges
+	}
+	var txs []*Transaction
+	for value, block := range values {
+		txs = append(txs, &Transaction{
+			TxHash:         "0x" + value,
+			BlockNumber:    block,
+			FromAddress:    fmt.Sprintf("0x%040d", 1),
+			WhaleAddressID: int64Ptr(1),
+			Value:          value,
+		})
+	}
+	// token transfer amount is not ETH, never on top
+	txs = append(txs, &Transaction{TxHash: "0xtoken", BlockNumber: 10, FromAddress: fmt.Sprintf("0x%040d", 1), WhaleAddressID: int64Ptr(1), Value: "999999999", Asset: "USDC"})
+	if err := txRepo.BatchInsert(ctx, txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to int64
+		n        int
+		expected []string
+	}{
+		{name: "Top 4 in range", from: 10, to: 13, n: 4, expected: []string{"100000", "1234.56789", "99.99999", "10"}},
+		{name: "Whole range", from: 0, to: 100, n: 3, expected: []string{"10000000.25", "100000", "1234.56789"}},
+		{name: "Single block", from: 10, to: 10, n: 10, expected: []string{"10", "9"}},
+		{name: "Fractions", from: 11, to: 13, n: 10, expected: []string{"100000", "1234.56789", "99.99999", "2.5", "0.5"}},
+		{name: "Empty range", from: 14, to: 19, n: 10, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top, err := txRepo.GetTopByValue(ctx, tt.from, tt.to, tt.n)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if top == nil {
+				t.Fatal("Expected empty slice, got nil")
+			}
+			if len(top) != len(tt.expected) {
+				t.Fatalf("Expected %d transactions, got %d", len(tt.expected), len(top))
+			}
+			for i, value := range tt.expected {
+				if top[i].TxHash != "0x"+value {
+					t.Errorf("Expected 0x%s at %d, got %s", value, i, top[i].TxHash)
+				}
+			}
+		})
+	}
+}
+
+// TestDeleteByBlockRange tests deleting transactions of a block range
+func TestDeleteByBlockRange(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 5)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	deleted, err := txRepo.DeleteByBlockRange(ctx, 2, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deleted != 6 {
+		t.Errorf("Expected 6 deleted, got %d", deleted)
+	}
+
+	count, err := txRepo.CountByWhaleIDs(ctx, []int64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("Failed to count transactions: %v", err)
+	}
+	if count != 9 {
+		t.Errorf("Expected 9 transactions left, got %d", count)
+	}
+
+	deleted, err = txRepo.DeleteByBlockRange(ctx, 100, 200)
+	if err != nil || deleted != 0 {
+		t.Errorf("Expected 0 deleted without error for empty range, got %d, %v", deleted, err)
+	}
+
+	if _, err := txRepo.DeleteByBlockRange(ctx, 3, 2); err == nil {
+		t.Error("Expected error for inverted range")
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+// deref returns the pointed value or nil, for comparing nullable fields
+func deref[T any](p *T) interface{} {
+	if p == nil {
+		return nil
+	}
+	return *p
+}
+
+// TestValueHistogram tests bucket counts of whale transaction values
+func TestValueHistogram(t *testing.T) {
+	dm := newTestDatabase(t)
+	seedTransactions(t, dm, 0)
+	txRepo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	values := []string{"0.5", "1", "5.25", "10", "99.99999", "100", "999", "1000", "25000"}
+	var txs []*Transaction
+	for i, value := range values {
+		txs = append(txs, &Transaction{
+			TxHash:         fmt.Sprintf("0xvalue_%d", i),
+			BlockNumber:    int64(i + 1),
+			FromAddress:    fmt.Sprintf("0x%040d", 1),
+			WhaleAddressID: int64Ptr(1),
+			TransferType:   "FROM",
+			Value:          value,
+		})
+	}
+	// not a whale transaction, not counted
+	txs = append(txs, &Transaction{TxHash: "0xnot_whale", BlockNumber: 100, FromAddress: "0xother", Value: "5000"})
+	// token transfer, not counted
+	txs = append(txs, &Transaction{TxHash: "0xtoken", BlockNumber: 101, FromAddress: fmt.Sprintf("0x%040d", 1), WhaleAddressID: int64Ptr(1), TransferType: "FROM", Value: "5000", Asset: "USDC"})
+	if err := txRepo.BatchInsert(ctx, txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		buckets []float64
+		want    map[string]int
+		wantErr bool
+	}{
+		{
+			name:    "Default buckets",
+			buckets: DefaultValueHistogramBuckets,
+			want:    map[string]int{"<1": 1, "1-10": 2, "10-100": 2, "100-1000": 2, "1000+": 2},
+		},
+		{
+			name:    "Single boundary",
+			buckets: []float64{100},
+			want:    map[string]int{"<100": 5, "100+": 4},
+		},
+		{
+			name:    "Fractional boundaries and empty buckets",
+			buckets: []float64{0.1, 0.5, 50000},
+			want:    map[string]int{"<0.1": 0, "0.1-0.5": 0, "0.5-50000": 9, "50000+": 0},
+		},
+		{name: "No boundaries", buckets: nil, wantErr: true},
+		{name: "Not ascending", buckets: []float64{10, 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			histogram, err := txRepo.ValueHistogram(ctx, tt.buckets)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidBuckets) {
+					t.Errorf("Expected ErrInvalidBuckets, got %v, %v", histogram, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(histogram) != len(tt.want) {
+				t.Errorf("Expected %d buckets, got %v", len(tt.want), histogram)
+			}
+			for label, count := range tt.want {
+				if histogram[label] != count {
+					t.Errorf("Expected %d in bucket %s, got %d", count, label, histogram[label])
+				}
+			}
+		})
+	}
+}
+
+// TestMapParsedBlockToDatabaseBlock tests the gas used ratio and base fee of stored blocks
+func TestMapParsedBlockToDatabaseBlock(t *testing.T) {
+	tests := []struct {
+		name          string
+		block         *types.ParsedBlock
+		expectedRatio float64
+		expectedFee   interface{}
+	}{
+		{
+			name:          "Near gas limit",
+			block:         &types.ParsedBlock{Number: 20000000, GasUsed: 29_999_000, GasLimit: 30_000_000, BaseFeePerGas: big.NewInt(25_000_000_000)},
+			expectedRatio: 0.99996667,
+			expectedFee:   "25000000000",
+		},
+		{
+			name:          "Pre-London without base fee",
+			block:         &types.ParsedBlock{Number: 12000000, GasUsed: 7_500_000, GasLimit: 15_000_000},
+			expectedRatio: 0.5,
+			expectedFee:   nil,
+		},
+		{
+			name:          "Zero gas limit",
+			block:         &types.ParsedBlock{Number: 1, GasUsed: 0, GasLimit: 0},
+			expectedRatio: 0,
+			expectedFee:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := MapParsedBlockToDatabaseBlock(tt.block)
+			if math.Abs(block.GasUsedRatio-tt.expectedRatio) > 1e-8 {
+				t.Errorf("Expected gas used ratio %v, got %v", tt.expectedRatio, block.GasUsedRatio)
+			}
+			if fee := deref(block.BaseFeePerGas); fee != tt.expectedFee {
+				t.Errorf("Expected base fee %v, got %v", tt.expectedFee, fee)
+			}
+		})
+	}
+}
+
+// TestBlockRepository tests storing and listing blocks, newest first
+func TestBlockRepository(t *testing.T) {
+	dm := newTestDatabase(t)
+	repo := NewBlockRepository(dm, nil)
+	ctx := context.Background()
+
+	blocks := []*Block{
+		MapParsedBlockToDatabaseBlock(&types.ParsedBlock{Number: 1, Hash: "0x01", Timestamp: time.Unix(1, 0), GasUsed: 29_999_000, GasLimit: 30_000_000, BaseFeePerGas: big.NewInt(7)}),
+		MapParsedBlockToDatabaseBlock(&types.ParsedBlock{Number: 2, Hash: "0x02", ParentHash: "0x01", Timestamp: time.Unix(2, 0), GasUsed: 100, GasLimit: 200}),
+	}
+	if err := repo.BatchUpsert(ctx, blocks); err != nil {
+		t.Fatalf("Failed to insert blocks: %v", err)
+	}
+	// a re-parsed block replaces the stored one
+	if err := repo.BatchUpsert(ctx, []*Block{{Number: 2, Hash: "0x02b", ParentHash: "0x01", Timestamp: time.Unix(2, 0)}}); err != nil {
+		t.Fatalf("Failed to replace block: %v", err)
+	}
+
+	stored, err := repo.GetRecent(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to get blocks: %v", err)
+	}
+	if len(stored) != 2 || stored[0].Number != 2 || stored[0].Hash != "0x02b" {
+		t.Fatalf("Expected replaced block 2 first, got %+v", stored)
+	}
+	if math.Abs(stored[1].GasUsedRatio-0.99996667) > 1e-8 || deref(stored[1].BaseFeePerGas) != "7" {
+		t.Errorf("Expected ratio 0.99996667 and base fee 7, got %v and %v", stored[1].GasUsedRatio, deref(stored[1].BaseFeePerGas))
+	}
+	if stored[0].BaseFeePerGas != nil {
+		t.Errorf("Expected nil base fee, got %v", *stored[0].BaseFeePerGas)
+	}
+
+	count, err := repo.Count(ctx)
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 blocks, got %d (%v)", count, err)
+	}
+}
+
+// explainQueryPlan returns the EXPLAIN QUERY PLAN steps of a query, one detail per line
+func explainQueryPlan(t *testing.T, dm *DatabaseManager, query string, args ...interface{}) string {
+	t.Helper()
+	var steps []struct {
+		ID      int    `db:"id"`
+		Parent  int    `db:"parent"`
+		NotUsed int    `db:"notused"`
+		Detail  string `db:"detail"`
+	}
+	if err := dm.db.Select(&steps, "EXPLAIN QUERY PLAN "+query, args...); err != nil {
+		t.Fatalf("Failed to explain query: %v", err)
+	}
+	details := make([]string, len(steps))
+	for i, step := range steps {
+		details[i] = step.Detail
+	}
+	return strings.Join(details, "\n")
+}
+
+// TestGetByTransferType tests filtering by whale side, newest first, and that the composite index serves the query
+func TestGetByTransferType(t *testing.T) {
+	dm := newTestDatabase(t)
+	repo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	var txs []*Transaction
+	for i, transferType := range []string{"TO", "FROM", "TO", "INT", "TO", ""} {
+		txs = append(txs, &Transaction{
+			TxHash:           fmt.Sprintf("0x%d", i),
+			BlockNumber:      int64(100 + i/2),
+			TransactionIndex: int64(i),
+			FromAddress:      "0x1",
+			TransferType:     transferType,
+			Value:            "1",
+		})
+	}
+	if err := repo.BatchInsert(ctx, txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		transferType   string
+		limit, offset  int
+		expectedHashes []string
+		expectedTotal  int
+	}{
+		{"TO", 10, 0, []string{"0x4", "0x2", "0x0"}, 3},
+		{"TO", 2, 1, []string{"0x2", "0x0"}, 3},
+		{"FROM", 10, 0, []string{"0x1"}, 1},
+		{"INT", 10, 0, []string{"0x3"}, 1},
+		{"to", 10, 0, []string{}, 0}, // values are stored uppercase
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s limit %d offset %d", tt.transferType, tt.limit, tt.offset), func(t *testing.T) {
+			result, err := repo.GetByTransferType(ctx, tt.transferType, tt.limit, tt.offset)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			hashes := []string{}
+			for _, tx := range result {
+				hashes = append(hashes, tx.TxHash)
+			}
+			if strings.Join(hashes, ",") != strings.Join(tt.expectedHashes, ",") {
+				t.Errorf("Expected %v, got %v", tt.expectedHashes, hashes)
+			}
+
+			total, err := repo.CountByTransferType(ctx, tt.transferType)
+			if err != nil || total != tt.expectedTotal {
+				t.Errorf("Expected total %d, got %d (%v)", tt.expectedTotal, total, err)
+			}
+		})
+	}
+
+	plan := explainQueryPlan(t, dm, getByTransferTypeQuery, "TO", 10, 0)
+	if !strings.Contains(plan, "USING INDEX idx_transactions_type_block") {
+		t.Errorf("Expected query to use idx_transactions_type_block, got plan:\n%s", plan)
+	}
+	if strings.Contains(plan, "TEMP B-TREE") {
+		t.Errorf("Expected ORDER BY served by the index without sorting, got plan:\n%s", plan)
+	}
+}
+
+// TestGetMissingReceipts tests that only txs without receipt data are returned, newest first,
+// through the partial index
+func TestGetMissingReceipts(t *testing.T) {
+	dm := newTestDatabase(t)
+	repo := NewTransactionRepository(dm, nil)
+	ctx := context.Background()
+
+	gasUsed := int64(21000)
+	success, failed := 1, 0
+	rows := []struct {
+		hash    string
+		fetched bool
+		status  *int
+	}{
+		{"0xmissing_old", false, nil},
+		{"0xfetched_ok", true, &success},
+		{"0xmissing_mid", false, nil},
+		{"0xfetched_failed", true, &failed}, // status 0 is a failed tx, not a missing receipt
+		{"0xmissing_new", false, nil},
+	}
+	var txs []*Transaction
+	for i, row := range rows {
+		tx := &Transaction{
+			TxHash:         row.hash,
+			BlockNumber:    int64(100 + i),
+			FromAddress:    "0x1",
+			Value:          "1",
+			ReceiptFetched: row.fetched,
+			Status:         row.status,
+		}
+		if row.fetched {
+			tx.GasUsed = &gasUsed
+		}
+		txs = append(txs, tx)
+	}
+	if err := repo.BatchInsert(ctx, txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		limit          int
+		expectedHashes []string
+	}{
+		{10, []string{"0xmissing_new", "0xmissing_mid", "0xmissing_old"}},
+		{2, []string{"0xmissing_new", "0xmissing_mid"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("limit %d", tt.limit), func(t *testing.T) {
+			result, err := repo.GetMissingReceipts(ctx, tt.limit)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			hashes := []string{}
+			for _, tx := range result {
+				hashes = append(hashes, tx.TxHash)
+				if tx.GasUsed != nil || tx.Status != nil {
+					t.Errorf("Expected %s without receipt data, got gas_used %v status %v", tx.TxHash, tx.GasUsed, tx.Status)
+				}
+			}
+			if strings.Join(hashes, ",") != strings.Join(tt.expectedHashes, ",") {
+				t.Errorf("Expected %v, got %v", tt.expectedHashes, hashes)
+			}
+		})
+	}
+
+	// enriched rows drop out
+	txs[4].ReceiptFetched, txs[4].GasUsed, txs[4].Status = true, &gasUsed, &success
+	if err := repo.UpdateReceiptData(ctx, []*Transaction{txs[4]}); err != nil {
+		t.Fatalf("Failed to update receipt data: %v", err)
+	}
+	if result, _ := repo.GetMissingReceipts(ctx, 10); len(result) != 2 {
+		t.Errorf("Expected 2 txs left after enrichment, got %d", len(result))
+	}
+
+	plan := explainQueryPlan(t, dm, getMissingReceiptsQuery, 10)
+	if !strings.Contains(plan, "USING INDEX idx_transactions_missing_receipts") {
+		t.Errorf("Expected query to use idx_transactions_missing_receipts, got plan:\n%s", plan)
+	}
+	if strings.Contains(plan, "TEMP B-TREE") {
+		t.Errorf("Expected ORDER BY served by the index without sorting, got plan:\n%s", plan)
+	}
+}
+
+// TestRepositoryClock tests that written rows and the settings cache age use the manager clock
+func TestRepositoryClock(t *testing.T) {
+	ctx := context.Background()
+	dm := newTestDatabase(t)
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := types.NewFakeClock(start)
+	dm.SetClock(clock)
+	txRepo := NewTransactionRepository(dm, nil)
+
+	if err := txRepo.Insert(ctx, &Transaction{TxHash: "0xa", BlockNumber: 1, FromAddress: "0x01", Value: "1"}); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	if err := txRepo.BatchInsert(ctx, []*Transaction{{TxHash: "0xb", BlockNumber: 1, FromAddress: "0x01", Value: "1"}}); err != nil {
+		t.Fatalf("Failed to batch insert: %v", err)
+	}
+	clock.Advance(time.Hour)
+	gasUsed, status := int64(21000), 1
+	if err := txRepo.UpdateReceiptData(ctx, []*Transaction{{TxHash: "0xb", GasUsed: &gasUsed, Status: &status, ReceiptFetched: true}}); err != nil {
+		t.Fatalf("Failed to update receipt data: %v", err)
+	}
+
+	tests := []struct {
+		hash            string
+		expectedCreated time.Time
+		expectedUpdated time.Time
+	}{
+		{"0xa", start, start},
+		{"0xb", start, start.Add(time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			tx, err := txRepo.GetByHash(ctx, tt.hash)
+			if err != nil || tx == nil {
+				t.Fatalf("Failed to read back %s: %v", tt.hash, err)
+			}
+			if !tx.CreatedAt.Equal(tt.expectedCreated) || !tx.UpdatedAt.Equal(tt.expectedUpdated) {
+				t.Errorf("Expected created/updated %v / %v, got %v / %v", tt.expectedCreated, tt.expectedUpdated, tx.CreatedAt, tx.UpdatedAt)
+			}
+		})
+	}
+
+	// the settings TTL counts on the same clock, no sleeping
+	writer := NewSettingsRepository(dm, nil, 0)
+	reader := NewSettingsRepository(dm, nil, time.Minute)
+	if err := writer.SetString(ctx, "min_eth_value", "1"); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if value, _, _ := reader.GetString(ctx, "min_eth_value"); value != "1" {
+		t.Fatalf("Expected 1, got %q", value)
+	}
+	if err := writer.SetString(ctx, "min_eth_value", "2"); err != nil {
+		t.Fatalf("Failed to set: %v", err)
+	}
+	if value, _, _ := reader.GetString(ctx, "min_eth_value"); value != "1" {
+		t.Errorf("Expected cached 1 before the TTL, got %q", value)
+	}
+	clock.Advance(2 * time.Minute)
+	if value, _, _ := reader.GetString(ctx, "min_eth_value"); value != "2" {
+		t.Errorf("Expected reloaded 2 after the TTL, got %q", value)
+	}
+}
+
+// TestSettingsRepository tests typed get/set of settings and cache invalidation on write
+func TestSettingsRepository(t *testing.T) {
+	ctx := context.Background()
+	dm := newTestDatabase(t)
+	settings := NewSettingsRepository(dm, nil, 0)
+
+	if _, ok, err := settings.GetString(ctx, "whale_direction"); err != nil || ok {
+		t.Fatalf("Expected missing setting, got ok %v, err %v", ok, err)
+	}
+
+	if err := settings.SetString(ctx, "whale_direction", "from"); err != nil {
+		t.Fatalf("Failed to set string: %v", err)
+	}
+	if err := settings.SetInt(ctx, "retention_days", 7); err != nil {
+		t.Fatalf("Failed to set int: %v", err)
+	}
+	if err := settings.SetDecimal(ctx, "min_eth_value", decimal.RequireFromString("0.5")); err != nil {
+		t.Fatalf("Failed to set decimal: %v", err)
+	}
+
+	if direction, ok, err := settings.GetString(ctx, "whale_direction"); err != nil || !ok || direction != "from" {
+		t.Errorf("Expected direction from, got %q (ok %v, err %v)", direction, ok, err)
+	}
+	if days, ok, err := settings.GetInt(ctx, "retention_days"); err != nil || !ok || days != 7 {
+		t.Errorf("Expected 7 retention days, got %d (ok %v, err %v)", days, ok, err)
+	}
+	if minETH, ok, err := settings.GetDecimal(ctx, "min_eth_value"); err != nil || !ok || minETH.String() != "0.5" {
+		t.Errorf("Expected min ETH 0.5, got %s (ok %v, err %v)", minETH, ok, err)
+	}
+	if _, _, err := settings.GetInt(ctx, "whale_direction"); err == nil {
+		t.Error("Expected error reading a string setting as int, got nil")
+	}
+
+	// overwrite after the values are cached, the cache must not serve the old value
+	if err := settings.SetInt(ctx, "retention_days", 30); err != nil {
+		t.Fatalf("Failed to update int: %v", err)
+	}
+	if days, _, _ := settings.GetInt(ctx, "retention_days"); days != 30 {
+		t.Errorf("Expected updated 30 retention days, got %d", days)
+	}
+
+	// empty value deletes the setting
+	if err := settings.SetString(ctx, "whale_direction", ""); err != nil {
+		t.Fatalf("Failed to reset setting: %v", err)
+	}
+	all, err := settings.All(ctx)
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+	if _, ok := all["whale_direction"]; ok || len(all) != 2 {
+		t.Errorf("Expected whale_direction reset and 2 settings left, got %v", all)
+	}
+}
+
+// TestMetaRepositoryRequestCount tests that the daily request count round-trips and a new day replaces the old one
+func TestMetaRepositoryRequestCount(t *testing.T) {
+	ctx := context.Background()
+	dm := newTestDatabase(t)
+	meta := NewMetaRepository(dm, nil)
+
+	steps := []struct {
+		name  string
+		day   string
+		count uint64
+	}{
+		{"First save", "2024-01-01", 50},
+		{"Update", "2024-01-01", 100},
+		{"Next day", "2024-01-02", 50},
+	}
+	for _, step := range steps {
+		if err := meta.SaveRequestCount(ctx, step.day, step.count); err != nil {
+			t.Fatalf("%s: failed to save: %v", step.name, err)
+		}
+		if count, err := meta.LoadRequestCount(ctx, step.day); err != nil || count != step.count {
+			t.Errorf("%s: expected %d, got %d (err %v)", step.name, step.count, count, err)
+		}
+	}
+
+	if count, err := meta.LoadRequestCount(ctx, "2024-01-01"); err != nil || count != 0 {
+		t.Errorf("Expected the previous day dropped, got %d (err %v)", count, err)
+	}
+	if _, ok, err := meta.Get(ctx, "rpc_requests:2024-01-01"); err != nil || ok {
+		t.Errorf("Expected no row for the previous day, got ok %v (err %v)", ok, err)
+	}
+}
+
+// TestSettingsRepositoryTTL tests that writes of another repository show up after the cache TTL only
+func TestSettingsRepositoryTTL(t *testing.T) {
+	ctx := context.Background()
+	dm := newTestDatabase(t)
+	writer := NewSettingsRepository(dm, nil, 0)
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		wait      time.Duration
+		wantValue string
+	}{
+		{name: "Cached until write", ttl: 0, wait: 20 * time.Millisecond, wantValue: "1"},
+		{name: "Reloaded after TTL", ttl: 10 * time.Millisecond, wait: 20 * time.Millisecond, wantValue: "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := writer.SetString(ctx, "min_eth_value", "1"); err != nil {
+				t.Fatalf("Failed to set: %v", err)
+			}
+			reader := NewSettingsRepository(dm, nil, tt.ttl)
+			if value, _, _ := reader.GetString(ctx, "min_eth_value"); value != "1" {
+				t.Fatalf("Expected 1, got %q", value)
+			}
+
+			if err := writer.SetString(ctx, "min_eth_value", "2"); err != nil {
+				t.Fatalf("Failed to set: %v", err)
+			}
+			time.Sleep(tt.wait)
+			if value, _, _ := reader.GetString(ctx, "min_eth_value"); value != tt.wantValue {
+				t.Errorf("Expected %s, got %q", tt.wantValue, value)
+			}
+		})
+	}
+}
+
+// TestAddressNormalization tests that checksummed addresses are stored normalized and found
+// by lookups with any spelling of the address
+func TestAddressNormalization(t *testing.T) {
+	ctx := context.Background()
+	dm := newTestDatabase(t)
+	txRepo := NewTransactionRepository(dm, nil)
+	addrRepo := NewAddressRepository(dm, nil)
+
+	whale := "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"
+	receiver := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	contract := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	label := "Vitalik"
+	if err := addrRepo.BatchInsert(ctx, []*WhaleAddress{{Address: whale, Label: &label}}); err != nil {
+		t.Fatalf("Failed to insert whale: %v", err)
+	}
+	err := txRepo.BatchInsert(ctx, []*Transaction{
+		{TxHash: "0xsend", BlockNumber: 1, FromAddress: whale, ToAddress: &receiver, Value: "3"},
+		{TxHash: "0xcreate", BlockNumber: 2, FromAddress: whale, ContractAddress: &contract, Value: "0"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	spellings := []struct {
+		name  string
+		apply func(string) string
+	}{
+		{"Checksummed", func(a string) string { return a }},
+		{"Lowercase", strings.ToLower},
+		{"Uppercase hex", func(a string) string { return "0x" + strings.ToUpper(a[2:]) }},
+		{"Padded", func(a string) string { return " " + a + " " }},
+	}
+	for _, sp := range spellings {
+		t.Run(sp.name, func(t *testing.T) {
+			if txs, err := txRepo.GetByAddress(ctx, sp.apply(whale), 10, 0); err != nil || len(txs) != 2 {
+				t.Errorf("Expected 2 txs of the whale, got %d (err %v)", len(txs), err)
+			}
+			if txs, err := txRepo.GetByAddress(ctx, sp.apply(receiver), 10, 0); err != nil || len(txs) != 1 {
+				t.Errorf("Expected 1 tx of the receiver, got %d (err %v)", len(txs), err)
+			}
+			if summary, err := txRepo.GetAddressSummary(ctx, sp.apply(whale)); err != nil || summary.TxCount != 2 || summary.TotalSentETH != "3" {
+				t.Errorf("Expected summary of 2 txs and 3 ETH sent, got %+v (err %v)", summary, err)
+			}
+			if tx, err := txRepo.GetByContractAddress(ctx, sp.apply(contract)); err != nil || tx == nil || tx.TxHash != "0xcreate" {
+				t.Errorf("Expected creation tx of the contract, got %v (err %v)", tx, err)
+			}
+			if whales, err := addrRepo.GetIdByAddress(ctx, sp.apply(whale)); err != nil || len(whales) != 1 {
+				t.Errorf("Expected the whale, got %v (err %v)", whales, err)
+			}
+		})
+	}
+
+	stored, err := txRepo.GetByHash(ctx, "0xsend")
+	if err != nil || stored == nil {
+		t.Fatalf("Failed to read back transaction: %v", err)
+	}
+	if stored.FromAddress != strings.ToLower(whale) || *stored.ToAddress != strings.ToLower(receiver) {
+		t.Errorf("Expected lowercase addresses stored, got %s -> %s", stored.FromAddress, *stored.ToAddress)
+	}
+}
+
+// TestLogRepository tests storing the logs of transactions and reading them back per transaction
+func TestLogRepository(t *testing.T) {
+	ctx := context.Background()
+	dm := newTestDatabase(t)
+	logRepo := NewLogRepository(dm, nil)
+
+	txHash := "0x" + strings.Repeat("ab", 32)
+	transfer := &types.ParsedLog{
+		Address:          "0xdAC17F958D2ee523a2206206994597C13D831ec7",
+		Topics:           []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef", "0x01", "0x02"},
+		Data:             "00000000000000000000000000000000000000000000000000000000000003e8",
+		BlockNumber:      100,
+		TxHash:           txHash,
+		LogIndex:         7,
+		DecodedEventName: "Transfer",
+		DecodedData:      map[string]interface{}{"from": "0x01", "to": "0x02", "value": "1000"},
+	}
+	unknown := &types.ParsedLog{
+		Address:     "0x0000000000000000000000000000000000000001",
+		Topics:      []string{"0x1234"},
+		BlockNumber: 100,
+		TxHash:      txHash,
+		LogIndex:    3,
+	}
+	other := &types.ParsedLog{Address: "0x0000000000000000000000000000000000000002", BlockNumber: 101, TxHash: "0xother", LogIndex: 0}
+
+	var logs []*Log
+	for _, parsed := range []*types.ParsedLog{transfer, unknown, other} {
+		l, err := MapParsedLog(parsed)
+		if err != nil {
+			t.Fatalf("Failed to map log: %v", err)
+		}
+		logs = append(logs, l)
+	}
+	if err := logRepo.BatchInsert(ctx, logs); err != nil {
+		t.Fatalf("Failed to insert logs: %v", err)
+	}
+	// stored again by a re-run over the same blocks
+	if err := logRepo.BatchInsert(ctx, logs[:1]); err != nil {
+		t.Fatalf("Failed to insert logs again: %v", err)
+	}
+
+	// uppercase hex as pasted from some explorers
+	got, err := logRepo.GetByTxHash(ctx, "0x"+strings.ToUpper(txHash[2:]))
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 logs, got %d", len(got))
+	}
+	if got[0].LogIndex != 3 || got[1].LogIndex != 7 {
+		t.Errorf("Expected logs in log index order 3, 7, got %d, %d", got[0].LogIndex, got[1].LogIndex)
+	}
+	if got[0].EventName != nil || got[0].DecodedData != nil {
+		t.Errorf("Expected unknown event undecoded, got %v %s", got[0].EventName, got[0].DecodedData)
+	}
+	decoded := got[1]
+	if decoded.EventName == nil || *decoded.EventName != "Transfer" {
+		t.Errorf("Expected event Transfer, got %v", decoded.EventName)
+	}
+	if decoded.Address != "0xdac17f958d2ee523a2206206994597c13d831ec7" {
+		t.Errorf("Expected lowercase address, got %s", decoded.Address)
+	}
+	if !reflect.DeepEqual([]string(decoded.Topics), transfer.Topics) {
+		t.Errorf("Expected topics %v, got %v", transfer.Topics, decoded.Topics)
+	}
+	var fields map[string]string
+	if err := json.Unmarshal(decoded.DecodedData, &fields); err != nil || fields["value"] != "1000" {
+		t.Errorf("Expected decoded value 1000, got %s (%v)", decoded.DecodedData, err)
+	}
+
+	none, err := logRepo.GetByTxHash(ctx, "0xnologs")
+	if err != nil {
+		t.Fatalf("Failed to get logs: %v", err)
+	}
+	if none == nil || len(none) != 0 {
+		t.Errorf("Expected an empty slice for a tx without logs, got %v", none)
+	}
+
+	// deleting the transaction deletes its logs
+	if _, err := NewTransactionRepository(dm, nil).DeleteByHash(ctx, txHash); err != nil {
+		t.Fatalf("Failed to delete transaction: %v", err)
+	}
+	if got, _ := logRepo.GetByTxHash(ctx, txHash); len(got) != 0 {
+		t.Errorf("Expected logs deleted with the transaction, got %d", len(got))
+	}
+}
+
+// TestDeleteKeepsTransactionsWhenLogsFail tests that the transaction and logs deletes commit together:
+// a failing logs delete leaves the transactions in place instead of orphaning the logs
+func TestDeleteKeepsTransactionsWhenLogsFail(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		delete func(tr *TransactionRepository) error
+	}{
+		{"DeleteByHash", func(tr *TransactionRepository) error {
+			_, err := tr.DeleteByHash(ctx, "0xa")
+			return err
+		}},
+		{"DeleteByBlockRange", func(tr *TransactionRepository) error {
+			_, err := tr.DeleteByBlockRange(ctx, 1, 10)
+			return err
+		}},
+		{"ClearOldTxns", func(tr *TransactionRepository) error {
+			return tr.ClearOldTxns(ctx, 1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dm := newTestDatabase(t)
+			txRepo := NewTransactionRepository(dm, nil)
+			old := time.Now().AddDate(0, 0, -7)
+			if err := txRepo.BatchInsert(ctx, []*Transaction{{TxHash: "0xa", BlockNumber: 5, FromAddress: "0x1", CreatedAt: old}}); err != nil {
+				t.Fatalf("Failed to insert transaction: %v", err)
+			}
+
+			db, err := dm.DB()
+			if err != nil {
+				t.Fatalf("Failed to get database connection: %v", err)
+			}
+			if _, err := db.Exec("DROP TABLE logs"); err != nil {
+				t.Fatalf("Failed to drop logs: %v", err)
+			}
+
+			if err := tt.delete(txRepo); err == nil {
+				t.Fatal("Expected an error without the logs table")
+			}
+			var count int
+			if err := db.Get(&count, "SELECT COUNT(*) FROM transactions"); err != nil {
+				t.Fatalf("Failed to count transactions: %v", err)
+			}
+			if count != 1 {
+				t.Errorf("Expected the transaction kept, got %d rows", count)
+			}
+		})
+	}
+}
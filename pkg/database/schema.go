@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -20,6 +21,9 @@ func NewSchema(logger *log.Logger) *Schema {
 	return &Schema{logger: logger}
 }
 
+// RequiredTables are the tables created by CreateAllTables, checked with DatabaseManager.CheckSchema
+var RequiredTables = []string{"transactions", "whale_addresses", "blocks", "parse_errors", "settings", "meta", "logs"}
+
 // CreateAllTables creates all required tables
 func (s *Schema) CreateAllTables(db *sqlx.DB) error {
 	tables := []struct {
@@ -28,6 +32,11 @@ func (s *Schema) CreateAllTables(db *sqlx.DB) error {
 	}{
 		{"transactions", s.transactionsTableSchema()},
 		{"whale_addresses", s.whaleAddressesTableSchema()},
+		{"blocks", s.blocksTableSchema()},
+		{"parse_errors", s.parseErrorsTableSchema()},
+		{"settings", s.settingsTableSchema()},
+		{"meta", s.metaTableSchema()},
+		{"logs", s.logsTableSchema()},
 	}
 
 	for _, table := range tables {
@@ -38,12 +47,26 @@ func (s *Schema) CreateAllTables(db *sqlx.DB) error {
 		s.logger.Printf("Successfully created table: %s", table.name)
 	}
 
+	if err := s.Upgrade(db); err != nil {
+		return err
+	}
+
+	s.logger.Println("Database schema created successfully")
+	return nil
+}
+
+// Upgrade brings existing tables to the current version: migrations, then indexes added since.
+// Enough on start when every table exists, see DatabaseManager.CheckSchema.
+func (s *Schema) Upgrade(db *sqlx.DB) error {
+	// Upgrade tables created by older versions, before indexes since a migration may rebuild a table
+	if err := s.Migrate(db); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
 	// Create indexes after tables
 	if err := s.createIndexes(db); err != nil {
 		return fmt.Errorf("failed to create indexes: %w", err)
 	}
-
-	s.logger.Println("Database schema created successfully")
 	return nil
 }
 
@@ -58,24 +81,248 @@ func (s *Schema) transactionsTableSchema() string {
 		transaction_index INTEGER NOT NULL,
 		from_address TEXT NOT NULL,
 		to_address TEXT,
-		whale_address_id INTEGER NOT NULL,
+		whale_address_id INTEGER,
 		transfer_type TEXT NOT NULL DEFAULT '',
 		value DECIMAL(10,5) NOT NULL DEFAULT '0',
 		gas INTEGER NOT NULL,
 		gas_price TEXT NOT NULL DEFAULT '0',
 		gas_used INTEGER,
 		status INTEGER,
+		receipt_fetched BOOLEAN NOT NULL DEFAULT FALSE,
 		nonce INTEGER NOT NULL,
 		input_data TEXT,
 		tx_type INTEGER NOT NULL DEFAULT 0,
 		max_fee_per_gas TEXT,
 		max_priority_fee TEXT,
+		contract_address TEXT,
+		asset TEXT NOT NULL DEFAULT 'ETH',
+		token_address TEXT,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (whale_address_id) REFERENCES whale_addresses(id) ON DELETE CASCADE
 	);`
 }
 
+// Migrate applies schema migrations to tables created by older versions.
+// Every migration checks whether it is needed, so Migrate is safe to run on each start.
+func (s *Schema) Migrate(db *sqlx.DB) error {
+	migrations := []struct {
+		name  string
+		apply func(db *sqlx.DB) (bool, error)
+	}{
+		// before the table rebuild of nullable_whale_address_id, which copies existing columns only
+		{"receipt_fetched", s.migrateReceiptFetched},
+		{"nullable_whale_address_id", s.migrateNullableWhaleAddressID},
+		{"whale_min_eth", s.migrateWhaleMinETH},
+		{"contract_address", s.migrateContractAddress},
+		{"asset", s.migrateAsset},
+		{"lowercase_addresses", s.migrateLowercaseAddresses},
+	}
+
+	for _, m := range migrations {
+		applied, err := m.apply(db)
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.name, err)
+		}
+		if applied {
+			s.logger.Printf("Applied migration: %s", m.name)
+		}
+	}
+	return nil
+}
+
+// migrateReceiptFetched adds transactions.receipt_fetched. Older versions stored status only
+// from a receipt (NULL otherwise), so rows with a status are marked as fetched.
+func (s *Schema) migrateReceiptFetched(db *sqlx.DB) (bool, error) {
+	var exists int
+	if err := db.Get(&exists, "SELECT COUNT(*) FROM pragma_table_info('transactions') WHERE name = 'receipt_fetched'"); err != nil {
+		return false, fmt.Errorf("failed to read transactions columns: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"ALTER TABLE transactions ADD COLUMN receipt_fetched BOOLEAN NOT NULL DEFAULT FALSE",
+		"UPDATE transactions SET receipt_fetched = TRUE WHERE status IS NOT NULL",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return false, fmt.Errorf("failed to add receipt_fetched: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// migrateNullableWhaleAddressID drops the NOT NULL constraint from transactions.whale_address_id.
+// SQLite can't alter a column, so the table is rebuilt. Rows without a transfer type were not
+// matched to a whale and got whale_address_id 1 by default, they are reset to NULL.
+func (s *Schema) migrateNullableWhaleAddressID(db *sqlx.DB) (bool, error) {
+	var columns []struct {
+		CID        int     `db:"cid"`
+		Name       string  `db:"name"`
+		Type       string  `db:"type"`
+		NotNull    bool    `db:"notnull"`
+		Default    *string `db:"dflt_value"`
+		PrimaryKey int     `db:"pk"`
+	}
+	if err := db.Select(&columns, "PRAGMA table_info(transactions)"); err != nil {
+		return false, fmt.Errorf("failed to read transactions columns: %w", err)
+	}
+
+	needed := false
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, col.Name)
+		if col.Name == "whale_address_id" && col.NotNull {
+			needed = true
+		}
+	}
+	if !needed {
+		return false, nil
+	}
+
+	newTable := strings.Replace(s.transactionsTableSchema(), "EXISTS transactions (", "EXISTS transactions_new (", 1)
+	columnList := strings.Join(names, ", ")
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		newTable,
+		fmt.Sprintf("INSERT INTO transactions_new (%s) SELECT %s FROM transactions", columnList, columnList),
+		"DROP TABLE transactions",
+		"ALTER TABLE transactions_new RENAME TO transactions",
+		"UPDATE transactions SET whale_address_id = NULL WHERE transfer_type = ''",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return false, fmt.Errorf("failed to rebuild transactions table: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// migrateWhaleMinETH adds whale_addresses.min_eth, existing whales keep the global threshold (NULL)
+func (s *Schema) migrateWhaleMinETH(db *sqlx.DB) (bool, error) {
+	var exists int
+	if err := db.Get(&exists, "SELECT COUNT(*) FROM pragma_table_info('whale_addresses') WHERE name = 'min_eth'"); err != nil {
+		return false, fmt.Errorf("failed to read whale_addresses columns: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+	if _, err := db.Exec("ALTER TABLE whale_addresses ADD COLUMN min_eth TEXT"); err != nil {
+		return false, fmt.Errorf("failed to add min_eth: %w", err)
+	}
+	return true, nil
+}
+
+// migrateContractAddress adds transactions.contract_address, stored creations stay NULL until reparsed
+func (s *Schema) migrateContractAddress(db *sqlx.DB) (bool, error) {
+	var exists int
+	if err := db.Get(&exists, "SELECT COUNT(*) FROM pragma_table_info('transactions') WHERE name = 'contract_address'"); err != nil {
+		return false, fmt.Errorf("failed to read transactions columns: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+	if _, err := db.Exec("ALTER TABLE transactions ADD COLUMN contract_address TEXT"); err != nil {
+		return false, fmt.Errorf("failed to add contract_address: %w", err)
+	}
+	return true, nil
+}
+
+// migrateAsset adds transactions.asset and token_address, stored rows are native ETH transfers
+func (s *Schema) migrateAsset(db *sqlx.DB) (bool, error) {
+	var exists int
+	if err := db.Get(&exists, "SELECT COUNT(*) FROM pragma_table_info('transactions') WHERE name = 'asset'"); err != nil {
+		return false, fmt.Errorf("failed to read transactions columns: %w", err)
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		"ALTER TABLE transactions ADD COLUMN asset TEXT NOT NULL DEFAULT 'ETH'",
+		"ALTER TABLE transactions ADD COLUMN token_address TEXT",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return false, fmt.Errorf("failed to add asset columns: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// lowercaseAddressesMigrated is the meta key recording that stored addresses were lowercased
+const lowercaseAddressesMigrated = "migration:lowercase_addresses"
+
+// migrateLowercaseAddresses lowercases addresses stored before repositories normalized them, so
+// lookups with types.NormalizeAddress find them. It scans whole tables, the meta table records that
+// it ran. A whale address whose lowercase form is already stored is left as is (UNIQUE address).
+func (s *Schema) migrateLowercaseAddresses(db *sqlx.DB) (bool, error) {
+	var done int
+	if err := db.Get(&done, "SELECT COUNT(*) FROM meta WHERE key = ?", lowercaseAddressesMigrated); err != nil {
+		return false, fmt.Errorf("failed to read meta: %w", err)
+	}
+	if done > 0 {
+		return false, nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`UPDATE transactions SET from_address = LOWER(from_address), to_address = LOWER(to_address),
+			contract_address = LOWER(contract_address), token_address = LOWER(token_address)
+		 WHERE from_address != LOWER(from_address) OR to_address != LOWER(to_address)
+			OR contract_address != LOWER(contract_address) OR token_address != LOWER(token_address)`,
+		"UPDATE OR IGNORE whale_addresses SET address = LOWER(address) WHERE address != LOWER(address)",
+		"INSERT INTO meta (key, value) VALUES ('" + lowercaseAddressesMigrated + "', '1')",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return false, fmt.Errorf("failed to lowercase addresses: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
 // addressesTableSchema returns the SQL for creating the addresses table
 func (s *Schema) whaleAddressesTableSchema() string {
 	return `
@@ -84,11 +331,84 @@ func (s *Schema) whaleAddressesTableSchema() string {
 		address TEXT NOT NULL UNIQUE,
 		label TEXT,
 		is_watched BOOLEAN NOT NULL DEFAULT TRUE,
+		min_eth TEXT, -- whale threshold in ETH as a decimal string, NULL = global min ETH value
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+}
+
+// blocksTableSchema returns the SQL for creating the blocks table
+func (s *Schema) blocksTableSchema() string {
+	return `
+	CREATE TABLE IF NOT EXISTS blocks (
+		number INTEGER PRIMARY KEY,
+		hash TEXT NOT NULL,
+		parent_hash TEXT NOT NULL,
+		timestamp DATETIME NOT NULL,
+		gas_used INTEGER NOT NULL DEFAULT 0,
+		gas_limit INTEGER NOT NULL DEFAULT 0,
+		gas_used_ratio REAL NOT NULL DEFAULT 0,
+		base_fee_per_gas TEXT,
+		tx_count INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+}
+
+// parseErrorsTableSchema returns the SQL for creating the dead-letter table of transactions that failed to parse
+func (s *Schema) parseErrorsTableSchema() string {
+	return `
+	CREATE TABLE IF NOT EXISTS parse_errors (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tx_hash TEXT NOT NULL UNIQUE,
+		block_number INTEGER NOT NULL,
+		transaction_index INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);`
 }
 
+// settingsTableSchema returns the SQL for creating the key-value table of runtime-tunable settings
+func (s *Schema) settingsTableSchema() string {
+	return `
+	CREATE TABLE IF NOT EXISTS settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+}
+
+// metaTableSchema returns the SQL for creating the key-value table of internal state (e.g. RPC
+// request counters), kept apart from settings so it is not exposed by /api/settings
+func (s *Schema) metaTableSchema() string {
+	return `
+	CREATE TABLE IF NOT EXISTS meta (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);`
+}
+
+// logsTableSchema returns the SQL for creating the event logs table of stored transactions,
+// topics is a JSON array and decoded_data a JSON object of the decoded event fields
+func (s *Schema) logsTableSchema() string {
+	return `
+	CREATE TABLE IF NOT EXISTS logs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		tx_hash TEXT NOT NULL,
+		log_index INTEGER NOT NULL,
+		block_number INTEGER NOT NULL,
+		address TEXT NOT NULL,
+		topics TEXT NOT NULL DEFAULT '[]',
+		data TEXT NOT NULL DEFAULT '',
+		event_name TEXT,
+		decoded_data TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(tx_hash, log_index)
+	);`
+}
+
 // createIndexes creates all necessary indexes for performance
 func (s *Schema) createIndexes(db *sqlx.DB) error {
 	indexes := []struct {
@@ -100,6 +420,19 @@ func (s *Schema) createIndexes(db *sqlx.DB) error {
 		{"idx_transactions_to", "CREATE INDEX IF NOT EXISTS idx_transactions_to ON transactions(to_address);"},
 		{"idx_transactions_value", "CREATE INDEX IF NOT EXISTS idx_transactions_value ON transactions(value);"},
 		{"idx_transactions_tr_type", "CREATE INDEX IF NOT EXISTS idx_transactions_tr_type ON transactions(transfer_type);"},
+		// recent txs of a transfer type, transaction_index keeps ORDER BY fully covered
+		{"idx_transactions_type_block", "CREATE INDEX IF NOT EXISTS idx_transactions_type_block ON transactions(transfer_type, block_number DESC, transaction_index DESC);"},
+		// txs waiting for receipts, partial so enriched rows don't grow it
+		{"idx_transactions_missing_receipts", "CREATE INDEX IF NOT EXISTS idx_transactions_missing_receipts ON transactions(block_number DESC, transaction_index DESC) WHERE receipt_fetched = FALSE;"},
+
+		// contract creations, partial since most txs don't create a contract
+		{"idx_transactions_contract", "CREATE INDEX IF NOT EXISTS idx_transactions_contract ON transactions(contract_address) WHERE contract_address IS NOT NULL;"},
+
+		// Parse error indexes
+		{"idx_parse_errors_block", "CREATE INDEX IF NOT EXISTS idx_parse_errors_block ON parse_errors(block_number);"},
+
+		// logs of reorged blocks are deleted by block range
+		{"idx_logs_block", "CREATE INDEX IF NOT EXISTS idx_logs_block ON logs(block_number);"},
 
 		// Address indexes
 		{"idx_addresses_address", "CREATE INDEX IF NOT EXISTS idx_addresses_address ON whale_addresses(address);"},
@@ -121,6 +454,11 @@ func (s *Schema) DropAllTables(db *sqlx.DB) error {
 	tables := []string{
 		"transactions",
 		"whale_addresses",
+		"blocks",
+		"parse_errors",
+		"settings",
+		"meta",
+		"logs",
 	}
 
 	for _, table := range tables {
@@ -178,3 +516,69 @@ func (s *Schema) GetTableInfo(db *sqlx.DB) (map[string]interface{}, error) {
 
 	return info, nil
 }
+
+// SchemaInfo describes the tables and indexes of the database, built by GetSchemaInfo
+type SchemaInfo struct {
+	Tables    []TableInfo `json:"tables"`
+	Indexes   []IndexInfo `json:"indexes"`
+	SizeBytes int64       `json:"size_bytes"`
+}
+
+// TableInfo describes a table with its columns in declaration order
+type TableInfo struct {
+	Name     string       `json:"name"`
+	Columns  []ColumnInfo `json:"columns"`
+	RowCount int64        `json:"row_count"`
+}
+
+// ColumnInfo describes a column as reported by PRAGMA table_info
+type ColumnInfo struct {
+	Name       string  `json:"name" db:"name"`
+	Type       string  `json:"type" db:"type"`
+	NotNull    bool    `json:"not_null" db:"notnull"`
+	Default    *string `json:"default" db:"dflt_value"`
+	PrimaryKey int     `json:"primary_key" db:"pk"` // position in the primary key, 0 = not part of it
+}
+
+// IndexInfo describes an index, SQL is nil for indexes SQLite creates for UNIQUE/PRIMARY KEY constraints
+type IndexInfo struct {
+	Name  string  `json:"name" db:"name"`
+	Table string  `json:"table" db:"tbl_name"`
+	SQL   *string `json:"sql" db:"sql"`
+}
+
+// GetSchemaInfo returns the typed schema description: tables (without SQLite internal ones) with
+// columns and row counts, indexes and the database size
+func (s *Schema) GetSchemaInfo(db *sqlx.DB) (*SchemaInfo, error) {
+	var tableNames []string
+	if err := db.Select(&tableNames, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"); err != nil {
+		return nil, fmt.Errorf("failed to get table list: %w", err)
+	}
+
+	info := &SchemaInfo{Tables: make([]TableInfo, 0, len(tableNames))}
+	for _, name := range tableNames {
+		table := TableInfo{Name: name}
+		query := `SELECT name, type, "notnull", dflt_value, pk FROM pragma_table_info(?) ORDER BY cid`
+		if err := db.Select(&table.Columns, query, name); err != nil {
+			return nil, fmt.Errorf("failed to get columns of %s: %w", name, err)
+		}
+		if err := db.Get(&table.RowCount, fmt.Sprintf("SELECT COUNT(*) FROM %q", name)); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", name, err)
+		}
+		info.Tables = append(info.Tables, table)
+	}
+
+	if err := db.Select(&info.Indexes, "SELECT name, tbl_name, sql FROM sqlite_master WHERE type = 'index' ORDER BY tbl_name, name"); err != nil {
+		return nil, fmt.Errorf("failed to get index list: %w", err)
+	}
+
+	var pageCount, pageSize int64
+	if err := db.Get(&pageCount, "PRAGMA page_count"); err != nil {
+		return nil, fmt.Errorf("failed to get page count: %w", err)
+	}
+	if err := db.Get(&pageSize, "PRAGMA page_size"); err != nil {
+		return nil, fmt.Errorf("failed to get page size: %w", err)
+	}
+	info.SizeBytes = pageCount * pageSize
+	return info, nil
+}
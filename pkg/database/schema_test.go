@@ -0,0 +1,465 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"eth-blockchain-parser/internal/types"
+)
+
+// TestMapperNonWhaleTransaction tests that a transaction mapped without whale params has no whale reference
+func TestMapperNonWhaleTransaction(t *testing.T) {
+	dm := newTestDatabase(t)
+	ctx := context.Background()
+
+	label := "Binance 7"
+	addrRepo := NewAddressRepository(dm, nil)
+	if err := addrRepo.BatchInsert(ctx, []*WhaleAddress{{Address: "0xwhale", Label: &label}}); err != nil {
+		t.Fatalf("Failed to insert whale address: %v", err)
+	}
+
+	to := "0xreceiver"
+	tx, err := MapParsedTxToDatabaseTx(&types.ParsedTransaction{
+		Hash:        "0xplain",
+		BlockNumber: 100,
+		From:        "0xsender",
+		To:          &to,
+		Value:       big.NewInt(1),
+		GasPrice:    big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatalf("Failed to map transaction: %v", err)
+	}
+	if tx.WhaleAddressID != nil {
+		t.Fatalf("Expected nil WhaleAddressID, got %d", *tx.WhaleAddressID)
+	}
+
+	txRepo := NewTransactionRepository(dm, nil)
+	if err := txRepo.BatchInsert(ctx, []*Transaction{tx}); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	got, err := txRepo.GetByHash(ctx, "0xplain")
+	if err != nil {
+		t.Fatalf("Failed to get transaction: %v", err)
+	}
+	if got.WhaleAddressID != nil {
+		t.Errorf("Expected stored transaction without whale, got whale ID %d", *got.WhaleAddressID)
+	}
+
+	count, err := txRepo.CountByWhaleIDs(ctx, []int64{1})
+	if err != nil {
+		t.Fatalf("Failed to count whale transactions: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no transactions for whale #1, got %d", count)
+	}
+}
+
+// TestMapperUnknownSender tests that the "unknown" sender placeholder is not stored as an address
+func TestMapperUnknownSender(t *testing.T) {
+	tx, err := MapParsedTxToDatabaseTx(&types.ParsedTransaction{Hash: "0xunknown", From: types.UnknownSender})
+	if err != nil {
+		t.Fatalf("Failed to map transaction: %v", err)
+	}
+	if tx.FromAddress != "" {
+		t.Errorf("Expected empty from address, got %q", tx.FromAddress)
+	}
+}
+
+// TestMapperReceiptStates tests that a failed tx, a successful tx and a tx without receipt stay distinguishable in DB
+func TestMapperReceiptStates(t *testing.T) {
+	dm := newTestDatabase(t)
+	ctx := context.Background()
+	txRepo := NewTransactionRepository(dm, nil)
+
+	tests := []struct {
+		name        string
+		parsed      types.ParsedTransaction
+		wantFetched bool
+		wantStatus  *int
+		wantGasUsed *int64
+	}{
+		{"Failed", types.ParsedTransaction{Hash: "0xfailed", Status: 0, GasUsed: 45000, ReceiptFetched: true}, true, intPtr(0), int64Ptr(45000)},
+		{"Success", types.ParsedTransaction{Hash: "0xsuccess", Status: 1, GasUsed: 21000, ReceiptFetched: true}, true, intPtr(1), int64Ptr(21000)},
+		{"Unfetched", types.ParsedTransaction{Hash: "0xunfetched"}, false, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := MapParsedTxToDatabaseTx(&tt.parsed)
+			if err != nil {
+				t.Fatalf("Failed to map transaction: %v", err)
+			}
+			if err := txRepo.BatchInsert(ctx, []*Transaction{tx}); err != nil {
+				t.Fatalf("Failed to insert transaction: %v", err)
+			}
+
+			got, err := txRepo.GetByHash(ctx, tt.parsed.Hash)
+			if err != nil {
+				t.Fatalf("Failed to get transaction: %v", err)
+			}
+			if got.ReceiptFetched != tt.wantFetched {
+				t.Errorf("Expected receipt_fetched %v, got %v", tt.wantFetched, got.ReceiptFetched)
+			}
+			if fmt.Sprint(deref(got.Status)) != fmt.Sprint(deref(tt.wantStatus)) {
+				t.Errorf("Expected status %v, got %v", deref(tt.wantStatus), deref(got.Status))
+			}
+			if fmt.Sprint(deref(got.GasUsed)) != fmt.Sprint(deref(tt.wantGasUsed)) {
+				t.Errorf("Expected gas_used %v, got %v", deref(tt.wantGasUsed), deref(got.GasUsed))
+			}
+		})
+	}
+}
+
+// TestMapperAsset tests that a native transfer is stored as ETH without token and a token transfer keeps both
+func TestMapperAsset(t *testing.T) {
+	dm := newTestDatabase(t)
+	ctx := context.Background()
+	txRepo := NewTransactionRepository(dm, nil)
+	usdc := "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48"
+
+	tests := []struct {
+		name             string
+		parsed           types.ParsedTransaction
+		wantAsset        string
+		wantTokenAddress *string
+	}{
+		{"ETH", types.ParsedTransaction{Hash: "0xeth", Value: big.NewInt(1)}, "ETH", nil},
+		{"USDC", types.ParsedTransaction{Hash: "0xusdc", Value: big.NewInt(0), Asset: "USDC", TokenAddress: &usdc}, "USDC", &usdc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := MapParsedTxToDatabaseTx(&tt.parsed)
+			if err != nil {
+				t.Fatalf("Failed to map transaction: %v", err)
+			}
+			if err := txRepo.BatchInsert(ctx, []*Transaction{tx}); err != nil {
+				t.Fatalf("Failed to insert transaction: %v", err)
+			}
+
+			got, err := txRepo.GetByHash(ctx, tt.parsed.Hash)
+			if err != nil || got == nil {
+				t.Fatalf("Failed to get transaction: %v", err)
+			}
+			if got.Asset != tt.wantAsset {
+				t.Errorf("Expected asset %q, got %q", tt.wantAsset, got.Asset)
+			}
+			if fmt.Sprint(deref(got.TokenAddress)) != fmt.Sprint(deref(tt.wantTokenAddress)) {
+				t.Errorf("Expected token_address %v, got %v", deref(tt.wantTokenAddress), deref(got.TokenAddress))
+			}
+		})
+	}
+}
+
+// TestMigrateAsset tests adding asset/token_address to an old table, stored rows become ETH transfers
+func TestMigrateAsset(t *testing.T) {
+	dm := newTestDatabase(t)
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+
+	schema := NewSchema(nil)
+	oldTable := strings.Replace(schema.transactionsTableSchema(), "asset TEXT NOT NULL DEFAULT 'ETH',\n\t\ttoken_address TEXT,", "", 1)
+	if strings.Contains(oldTable, "asset") {
+		t.Fatal("Expected asset columns removed from the old table schema")
+	}
+	setup := []string{
+		"DROP TABLE transactions",
+		oldTable,
+		`INSERT INTO transactions (tx_hash, block_number, transaction_index, from_address, gas, nonce)
+		 VALUES ('0xold', 1, 0, '0xa', 21000, 0)`,
+	}
+	for _, stmt := range setup {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up old schema: %v", err)
+		}
+	}
+
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to migrate schema: %v", err)
+	}
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to re-run migration: %v", err)
+	}
+
+	tx, err := NewTransactionRepository(dm, nil).GetByHash(context.Background(), "0xold")
+	if err != nil || tx == nil {
+		t.Fatalf("Expected the old transaction, got %v (%v)", tx, err)
+	}
+	if tx.Asset != types.NativeAsset || tx.TokenAddress != nil {
+		t.Errorf("Expected ETH without token, got %q %v", tx.Asset, tx.TokenAddress)
+	}
+}
+
+// TestMigrateReceiptFetched tests adding receipt_fetched to an old table, rows with a status count as fetched
+func TestMigrateReceiptFetched(t *testing.T) {
+	dm, err := NewDatabaseManager(InMemoryConfig(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer dm.Close()
+
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+
+	schema := NewSchema(nil)
+	oldTable := strings.Replace(schema.transactionsTableSchema(), "receipt_fetched BOOLEAN NOT NULL DEFAULT FALSE,", "", 1)
+	setup := []string{
+		schema.whaleAddressesTableSchema(),
+		oldTable,
+		`INSERT INTO transactions (tx_hash, block_number, transaction_index, from_address, gas, status, nonce)
+		 VALUES ('0xfailed', 1, 0, '0xa', 21000, 0, 0), ('0xunfetched', 1, 1, '0xb', 21000, NULL, 0)`,
+	}
+	for _, stmt := range setup {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up old schema: %v", err)
+		}
+	}
+
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to migrate schema: %v", err)
+	}
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to re-run migration: %v", err)
+	}
+
+	txRepo := NewTransactionRepository(dm, nil)
+	for hash, wantFetched := range map[string]bool{"0xfailed": true, "0xunfetched": false} {
+		tx, err := txRepo.GetByHash(context.Background(), hash)
+		if err != nil {
+			t.Fatalf("Failed to get transaction %s: %v", hash, err)
+		}
+		if tx.ReceiptFetched != wantFetched {
+			t.Errorf("Expected receipt_fetched %v for %s, got %v", wantFetched, hash, tx.ReceiptFetched)
+		}
+	}
+}
+
+// TestMigrateWhaleMinETH tests adding whale_addresses.min_eth to a table created without it
+func TestMigrateWhaleMinETH(t *testing.T) {
+	dm := newTestDatabase(t)
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+
+	schema := NewSchema(nil)
+	oldTable := strings.Replace(schema.whaleAddressesTableSchema(), "min_eth TEXT, -- whale threshold in ETH as a decimal string, NULL = global min ETH value", "", 1)
+	if strings.Contains(oldTable, "min_eth") {
+		t.Fatal("Expected min_eth removed from the old table schema")
+	}
+	setup := []string{
+		"DROP TABLE transactions",
+		"DROP TABLE whale_addresses",
+		oldTable,
+		"INSERT INTO whale_addresses (address, label) VALUES ('0xold', 'Old whale')",
+	}
+	for _, stmt := range setup {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up old schema: %v", err)
+		}
+	}
+
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to migrate schema: %v", err)
+	}
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to re-run migration: %v", err)
+	}
+
+	stored, err := NewAddressRepository(dm, nil).GetIdByAddress(context.Background(), "0xold")
+	if err != nil || len(stored) != 1 {
+		t.Fatalf("Expected the old whale, got %v (%v)", stored, err)
+	}
+	if stored[0].MinETH != nil {
+		t.Errorf("Expected no own threshold, got %s", *stored[0].MinETH)
+	}
+}
+
+// TestMigrateNullableWhaleAddressID tests upgrading a transactions table with NOT NULL whale_address_id
+func TestMigrateNullableWhaleAddressID(t *testing.T) {
+	dm, err := NewDatabaseManager(InMemoryConfig(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	defer dm.Close()
+
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+
+	schema := NewSchema(nil)
+	oldTable := strings.Replace(schema.transactionsTableSchema(), "whale_address_id INTEGER,", "whale_address_id INTEGER NOT NULL,", 1)
+	setup := []string{
+		schema.whaleAddressesTableSchema(),
+		oldTable,
+		"INSERT INTO whale_addresses (address) VALUES ('0xwhale')",
+		`INSERT INTO transactions (tx_hash, block_number, transaction_index, from_address, whale_address_id, transfer_type, gas, nonce)
+		 VALUES ('0xwhaletx', 1, 0, '0xwhale', 1, 'FROM', 21000, 0), ('0xplain', 1, 1, '0xother', 1, '', 21000, 0)`,
+	}
+	for _, stmt := range setup {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up old schema: %v", err)
+		}
+	}
+
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to migrate schema: %v", err)
+	}
+	// second run must be a no-op
+	if err := schema.CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to re-run migration: %v", err)
+	}
+
+	txRepo := NewTransactionRepository(dm, nil)
+	tests := []struct {
+		hash      string
+		wantWhale bool
+	}{
+		{"0xwhaletx", true},
+		{"0xplain", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			tx, err := txRepo.GetByHash(context.Background(), tt.hash)
+			if err != nil {
+				t.Fatalf("Failed to get transaction: %v", err)
+			}
+			if (tx.WhaleAddressID != nil) != tt.wantWhale {
+				t.Errorf("Expected whale reference %v, got %v", tt.wantWhale, tx.WhaleAddressID)
+			}
+		})
+	}
+}
+
+// TestGetSchemaInfo tests that the known tables, their columns, row counts and indexes are reported
+func TestGetSchemaInfo(t *testing.T) {
+	dm := newTestDatabase(t)
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if err := NewTransactionRepository(dm, nil).BatchInsert(context.Background(), []*Transaction{{TxHash: "0x1", Value: "1"}}); err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	info, err := NewSchema(nil).GetSchemaInfo(db)
+	if err != nil {
+		t.Fatalf("Failed to get schema info: %v", err)
+	}
+
+	tables := make(map[string]TableInfo)
+	for _, table := range info.Tables {
+		tables[table.Name] = table
+	}
+	if _, ok := tables["sqlite_sequence"]; ok {
+		t.Error("Expected SQLite internal tables to be skipped")
+	}
+
+	tests := []struct {
+		table    string
+		columns  []string
+		rowCount int64
+	}{
+		{"transactions", []string{"id", "tx_hash", "block_number", "value", "contract_address", "asset", "token_address"}, 1},
+		{"whale_addresses", []string{"id", "address", "label", "min_eth"}, 0},
+		{"blocks", []string{"number", "hash", "timestamp"}, 0},
+		{"parse_errors", nil, 0},
+		{"settings", []string{"key", "value", "updated_at"}, 0},
+		{"meta", []string{"key", "value", "updated_at"}, 1}, // lowercase_addresses migration record
+		{"logs", []string{"tx_hash", "log_index", "topics", "event_name", "decoded_data"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			table, ok := tables[tt.table]
+			if !ok {
+				t.Fatalf("Expected table %s, got %v", tt.table, info.Tables)
+			}
+			if table.RowCount != tt.rowCount {
+				t.Errorf("Expected %d rows, got %d", tt.rowCount, table.RowCount)
+			}
+			columns := make(map[string]ColumnInfo)
+			for _, col := range table.Columns {
+				columns[col.Name] = col
+			}
+			for _, name := range tt.columns {
+				if _, ok := columns[name]; !ok {
+					t.Errorf("Expected column %s, got %v", name, table.Columns)
+				}
+			}
+		})
+	}
+
+	txColumns := tables["transactions"].Columns
+	if txColumns[0].Name != "id" || txColumns[0].PrimaryKey != 1 || txColumns[0].Type != "INTEGER" {
+		t.Errorf("Expected INTEGER primary key id first, got %+v", txColumns[0])
+	}
+	for _, col := range txColumns {
+		if col.Name == "asset" && (!col.NotNull || col.Default == nil || *col.Default != "'ETH'") {
+			t.Errorf("Expected asset NOT NULL DEFAULT 'ETH', got %+v", col)
+		}
+	}
+
+	foundIndex := false
+	for _, idx := range info.Indexes {
+		if idx.Name == "idx_transactions_contract" && idx.Table == "transactions" && idx.SQL != nil {
+			foundIndex = true
+		}
+	}
+	if !foundIndex {
+		t.Errorf("Expected idx_transactions_contract, got %v", info.Indexes)
+	}
+	if info.SizeBytes <= 0 {
+		t.Errorf("Expected a positive database size, got %d", info.SizeBytes)
+	}
+}
+
+// TestMigrateLowercaseAddresses tests that addresses stored before normalization are lowercased once
+func TestMigrateLowercaseAddresses(t *testing.T) {
+	dm := newTestDatabase(t)
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+
+	setup := []string{
+		"DELETE FROM meta",
+		`INSERT INTO transactions (tx_hash, block_number, transaction_index, from_address, to_address, gas, nonce)
+		 VALUES ('0xold', 1, 0, '0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B', '0xdAC17F958D2ee523a2206206994597C13D831ec7', 21000, 0)`,
+		"INSERT INTO whale_addresses (address, label) VALUES ('0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B', 'Old whale')",
+	}
+	for _, stmt := range setup {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("Failed to set up old rows: %v", err)
+		}
+	}
+
+	schema := NewSchema(nil)
+	applied, err := schema.migrateLowercaseAddresses(db)
+	if err != nil || !applied {
+		t.Fatalf("Expected the migration to apply, got %v (err %v)", applied, err)
+	}
+	if applied, err := schema.migrateLowercaseAddresses(db); err != nil || applied {
+		t.Errorf("Expected the migration to run once, got applied %v (err %v)", applied, err)
+	}
+
+	tx, err := NewTransactionRepository(dm, nil).GetByHash(context.Background(), "0xold")
+	if err != nil || tx == nil {
+		t.Fatalf("Failed to get transaction: %v", err)
+	}
+	if tx.FromAddress != "0xab5801a7d398351b8be11c439e05c5b3259aec9b" || *tx.ToAddress != "0xdac17f958d2ee523a2206206994597c13d831ec7" {
+		t.Errorf("Expected lowercase addresses, got %s -> %s", tx.FromAddress, *tx.ToAddress)
+	}
+	if whales, err := NewAddressRepository(dm, nil).GetIdByAddress(context.Background(), "0xAb5801a7D398351b8bE11C439e05C5B3259aeC9B"); err != nil || len(whales) != 1 {
+		t.Errorf("Expected the lowercased whale, got %v (err %v)", whales, err)
+	}
+}
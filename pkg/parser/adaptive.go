@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"context"
+	"sync"
+)
+
+// aimdLimiter limits concurrent block fetches with additive increase / multiplicative decrease:
+// every successful fetch grows the limit by 1/limit (about +1 per round of limit fetches),
+// a rate limited fetch halves it. The limit stays within [min, max].
+//
+// Fetches started before the last decrease belong to an older epoch; their rate limit errors
+// were caused by the old, higher concurrency and don't halve the limit again.
+type aimdLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	min, max float64
+	inFlight int
+	epoch    uint64
+	changed  chan struct{} // closed and replaced when a slot may have freed up
+}
+
+// newAIMDLimiter creates a limiter starting at min concurrency, so a backfill ramps up gradually
+func newAIMDLimiter(min, max int) *aimdLimiter {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return &aimdLimiter{
+		limit:   float64(min),
+		min:     float64(min),
+		max:     float64(max),
+		changed: make(chan struct{}),
+	}
+}
+
+// Acquire waits for a free slot and returns the epoch to pass to Release
+func (l *aimdLimiter) Acquire(ctx context.Context) (uint64, error) {
+	for {
+		l.mu.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			epoch := l.epoch
+			l.mu.Unlock()
+			return epoch, nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+}
+
+// Release frees a slot and adjusts the limit by the fetch outcome
+func (l *aimdLimiter) Release(epoch uint64, rateLimited bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+	switch {
+	case rateLimited && epoch == l.epoch:
+		l.limit /= 2
+		if l.limit < l.min {
+			l.limit = l.min
+		}
+		l.epoch++
+	case !rateLimited:
+		l.limit += 1 / l.limit
+		if l.limit > l.max {
+			l.limit = l.max
+		}
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// Limit returns the current concurrency limit
+func (l *aimdLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// mockThrottledClient returns 429 for block fetches above a concurrency threshold
+type mockThrottledClient struct {
+	mockBlockClient
+	threshold   int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+	rejected    atomic.Int32
+}
+
+func (m *mockThrottledClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*gethTypes.Block, error) {
+	n := m.inFlight.Add(1)
+	defer m.inFlight.Add(-1)
+	for {
+		seen := m.maxInFlight.Load()
+		if n <= seen || m.maxInFlight.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	if n > m.threshold {
+		m.rejected.Add(1)
+		return nil, errors.New("429 Too Many Requests")
+	}
+	return m.mockBlockClient.GetBlockByNumber(ctx, blockNumber)
+}
+
+// runFetches acquires and releases the limiter count times sequentially with the given outcome
+func runFetches(t *testing.T, l *aimdLimiter, count int, rateLimited bool) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		epoch, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		l.Release(epoch, rateLimited)
+	}
+}
+
+// TestAIMDLimiter tests the ramp up, the halving on a rate limit and the recovery afterwards
+func TestAIMDLimiter(t *testing.T) {
+	l := newAIMDLimiter(1, 8)
+	if l.Limit() != 1 {
+		t.Fatalf("Expected start at min 1, got %d", l.Limit())
+	}
+
+	runFetches(t, l, 20, false)
+	ramped := l.Limit()
+	if ramped < 5 {
+		t.Fatalf("Expected limit to ramp up to at least 5, got %d", ramped)
+	}
+
+	// two fetches in flight when the provider starts rejecting: only the first one halves the limit
+	first, _ := l.Acquire(context.Background())
+	second, _ := l.Acquire(context.Background())
+	l.Release(first, true)
+	backedOff := l.Limit()
+	if backedOff != ramped/2 && backedOff != (ramped+1)/2 {
+		t.Errorf("Expected limit halved from %d, got %d", ramped, backedOff)
+	}
+	l.Release(second, true)
+	if l.Limit() != backedOff {
+		t.Errorf("Expected stale rate limit to keep limit %d, got %d", backedOff, l.Limit())
+	}
+
+	runFetches(t, l, 40, false)
+	if l.Limit() <= backedOff {
+		t.Errorf("Expected limit to recover above %d, got %d", backedOff, l.Limit())
+	}
+	if l.Limit() > 8 {
+		t.Errorf("Expected limit capped at max 8, got %d", l.Limit())
+	}
+
+	runFetches(t, l, 10, true)
+	if l.Limit() != 1 {
+		t.Errorf("Expected limit floored at min 1, got %d", l.Limit())
+	}
+}
+
+// TestAIMDLimiterAcquireCanceled tests that a waiting Acquire returns when the context is canceled
+func TestAIMDLimiterAcquireCanceled(t *testing.T) {
+	l := newAIMDLimiter(1, 1)
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+// TestParseBlockRangeAdaptive tests that a backfill against a throttling provider backs off,
+// retries rejected blocks and still parses the whole range
+func TestParseBlockRangeAdaptive(t *testing.T) {
+	tests := []struct {
+		name         string
+		adaptive     bool
+		wantAllParse bool
+	}{
+		{"Adaptive", true, true},
+		{"Fixed workers", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockThrottledClient{threshold: 3}
+			p := newTestParser(mock, func(c *types.Config) {
+				c.Workers = 8
+				c.MinWorkers = 1
+				c.AdaptiveWorkers = tt.adaptive
+			})
+
+			blocks, err := p.ParseBlockRange(context.Background(), 0, 99)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if allParsed := len(blocks) == 100; allParsed != tt.wantAllParse {
+				t.Errorf("Expected all blocks parsed %v, got %d blocks", tt.wantAllParse, len(blocks))
+			}
+
+			stats := p.GetStats()
+			if !tt.adaptive {
+				if mock.rejected.Load() == 0 {
+					t.Error("Expected fixed workers to trip the provider limit")
+				}
+				return
+			}
+			if stats.RateLimitHits == 0 || stats.RateLimitHits != uint64(mock.rejected.Load()) {
+				t.Errorf("Expected rate limit hits to match %d rejections, got %d", mock.rejected.Load(), stats.RateLimitHits)
+			}
+			if stats.ErrorsEncountered != 0 {
+				t.Errorf("Expected no failed blocks, got %d", stats.ErrorsEncountered)
+			}
+			if mock.maxInFlight.Load() <= 1 {
+				t.Errorf("Expected concurrency to ramp above min, got max %d in flight", mock.maxInFlight.Load())
+			}
+			if stats.WorkerLimit < 1 || stats.WorkerLimit > 8 {
+				t.Errorf("Expected worker limit within [1, 8], got %d", stats.WorkerLimit)
+			}
+		})
+	}
+}
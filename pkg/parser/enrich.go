@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"eth-blockchain-parser/pkg/database"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// ReceiptFetcher fetches transaction receipts in batch, implemented by client.EthClient
+type ReceiptFetcher interface {
+	GetTransactionReceiptsBatch(ctx context.Context, txHashes []common.Hash) ([]*gethTypes.Receipt, error)
+}
+
+// Enricher fills receipt data (gas_used, status) for transactions already stored in the DB.
+// Receipts are usually skipped during parsing to stay within Infura limits, this pass fetches them later.
+type Enricher struct {
+	fetcher ReceiptFetcher
+	txRepo  *database.TransactionRepository
+}
+
+// NewEnricher creates a new receipt enricher
+func NewEnricher(fetcher ReceiptFetcher, txRepo *database.TransactionRepository) *Enricher {
+	return &Enricher{
+		fetcher: fetcher,
+		txRepo:  txRepo,
+	}
+}
+
+// EnrichTransactions fetches receipts for txs and updates the stored rows.
+// Transactions without a receipt (pending or unknown) are left unchanged.
+// Returns the number of updated transactions.
+func (e *Enricher) EnrichTransactions(ctx context.Context, txs []*database.Transaction) (int, error) {
+	if len(txs) == 0 {
+		return 0, nil
+	}
+
+	enriched, err := e.FetchReceipts(ctx, txs)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := e.txRepo.UpdateReceiptData(ctx, enriched); err != nil {
+		return 0, fmt.Errorf("failed to update enriched transactions: %w", err)
+	}
+
+	log.Printf("Enriched %d of %d transactions with receipt data", len(enriched), len(txs))
+	return len(enriched), nil
+}
+
+// FetchReceipts fetches receipts for the txs without receipt data and fills gas_used/status in place,
+// nothing is written to the DB. Returns the updated transactions. Used on matched whale txs before
+// they are stored, so a block whose receipts were skipped costs one receipt call per whale tx only.
+func (e *Enricher) FetchReceipts(ctx context.Context, txs []*database.Transaction) ([]*database.Transaction, error) {
+	var missing []*database.Transaction
+	var txHashes []common.Hash
+	for _, tx := range txs {
+		if tx.ReceiptFetched {
+			continue
+		}
+		missing = append(missing, tx)
+		txHashes = append(txHashes, common.HexToHash(tx.TxHash))
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	receipts, err := e.fetcher.GetTransactionReceiptsBatch(ctx, txHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction receipts: %w", err)
+	}
+
+	var fetched []*database.Transaction
+	for i, tx := range missing {
+		if i >= len(receipts) || receipts[i] == nil {
+			log.Printf("No receipt for tx %s, skipping", tx.TxHash)
+			continue
+		}
+		applyReceipt(tx, receipts[i])
+		fetched = append(fetched, tx)
+	}
+	return fetched, nil
+}
+
+// applyReceipt copies receipt fields to the database transaction, including the deployed contract of a creation
+func applyReceipt(tx *database.Transaction, receipt *gethTypes.Receipt) {
+	gasUsed := int64(receipt.GasUsed)
+	status := int(receipt.Status)
+	tx.GasUsed = &gasUsed
+	tx.Status = &status
+	tx.ReceiptFetched = true
+	if receipt.ContractAddress != (common.Address{}) {
+		contractAddr := receipt.ContractAddress.Hex()
+		tx.ContractAddress = &contractAddr
+	}
+}
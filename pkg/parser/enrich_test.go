@@ -0,0 +1,219 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+)
+
+// mockReceiptFetcher returns receipts from a map keyed by tx hash and records the requested hashes
+type mockReceiptFetcher struct {
+	receipts  map[common.Hash]*gethTypes.Receipt
+	err       error
+	mu        sync.Mutex // guards requested, mockBlockClient embeds the fetcher and is called by receipt workers
+	requested []common.Hash
+}
+
+func (m *mockReceiptFetcher) GetTransactionReceiptsBatch(ctx context.Context, txHashes []common.Hash) ([]*gethTypes.Receipt, error) {
+	m.mu.Lock()
+	m.requested = append(m.requested, txHashes...)
+	m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	receipts := make([]*gethTypes.Receipt, len(txHashes))
+	for i, h := range txHashes {
+		receipts[i] = m.receipts[h]
+	}
+	return receipts, nil
+}
+
+// newTestTxRepo creates an in-memory DB with one whale and the given stored transactions
+func newTestTxRepo(t *testing.T, hashes ...string) *database.TransactionRepository {
+	t.Helper()
+	ctx := context.Background()
+
+	dm, err := database.NewDatabaseManager(database.InMemoryConfig(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if err := database.NewSchema(nil).CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	label := "Whale"
+	if err := database.NewAddressRepository(dm, nil).BatchInsert(ctx, []*database.WhaleAddress{{Address: "0xwhale", Label: &label}}); err != nil {
+		t.Fatalf("Failed to insert whale: %v", err)
+	}
+
+	txRepo := database.NewTransactionRepository(dm, nil)
+	var txs []*database.Transaction
+	for i, h := range hashes {
+		txs = append(txs, &database.Transaction{
+			TxHash:         h,
+			BlockNumber:    int64(100 + i),
+			FromAddress:    "0xwhale",
+			WhaleAddressID: int64Ptr(1),
+			TransferType:   "FROM",
+			Value:          "2",
+		})
+	}
+	if err := txRepo.BatchInsert(ctx, txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+	return txRepo
+}
+
+// TestEnrichTransactions tests that receipt status/gas_used are written to stored rows
+func TestEnrichTransactions(t *testing.T) {
+	ctx := context.Background()
+	hashFound := common.HexToHash("0x01").Hex()
+	hashFailed := common.HexToHash("0x02").Hex()
+	hashMissing := common.HexToHash("0x03").Hex()
+	hashCreate := common.HexToHash("0x04").Hex()
+	contract := common.HexToAddress("0xc1")
+	txRepo := newTestTxRepo(t, hashFound, hashFailed, hashMissing, hashCreate)
+
+	fetcher := &mockReceiptFetcher{receipts: map[common.Hash]*gethTypes.Receipt{
+		common.HexToHash(hashFound):  {Status: gethTypes.ReceiptStatusSuccessful, GasUsed: 21000},
+		common.HexToHash(hashFailed): {Status: gethTypes.ReceiptStatusFailed, GasUsed: 45000},
+		common.HexToHash(hashCreate): {Status: gethTypes.ReceiptStatusSuccessful, GasUsed: 500000, ContractAddress: contract},
+	}}
+
+	stored, err := txRepo.GetRecent(ctx, 10)
+	if err != nil {
+		t.Fatalf("Failed to get stored transactions: %v", err)
+	}
+
+	updated, err := NewEnricher(fetcher, txRepo).EnrichTransactions(ctx, stored)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if updated != 3 {
+		t.Errorf("Expected 3 updated transactions, got %d", updated)
+	}
+
+	tests := []struct {
+		hash             string
+		expectedStatus   *int
+		expectedGas      *int64
+		expectedContract string
+	}{
+		{hash: hashFound, expectedStatus: intPtr(1), expectedGas: int64Ptr(21000)},
+		{hash: hashFailed, expectedStatus: intPtr(0), expectedGas: int64Ptr(45000)},
+		{hash: hashMissing, expectedStatus: nil, expectedGas: nil},
+		{hash: hashCreate, expectedStatus: intPtr(1), expectedGas: int64Ptr(500000), expectedContract: types.NormalizeAddress(contract.Hex())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			tx, err := txRepo.GetByHash(ctx, tt.hash)
+			if err != nil || tx == nil {
+				t.Fatalf("Failed to read back %s: %v", tt.hash, err)
+			}
+			if fmt.Sprint(deref(tx.Status)) != fmt.Sprint(deref(tt.expectedStatus)) {
+				t.Errorf("Expected status %v, got %v", deref(tt.expectedStatus), deref(tx.Status))
+			}
+			if fmt.Sprint(deref(tx.GasUsed)) != fmt.Sprint(deref(tt.expectedGas)) {
+				t.Errorf("Expected gas_used %v, got %v", deref(tt.expectedGas), deref(tx.GasUsed))
+			}
+			if fetched := tt.expectedStatus != nil; tx.ReceiptFetched != fetched {
+				t.Errorf("Expected receipt_fetched %v, got %v", fetched, tx.ReceiptFetched)
+			}
+			contractAddr := ""
+			if tx.ContractAddress != nil {
+				contractAddr = *tx.ContractAddress
+			}
+			if contractAddr != tt.expectedContract {
+				t.Errorf("Expected contract_address %q, got %q", tt.expectedContract, contractAddr)
+			}
+		})
+	}
+}
+
+// TestEnrichTransactionsFetchError tests that fetch errors are returned and nothing is updated
+func TestEnrichTransactionsFetchError(t *testing.T) {
+	ctx := context.Background()
+	hash := common.HexToHash("0x01").Hex()
+	txRepo := newTestTxRepo(t, hash)
+
+	stored, _ := txRepo.GetRecent(ctx, 10)
+	_, err := NewEnricher(&mockReceiptFetcher{err: fmt.Errorf("rate limit")}, txRepo).EnrichTransactions(ctx, stored)
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	tx, _ := txRepo.GetByHash(ctx, hash)
+	if tx.Status != nil {
+		t.Errorf("Expected status to stay NULL, got %d", *tx.Status)
+	}
+}
+
+// TestFetchReceiptsWhaleOnly tests that after filtering a block without receipts only the matched
+// whale txs are fetched, and txs that already have receipt data are not fetched again
+func TestFetchReceiptsWhaleOnly(t *testing.T) {
+	whale := "0x1234567890abcdef1234567890abcdef12345678"
+	other := "0x9999999999999999999999999999999999999999"
+	hashWhale := common.HexToHash("0x01")
+	hashOther := common.HexToHash("0x02")
+	hashFetched := common.HexToHash("0x03")
+	blocks := []*types.ParsedBlock{{
+		Number: 100,
+		Transactions: []*types.ParsedTransaction{
+			{Hash: hashWhale.Hex(), BlockNumber: 100, From: whale, To: &other, Value: big.NewInt(2e18)},
+			{Hash: hashOther.Hex(), BlockNumber: 100, From: other, To: &other, Value: big.NewInt(5e18)},
+			{Hash: hashFetched.Hex(), BlockNumber: 100, From: other, To: &whale, Value: big.NewInt(3e18),
+				ReceiptFetched: true, GasUsed: 21000, Status: 1},
+		},
+	}}
+	whales := filtering.ParseWhaleTransactionsWithFilter(blocks, map[string]string{whale: "1"}, filtering.NewWhaleFilter(types.DefaultConfig()))
+	if len(whales) != 2 {
+		t.Fatalf("Expected 2 whale txs, got %d", len(whales))
+	}
+
+	fetcher := &mockReceiptFetcher{receipts: map[common.Hash]*gethTypes.Receipt{
+		hashWhale: {Status: gethTypes.ReceiptStatusFailed, GasUsed: 30000},
+		hashOther: {Status: gethTypes.ReceiptStatusSuccessful, GasUsed: 21000},
+	}}
+	fetched, err := NewEnricher(fetcher, nil).FetchReceipts(context.Background(), whales)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(fetcher.requested, []common.Hash{hashWhale}) {
+		t.Errorf("Expected only the whale tx without receipt to be fetched, got %v", fetcher.requested)
+	}
+	if len(fetched) != 1 || fetched[0].TxHash != hashWhale.Hex() {
+		t.Fatalf("Expected the whale tx updated, got %v", fetched)
+	}
+	if !fetched[0].ReceiptFetched || deref(fetched[0].Status) != 0 || deref(fetched[0].GasUsed) != int64(30000) {
+		t.Errorf("Expected failed status and 30000 gas, got status %v gas %v", deref(fetched[0].Status), deref(fetched[0].GasUsed))
+	}
+}
+
+func intPtr(v int) *int       { return &v }
+func int64Ptr(v int64) *int64 { return &v }
+
+// deref returns the pointed value or "<nil>"
+func deref[T any](p *T) interface{} {
+	if p == nil {
+		return "<nil>"
+	}
+	return *p
+}
@@ -0,0 +1,48 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/client"
+)
+
+// FinalizedClient is a NetworkClient that can return the chain's finalized block, implemented by client.EthClient
+type FinalizedClient interface {
+	GetFinalizedBlockNumber(ctx context.Context) (uint64, error)
+}
+
+var _ FinalizedClient = (*client.EthClient)(nil)
+
+// TargetBlock returns the last block to parse: the latest block, or with Config.TargetFinalized the finalized one.
+// When c isn't a FinalizedClient or its provider doesn't support the finalized tag, the range ends at
+// the newest block with MinConfirmations confirmations instead (latest - MinConfirmations + 1).
+func TargetBlock(ctx context.Context, c NetworkClient, config *types.Config) (uint64, error) {
+	if config.TargetFinalized {
+		if fc, ok := c.(FinalizedClient); ok {
+			finalized, err := fc.GetFinalizedBlockNumber(ctx)
+			if err == nil {
+				return finalized, nil
+			}
+			if !errors.Is(err, client.ErrBlockTagUnsupported) {
+				return 0, fmt.Errorf("failed to get finalized block: %w", err)
+			}
+			log.Printf("Finalized block not available, falling back to %d confirmations: %v", config.MinConfirmations, err)
+		}
+	}
+
+	latest, err := c.GetLatestBlockNumber(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if !config.TargetFinalized || config.MinConfirmations <= 1 {
+		return latest, nil
+	}
+	if latest < config.MinConfirmations-1 {
+		return 0, nil
+	}
+	return latest - (config.MinConfirmations - 1), nil
+}
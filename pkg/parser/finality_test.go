@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/client"
+)
+
+// mockFinalizedClient is a mockNetworkClient whose provider returns a finalized block
+type mockFinalizedClient struct {
+	mockNetworkClient
+	finalized    uint64
+	finalizedErr error
+}
+
+func (m *mockFinalizedClient) GetFinalizedBlockNumber(ctx context.Context) (uint64, error) {
+	return m.finalized, m.finalizedErr
+}
+
+// TestTargetBlock tests the end block of a parse range with and without TargetFinalized
+func TestTargetBlock(t *testing.T) {
+	unsupported := fmt.Errorf("finalized block: %w", client.ErrBlockTagUnsupported)
+	tests := []struct {
+		name            string
+		client          NetworkClient
+		targetFinalized bool
+		confirmations   uint64
+		expected        uint64
+		expectErr       bool
+	}{
+		{"latest", &mockFinalizedClient{mockNetworkClient: mockNetworkClient{latest: 200}, finalized: 136}, false, 12, 200, false},
+		{"finalized", &mockFinalizedClient{mockNetworkClient: mockNetworkClient{latest: 200}, finalized: 136}, true, 12, 136, false},
+		{"finalized not supported", &mockFinalizedClient{mockNetworkClient: mockNetworkClient{latest: 200}, finalizedErr: unsupported}, true, 12, 189, false},
+		{"finalized not supported, no confirmations", &mockFinalizedClient{mockNetworkClient: mockNetworkClient{latest: 200}, finalizedErr: unsupported}, true, 0, 200, false},
+		{"client without finalized", &mockNetworkClient{latest: 200}, true, 12, 189, false},
+		{"fewer blocks than confirmations", &mockNetworkClient{latest: 5}, true, 12, 0, false},
+		{"finalized lookup failed", &mockFinalizedClient{mockNetworkClient: mockNetworkClient{latest: 200}, finalizedErr: errors.New("connection refused")}, true, 12, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			config.TargetFinalized = tt.targetFinalized
+			config.MinConfirmations = tt.confirmations
+
+			target, err := TargetBlock(context.Background(), tt.client, config)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("Expected an error, got target %d", target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if target != tt.expected {
+				t.Errorf("Expected target block %d, got %d", tt.expected, target)
+			}
+		})
+	}
+}
+
+// TestStartBlockFinalizedBehindLastBlock tests that a last block written by a run against latest is
+// rejected by a finalized run instead of re-parsing the MaxBlockDelta window before the finalized block
+func TestStartBlockFinalizedBehindLastBlock(t *testing.T) {
+	config := types.DefaultConfig()
+	config.TargetFinalized = true
+	config.MaxBlockDelta = 100
+	c := &mockFinalizedClient{mockNetworkClient: mockNetworkClient{latest: 200}, finalized: 136}
+
+	endBlock, err := TargetBlock(context.Background(), c, config)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	start, err := StartBlock(200, endBlock, config.MaxBlockDelta)
+	if !errors.Is(err, ErrHeadBehind) {
+		t.Fatalf("Expected ErrHeadBehind, got start block %d, error %v", start, err)
+	}
+}
@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+)
+
+// NetworkClient is a BlockClient that also reports the chain head, implemented by client.EthClient
+type NetworkClient interface {
+	BlockClient
+	GetLatestBlockNumber(ctx context.Context) (uint64, error)
+}
+
+// Network is one chain parsed by a MultiParser. Each network has its own client (and so its own
+// rate limiter) and config; Config.LastBlockPath keeps the last parsed block of this network only.
+type Network struct {
+	Name   string // namespace key, e.g. "mainnet" or "polygon-mainnet"
+	Client NetworkClient
+	Config *types.Config
+}
+
+// BlocksHandler stores the blocks parsed for a network, e.g. into the network's own DB.
+// The last parsed block is only saved after the handler succeeds, so failed blocks are parsed again.
+type BlocksHandler func(ctx context.Context, network string, blocks []*types.ParsedBlock) error
+
+// MultiParser parses several networks concurrently in one process, one Parser per network
+type MultiParser struct {
+	networks []Network
+	parsers  map[string]*Parser
+}
+
+// MultiParserStats holds the stats of every network and their totals
+type MultiParserStats struct {
+	Networks           map[string]types.ParsingStats `json:"networks"`
+	BlocksParsed       uint64                        `json:"blocks_parsed"`
+	TransactionsParsed uint64                        `json:"transactions_parsed"`
+	LogsParsed         uint64                        `json:"logs_parsed"`
+	ErrorsEncountered  uint64                        `json:"errors_encountered"`
+}
+
+// NewMultiParser creates a parser per network. Names and last block files must be unique,
+// otherwise two networks would overwrite each other's state.
+func NewMultiParser(networks []Network) (*MultiParser, error) {
+	if len(networks) == 0 {
+		return nil, errors.New("no networks configured")
+	}
+
+	m := &MultiParser{parsers: make(map[string]*Parser, len(networks))}
+	lastBlockPaths := make(map[string]string, len(networks))
+	for _, network := range networks {
+		if network.Name == "" {
+			return nil, errors.New("network name is required")
+		}
+		if _, ok := m.parsers[network.Name]; ok {
+			return nil, fmt.Errorf("duplicate network %q", network.Name)
+		}
+		if network.Client == nil || network.Config == nil {
+			return nil, fmt.Errorf("network %q: client and config are required", network.Name)
+		}
+		if other, ok := lastBlockPaths[network.Config.LastBlockPath]; ok {
+			return nil, fmt.Errorf("networks %q and %q share last block file %s", other, network.Name, network.Config.LastBlockPath)
+		}
+		lastBlockPaths[network.Config.LastBlockPath] = network.Name
+
+		m.networks = append(m.networks, network)
+		m.parsers[network.Name] = NewParser(network.Client, network.Config)
+	}
+	return m, nil
+}
+
+// Networks returns the network names in configuration order
+func (m *MultiParser) Networks() []string {
+	names := make([]string, len(m.networks))
+	for i, network := range m.networks {
+		names[i] = network.Name
+	}
+	return names
+}
+
+// Parser returns the parser of a network
+func (m *MultiParser) Parser(network string) (*Parser, bool) {
+	p, ok := m.parsers[network]
+	return p, ok
+}
+
+// ParseNew parses every network concurrently from its last parsed block to its latest block,
+// at most Config.MaxBlockDelta blocks behind, and passes the blocks to handle. A network whose head
+// is still the last parsed block is skipped.
+// A failing network doesn't stop the others, the errors of all networks are joined.
+func (m *MultiParser) ParseNew(ctx context.Context, handle BlocksHandler) error {
+	errs := make([]error, len(m.networks))
+	var wg sync.WaitGroup
+	for i, network := range m.networks {
+		wg.Add(1)
+		go func(i int, network Network) {
+			defer wg.Done()
+			if err := m.parseNetwork(ctx, network, handle); err != nil {
+				errs[i] = fmt.Errorf("network %s: %w", network.Name, err)
+			}
+		}(i, network)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ErrHeadBehind is returned by StartBlock when the last parsed block is after the target block,
+// e.g. a lagging node behind a load balancer or a finalized target after runs against latest
+var ErrHeadBehind = errors.New("last parsed block is after the target block")
+
+// StartBlock returns the first block of a range ending at endBlock that continues from lastBlock
+// (0 = nothing parsed yet), at most maxDelta blocks before endBlock so a long downtime isn't caught up block by block
+func StartBlock(lastBlock, endBlock, maxDelta uint64) (uint64, error) {
+	if lastBlock > endBlock {
+		return 0, fmt.Errorf("start block %d is after end block %d: %w", lastBlock, endBlock, ErrHeadBehind)
+	}
+	if endBlock-lastBlock > maxDelta {
+		return endBlock - maxDelta, nil
+	}
+	return lastBlock, nil
+}
+
+// parseNetwork runs one parse cycle of a network, same range selection as the parse command
+func (m *MultiParser) parseNetwork(ctx context.Context, network Network, handle BlocksHandler) error {
+	endBlock, err := TargetBlock(ctx, network.Client, network.Config)
+	if err != nil {
+		return err
+	}
+	lastBlock := filtering.ReadLastBlock(network.Config.LastBlockPath)
+	if lastBlock != 0 && lastBlock == endBlock {
+		// head didn't move since the last cycle, its blocks were already handled
+		return nil
+	}
+	startBlock, err := StartBlock(lastBlock, endBlock, network.Config.MaxBlockDelta)
+	if errors.Is(err, ErrHeadBehind) {
+		// a lagging node, the next cycle continues once the head passes the last block
+		log.Printf("[%s] Skipping cycle: %v", network.Name, err)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[%s] Parsing blocks %d to %d", network.Name, startBlock, endBlock)
+	blocks, err := m.parsers[network.Name].ParseBlockRange(ctx, startBlock, endBlock)
+	if err != nil {
+		return fmt.Errorf("failed to parse blocks: %w", err)
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("no blocks parsed in range %d-%d", startBlock, endBlock)
+	}
+
+	if err := handle(ctx, network.Name, blocks); err != nil {
+		return err
+	}
+	filtering.WriteLastBlock(network.Config.LastBlockPath, blocks[len(blocks)-1].Number)
+	return nil
+}
+
+// GetStats returns the parsing stats of every network and their totals
+func (m *MultiParser) GetStats() MultiParserStats {
+	stats := MultiParserStats{Networks: make(map[string]types.ParsingStats, len(m.networks))}
+	for _, network := range m.networks {
+		networkStats := m.parsers[network.Name].GetStats()
+		stats.Networks[network.Name] = networkStats
+		stats.BlocksParsed += networkStats.BlocksParsed
+		stats.TransactionsParsed += networkStats.TransactionsParsed
+		stats.LogsParsed += networkStats.LogsParsed
+		stats.ErrorsEncountered += networkStats.ErrorsEncountered
+	}
+	return stats
+}
+
+// Close closes the parsers and the clients of all networks
+func (m *MultiParser) Close() {
+	for _, p := range m.parsers {
+		p.Close()
+	}
+}
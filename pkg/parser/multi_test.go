@@ -0,0 +1,255 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+)
+
+// mockNetworkClient is a mockBlockClient of a network with the given head
+type mockNetworkClient struct {
+	mockBlockClient
+	latest    uint64
+	latestErr error
+}
+
+func (m *mockNetworkClient) GetLatestBlockNumber(ctx context.Context) (uint64, error) {
+	return m.latest, m.latestErr
+}
+
+// newTestNetwork creates a network with its own last block file in dir
+func newTestNetwork(dir, name string, client *mockNetworkClient) Network {
+	config := types.DefaultConfig()
+	config.Workers = 2
+	config.MaxBlockDelta = 100
+	config.LastBlockPath = filepath.Join(dir, "last_block_"+name+".dat")
+	return Network{Name: name, Client: client, Config: config}
+}
+
+// newTestBlockRepo creates an in-memory DB with all tables
+func newTestBlockRepo(t *testing.T) *database.BlockRepository {
+	t.Helper()
+	dm, err := database.NewDatabaseManager(database.InMemoryConfig(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if err := database.NewSchema(nil).CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	return database.NewBlockRepository(dm, nil)
+}
+
+// TestMultiParserTwoNetworks tests that two networks are parsed into separate DBs with separate state and stats
+func TestMultiParserTwoNetworks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	mainnet := newTestNetwork(dir, "mainnet", &mockNetworkClient{latest: 10})
+	polygon := newTestNetwork(dir, "polygon-mainnet", &mockNetworkClient{latest: 3})
+	filtering.WriteLastBlock(mainnet.Config.LastBlockPath, 5)
+
+	multi, err := NewMultiParser([]Network{mainnet, polygon})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer multi.Close()
+
+	repos := map[string]*database.BlockRepository{
+		"mainnet":         newTestBlockRepo(t),
+		"polygon-mainnet": newTestBlockRepo(t),
+	}
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	err = multi.ParseNew(ctx, func(ctx context.Context, network string, blocks []*types.ParsedBlock) error {
+		mu.Lock()
+		handled[network]++
+		mu.Unlock()
+
+		stored := make([]*database.Block, len(blocks))
+		for i, block := range blocks {
+			stored[i] = database.MapParsedBlockToDatabaseBlock(block)
+		}
+		return repos[network].BatchUpsert(ctx, stored)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		network   string
+		lastBlock uint64
+		blocks    int
+	}{
+		{"mainnet", 10, 6},        // 5..10 from the last block file
+		{"polygon-mainnet", 3, 4}, // 0..3 without a last block file
+	}
+	stats := multi.GetStats()
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			if handled[tt.network] != 1 {
+				t.Errorf("Expected handler called once, got %d", handled[tt.network])
+			}
+			count, err := repos[tt.network].Count(ctx)
+			if err != nil {
+				t.Fatalf("Failed to count blocks: %v", err)
+			}
+			if count != tt.blocks {
+				t.Errorf("Expected %d stored blocks, got %d", tt.blocks, count)
+			}
+			config := map[string]*types.Config{"mainnet": mainnet.Config, "polygon-mainnet": polygon.Config}[tt.network]
+			if last := filtering.ReadLastBlock(config.LastBlockPath); last != tt.lastBlock {
+				t.Errorf("Expected last block %d, got %d", tt.lastBlock, last)
+			}
+			if parsed := stats.Networks[tt.network].BlocksParsed; parsed != uint64(tt.blocks) {
+				t.Errorf("Expected %d parsed blocks, got %d", tt.blocks, parsed)
+			}
+		})
+	}
+
+	if stats.BlocksParsed != 10 {
+		t.Errorf("Expected 10 parsed blocks in total, got %d", stats.BlocksParsed)
+	}
+	if names := multi.Networks(); strings.Join(names, ",") != "mainnet,polygon-mainnet" {
+		t.Errorf("Expected networks in config order, got %v", names)
+	}
+}
+
+// TestMultiParserNetworkFailure tests that a failing network doesn't stop the others or advance its state
+func TestMultiParserNetworkFailure(t *testing.T) {
+	dir := t.TempDir()
+	mainnet := newTestNetwork(dir, "mainnet", &mockNetworkClient{latest: 2})
+	sepolia := newTestNetwork(dir, "sepolia", &mockNetworkClient{latestErr: errors.New("connection refused")})
+	polygon := newTestNetwork(dir, "polygon-mainnet", &mockNetworkClient{latest: 2})
+
+	multi, err := NewMultiParser([]Network{mainnet, sepolia, polygon})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	handleErr := errors.New("disk full")
+	err = multi.ParseNew(context.Background(), func(ctx context.Context, network string, blocks []*types.ParsedBlock) error {
+		if network == "polygon-mainnet" {
+			return handleErr
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Expected error from failing networks")
+	}
+	if !strings.Contains(err.Error(), "network sepolia") || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Expected sepolia error, got %v", err)
+	}
+	if !errors.Is(err, handleErr) {
+		t.Errorf("Expected handler error to be wrapped, got %v", err)
+	}
+
+	if last := filtering.ReadLastBlock(mainnet.Config.LastBlockPath); last != 2 {
+		t.Errorf("Expected mainnet last block 2, got %d", last)
+	}
+	for _, network := range []Network{sepolia, polygon} {
+		if _, err := os.Stat(network.Config.LastBlockPath); !os.IsNotExist(err) {
+			t.Errorf("Expected no last block file for %s, got %v", network.Name, err)
+		}
+	}
+}
+
+// TestStartBlock tests continuing from the last block, the MaxBlockDelta clamp and a head behind the last block
+func TestStartBlock(t *testing.T) {
+	tests := []struct {
+		name      string
+		lastBlock uint64
+		endBlock  uint64
+		expected  uint64
+		err       error
+	}{
+		{"Continue from last block", 95, 120, 95, nil},
+		{"Head at last block", 120, 120, 120, nil},
+		{"Long downtime clamped", 10, 500, 400, nil},
+		{"No last block clamped", 0, 500, 400, nil},
+		{"No last block short chain", 0, 50, 0, nil},
+		{"Head behind last block", 120, 110, 0, ErrHeadBehind},
+		{"Head far behind last block", 1000, 10, 0, ErrHeadBehind},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, err := StartBlock(tt.lastBlock, tt.endBlock, 100)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if start != tt.expected {
+				t.Errorf("Expected start block %d, got %d", tt.expected, start)
+			}
+		})
+	}
+}
+
+// TestMultiParserHeadBehind tests that a lagging node skips the cycle instead of re-parsing old blocks
+func TestMultiParserHeadBehind(t *testing.T) {
+	dir := t.TempDir()
+	mainnet := newTestNetwork(dir, "mainnet", &mockNetworkClient{latest: 150})
+	filtering.WriteLastBlock(mainnet.Config.LastBlockPath, 200)
+
+	multi, err := NewMultiParser([]Network{mainnet})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer multi.Close()
+
+	handled := 0
+	err = multi.ParseNew(context.Background(), func(ctx context.Context, network string, blocks []*types.ParsedBlock) error {
+		handled++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if handled != 0 {
+		t.Errorf("Expected no handled blocks, got %d calls", handled)
+	}
+	if last := filtering.ReadLastBlock(mainnet.Config.LastBlockPath); last != 200 {
+		t.Errorf("Expected last block 200 kept, got %d", last)
+	}
+	if parsed := multi.GetStats().BlocksParsed; parsed != 0 {
+		t.Errorf("Expected 0 parsed blocks, got %d", parsed)
+	}
+}
+
+// TestNewMultiParserValidation tests rejecting configs where networks would share state
+func TestNewMultiParserValidation(t *testing.T) {
+	dir := t.TempDir()
+	mainnet := newTestNetwork(dir, "mainnet", &mockNetworkClient{})
+	sharedFile := newTestNetwork(dir, "sepolia", &mockNetworkClient{})
+	sharedFile.Config.LastBlockPath = mainnet.Config.LastBlockPath
+
+	tests := []struct {
+		name     string
+		networks []Network
+	}{
+		{"No networks", nil},
+		{"Empty name", []Network{newTestNetwork(dir, "", &mockNetworkClient{})}},
+		{"Duplicate name", []Network{mainnet, newTestNetwork(t.TempDir(), "mainnet", &mockNetworkClient{})}},
+		{"Missing client", []Network{{Name: "mainnet", Config: mainnet.Config}}},
+		{"Shared last block file", []Network{mainnet, sharedFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewMultiParser(tt.networks); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,87 +18,179 @@ import (
 	gethTypes "github.com/ethereum/go-ethereum/core/types"
 )
 
+// BlockClient is the part of client.EthClient used by the parser, allows mocking in tests
+// and replaying recorded blocks with client.FixtureClient
+type BlockClient = client.BlockSource
+
+// ErrParserClosed is returned when parsing with a closed parser
+var ErrParserClosed = errors.New("parser is closed")
+
+// ErrRangeTooLarge is returned when a range has more blocks than Config.MaxRangeSize
+var ErrRangeTooLarge = errors.New("block range too large")
+
 // Parser handles blockchain data parsing
 type Parser struct {
-	client *client.EthClient
+	client BlockClient
 	config *types.Config
 	stats  *types.ParsingStats
 	mu     sync.RWMutex
+	clock  types.Clock // StartTime/EndTime of the stats
+
+	// receiptSlots limits concurrent receipt batch calls to Config.ReceiptWorkers, nil = no limit
+	receiptSlots chan struct{}
+
+	closeOnce sync.Once
+	closed    bool
 }
 
 // NewParser creates a new blockchain parser
-func NewParser(ethClient *client.EthClient, config *types.Config) *Parser {
-	return &Parser{
+func NewParser(ethClient BlockClient, config *types.Config) *Parser {
+	p := &Parser{
 		client: ethClient,
 		config: config,
 		stats: &types.ParsingStats{
-			StartTime: time.Now(),
+			StartTime: types.RealClock.Now(),
 		},
+		clock: types.RealClock,
 	}
+	if config.ReceiptWorkers > 0 {
+		p.receiptSlots = make(chan struct{}, config.ReceiptWorkers)
+	}
+	return p
+}
+
+// getReceipts fetches a receipt batch, waiting for a free receipt slot when ReceiptWorkers is set
+func (p *Parser) getReceipts(ctx context.Context, txHashes []common.Hash) ([]*gethTypes.Receipt, error) {
+	if p.receiptSlots != nil {
+		select {
+		case p.receiptSlots <- struct{}{}:
+			defer func() { <-p.receiptSlots }()
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return p.client.GetTransactionReceiptsBatch(ctx, txHashes)
+}
+
+// Close closes the clients owned by the parser (and their rate limit tickers).
+// Safe to call more than once, parsing after Close returns ErrParserClosed.
+func (p *Parser) Close() {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+
+		if c, ok := p.client.(interface{ Close() }); ok {
+			c.Close()
+		}
+	})
+}
+
+// isClosed reports whether Close was called
+func (p *Parser) isClosed() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.closed
 }
 
 // ParseBlockRange - заложена на будущее возможность использовать несколько infura API key в разных воркерах,
 // чтобы не упираться в лимиты Infura
 func (p *Parser) ParseBlockRange(ctx context.Context, startBlock, endBlock uint64) ([]*types.ParsedBlock, error) {
+	if p.isClosed() {
+		return nil, ErrParserClosed
+	}
+	if maxSize := p.config.MaxRangeSize; maxSize > 0 && endBlock >= startBlock && endBlock-startBlock >= maxSize {
+		return nil, fmt.Errorf("%w: %d blocks from %d to %d, max_range_size is %d",
+			ErrRangeTooLarge, endBlock-startBlock+1, startBlock, endBlock, maxSize)
+	}
 	log.Printf("Parsing blocks from %d to %d", startBlock, endBlock)
 
+	// Hard deadline: unlike MaxDuration it also cancels in-flight calls to a stuck node
+	parentCtx := ctx
+	if p.config.RangeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.config.RangeTimeout)
+		defer cancel()
+	}
+	// canceled once the daily request quota is used up, the remaining blocks would all fail
+	ctx, stopOnQuota := context.WithCancel(ctx)
+	defer stopOnQuota()
+	quotaExceeded := false
+
 	p.mu.Lock()
-	p.stats.StartTime = time.Now()
+	p.stats.StartTime = p.clock.Now()
+	p.stats.StoppedByBudget = false
+	p.stats.BudgetReason = ""
 	p.mu.Unlock()
 
 	var allBlocks []*types.ParsedBlock
 	var mu sync.Mutex
 	var wg sync.WaitGroup
+	collectorDone := make(chan struct{})
 
 	// Create worker pool
 	blockChan := make(chan uint64, p.config.Workers*2)
 	resultChan := make(chan *types.ParseResult, p.config.Workers)
 
+	// With AdaptiveWorkers all Workers are started, but only limiter.Limit() of them fetch at a time
+	var limiter *aimdLimiter
+	if p.config.AdaptiveWorkers {
+		limiter = newAIMDLimiter(p.config.MinWorkers, p.config.Workers)
+	}
+
 	// Start workers
 	for i := 0; i < p.config.Workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			// TODO: pass to every worker separate infura API key
-			p.worker(ctx, blockChan, resultChan)
+			p.worker(ctx, limiter, blockChan, resultChan)
 		}()
 	}
 
 	// Start result collector
 	go func() {
+		defer close(collectorDone)
 		for result := range resultChan {
+			p.recordResult(result)
 			if result.Error != nil {
-				log.Printf("Error parsing block: %v", result.Error)
-				p.mu.Lock()
-				p.stats.ErrorsEncountered++
-				p.mu.Unlock()
+				if errors.Is(result.Error, client.ErrQuotaExceeded) && !quotaExceeded {
+					quotaExceeded = true
+					p.stopByBudget("daily_request_cap")
+					stopOnQuota()
+				}
 				continue
 			}
 
 			mu.Lock()
 			allBlocks = append(allBlocks, result.Block)
 			mu.Unlock()
-
-			p.mu.Lock()
-			p.stats.BlocksParsed++
-			if result.Block != nil {
-				p.stats.TransactionsParsed += uint64(len(result.Block.Transactions))
-				for _, tx := range result.Block.Transactions {
-					if tx.Logs != nil {
-						p.stats.LogsParsed += uint64(len(tx.Logs))
-					}
-				}
-			}
-			p.mu.Unlock()
 		}
 	}()
 
+	// Budget: stop feeding new blocks after MaxDuration, in-flight blocks are finished
+	var deadline <-chan time.Time
+	if p.config.MaxDuration > 0 {
+		timer := time.NewTimer(p.config.MaxDuration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
 	// Send block numbers to workers
 	go func() {
 		defer close(blockChan)
+		var sent uint64
 		for blockNum := startBlock; blockNum <= endBlock; blockNum++ {
+			if p.config.MaxBlocks > 0 && sent >= p.config.MaxBlocks {
+				p.stopByBudget("max_blocks")
+				return
+			}
 			select {
 			case blockChan <- blockNum:
+				sent++
+			case <-deadline:
+				p.stopByBudget("max_duration")
+				return
 			case <-ctx.Done():
 				return
 			}
@@ -106,46 +200,130 @@ func (p *Parser) ParseBlockRange(ctx context.Context, startBlock, endBlock uint6
 	// Wait for all workers to complete
 	wg.Wait()
 	close(resultChan)
+	<-collectorDone
 
 	p.mu.Lock()
-	p.stats.EndTime = time.Now()
+	p.stats.EndTime = p.clock.Now()
 	p.stats.TotalDuration = p.stats.EndTime.Sub(p.stats.StartTime)
+	if limiter != nil {
+		p.stats.WorkerLimit = limiter.Limit()
+	}
 	p.mu.Unlock()
 
 	log.Printf("Parsing completed. Processed %d blocks, %d transactions, %d logs",
 		p.stats.BlocksParsed, p.stats.TransactionsParsed, p.stats.LogsParsed)
 
+	// workers finish in any order, callers rely on the last block being the highest one
+	sort.Slice(allBlocks, func(i, j int) bool {
+		return allBlocks[i].Number < allBlocks[j].Number
+	})
+
+	if ctx.Err() != nil {
+		if parentCtx.Err() == nil && !quotaExceeded {
+			p.stopByBudget("timeout")
+		}
+		// blocks canceled in flight leave gaps, a caller saving the last block would skip them
+		allBlocks = contiguousFrom(allBlocks, startBlock)
+	}
+
 	return allBlocks, nil
 }
 
+// contiguousFrom returns the leading blocks of sorted blocks numbered start, start+1, ... without a gap
+func contiguousFrom(blocks []*types.ParsedBlock, start uint64) []*types.ParsedBlock {
+	for i, block := range blocks {
+		if block.Number != start+uint64(i) {
+			return blocks[:i]
+		}
+	}
+	return blocks
+}
+
+// recordResult updates the parsing stats with a worker result and logs slow blocks
+func (p *Parser) recordResult(result *types.ParseResult) {
+	if result.Error != nil {
+		log.Printf("Error parsing block: %v", result.Error)
+		p.mu.Lock()
+		p.stats.ErrorsEncountered++
+		p.mu.Unlock()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.BlocksParsed++
+	if result.Block == nil {
+		return
+	}
+
+	p.stats.TransactionsParsed += uint64(len(result.Block.Transactions))
+	p.stats.TxParseErrors += uint64(len(result.Block.ParseErrors))
+	if p.stats.TxTypeCounts == nil {
+		p.stats.TxTypeCounts = make(map[uint8]uint64)
+	}
+	for _, tx := range result.Block.Transactions {
+		p.stats.TxTypeCounts[tx.Type]++
+		if tx.Logs != nil {
+			p.stats.LogsParsed += uint64(len(tx.Logs))
+		}
+	}
+
+	p.stats.AddBlockTime(result.Block.Number, result.ProcessTime)
+	if p.config.SlowBlockThreshold > 0 && result.ProcessTime > p.config.SlowBlockThreshold {
+		p.stats.SlowBlocks++
+		log.Printf("Slow block %d: parsed in %v (%d txs, threshold %v)",
+			result.Block.Number, result.ProcessTime, len(result.Block.Transactions), p.config.SlowBlockThreshold)
+	}
+}
+
+// stopByBudget records that a ParseBlockRange budget stopped the run
+func (p *Parser) stopByBudget(reason string) {
+	log.Printf("Parse budget %s reached, returning blocks parsed so far", reason)
+	p.mu.Lock()
+	p.stats.StoppedByBudget = true
+	p.stats.BudgetReason = reason
+	p.mu.Unlock()
+}
+
 // ParseSingleBlock parses a single block by number
 func (p *Parser) ParseSingleBlock(ctx context.Context, blockNumber uint64) (*types.ParsedBlock, error) {
+	if p.isClosed() {
+		return nil, ErrParserClosed
+	}
 	startTime := time.Now()
 
-	// Get block data
+	// Get block data, a reconstructed block comes with a PartialBlockError
 	gethBlock, err := p.client.GetBlockByNumber(ctx, blockNumber)
-	if err != nil {
+	var partial *client.PartialBlockError
+	if err != nil && !errors.As(err, &partial) {
 		return nil, fmt.Errorf("failed to get block %d: %w", blockNumber, err)
 	}
 
 	// Convert to parsed block
 	parsedBlock := types.NewParsedBlockFromGethBlock(gethBlock)
+	if partial != nil {
+		log.Printf("Warning: block %d is degraded: %v", blockNumber, partial)
+		parsedBlock.Degraded = true
+		parsedBlock.DegradedReason = partial.Error()
+	}
 
 	// Parse transactions
-	transactions, err := p.parseBlockTransactions(ctx, gethBlock)
+	transactions, parseErrs, err := p.parseBlockTransactions(ctx, gethBlock)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse transactions for block %d: %w", blockNumber, err)
 	}
 	parsedBlock.Transactions = transactions
+	parsedBlock.ParseErrors = parseErrs
 
 	// Check if we should skip receipts for large blocks
-	if p.config.SkipReceiptsOnLargeBlocks && len(transactions) > p.config.MaxTransactionsForReceipts {
-		log.Printf("Skipping receipt processing for block %d: %d transactions exceeds limit of %d",
-			blockNumber, len(transactions), p.config.MaxTransactionsForReceipts)
+	if reason := p.largeBlockReason(len(transactions), gethBlock.GasUsed()); reason != "" {
+		log.Printf("Skipping receipt processing for block %d: %s", blockNumber, reason)
 		// Set basic transaction info without receipts
 		for _, tx := range transactions {
 			tx.GasUsed = 0
-			tx.Status = 2 // Use 2 to indicate "receipt not fetched"
+			tx.Status = 0
+			tx.ReceiptFetched = false
 		}
 	}
 
@@ -155,28 +333,44 @@ func (p *Parser) ParseSingleBlock(ctx context.Context, blockNumber uint64) (*typ
 	return parsedBlock, nil
 }
 
-// parseBlockTransactions parses all transactions in a block
-func (p *Parser) parseBlockTransactions(ctx context.Context, gethBlock *gethTypes.Block) ([]*types.ParsedTransaction, error) {
+// largeBlockReason returns why receipts are skipped for a block, or "" when they are fetched.
+// The tx count limit is checked first, then the gas limit; exceeding either one is enough.
+func (p *Parser) largeBlockReason(txCount int, gasUsed uint64) string {
+	if !p.config.SkipReceiptsOnLargeBlocks {
+		return ""
+	}
+	if txCount > p.config.MaxTransactionsForReceipts {
+		return fmt.Sprintf("%d transactions exceeds limit of %d", txCount, p.config.MaxTransactionsForReceipts)
+	}
+	if p.config.MaxGasForReceipts > 0 && gasUsed > p.config.MaxGasForReceipts {
+		return fmt.Sprintf("%d gas used exceeds limit of %d", gasUsed, p.config.MaxGasForReceipts)
+	}
+	return ""
+}
+
+// parseBlockTransactions parses all transactions in a block. Transactions that fail to parse
+// are returned as parse errors for the dead-letter store instead of placeholder records.
+func (p *Parser) parseBlockTransactions(ctx context.Context, gethBlock *gethTypes.Block) ([]*types.ParsedTransaction, []*types.TxParseError, error) {
 	blockTxs := gethBlock.Transactions()
 	if len(blockTxs) == 0 {
-		return []*types.ParsedTransaction{}, nil
+		return []*types.ParsedTransaction{}, nil, nil
 	}
 
 	var parsedTxs []*types.ParsedTransaction
+	var parseErrs []*types.TxParseError
 	// Check if we should skip receipts for large blocks
-	if p.config.SkipReceiptsOnLargeBlocks && len(blockTxs) > p.config.MaxTransactionsForReceipts {
-		log.Printf("Skipping receipts for block with %d transactions (exceeds limit of %d)",
-			len(blockTxs), p.config.MaxTransactionsForReceipts)
+	if reason := p.largeBlockReason(len(blockTxs), gethBlock.GasUsed()); reason != "" {
+		log.Printf("Skipping receipts for block %d: %s", gethBlock.NumberU64(), reason)
 		// Parse transactions without receipts
 		for i, gethTx := range blockTxs {
 			parsedTx, err := p.parseTransactionWithoutReceipt(gethTx, gethBlock, uint(i))
 			if err != nil {
-				log.Printf("Warning: Failed to parse transaction %s: %v", gethTx.Hash().Hex(), err)
+				parseErrs = append(parseErrs, p.txParseError(gethTx, gethBlock, i, err))
 				continue
 			}
 			parsedTxs = append(parsedTxs, parsedTx)
 		}
-		return parsedTxs, nil
+		return p.handleUnknownSenders(parsedTxs), parseErrs, nil
 	}
 
 	// Get transaction receipts in batch for smaller blocks
@@ -186,48 +380,73 @@ func (p *Parser) parseBlockTransactions(ctx context.Context, gethBlock *gethType
 	}
 
 	if p.config.IncludeLogs {
-		receipts, err := p.client.GetTransactionReceiptsBatch(ctx, txHashes)
+		receipts, err := p.getReceipts(ctx, txHashes)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get transaction receipts: %w", err)
+			return nil, nil, fmt.Errorf("failed to get transaction receipts: %w", err)
 		}
 
 		// Parse each transaction with error handling
 		var parsedTxs []*types.ParsedTransaction
 		for i, gethTx := range blockTxs {
-			// Try to parse transaction, skip if it fails
+			// Try to parse transaction, route it to the parse errors if it fails
 			parsedTx, err := p.parseTransactionSafely(gethTx, gethBlock, uint(i), receipts, i)
 			if err != nil {
-				log.Printf("Warning: Failed to parse transaction %s in block %d: %v (skipping)",
-					gethTx.Hash().Hex(), gethBlock.NumberU64(), err)
-				// Create a minimal transaction record for unknown types
-				parsedTx = &types.ParsedTransaction{
-					Hash:             gethTx.Hash().Hex(),
-					BlockNumber:      gethBlock.NumberU64(),
-					BlockHash:        gethBlock.Hash().Hex(),
-					TransactionIndex: uint64(i),
-					From:             "unknown",
-					Value:            big.NewInt(0),
-					Gas:              0,
-					GasPrice:         big.NewInt(0),
-					Nonce:            0,
-					Type:             255, // Use 255 to indicate unknown/unsupported type (e.g., blob txs)
-					InputData:        "parse_error",
-				}
+				parseErrs = append(parseErrs, p.txParseError(gethTx, gethBlock, i, err))
+				continue
 			}
 			parsedTxs = append(parsedTxs, parsedTx)
 		}
-		return parsedTxs, nil
+		return p.handleUnknownSenders(parsedTxs), parseErrs, nil
 	}
-	return parsedTxs, nil
+	return parsedTxs, nil, nil
 
 }
 
+// txParseError logs a failed transaction and builds its dead-letter record
+func (p *Parser) txParseError(gethTx *gethTypes.Transaction, gethBlock *gethTypes.Block, txIndex int, err error) *types.TxParseError {
+	log.Printf("Warning: Failed to parse transaction %s in block %d: %v (moved to parse errors)",
+		gethTx.Hash().Hex(), gethBlock.NumberU64(), err)
+	return &types.TxParseError{
+		BlockNumber:      gethBlock.NumberU64(),
+		TxHash:           gethTx.Hash().Hex(),
+		TransactionIndex: uint64(txIndex),
+		Error:            err.Error(),
+	}
+}
+
+// handleUnknownSenders counts transactions whose sender could not be recovered
+// and drops them when Config.DropUnknownSender is set
+func (p *Parser) handleUnknownSenders(txs []*types.ParsedTransaction) []*types.ParsedTransaction {
+	kept := txs[:0]
+	var unknown uint64
+	for _, tx := range txs {
+		if tx.From == types.UnknownSender {
+			unknown++
+			if p.config.DropUnknownSender {
+				continue
+			}
+		}
+		kept = append(kept, tx)
+	}
+
+	if unknown > 0 {
+		p.mu.Lock()
+		p.stats.UnknownSenders += unknown
+		p.mu.Unlock()
+		if p.config.DropUnknownSender {
+			log.Printf("Dropped %d transactions with unknown sender", unknown)
+		}
+	}
+	return kept
+}
+
 // parseTransactionSafely safely parses a transaction with error handling for unknown types
-func (p *Parser) parseTransactionSafely(gethTx *gethTypes.Transaction, gethBlock *gethTypes.Block, txIndex uint, receipts []*gethTypes.Receipt, receiptIndex int) (*types.ParsedTransaction, error) {
-	// Try to parse the transaction with error recovery
+func (p *Parser) parseTransactionSafely(gethTx *gethTypes.Transaction, gethBlock *gethTypes.Block, txIndex uint, receipts []*gethTypes.Receipt, receiptIndex int) (parsed *types.ParsedTransaction, err error) {
+	// Try to parse the transaction with error recovery, a panic fails only this transaction
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("Recovered from panic while parsing transaction %s: %v", gethTx.Hash().Hex(), r)
+			parsed, err = nil, fmt.Errorf("panic while parsing transaction: %v", r)
 		}
 	}()
 
@@ -239,7 +458,7 @@ func (p *Parser) parseTransactionSafely(gethTx *gethTypes.Transaction, gethBlock
 	}
 
 	// Safe from address extraction
-	from := "unknown"
+	from := types.UnknownSender
 	txType := gethTx.Type()
 
 	// Try different signer types for different transaction types
@@ -298,6 +517,7 @@ func (p *Parser) parseTransactionSafely(gethTx *gethTypes.Transaction, gethBlock
 		receipt := receipts[receiptIndex]
 		parsedTx.GasUsed = receipt.GasUsed
 		parsedTx.Status = receipt.Status
+		parsedTx.ReceiptFetched = true
 
 		// Add contract address if this is a contract creation
 		if receipt.ContractAddress != (common.Address{}) {
@@ -334,11 +554,16 @@ func (p *Parser) parseTransactionSafely(gethTx *gethTypes.Transaction, gethBlock
 		}()
 	}
 
+	// Access list (EIP-2930), legacy transactions have none
+	if txType != gethTypes.LegacyTxType {
+		parsedTx.AccessList = types.NewAccessListFromGeth(gethTx.AccessList())
+	}
+
 	return parsedTx, nil
 }
 
-// worker processes block numbers from the channel
-func (p *Parser) worker(ctx context.Context, blockChan <-chan uint64, resultChan chan<- *types.ParseResult) {
+// worker processes block numbers from the channel, limiter is nil without AdaptiveWorkers
+func (p *Parser) worker(ctx context.Context, limiter *aimdLimiter, blockChan <-chan uint64, resultChan chan<- *types.ParseResult) {
 	for {
 		select {
 		case blockNum, ok := <-blockChan:
@@ -347,7 +572,13 @@ func (p *Parser) worker(ctx context.Context, blockChan <-chan uint64, resultChan
 			}
 
 			startTime := time.Now()
-			block, err := p.ParseSingleBlock(ctx, blockNum)
+			var block *types.ParsedBlock
+			var err error
+			if limiter != nil {
+				block, err = p.parseBlockAdaptive(ctx, limiter, blockNum)
+			} else {
+				block, err = p.ParseSingleBlock(ctx, blockNum)
+			}
 
 			resultChan <- &types.ParseResult{
 				Block:       block,
@@ -361,6 +592,92 @@ func (p *Parser) worker(ctx context.Context, blockChan <-chan uint64, resultChan
 	}
 }
 
+// Rate limited blocks are retried by adaptive workers after an increasing delay
+const (
+	maxRateLimitRetries = 5
+	rateLimitRetryDelay = 100 * time.Millisecond
+)
+
+// parseBlockAdaptive parses a block within the limiter's concurrency and reports the outcome to it.
+// A fetch is rate limited when it failed with a rate limit error, or when the client retried rate
+// limit errors meanwhile (client.EthClient retries them internally, see RateLimitHits).
+func (p *Parser) parseBlockAdaptive(ctx context.Context, limiter *aimdLimiter, blockNumber uint64) (*types.ParsedBlock, error) {
+	counter, _ := p.client.(interface{ RateLimitHits() uint64 })
+	for attempt := 0; ; attempt++ {
+		epoch, err := limiter.Acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var hitsBefore uint64
+		if counter != nil {
+			hitsBefore = counter.RateLimitHits()
+		}
+
+		block, err := p.ParseSingleBlock(ctx, blockNumber)
+		rateLimitErr := client.IsRateLimitError(err)
+		rateLimited := rateLimitErr || (counter != nil && counter.RateLimitHits() > hitsBefore)
+		limiter.Release(epoch, rateLimited)
+		if rateLimited {
+			p.mu.Lock()
+			p.stats.RateLimitHits++
+			p.mu.Unlock()
+		}
+
+		if !rateLimitErr || attempt >= maxRateLimitRetries {
+			return block, err
+		}
+		select {
+		case <-time.After(time.Duration(attempt+1) * rateLimitRetryDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ReparseErrors parses the blocks of dead-letter transactions again for reprocessing.
+// The returned blocks hold only those transactions: the ones that parse now in Transactions,
+// still failing ones in ParseErrors. fixed lists the hashes that parse now.
+// A block that can't be fetched is skipped, its error is joined into the returned error.
+func (p *Parser) ReparseErrors(ctx context.Context, parseErrs []*types.TxParseError) (blocks []*types.ParsedBlock, fixed []string, err error) {
+	pending := make(map[uint64]map[string]bool)
+	var blockNumbers []uint64
+	for _, pe := range parseErrs {
+		if pending[pe.BlockNumber] == nil {
+			pending[pe.BlockNumber] = make(map[string]bool)
+			blockNumbers = append(blockNumbers, pe.BlockNumber)
+		}
+		pending[pe.BlockNumber][pe.TxHash] = true
+	}
+	sort.Slice(blockNumbers, func(i, j int) bool { return blockNumbers[i] < blockNumbers[j] })
+
+	var errs []error
+	for _, number := range blockNumbers {
+		block, err := p.ParseSingleBlock(ctx, number)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		hashes := pending[number]
+		var txs []*types.ParsedTransaction
+		for _, tx := range block.Transactions {
+			if hashes[tx.Hash] {
+				txs = append(txs, tx)
+				fixed = append(fixed, tx.Hash)
+			}
+		}
+		var stillFailing []*types.TxParseError
+		for _, pe := range block.ParseErrors {
+			if hashes[pe.TxHash] {
+				stillFailing = append(stillFailing, pe)
+			}
+		}
+		block.Transactions, block.ParseErrors = txs, stillFailing
+		blocks = append(blocks, block)
+	}
+	return blocks, fixed, errors.Join(errs...)
+}
+
 // ParseBlockByHash parses a block by its hash
 func (p *Parser) ParseBlockByHash(ctx context.Context, blockHash string) (*types.ParsedBlock, error) {
 	hash := common.HexToHash(blockHash)
@@ -372,11 +689,12 @@ func (p *Parser) ParseBlockByHash(ctx context.Context, blockHash string) (*types
 
 	parsedBlock := types.NewParsedBlockFromGethBlock(gethBlock)
 
-	transactions, err := p.parseBlockTransactions(ctx, gethBlock)
+	transactions, parseErrs, err := p.parseBlockTransactions(ctx, gethBlock)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse transactions for block %s: %w", blockHash, err)
 	}
 	parsedBlock.Transactions = transactions
+	parsedBlock.ParseErrors = parseErrs
 
 	return parsedBlock, nil
 }
@@ -425,27 +743,39 @@ func (p *Parser) GetLogsInRange(ctx context.Context, startBlock, endBlock uint64
 	return parsedLogs, nil
 }
 
+// SetClock replaces the clock of the stats start/end times, for deterministic tests
+func (p *Parser) SetClock(clock types.Clock) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clock = clock
+}
+
 // GetStats returns current parsing statistics
 func (p *Parser) GetStats() types.ParsingStats {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
-	return *p.stats
+	stats := *p.stats
+	// copy the map so callers don't race with the collector
+	stats.TxTypeCounts = make(map[uint8]uint64, len(p.stats.TxTypeCounts))
+	for txType, count := range p.stats.TxTypeCounts {
+		stats.TxTypeCounts[txType] = count
+	}
+	return stats
 }
 
-// FilterTransactionsByAddress filters transactions by from/to address
+// FilterTransactionsByAddress filters transactions by from/to address, case-insensitive
 func (p *Parser) FilterTransactionsByAddress(transactions []*types.ParsedTransaction, addresses []string) []*types.ParsedTransaction {
 	if len(addresses) == 0 {
 		return transactions
 	}
+	return p.FilterTransactionsByAddressSet(transactions, types.NewAddressSet(addresses...))
+}
 
-	addressMap := make(map[string]bool)
-	for _, addr := range addresses {
-		addressMap[addr] = true
-	}
-
+// FilterTransactionsByAddressSet is FilterTransactionsByAddress with a prebuilt set, for filtering many blocks
+func (p *Parser) FilterTransactionsByAddressSet(transactions []*types.ParsedTransaction, addresses types.AddressSet) []*types.ParsedTransaction {
 	var filtered []*types.ParsedTransaction
 	for _, tx := range transactions {
-		if addressMap[tx.From] || (tx.To != nil && addressMap[*tx.To]) {
+		if addresses.Contains(tx.From) || (tx.To != nil && addresses.Contains(*tx.To)) {
 			filtered = append(filtered, tx)
 		}
 	}
@@ -463,7 +793,7 @@ func (p *Parser) parseTransactionWithoutReceipt(gethTx *gethTypes.Transaction, g
 	}
 
 	// Safe from address extraction
-	from := "unknown"
+	from := types.UnknownSender
 	txType := gethTx.Type()
 
 	// Try different signer types for different transaction types
@@ -515,8 +845,7 @@ func (p *Parser) parseTransactionWithoutReceipt(gethTx *gethTypes.Transaction, g
 		InputData:        inputData,
 		Nonce:            gethTx.Nonce(),
 		Type:             txType,
-		GasUsed:          0, // Not available without receipt
-		Status:           2, // Use 2 to indicate "receipt not fetched"
+		// GasUsed and Status are not available without receipt, ReceiptFetched stays false
 	}
 
 	// Safely add EIP-1559 fields for type 2 transactions
@@ -537,6 +866,11 @@ func (p *Parser) parseTransactionWithoutReceipt(gethTx *gethTypes.Transaction, g
 		}()
 	}
 
+	// Access list (EIP-2930), legacy transactions have none
+	if txType != gethTypes.LegacyTxType {
+		parsedTx.AccessList = types.NewAccessListFromGeth(gethTx.AccessList())
+	}
+
 	return parsedTx, nil
 }
 
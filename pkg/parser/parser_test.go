@@ -0,0 +1,868 @@
+package parser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/client"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// mockBlockClient serves empty blocks, optionally with a delay per block.
+// With headerOnly set it behaves like EthClient falling back to header-only reconstruction.
+type mockBlockClient struct {
+	mockReceiptFetcher
+	delay       time.Duration
+	blockDelays map[uint64]time.Duration // per-block delay, overrides delay
+	headerOnly  bool
+	closeCalls  int
+	txs         []*gethTypes.Transaction // included in every served block
+	withdrawals []*gethTypes.Withdrawal  // included in every served block, nil serves pre-Shanghai blocks
+	gasUsed     uint64                   // header gas used of every served block
+}
+
+func (m *mockBlockClient) Close() {
+	m.closeCalls++
+}
+
+func (m *mockBlockClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*gethTypes.Block, error) {
+	if d, ok := m.blockDelays[blockNumber]; ok {
+		time.Sleep(d)
+	} else if m.delay > 0 {
+		time.Sleep(m.delay)
+	}
+	header := &gethTypes.Header{Number: new(big.Int).SetUint64(blockNumber), Difficulty: big.NewInt(0), GasUsed: m.gasUsed}
+	block := gethTypes.NewBlockWithHeader(header).WithBody(gethTypes.Body{Transactions: m.txs, Withdrawals: m.withdrawals})
+	if m.headerOnly {
+		return block, &client.PartialBlockError{
+			BlockNumber: blockNumber,
+			Block:       block,
+			HeaderOnly:  true,
+			Reason:      errors.New("transaction type not supported"),
+		}
+	}
+	return block, nil
+}
+
+func (m *mockBlockClient) GetBlockByHash(ctx context.Context, blockHash common.Hash) (*gethTypes.Block, error) {
+	return m.GetBlockByNumber(ctx, 1)
+}
+
+func (m *mockBlockClient) GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]gethTypes.Log, error) {
+	return nil, nil
+}
+
+// newTestParser creates a parser over a mock client with 2 workers
+func newTestParser(client BlockClient, configure func(*types.Config)) *Parser {
+	config := types.DefaultConfig()
+	config.Workers = 2
+	if configure != nil {
+		configure(config)
+	}
+	return NewParser(client, config)
+}
+
+// TestParseBlockRangeNoBudget tests that the whole range is parsed without a budget
+func TestParseBlockRangeNoBudget(t *testing.T) {
+	p := newTestParser(&mockBlockClient{}, nil)
+
+	blocks, err := p.ParseBlockRange(context.Background(), 1, 20)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocks) != 20 {
+		t.Errorf("Expected 20 blocks, got %d", len(blocks))
+	}
+	if stats := p.GetStats(); stats.StoppedByBudget {
+		t.Errorf("Expected no budget stop, got reason %q", stats.BudgetReason)
+	}
+}
+
+// TestParseBlockRangeClock tests that the stats start/end times come from the parser clock
+func TestParseBlockRangeClock(t *testing.T) {
+	p := newTestParser(&mockBlockClient{}, nil)
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p.SetClock(types.NewFakeClock(now))
+
+	if _, err := p.ParseBlockRange(context.Background(), 1, 5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stats := p.GetStats()
+	if !stats.StartTime.Equal(now) || !stats.EndTime.Equal(now) || stats.TotalDuration != 0 {
+		t.Errorf("Expected start/end %v and no duration, got %v / %v / %v", now, stats.StartTime, stats.EndTime, stats.TotalDuration)
+	}
+}
+
+// TestParseBlockRangeOrdered tests that blocks are returned by number even when workers finish out of order
+func TestParseBlockRangeOrdered(t *testing.T) {
+	// lower blocks are slower, so they complete last
+	mock := &mockBlockClient{blockDelays: map[uint64]time.Duration{
+		1: 80 * time.Millisecond,
+		2: 60 * time.Millisecond,
+		3: 40 * time.Millisecond,
+		4: 20 * time.Millisecond,
+	}}
+	p := newTestParser(mock, func(c *types.Config) { c.Workers = 5 })
+
+	blocks, err := p.ParseBlockRange(context.Background(), 1, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocks) != 5 {
+		t.Fatalf("Expected 5 blocks, got %d", len(blocks))
+	}
+	if last := blocks[len(blocks)-1].Number; last != 5 {
+		t.Errorf("Expected last block 5, got %d", last)
+	}
+	for i, block := range blocks {
+		if block.Number != uint64(i+1) {
+			t.Errorf("Expected block %d at index %d, got %d", i+1, i, block.Number)
+		}
+	}
+}
+
+// TestParseBlockRangeMaxBlocks tests stopping after MaxBlocks blocks
+func TestParseBlockRangeMaxBlocks(t *testing.T) {
+	p := newTestParser(&mockBlockClient{}, func(c *types.Config) { c.MaxBlocks = 7 })
+
+	blocks, err := p.ParseBlockRange(context.Background(), 1, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocks) != 7 {
+		t.Errorf("Expected 7 blocks, got %d", len(blocks))
+	}
+	stats := p.GetStats()
+	if !stats.StoppedByBudget || stats.BudgetReason != "max_blocks" {
+		t.Errorf("Expected max_blocks budget stop, got %v %q", stats.StoppedByBudget, stats.BudgetReason)
+	}
+}
+
+// TestParseBlockRangeMaxDuration tests stopping after MaxDuration with in-flight blocks finished
+func TestParseBlockRangeMaxDuration(t *testing.T) {
+	p := newTestParser(&mockBlockClient{delay: 20 * time.Millisecond}, func(c *types.Config) {
+		c.MaxDuration = 100 * time.Millisecond
+	})
+
+	start := time.Now()
+	blocks, err := p.ParseBlockRange(context.Background(), 1, 1000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected early return, took %v", elapsed)
+	}
+	if len(blocks) == 0 || len(blocks) >= 1000 {
+		t.Errorf("Expected a partial result, got %d blocks", len(blocks))
+	}
+	stats := p.GetStats()
+	if !stats.StoppedByBudget || stats.BudgetReason != "max_duration" {
+		t.Errorf("Expected max_duration budget stop, got %v %q", stats.StoppedByBudget, stats.BudgetReason)
+	}
+}
+
+// mockStuckClient hangs on the stuck blocks until the request is canceled, like a node that stops answering
+type mockStuckClient struct {
+	mockBlockClient
+	stuck map[uint64]bool
+}
+
+func (m *mockStuckClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*gethTypes.Block, error) {
+	if m.stuck[blockNumber] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return m.mockBlockClient.GetBlockByNumber(ctx, blockNumber)
+}
+
+// TestParseBlockRangeTimeout tests that RangeTimeout cancels a stuck block and returns the blocks
+// before it, without the ones parsed after the gap
+func TestParseBlockRangeTimeout(t *testing.T) {
+	mock := &mockStuckClient{
+		mockBlockClient: mockBlockClient{delay: time.Millisecond},
+		stuck:           map[uint64]bool{6: true},
+	}
+	p := newTestParser(mock, func(c *types.Config) {
+		c.Workers = 3
+		c.RangeTimeout = 100 * time.Millisecond
+	})
+
+	start := time.Now()
+	blocks, err := p.ParseBlockRange(context.Background(), 1, 1000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected return soon after the timeout, took %v", elapsed)
+	}
+	if len(blocks) != 5 {
+		t.Fatalf("Expected blocks 1-5 before the stuck block, got %d blocks", len(blocks))
+	}
+	for i, block := range blocks {
+		if block.Number != uint64(i+1) {
+			t.Errorf("Expected block %d at index %d, got %d", i+1, i, block.Number)
+		}
+	}
+
+	stats := p.GetStats()
+	if !stats.StoppedByBudget || stats.BudgetReason != "timeout" {
+		t.Errorf("Expected timeout stop, got %v %q", stats.StoppedByBudget, stats.BudgetReason)
+	}
+	if stats.BlocksParsed <= 5 {
+		t.Errorf("Expected blocks after the stuck one to be parsed meanwhile, got %d", stats.BlocksParsed)
+	}
+}
+
+// TestParseBlockRangeCanceled tests that a caller canceling ctx is not reported as a timeout
+func TestParseBlockRangeCanceled(t *testing.T) {
+	p := newTestParser(&mockStuckClient{stuck: map[uint64]bool{3: true}}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	blocks, err := p.ParseBlockRange(ctx, 1, 10)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocks) != 2 {
+		t.Errorf("Expected blocks 1-2, got %d blocks", len(blocks))
+	}
+	if stats := p.GetStats(); stats.StoppedByBudget {
+		t.Errorf("Expected no budget stop for a canceled ctx, got reason %q", stats.BudgetReason)
+	}
+}
+
+// mockQuotaClient fails every block from quotaFrom on as if the daily request cap was used up
+type mockQuotaClient struct {
+	mockBlockClient
+	quotaFrom uint64
+}
+
+func (m *mockQuotaClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*gethTypes.Block, error) {
+	if blockNumber >= m.quotaFrom {
+		return nil, &client.QuotaExceededError{Day: "2024-01-01", Used: 10, Cap: 10}
+	}
+	return m.mockBlockClient.GetBlockByNumber(ctx, blockNumber)
+}
+
+// TestParseBlockRangeQuotaExceeded tests that an exhausted daily request cap stops the range
+// with the blocks parsed before it instead of failing every remaining block
+func TestParseBlockRangeQuotaExceeded(t *testing.T) {
+	p := newTestParser(&mockQuotaClient{quotaFrom: 5}, nil)
+
+	blocks, err := p.ParseBlockRange(context.Background(), 1, 1000)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocks) != 4 {
+		t.Fatalf("Expected blocks 1-4, got %d blocks", len(blocks))
+	}
+	stats := p.GetStats()
+	if !stats.StoppedByBudget || stats.BudgetReason != "daily_request_cap" {
+		t.Errorf("Expected daily_request_cap stop, got %v %q", stats.StoppedByBudget, stats.BudgetReason)
+	}
+	if stats.ErrorsEncountered > 10 {
+		t.Errorf("Expected the range to stop soon after the cap, got %d failed blocks", stats.ErrorsEncountered)
+	}
+}
+
+// TestParseBlockRangeMaxRangeSize tests that a range over MaxRangeSize fails before any block is fetched
+func TestParseBlockRangeMaxRangeSize(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end uint64
+		expectErr  bool
+	}{
+		{"at the limit", 1, 100, false},
+		{"over the limit", 0, 18000000, true},
+		{"one block over", 1, 101, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(&mockBlockClient{}, func(config *types.Config) {
+				config.MaxRangeSize = 100
+			})
+
+			blocks, err := p.ParseBlockRange(context.Background(), tt.start, tt.end)
+			if tt.expectErr {
+				if !errors.Is(err, ErrRangeTooLarge) {
+					t.Fatalf("Expected ErrRangeTooLarge, got %v", err)
+				}
+				if stats := p.GetStats(); stats.BlocksParsed != 0 || stats.ErrorsEncountered != 0 {
+					t.Errorf("Expected no blocks fetched, got %d parsed and %d failed", stats.BlocksParsed, stats.ErrorsEncountered)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if uint64(len(blocks)) != tt.end-tt.start+1 {
+				t.Errorf("Expected %d blocks, got %d", tt.end-tt.start+1, len(blocks))
+			}
+		})
+	}
+}
+
+// TestParseSingleBlockHeaderOnly tests that a header-only reconstructed block is marked degraded
+func TestParseSingleBlockHeaderOnly(t *testing.T) {
+	t.Run("Header only", func(t *testing.T) {
+		p := newTestParser(&mockBlockClient{headerOnly: true}, nil)
+
+		block, err := p.ParseSingleBlock(context.Background(), 42)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !block.Degraded {
+			t.Error("Expected block to be degraded")
+		}
+		if block.DegradedReason == "" {
+			t.Error("Expected degraded reason to be set")
+		}
+		if block.Number != 42 {
+			t.Errorf("Expected block 42, got %d", block.Number)
+		}
+	})
+
+	t.Run("Full block", func(t *testing.T) {
+		p := newTestParser(&mockBlockClient{}, nil)
+
+		block, err := p.ParseSingleBlock(context.Background(), 42)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if block.Degraded {
+			t.Errorf("Expected complete block, got degraded: %s", block.DegradedReason)
+		}
+	})
+}
+
+// TestParserClose tests that Close closes the injected client once and stops parsing
+func TestParserClose(t *testing.T) {
+	mock := &mockBlockClient{}
+	p := newTestParser(mock, nil)
+
+	p.Close()
+	p.Close()
+	if mock.closeCalls != 1 {
+		t.Errorf("Expected client to be closed once, got %d", mock.closeCalls)
+	}
+
+	if _, err := p.ParseBlockRange(context.Background(), 1, 5); !errors.Is(err, ErrParserClosed) {
+		t.Errorf("Expected ErrParserClosed from ParseBlockRange, got %v", err)
+	}
+	if _, err := p.ParseSingleBlock(context.Background(), 1); !errors.Is(err, ErrParserClosed) {
+		t.Errorf("Expected ErrParserClosed from ParseSingleBlock, got %v", err)
+	}
+}
+
+// TestRecordResultBlockTimes tests min/max/avg per-block parse time aggregation
+func TestRecordResultBlockTimes(t *testing.T) {
+	p := newTestParser(&mockBlockClient{}, func(c *types.Config) { c.SlowBlockThreshold = 250 * time.Millisecond })
+
+	results := []*types.ParseResult{
+		{Block: &types.ParsedBlock{Number: 1}, ProcessTime: 100 * time.Millisecond},
+		{Block: &types.ParsedBlock{Number: 2}, ProcessTime: 300 * time.Millisecond},
+		{Block: &types.ParsedBlock{Number: 3}, ProcessTime: 200 * time.Millisecond},
+		// errors are not part of the timing aggregates
+		{Error: errors.New("boom"), ProcessTime: time.Hour},
+	}
+	for _, r := range results {
+		p.recordResult(r)
+	}
+
+	stats := p.GetStats()
+	if stats.BlocksParsed != 3 || stats.ErrorsEncountered != 1 {
+		t.Fatalf("Expected 3 parsed and 1 error, got %d and %d", stats.BlocksParsed, stats.ErrorsEncountered)
+	}
+	if stats.MinBlockTime != 100*time.Millisecond {
+		t.Errorf("Expected min 100ms, got %v", stats.MinBlockTime)
+	}
+	if stats.MaxBlockTime != 300*time.Millisecond || stats.SlowestBlock != 2 {
+		t.Errorf("Expected max 300ms at block 2, got %v at block %d", stats.MaxBlockTime, stats.SlowestBlock)
+	}
+	if stats.AvgBlockTime != 200*time.Millisecond {
+		t.Errorf("Expected avg 200ms, got %v", stats.AvgBlockTime)
+	}
+	if stats.SlowBlocks != 1 {
+		t.Errorf("Expected 1 slow block, got %d", stats.SlowBlocks)
+	}
+}
+
+// TestRecordResultTxTypeCounts tests per-type transaction counts for a mixed-type block
+func TestRecordResultTxTypeCounts(t *testing.T) {
+	p := newTestParser(&mockBlockClient{}, nil)
+
+	var txs []*types.ParsedTransaction
+	for _, txType := range []uint8{0, 0, 1, 2, 2, 2, 3, types.UnknownTxType} {
+		txs = append(txs, &types.ParsedTransaction{Type: txType})
+	}
+	p.recordResult(&types.ParseResult{Block: &types.ParsedBlock{Number: 1, Transactions: txs}})
+	p.recordResult(&types.ParseResult{Block: &types.ParsedBlock{Number: 2, Transactions: txs[:1]}})
+
+	expected := map[uint8]uint64{0: 3, 1: 1, 2: 3, 3: 1, types.UnknownTxType: 1}
+	stats := p.GetStats()
+	if len(stats.TxTypeCounts) != len(expected) {
+		t.Errorf("Expected %d tx types, got %v", len(expected), stats.TxTypeCounts)
+	}
+	for txType, count := range expected {
+		if stats.TxTypeCounts[txType] != count {
+			t.Errorf("Expected %d txs of type %d, got %d", count, txType, stats.TxTypeCounts[txType])
+		}
+	}
+
+	// the returned map is a copy
+	stats.TxTypeCounts[0] = 100
+	if p.GetStats().TxTypeCounts[0] != 3 {
+		t.Error("GetStats must return a copy of TxTypeCounts")
+	}
+}
+
+// TestUnknownSender tests counting and dropping transactions whose sender can't be recovered
+func TestUnknownSender(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	chainID := big.NewInt(1)
+	signed, err := gethTypes.SignNewTx(key, gethTypes.LatestSignerForChainID(chainID), &gethTypes.DynamicFeeTx{
+		ChainID: chainID, Nonce: 1, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Value: big.NewInt(1),
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+	// unsigned legacy tx: no chain ID and no signature, sender can't be recovered
+	unsigned := gethTypes.NewTx(&gethTypes.LegacyTx{Nonce: 2, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1)})
+
+	tests := []struct {
+		name     string
+		drop     bool
+		wantTxs  int
+		wantFrom []string
+	}{
+		{"Keep", false, 2, []string{crypto.PubkeyToAddress(key.PublicKey).Hex(), types.UnknownSender}},
+		{"Drop", true, 1, []string{crypto.PubkeyToAddress(key.PublicKey).Hex()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestParser(&mockBlockClient{txs: []*gethTypes.Transaction{signed, unsigned}}, func(c *types.Config) {
+				c.DropUnknownSender = tt.drop
+				c.MaxTransactionsForReceipts = 0
+			})
+
+			block, err := p.ParseSingleBlock(context.Background(), 1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(block.Transactions) != tt.wantTxs {
+				t.Fatalf("Expected %d transactions, got %d", tt.wantTxs, len(block.Transactions))
+			}
+			for i, from := range tt.wantFrom {
+				if block.Transactions[i].From != from {
+					t.Errorf("Expected tx %d from %s, got %s", i, from, block.Transactions[i].From)
+				}
+			}
+			if stats := p.GetStats(); stats.UnknownSenders != 1 {
+				t.Errorf("Expected 1 unknown sender, got %d", stats.UnknownSenders)
+			}
+		})
+	}
+}
+
+// TestAccessListTransaction tests that the access list of a type 1 transaction is preserved
+func TestAccessListTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	slot := common.HexToHash("0x01")
+	chainID := big.NewInt(1)
+	accessListTx, err := gethTypes.SignNewTx(key, gethTypes.LatestSignerForChainID(chainID), &gethTypes.AccessListTx{
+		ChainID:    chainID,
+		Nonce:      1,
+		Gas:        30000,
+		GasPrice:   big.NewInt(1),
+		To:         &contract,
+		AccessList: gethTypes.AccessList{{Address: contract, StorageKeys: []common.Hash{slot}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign transaction: %v", err)
+	}
+
+	// both parse paths: without receipts (large block) and with receipts
+	for _, maxForReceipts := range []int{0, 10} {
+		p := newTestParser(&mockBlockClient{txs: []*gethTypes.Transaction{accessListTx}}, func(c *types.Config) {
+			c.MaxTransactionsForReceipts = maxForReceipts
+			c.IncludeLogs = true
+		})
+
+		block, err := p.ParseSingleBlock(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(block.Transactions) != 1 {
+			t.Fatalf("Expected 1 transaction, got %d", len(block.Transactions))
+		}
+
+		tx := block.Transactions[0]
+		if tx.Type != gethTypes.AccessListTxType {
+			t.Errorf("Expected type 1, got %d", tx.Type)
+		}
+		if len(tx.AccessList) != 1 || tx.AccessList[0].Address != contract.Hex() ||
+			len(tx.AccessList[0].StorageKeys) != 1 || tx.AccessList[0].StorageKeys[0] != slot.Hex() {
+			t.Errorf("Unexpected access list: %+v", tx.AccessList)
+		}
+
+		data, err := json.Marshal(tx)
+		if err != nil {
+			t.Fatalf("Failed to marshal transaction: %v", err)
+		}
+		if !strings.Contains(string(data), `"access_list":[{"address":"`+contract.Hex()) {
+			t.Errorf("Expected access list in JSON, got %s", data)
+		}
+	}
+}
+
+// TestLargeBlockReceipts tests that receipts are skipped when either the tx count or the gas limit is exceeded
+func TestLargeBlockReceipts(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	chainID := big.NewInt(1)
+	var txs []*gethTypes.Transaction
+	for nonce := uint64(0); nonce < 5; nonce++ {
+		tx, err := gethTypes.SignNewTx(key, gethTypes.LatestSignerForChainID(chainID), &gethTypes.DynamicFeeTx{
+			ChainID: chainID, Nonce: nonce, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Value: big.NewInt(1),
+		})
+		if err != nil {
+			t.Fatalf("Failed to sign transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+
+	tests := []struct {
+		name         string
+		txs          int
+		gasUsed      uint64
+		maxTxs       int
+		maxGas       uint64
+		skipDisabled bool
+		wantSkip     bool
+	}{
+		{"Low count high gas", 1, 25_000_000, 10, 10_000_000, false, true},
+		{"High count low gas", 5, 105_000, 2, 10_000_000, false, true},
+		{"Both below limits", 2, 42_000, 10, 10_000_000, false, false},
+		{"Gas at limit", 1, 10_000_000, 10, 10_000_000, false, false},
+		{"No gas limit", 1, 25_000_000, 10, 0, false, false},
+		{"Skipping disabled", 5, 25_000_000, 2, 10_000_000, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// a receipt fetch fails the block, so success means receipts were skipped
+			mock := &mockBlockClient{txs: txs[:tt.txs], gasUsed: tt.gasUsed}
+			mock.err = errors.New("receipts fetched")
+			p := newTestParser(mock, func(c *types.Config) {
+				c.IncludeLogs = true
+				c.MaxTransactionsForReceipts = tt.maxTxs
+				c.MaxGasForReceipts = tt.maxGas
+				c.SkipReceiptsOnLargeBlocks = !tt.skipDisabled
+			})
+
+			if reason := p.largeBlockReason(tt.txs, tt.gasUsed); (reason != "") != tt.wantSkip {
+				t.Errorf("Expected skip %v, got reason %q", tt.wantSkip, reason)
+			}
+
+			block, err := p.ParseSingleBlock(context.Background(), 1)
+			if tt.wantSkip {
+				if err != nil {
+					t.Fatalf("Expected receipts to be skipped, got %v", err)
+				}
+				if len(block.Transactions) != tt.txs {
+					t.Errorf("Expected %d transactions, got %d", tt.txs, len(block.Transactions))
+				}
+			} else if err == nil || !strings.Contains(err.Error(), "receipts fetched") {
+				t.Errorf("Expected receipts to be fetched, got %v", err)
+			}
+		})
+	}
+}
+
+// TestParseErrorsDeadLetter tests that a transaction failing to parse is routed to ParseErrors
+// instead of a placeholder record, and that ReparseErrors recovers it once it parses
+func TestParseErrorsDeadLetter(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	chainID := big.NewInt(1)
+	var txs []*gethTypes.Transaction
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		tx, err := gethTypes.SignNewTx(key, gethTypes.LatestSignerForChainID(chainID), &gethTypes.DynamicFeeTx{
+			ChainID: chainID, Nonce: nonce, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Value: big.NewInt(1),
+		})
+		if err != nil {
+			t.Fatalf("Failed to sign transaction: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	good, bad := txs[0], txs[1]
+
+	// a nil log in the receipt makes parsing of the bad tx panic
+	mock := &mockBlockClient{txs: txs}
+	mock.receipts = map[common.Hash]*gethTypes.Receipt{
+		good.Hash(): {Status: 1, GasUsed: 21000},
+		bad.Hash():  {Status: 1, GasUsed: 21000, Logs: []*gethTypes.Log{nil}},
+	}
+	p := newTestParser(mock, func(c *types.Config) {
+		c.IncludeLogs = true
+		c.MaxTransactionsForReceipts = 10
+	})
+
+	block, err := p.ParseSingleBlock(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(block.Transactions) != 1 || block.Transactions[0].Hash != good.Hash().Hex() {
+		t.Fatalf("Expected only the good transaction, got %d transactions", len(block.Transactions))
+	}
+	for _, tx := range block.Transactions {
+		if tx.Type == types.UnknownTxType || tx.InputData == "parse_error" {
+			t.Errorf("Expected no placeholder records, got %+v", tx)
+		}
+	}
+	if len(block.ParseErrors) != 1 {
+		t.Fatalf("Expected 1 parse error, got %d", len(block.ParseErrors))
+	}
+	pe := block.ParseErrors[0]
+	if pe.TxHash != bad.Hash().Hex() || pe.BlockNumber != 7 || pe.TransactionIndex != 1 || !strings.Contains(pe.Error, "panic") {
+		t.Errorf("Unexpected parse error: %+v", pe)
+	}
+
+	if _, err := p.ParseBlockRange(context.Background(), 1, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats := p.GetStats(); stats.TxParseErrors != 2 || stats.TransactionsParsed != 2 {
+		t.Errorf("Expected 2 parse errors and 2 parsed txs, got %d and %d", stats.TxParseErrors, stats.TransactionsParsed)
+	}
+
+	// still failing: stays a parse error
+	blocks, fixed, err := p.ReparseErrors(context.Background(), block.ParseErrors)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fixed) != 0 || len(blocks) != 1 || len(blocks[0].Transactions) != 0 || len(blocks[0].ParseErrors) != 1 {
+		t.Errorf("Expected the tx to keep failing, got fixed %v", fixed)
+	}
+
+	// fixed receipt: only the previously failing tx is returned
+	mock.receipts[bad.Hash()] = &gethTypes.Receipt{Status: 1, GasUsed: 21000}
+	blocks, fixed, err = p.ReparseErrors(context.Background(), block.ParseErrors)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(fixed) != 1 || fixed[0] != bad.Hash().Hex() {
+		t.Errorf("Expected %s fixed, got %v", bad.Hash().Hex(), fixed)
+	}
+	if len(blocks) != 1 || len(blocks[0].Transactions) != 1 || blocks[0].Transactions[0].Hash != bad.Hash().Hex() || len(blocks[0].ParseErrors) != 0 {
+		t.Errorf("Expected block with only the reprocessed tx, got %+v", blocks)
+	}
+}
+
+// TestParseSingleBlockWithdrawals tests that post-Shanghai withdrawals are parsed with amounts in wei
+func TestParseSingleBlockWithdrawals(t *testing.T) {
+	recipient := common.HexToAddress("0x00000000219ab540356cbb839cbe05303d7705fa")
+
+	t.Run("Post-Shanghai block", func(t *testing.T) {
+		p := newTestParser(&mockBlockClient{withdrawals: []*gethTypes.Withdrawal{
+			{Index: 7, Validator: 1001, Address: recipient, Amount: 32_000_000_000},
+			{Index: 8, Validator: 1002, Address: recipient, Amount: 15},
+		}}, nil)
+
+		block, err := p.ParseSingleBlock(context.Background(), 17034870)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(block.Withdrawals) != 2 {
+			t.Fatalf("Expected 2 withdrawals, got %d", len(block.Withdrawals))
+		}
+
+		w := block.Withdrawals[0]
+		if w.Index != 7 || w.ValidatorIndex != 1001 {
+			t.Errorf("Expected index 7 validator 1001, got %d %d", w.Index, w.ValidatorIndex)
+		}
+		if w.Address != recipient.Hex() {
+			t.Errorf("Expected address %s, got %s", recipient.Hex(), w.Address)
+		}
+		wantWei, _ := new(big.Int).SetString("32000000000000000000", 10)
+		if w.Amount.Cmp(wantWei) != 0 {
+			t.Errorf("Expected amount %s wei, got %s", wantWei, w.Amount)
+		}
+		if got := block.Withdrawals[1].Amount.String(); got != "15000000000" {
+			t.Errorf("Expected amount 15000000000 wei, got %s", got)
+		}
+	})
+
+	t.Run("Pre-Shanghai block", func(t *testing.T) {
+		p := newTestParser(&mockBlockClient{}, nil)
+
+		block, err := p.ParseSingleBlock(context.Background(), 42)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if block.Withdrawals != nil {
+			t.Errorf("Expected no withdrawals, got %v", block.Withdrawals)
+		}
+		data, err := json.Marshal(block)
+		if err != nil {
+			t.Fatalf("Failed to marshal block: %v", err)
+		}
+		if strings.Contains(string(data), "withdrawals") {
+			t.Errorf("Expected withdrawals to be omitted from JSON, got %s", data)
+		}
+	})
+}
+
+// TestFilterTransactionsByAddress tests that address filtering matches checksummed and lowercase forms
+func TestFilterTransactionsByAddress(t *testing.T) {
+	p := newTestParser(&mockBlockClient{}, nil)
+	whale := "0xdAC17F958D2ee523a2206206994597C13D831ec7"
+	lowerWhale := strings.ToLower(whale)
+	other := "0x0000000000000000000000000000000000000002"
+	txs := []*types.ParsedTransaction{
+		{Hash: "0xfrom", From: whale, To: &other},
+		{Hash: "0xto", From: "0x0000000000000000000000000000000000000001", To: &lowerWhale},
+		{Hash: "0xother", From: "0x0000000000000000000000000000000000000001", To: nil},
+	}
+
+	filtered := p.FilterTransactionsByAddress(txs, []string{lowerWhale})
+	if len(filtered) != 2 || filtered[0].Hash != "0xfrom" || filtered[1].Hash != "0xto" {
+		t.Errorf("Expected 0xfrom and 0xto, got %d transactions", len(filtered))
+	}
+
+	if all := p.FilterTransactionsByAddress(txs, nil); len(all) != len(txs) {
+		t.Errorf("Expected all %d transactions without addresses, got %d", len(txs), len(all))
+	}
+}
+
+// receiptTrackingClient records the peak number of concurrent receipt batch calls
+type receiptTrackingClient struct {
+	mockBlockClient
+	receiptDelay time.Duration
+	mu           sync.Mutex
+	inFlight     int
+	peak         int
+}
+
+func (m *receiptTrackingClient) GetTransactionReceiptsBatch(ctx context.Context, txHashes []common.Hash) ([]*gethTypes.Receipt, error) {
+	m.mu.Lock()
+	m.inFlight++
+	m.peak = max(m.peak, m.inFlight)
+	m.mu.Unlock()
+
+	time.Sleep(m.receiptDelay)
+
+	m.mu.Lock()
+	m.inFlight--
+	m.mu.Unlock()
+	return m.mockBlockClient.GetTransactionReceiptsBatch(ctx, txHashes)
+}
+
+// TestReceiptWorkers tests that receipt batch calls are capped independently of block workers
+func TestReceiptWorkers(t *testing.T) {
+	tx := gethTypes.NewTx(&gethTypes.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1)})
+
+	tests := []struct {
+		name           string
+		receiptWorkers int
+		wantMaxPeak    int
+	}{
+		{"Capped at 1", 1, 1},
+		{"Capped at 2", 2, 2},
+		{"Unlimited follows block workers", 0, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &receiptTrackingClient{
+				mockBlockClient: mockBlockClient{txs: []*gethTypes.Transaction{tx}},
+				receiptDelay:    20 * time.Millisecond,
+			}
+			p := newTestParser(client, func(c *types.Config) {
+				c.Workers = 8
+				c.ReceiptWorkers = tt.receiptWorkers
+				c.IncludeLogs = true
+				c.SkipReceiptsOnLargeBlocks = false
+			})
+
+			blocks, err := p.ParseBlockRange(context.Background(), 1, 16)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(blocks) != 16 {
+				t.Fatalf("Expected 16 blocks, got %d", len(blocks))
+			}
+			if client.peak > tt.wantMaxPeak {
+				t.Errorf("Expected at most %d concurrent receipt calls, got %d", tt.wantMaxPeak, client.peak)
+			}
+			if tt.receiptWorkers > 0 && client.peak != tt.receiptWorkers {
+				t.Errorf("Expected receipt calls to reach the cap %d, got %d", tt.receiptWorkers, client.peak)
+			}
+		})
+	}
+}
+
+// TestReceiptFetched tests that transactions parsed without receipts are marked unfetched, not failed
+func TestReceiptFetched(t *testing.T) {
+	tx := gethTypes.NewTx(&gethTypes.LegacyTx{Nonce: 1, Gas: 21000, GasPrice: big.NewInt(1), Value: big.NewInt(1)})
+
+	tests := []struct {
+		name        string
+		skip        bool
+		wantFetched bool
+		wantStatus  uint64
+	}{
+		{"Receipts skipped", true, false, 0},
+		{"Receipt fetched", false, true, gethTypes.ReceiptStatusSuccessful},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockBlockClient{txs: []*gethTypes.Transaction{tx}}
+			client.receipts = map[common.Hash]*gethTypes.Receipt{tx.Hash(): {Status: gethTypes.ReceiptStatusSuccessful, GasUsed: 21000}}
+			p := newTestParser(client, func(c *types.Config) {
+				c.IncludeLogs = true
+				c.SkipReceiptsOnLargeBlocks = tt.skip
+				c.MaxTransactionsForReceipts = 0
+			})
+
+			block, err := p.ParseSingleBlock(context.Background(), 1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(block.Transactions) != 1 {
+				t.Fatalf("Expected 1 transaction, got %d", len(block.Transactions))
+			}
+			parsed := block.Transactions[0]
+			if parsed.ReceiptFetched != tt.wantFetched {
+				t.Errorf("Expected receipt_fetched %v, got %v", tt.wantFetched, parsed.ReceiptFetched)
+			}
+			if parsed.Status != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, parsed.Status)
+			}
+		})
+	}
+}
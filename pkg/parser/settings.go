@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+)
+
+// Keys of the runtime-tunable settings, stored in the settings table and overriding the config
+const (
+	SettingMinETHValue    = "min_eth_value"   // whale threshold in ETH, fractional like "0.5"
+	SettingRetentionDays  = "retention_days"  // days stored transactions are kept, 0 = forever
+	SettingWhaleDirection = "whale_direction" // from, to or both
+)
+
+// Settings is the source of runtime-tunable settings, implemented by database.SettingsRepository
+type Settings interface {
+	All(ctx context.Context) (map[string]string, error)
+}
+
+var _ Settings = (*database.SettingsRepository)(nil)
+
+// settingAppliers validate a setting value and write it into the config
+var settingAppliers = map[string]func(config *types.Config, value string) error{
+	SettingMinETHValue: func(config *types.Config, value string) error {
+		minValue, err := filtering.ParseMinETH(value)
+		if err != nil {
+			return err
+		}
+		// MinETHDecimal overrides MinETHValue, which still has to follow for a "0" threshold
+		config.MinETHDecimal = minValue.String()
+		config.MinETHValue = uint64(minValue.IntPart())
+		return nil
+	},
+	SettingRetentionDays: func(config *types.Config, value string) error {
+		days, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || days < 0 {
+			return fmt.Errorf("invalid retention days %q, expected a non-negative integer", value)
+		}
+		config.RetentionDays = days
+		return nil
+	},
+	SettingWhaleDirection: func(config *types.Config, value string) error {
+		direction, err := filtering.ParseDirection(value)
+		if err != nil {
+			return err
+		}
+		config.WhaleDirection = direction
+		return nil
+	},
+}
+
+// SettingKeys returns the keys of all runtime-tunable settings, sorted
+func SettingKeys() []string {
+	keys := make([]string, 0, len(settingAppliers))
+	for key := range settingAppliers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ValidateSetting checks a setting before it is stored, an empty value (reset to default) is valid
+func ValidateSetting(key, value string) error {
+	apply, ok := settingAppliers[key]
+	if !ok {
+		return fmt.Errorf("unknown setting %q, expected one of %s", key, strings.Join(SettingKeys(), ", "))
+	}
+	if value == "" {
+		return nil
+	}
+	return apply(types.DefaultConfig(), value)
+}
+
+// ApplySettings returns a copy of config with the stored settings applied. Settings that are not
+// stored keep the config value, invalid stored values are logged and ignored.
+func ApplySettings(ctx context.Context, config *types.Config, settings Settings) (*types.Config, error) {
+	values, err := settings.All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+
+	tuned := *config
+	for _, key := range SettingKeys() {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if err := settingAppliers[key](&tuned, value); err != nil {
+			log.Printf("Warning: ignoring setting %s: %v", key, err)
+		}
+	}
+	return &tuned, nil
+}
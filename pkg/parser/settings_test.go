@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+)
+
+// mapSettings is a Settings source backed by a map
+type mapSettings struct {
+	values map[string]string
+	err    error
+}
+
+func (m mapSettings) All(ctx context.Context) (map[string]string, error) {
+	return m.values, m.err
+}
+
+// TestApplySettings tests that stored settings override the config and missing or invalid ones keep it
+func TestApplySettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		values        map[string]string
+		wantMinETH    string
+		wantDays      int
+		wantDirection string
+	}{
+		{name: "Nothing stored", values: map[string]string{}, wantMinETH: "", wantDays: 14, wantDirection: "both"},
+		{
+			name:          "All stored",
+			values:        map[string]string{SettingMinETHValue: "2.5", SettingRetentionDays: "3", SettingWhaleDirection: "to"},
+			wantMinETH:    "2.5",
+			wantDays:      3,
+			wantDirection: "to",
+		},
+		{
+			name:          "Invalid ignored",
+			values:        map[string]string{SettingMinETHValue: "lots", SettingRetentionDays: "-1", SettingWhaleDirection: "up"},
+			wantMinETH:    "",
+			wantDays:      14,
+			wantDirection: "both",
+		},
+		{name: "Unknown ignored", values: map[string]string{"workers": "100"}, wantMinETH: "", wantDays: 14, wantDirection: "both"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := types.DefaultConfig()
+			tuned, err := ApplySettings(context.Background(), config, mapSettings{values: tt.values})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tuned.MinETHDecimal != tt.wantMinETH {
+				t.Errorf("Expected min ETH %q, got %q", tt.wantMinETH, tuned.MinETHDecimal)
+			}
+			if tuned.RetentionDays != tt.wantDays {
+				t.Errorf("Expected %d retention days, got %d", tt.wantDays, tuned.RetentionDays)
+			}
+			if tuned.WhaleDirection != tt.wantDirection {
+				t.Errorf("Expected direction %s, got %s", tt.wantDirection, tuned.WhaleDirection)
+			}
+			if config.MinETHDecimal != "" || config.RetentionDays != 14 || config.WhaleDirection != "both" {
+				t.Errorf("Expected config left unchanged, got %+v", config)
+			}
+		})
+	}
+
+	if _, err := ApplySettings(context.Background(), types.DefaultConfig(), mapSettings{err: errors.New("db down")}); err == nil {
+		t.Error("Expected error from a failing settings source, got nil")
+	}
+}
+
+// TestValidateSetting tests the checks run before a setting is stored
+func TestValidateSetting(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{SettingMinETHValue, "0.5", false},
+		{SettingMinETHValue, "-1", true},
+		{SettingRetentionDays, "0", false},
+		{SettingRetentionDays, "1.5", true},
+		{SettingWhaleDirection, "FROM", false},
+		{SettingWhaleDirection, "sideways", true},
+		{SettingWhaleDirection, "", false},
+		{"workers", "10", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			err := ValidateSetting(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestApplySettingsThreshold tests that whale filtering follows a threshold updated in the settings table
+func TestApplySettingsThreshold(t *testing.T) {
+	ctx := context.Background()
+	dm, err := database.NewDatabaseManager(database.InMemoryConfig(), nil)
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if err := database.NewSchema(nil).CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	whale := "0x0000000000000000000000000000000000000001"
+	to := "0x0000000000000000000000000000000000000002"
+	eth := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e18)) }
+	blocks := []*types.ParsedBlock{{
+		Number: 100,
+		Transactions: []*types.ParsedTransaction{
+			{Hash: "0xsmall", BlockNumber: 100, From: whale, To: &to, Value: eth(2)},
+			{Hash: "0xlarge", BlockNumber: 100, From: whale, To: &to, Value: eth(10)},
+		},
+	}}
+	whaleIDs := map[string]string{whale: "1"}
+
+	config := types.DefaultConfig()
+	settings := database.NewSettingsRepository(dm, nil, 0)
+	whaleHashes := func() []string {
+		tuned, err := ApplySettings(ctx, config, settings)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var hashes []string
+		for _, tx := range filtering.ParseWhaleTransactionsWithFilter(blocks, whaleIDs, filtering.NewWhaleFilter(tuned)) {
+			hashes = append(hashes, tx.TxHash)
+		}
+		return hashes
+	}
+
+	if hashes := whaleHashes(); len(hashes) != 2 {
+		t.Fatalf("Expected both txs with the default 1 ETH threshold, got %v", hashes)
+	}
+
+	if err := settings.SetString(ctx, SettingMinETHValue, "5"); err != nil {
+		t.Fatalf("Failed to store setting: %v", err)
+	}
+	if hashes := whaleHashes(); len(hashes) != 1 || hashes[0] != "0xlarge" {
+		t.Errorf("Expected only 0xlarge with a 5 ETH threshold, got %v", hashes)
+	}
+
+	if err := settings.SetString(ctx, SettingMinETHValue, ""); err != nil {
+		t.Fatalf("Failed to reset setting: %v", err)
+	}
+	if hashes := whaleHashes(); len(hashes) != 2 {
+		t.Errorf("Expected both txs after reset to the config default, got %v", hashes)
+	}
+}
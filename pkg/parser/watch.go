@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// Watch runs ParseNew every interval until ctx is done, a daemon alternative to running the parser from cron.
+// A failed cycle is logged and retried on the next tick. Cancelling ctx doesn't interrupt the cycle in
+// progress: its blocks are still handled and the last block saved, then Watch returns nil.
+// onCycle, if not nil, is called after every cycle with its error.
+func (m *MultiParser) Watch(ctx context.Context, interval time.Duration, handle BlocksHandler, onCycle func(err error)) error {
+	if interval <= 0 {
+		return errors.New("watch interval must be positive")
+	}
+	cycleCtx := context.WithoutCancel(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := m.ParseNew(cycleCtx, handle)
+		if err != nil {
+			log.Printf("Watch cycle failed: %v", err)
+		}
+		if onCycle != nil {
+			onCycle(err)
+		}
+
+		// checked first, select picks randomly when a tick is ready too
+		if ctx.Err() != nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
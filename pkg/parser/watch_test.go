@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
+)
+
+// mockHeadClient returns the next head from heads on every call, then keeps the last one.
+// A zero head stands for a failing call.
+type mockHeadClient struct {
+	mockBlockClient
+	mu    sync.Mutex
+	heads []uint64
+	calls int
+}
+
+func (m *mockHeadClient) GetLatestBlockNumber(ctx context.Context) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	head := m.heads[min(m.calls, len(m.heads)-1)]
+	m.calls++
+	if head == 0 {
+		return 0, errors.New("connection refused")
+	}
+	return head, nil
+}
+
+// TestWatch tests that the watch loop parses only new blocks each cycle, survives a failed cycle
+// and finishes the cycle in progress when stopped
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	config := types.DefaultConfig()
+	config.Workers = 2
+	config.MaxBlockDelta = 100
+	config.LastBlockPath = filepath.Join(dir, "last_block.dat")
+	filtering.WriteLastBlock(config.LastBlockPath, 5)
+
+	client := &mockHeadClient{heads: []uint64{10, 10, 0, 12, 15}}
+	multi, err := NewMultiParser([]Network{{Name: "mainnet", Client: client, Config: config}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	type blockRange struct{ first, last uint64 }
+	var handled []blockRange
+	var cycleErrs []error
+	err = multi.Watch(ctx, time.Millisecond, func(ctx context.Context, network string, blocks []*types.ParsedBlock) error {
+		handled = append(handled, blockRange{blocks[0].Number, blocks[len(blocks)-1].Number})
+		if len(handled) == 3 {
+			// stop requested while the cycle is in progress
+			cancel()
+		}
+		if ctx.Err() != nil {
+			t.Error("Expected the cycle in progress not to be canceled")
+		}
+		return nil
+	}, func(err error) {
+		cycleErrs = append(cycleErrs, err)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []blockRange{{5, 10}, {10, 12}, {12, 15}}
+	if len(handled) != len(expected) {
+		t.Fatalf("Expected %d handled cycles, got %d: %v", len(expected), len(handled), handled)
+	}
+	for i, r := range expected {
+		if handled[i] != r {
+			t.Errorf("Expected cycle %d to handle blocks %v, got %v", i, r, handled[i])
+		}
+	}
+
+	// 10, 10 (skipped), failure, 12, 15
+	if len(cycleErrs) != 5 {
+		t.Fatalf("Expected 5 cycles, got %d", len(cycleErrs))
+	}
+	for i, err := range cycleErrs {
+		if (err != nil) != (i == 2) {
+			t.Errorf("Expected only cycle 2 to fail, cycle %d got %v", i, err)
+		}
+	}
+
+	if last := filtering.ReadLastBlock(config.LastBlockPath); last != 15 {
+		t.Errorf("Expected last block 15 saved by the stopped cycle, got %d", last)
+	}
+}
+
+// TestWatchInvalidInterval tests that a non-positive interval is rejected
+func TestWatchInvalidInterval(t *testing.T) {
+	multi, err := NewMultiParser([]Network{newTestNetwork(t.TempDir(), "mainnet", &mockNetworkClient{latest: 1})})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := multi.Watch(context.Background(), 0, nil, nil); err == nil {
+		t.Error("Expected error for zero interval, got nil")
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// buildOpenAPISpec generates an OpenAPI 3 document from the route table
+func (s *Server) buildOpenAPISpec() map[string]interface{} {
+	schemas := map[string]interface{}{
+		"APIResponse":    schemaFor(reflect.TypeOf(APIResponse{}), nil),
+		"PaginationMeta": schemaFor(reflect.TypeOf(PaginationMeta{}), nil),
+	}
+
+	paths := make(map[string]interface{})
+	for _, rt := range s.routes() {
+		operation := map[string]interface{}{
+			"summary":   rt.Summary,
+			"responses": routeResponses(rt, schemas),
+		}
+
+		if len(rt.Params) > 0 {
+			params := make([]interface{}, 0, len(rt.Params))
+			for _, p := range rt.Params {
+				params = append(params, map[string]interface{}{
+					"name":        p.Name,
+					"in":          p.In,
+					"required":    p.Required || p.In == "path",
+					"description": p.Description,
+					"schema":      map[string]interface{}{"type": p.Type},
+				})
+			}
+			operation["parameters"] = params
+		}
+
+		if rt.Auth {
+			operation["security"] = []interface{}{map[string]interface{}{"basicAuth": []interface{}{}}}
+		}
+
+		item, ok := paths[rt.Path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[rt.Path] = item
+		}
+		item[strings.ToLower(rt.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "SQLite Blockchain API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"basicAuth": map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+// routeResponses describes the success and error responses of a route
+func routeResponses(rt route, schemas map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{
+		"success": map[string]interface{}{"type": "boolean"},
+		"data":    schemaFor(reflect.TypeOf(rt.Response), schemas),
+	}
+	if rt.Paged {
+		properties["count"] = map[string]interface{}{"type": "integer"}
+		properties["meta"] = map[string]interface{}{"$ref": "#/components/schemas/PaginationMeta"}
+	}
+
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/APIResponse"},
+			},
+		},
+	}
+
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "Success",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object", "properties": properties},
+				},
+			},
+		},
+		"default": errorResponse,
+	}
+	if rt.Auth {
+		responses["401"] = errorResponse
+	}
+	return responses
+}
+
+// schemaFor derives an OpenAPI schema from a Go type using its json tags.
+// Named structs are added to schemas and referenced, when schemas is not nil.
+func schemaFor(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		nullable = true
+		t = t.Elem()
+	}
+
+	var schema map[string]interface{}
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		schema = map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Bool:
+		schema = map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema = map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema = map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.String:
+		schema = map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		schema = map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), schemas)}
+	case t.Kind() == reflect.Map:
+		schema = map[string]interface{}{"type": "object"}
+	case t.Kind() == reflect.Struct:
+		if schemas != nil && t.Name() != "" {
+			if _, ok := schemas[t.Name()]; !ok {
+				schemas[t.Name()] = nil // placeholder to stop recursion
+				schemas[t.Name()] = structSchema(t, schemas)
+			}
+			schema = map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+		} else {
+			schema = structSchema(t, schemas)
+		}
+	default:
+		// interface{} and anything else: any value
+		schema = map[string]interface{}{}
+	}
+
+	if nullable {
+		if _, isRef := schema["$ref"]; !isRef {
+			schema["nullable"] = true
+		}
+	}
+	return schema
+}
+
+// structSchema builds an object schema from the exported json fields of a struct
+func structSchema(t reflect.Type, schemas map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = schemaFor(field.Type, schemas)
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// openAPISpec handles GET /openapi.json
+func (s *Server) openAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(s.buildOpenAPISpec()); err != nil {
+		s.logger.Printf("Failed to encode OpenAPI spec: %v", err)
+	}
+}
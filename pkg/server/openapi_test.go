@@ -0,0 +1,76 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPISpec tests that /openapi.json is valid JSON and documents every registered route
+func TestOpenAPISpec(t *testing.T) {
+	s := NewServer(nil, nil, nil)
+
+	rec := httptest.NewRecorder()
+	s.setupRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+
+	var spec struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Spec is not valid JSON: %v", err)
+	}
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected OpenAPI 3.x, got %q", spec.OpenAPI)
+	}
+
+	for _, rt := range s.routes() {
+		item, ok := spec.Paths[rt.Path]
+		if !ok {
+			t.Errorf("Route %s (%s) missing from spec", rt.Path, rt.Pattern)
+			continue
+		}
+		op, ok := item[strings.ToLower(rt.Method)]
+		if !ok {
+			t.Errorf("Method %s missing for %s", rt.Method, rt.Path)
+			continue
+		}
+		if _, ok := op["responses"]; !ok {
+			t.Errorf("Responses missing for %s %s", rt.Method, rt.Path)
+		}
+		_, hasSecurity := op["security"]
+		if hasSecurity != rt.Auth {
+			t.Errorf("Expected security=%v for %s, got %v", rt.Auth, rt.Path, hasSecurity)
+		}
+	}
+
+	for _, name := range []string{"APIResponse", "PaginationMeta", "Transaction"} {
+		if spec.Components.Schemas[name] == nil {
+			t.Errorf("Schema %s missing from spec", name)
+		}
+	}
+}
+
+// TestRoutesRegistered tests that every route in the table is served by the mux
+func TestRoutesRegistered(t *testing.T) {
+	s := NewServer(nil, nil, nil)
+	mux := s.setupRoutes()
+
+	for _, rt := range s.routes() {
+		path := strings.NewReplacer("{hash}", "0xabc", "{address}", "0xdef", "{number}", "100").Replace(rt.Path)
+		_, pattern := mux.Handler(httptest.NewRequest(rt.Method, path, nil))
+		if pattern != rt.Pattern {
+			t.Errorf("Expected %s to be served by %s, got %q", path, rt.Pattern, pattern)
+		}
+	}
+}
@@ -2,24 +2,46 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
 	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/internal/types"
 	"eth-blockchain-parser/pkg/database"
+	"eth-blockchain-parser/pkg/parser"
 )
 
 // Server represents the HTTP server with database access
 type Server struct {
-	dm       *database.DatabaseManager
-	txRepo   *database.TransactionRepository
-	addrRepo *database.AddressRepository
-	logger   *log.Logger
-	config   *ServerConfig
+	dm        *database.DatabaseManager
+	txRepo    *database.TransactionRepository
+	addrRepo  *database.AddressRepository
+	blockRepo *database.BlockRepository
+	logRepo   *database.LogRepository
+	settings  *database.SettingsRepository
+	labels    *database.LabelCache
+	logger    *log.Logger
+	config    *ServerConfig
+
+	// txByHash serves GET /api/transactions/{hash}: txCache, or txRepo when the cache is disabled
+	txByHash txGetter
+	txCache  *txCache
+
+	// blockClient and parseConfig serve POST /api/parse, nil client = on-demand parsing disabled
+	blockClient parser.BlockClient
+	parseConfig *types.Config
 }
 
 // ServerConfig holds server configuration
@@ -27,28 +49,98 @@ type ServerConfig struct {
 	Port     string
 	Username string
 	Password string
-	Host     string
+	// Host to bind: a hostname, IPv4 or IPv6 literal (with or without brackets).
+	// "", "0.0.0.0" or "::" bind to all interfaces.
+	Host string
+
+	// HTTP timeouts, zero values fall back to the defaults
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	// MaxPageLimit caps the ?limit= of paginated endpoints, zero falls back to DefaultMaxPageLimit
+	MaxPageLimit int
+
+	// LabelCacheTTL is how often whale labels are reloaded from DB, zero falls back to DefaultLabelCacheTTL.
+	// Whales written by other processes (init-whales) show up after at most this delay.
+	LabelCacheTTL time.Duration
+
+	// StrictParams answers 400 to malformed numeric query params (?limit=abc, ?page=-1)
+	// instead of silently using the default. Absent params use the default either way.
+	StrictParams bool
+
+	// MaxParseRange caps the number of blocks of POST /api/parse, zero falls back to DefaultMaxParseRange
+	MaxParseRange uint64
+	// ParseTimeout bounds a POST /api/parse run, zero falls back to DefaultParseTimeout.
+	// Keep it below WriteTimeout, otherwise the summary of a long run can't be written.
+	ParseTimeout time.Duration
+
+	// TxCacheSize enables an LRU of up to this many confirmed GET /api/transactions/{hash} results,
+	// zero disables it. Entries expire after TxCacheTTL, zero falls back to DefaultTxCacheTTL.
+	TxCacheSize int
+	TxCacheTTL  time.Duration
 }
 
+// Default HTTP timeouts
+const (
+	DefaultReadTimeout  = 15 * time.Second
+	DefaultWriteTimeout = 15 * time.Second
+	DefaultIdleTimeout  = 60 * time.Second
+)
+
+// DefaultMaxPageLimit is the default maximum page size
+const DefaultMaxPageLimit = 1000
+
+// DefaultLabelCacheTTL is the default refresh interval of the whale label cache
+const DefaultLabelCacheTTL = time.Minute
+
+// Defaults of on-demand parsing with POST /api/parse
+const (
+	DefaultMaxParseRange = 50
+	DefaultParseTimeout  = 10 * time.Second
+)
+
 // DefaultServerConfig returns default server configuration
 func DefaultServerConfig() *ServerConfig {
 	return &ServerConfig{
-		Port:     "8015",
-		Username: "admin",
-		Password: "password123", // Change this in production!
-		Host:     "localhost",
+		Port:          "8015",
+		Username:      "admin",
+		Password:      "password123", // Change this in production!
+		Host:          "localhost",
+		ReadTimeout:   DefaultReadTimeout,
+		WriteTimeout:  DefaultWriteTimeout,
+		IdleTimeout:   DefaultIdleTimeout,
+		MaxPageLimit:  DefaultMaxPageLimit,
+		LabelCacheTTL: DefaultLabelCacheTTL,
+		MaxParseRange: DefaultMaxParseRange,
+		ParseTimeout:  DefaultParseTimeout,
 	}
 }
 
 // APIResponse represents a standard API response format
 type APIResponse struct {
-	Success bool        `json:"success"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Count   int         `json:"count,omitempty"`
-	Meta    interface{} `json:"meta,omitempty"`
+	Success   bool        `json:"success"`
+	Data      interface{} `json:"data,omitempty"`
+	Error     string      `json:"error,omitempty"`
+	Code      string      `json:"code,omitempty"` // machine-readable error code, one of the Code* constants
+	Count     int         `json:"count,omitempty"`
+	Meta      interface{} `json:"meta,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
+// Error codes of APIResponse.Code, the "error" message next to them is for humans and may change
+const (
+	CodeInvalidParam     = "INVALID_PARAM"      // malformed or out of range query/path parameter
+	CodeInvalidBody      = "INVALID_BODY"       // request body is not the expected JSON
+	CodeNotFound         = "NOT_FOUND"          // requested record doesn't exist
+	CodeUnauthorized     = "UNAUTHORIZED"       // missing or wrong Basic Auth credentials
+	CodeMethodNotAllowed = "METHOD_NOT_ALLOWED" // endpoint doesn't support the HTTP method
+	CodeDBError          = "DB_ERROR"           // database query failed
+	CodeDBUnavailable    = "DB_UNAVAILABLE"     // database connection is down
+	CodeParseDisabled    = "PARSE_DISABLED"     // no block client configured for on-demand parsing
+	CodeParseFailed      = "PARSE_FAILED"       // fetching or parsing blocks failed
+)
+
 // PaginationMeta holds pagination information
 type PaginationMeta struct {
 	Page    int  `json:"page"`
@@ -67,14 +159,53 @@ func NewServer(dm *database.DatabaseManager, config *ServerConfig, logger *log.L
 	if config == nil {
 		config = DefaultServerConfig()
 	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = DefaultReadTimeout
+	}
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = DefaultWriteTimeout
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = DefaultIdleTimeout
+	}
+	if config.MaxPageLimit <= 0 {
+		config.MaxPageLimit = DefaultMaxPageLimit
+	}
 
-	return &Server{
-		dm:       dm,
-		txRepo:   database.NewTransactionRepository(dm, logger),
-		addrRepo: database.NewAddressRepository(dm, logger),
-		logger:   logger,
-		config:   config,
+	if config.LabelCacheTTL <= 0 {
+		config.LabelCacheTTL = DefaultLabelCacheTTL
 	}
+	if config.MaxParseRange == 0 {
+		config.MaxParseRange = DefaultMaxParseRange
+	}
+	if config.ParseTimeout <= 0 {
+		config.ParseTimeout = DefaultParseTimeout
+	}
+
+	addrRepo := database.NewAddressRepository(dm, logger)
+	s := &Server{
+		dm:        dm,
+		txRepo:    database.NewTransactionRepository(dm, logger),
+		addrRepo:  addrRepo,
+		blockRepo: database.NewBlockRepository(dm, logger),
+		logRepo:   database.NewLogRepository(dm, logger),
+		settings:  database.NewSettingsRepository(dm, logger, 0),
+		labels:    database.NewLabelCache(addrRepo, config.LabelCacheTTL),
+		logger:    logger,
+		config:    config,
+	}
+	s.txByHash = s.txRepo
+	if config.TxCacheSize > 0 {
+		s.txCache = newTxCache(s.txRepo, config.TxCacheSize, config.TxCacheTTL)
+		s.txByHash = s.txCache
+	}
+	return s
+}
+
+// SetBlockClient enables POST /api/parse, blocks are fetched with client and parsed and filtered with config
+func (s *Server) SetBlockClient(client parser.BlockClient, config *types.Config) {
+	s.blockClient = client
+	s.parseConfig = config
 }
 
 // basicAuth middleware for HTTP Basic Authentication
@@ -102,7 +233,7 @@ func (s *Server) basicAuth(next http.HandlerFunc) http.HandlerFunc {
 // unauthorized sends a 401 Unauthorized response
 func (s *Server) unauthorized(w http.ResponseWriter, message string) {
 	w.Header().Set("WWW-Authenticate", `Basic realm="SQLite API"`)
-	s.sendError(w, http.StatusUnauthorized, message)
+	s.sendError(w, http.StatusUnauthorized, CodeUnauthorized, message)
 }
 
 // sendJSON sends a JSON response
@@ -111,8 +242,9 @@ func (s *Server) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.WriteHeader(status)
 
 	response := APIResponse{
-		Success: status < 400,
-		Data:    data,
+		Success:   status < 400,
+		Data:      data,
+		RequestID: w.Header().Get(RequestIDHeader),
 	}
 
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -120,36 +252,65 @@ func (s *Server) sendJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-// sendError sends an error response
-func (s *Server) sendError(w http.ResponseWriter, status int, message string) {
+// sendError sends an error response with a Code* error code
+func (s *Server) sendError(w http.ResponseWriter, status int, code, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 
 	response := APIResponse{
-		Success: false,
-		Error:   message,
+		Success:   false,
+		Error:     message,
+		Code:      code,
+		RequestID: w.Header().Get(RequestIDHeader),
 	}
 
 	json.NewEncoder(w).Encode(response)
 }
 
+// sendPaginated sends a successful list response with pagination meta
+func (s *Server) sendPaginated(w http.ResponseWriter, data interface{}, count int, meta PaginationMeta) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	response := APIResponse{
+		Success:   true,
+		Data:      data,
+		Count:     count,
+		Meta:      meta,
+		RequestID: w.Header().Get(RequestIDHeader),
+	}
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		s.logger.Printf("Failed to encode JSON response: %v", err)
+	}
+}
+
 // getAllTransactions handles GET /api/transactions
 func (s *Server) getAllTransactions(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
 	// Parse pagination parameters
-	page := s.getIntParam(r, "page", 1)
-	limit := s.getIntParam(r, "limit", 100)
-	if limit > 1000 {
-		limit = 1000 // Maximum limit
+	page, limit, offset, ok := s.getPagination(w, r)
+	if !ok {
+		return
+	}
+
+	// Filter by whale address IDs if requested (?whale_ids=1,2,3)
+	if r.URL.Query().Get("whale_ids") != "" {
+		s.getTransactionsByWhaleIDs(w, r, page, limit, offset)
+		return
+	}
+	// Filter by whale side if requested (?transfer_type=TO)
+	if r.URL.Query().Get("transfer_type") != "" {
+		s.getTransactionsByTransferType(w, r, page, limit, offset)
+		return
 	}
-	offset := (page - 1) * limit
 
 	// Get transactions with pagination
 	db, err := s.dm.DB()
 	if err != nil {
-		s.sendError(w, http.StatusInternalServerError, "Database connection failed")
+		s.sendError(w, http.StatusInternalServerError, CodeDBUnavailable, "Database connection failed")
 		return
 	}
 
@@ -162,7 +323,7 @@ func (s *Server) getAllTransactions(w http.ResponseWriter, r *http.Request) {
 	err = db.SelectContext(ctx, &transactions, query, limit, offset)
 	if err != nil {
 		s.logger.Printf("Failed to fetch transactions: %v", err)
-		s.sendError(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch transactions")
 		return
 	}
 
@@ -184,17 +345,102 @@ func (s *Server) getAllTransactions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send response with pagination
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	s.sendPaginated(w, transactions, len(transactions), meta)
+}
 
-	response := APIResponse{
-		Success: true,
-		Data:    transactions,
-		Count:   len(transactions),
-		Meta:    meta,
+// getTransactionsByWhaleIDs handles GET /api/transactions?whale_ids=1,2,3
+func (s *Server) getTransactionsByWhaleIDs(w http.ResponseWriter, r *http.Request, page, limit, offset int) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	ids, err := parseIDList(r.URL.Query().Get("whale_ids"))
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, err.Error())
+		return
+	}
+	if len(ids) > database.MaxWhaleIDsPerQuery {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, fmt.Sprintf("Too many whale_ids (max %d)", database.MaxWhaleIDsPerQuery))
+		return
 	}
 
-	json.NewEncoder(w).Encode(response)
+	transactions, err := s.txRepo.GetByWhaleIDs(ctx, ids, limit, offset)
+	if err != nil {
+		s.logger.Printf("Failed to fetch transactions for whale IDs %v: %v", ids, err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch transactions")
+		return
+	}
+
+	total, err := s.txRepo.CountByWhaleIDs(ctx, ids)
+	if err != nil {
+		s.logger.Printf("Failed to get transaction count for whale IDs %v: %v", ids, err)
+		total = len(transactions) // Fallback
+	}
+
+	meta := PaginationMeta{
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		HasNext: offset+limit < total,
+		HasPrev: page > 1,
+	}
+
+	s.sendPaginated(w, transactions, len(transactions), meta)
+}
+
+// getTransactionsByTransferType handles GET /api/transactions?transfer_type=FROM|TO|INT|SELF
+func (s *Server) getTransactionsByTransferType(w http.ResponseWriter, r *http.Request, page, limit, offset int) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	transferType := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("transfer_type")))
+	if !slices.Contains(database.TransferTypes, transferType) {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, fmt.Sprintf("Invalid transfer_type %q, expected one of %s", transferType, strings.Join(database.TransferTypes, ", ")))
+		return
+	}
+
+	transactions, err := s.txRepo.GetByTransferType(ctx, transferType, limit, offset)
+	if err != nil {
+		s.logger.Printf("Failed to fetch transactions for transfer type %s: %v", transferType, err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch transactions")
+		return
+	}
+
+	total, err := s.txRepo.CountByTransferType(ctx, transferType)
+	if err != nil {
+		s.logger.Printf("Failed to get transaction count for transfer type %s: %v", transferType, err)
+		total = len(transactions) // Fallback
+	}
+
+	meta := PaginationMeta{
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		HasNext: offset+limit < total,
+		HasPrev: page > 1,
+	}
+
+	s.sendPaginated(w, transactions, len(transactions), meta)
+}
+
+// parseIDList parses a comma-separated list of positive integer IDs, skipping duplicates
+func parseIDList(str string) ([]int64, error) {
+	var ids []int64
+	seen := make(map[int64]bool)
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("Invalid ID: %s", part)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
 }
 
 // getTransactionByHash handles GET /api/transactions/{hash}
@@ -205,25 +451,524 @@ func (s *Server) getTransactionByHash(w http.ResponseWriter, r *http.Request) {
 	// Extract hash from URL path
 	hash := r.URL.Path[len("/api/transactions/"):]
 	if hash == "" {
-		s.sendError(w, http.StatusBadRequest, "Transaction hash required")
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Transaction hash required")
 		return
 	}
 
-	transaction, err := s.txRepo.GetByHash(ctx, hash)
+	transaction, err := s.txByHash.GetByHash(ctx, hash)
 	if err != nil {
 		s.logger.Printf("Failed to fetch transaction %s: %v", hash, err)
-		s.sendError(w, http.StatusInternalServerError, "Failed to fetch transaction")
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch transaction")
 		return
 	}
 
 	if transaction == nil {
-		s.sendError(w, http.StatusNotFound, "Transaction not found")
+		s.sendError(w, http.StatusNotFound, CodeNotFound, "Transaction not found")
 		return
 	}
 
 	s.sendJSON(w, http.StatusOK, transaction)
 }
 
+// getContractCreation handles GET /api/contracts/{address}
+func (s *Server) getContractCreation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	address := r.URL.Path[len("/api/contracts/"):]
+	if address == "" {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Contract address required")
+		return
+	}
+
+	transaction, err := s.txRepo.GetByContractAddress(ctx, address)
+	if err != nil {
+		s.logger.Printf("Failed to fetch creation of contract %s: %v", address, err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch contract creation")
+		return
+	}
+
+	if transaction == nil {
+		s.sendError(w, http.StatusNotFound, CodeNotFound, "Contract creation not found")
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, transaction)
+}
+
+// getStats handles GET /api/stats
+func (s *Server) getStats(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	counts, err := s.txRepo.CountByTxType(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to count transactions by type: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch stats")
+		return
+	}
+
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+
+	s.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"transactions":   total,
+		"tx_type_counts": counts,
+	})
+}
+
+// getSchema handles GET /api/schema
+func (s *Server) getSchema(w http.ResponseWriter, r *http.Request) {
+	db, err := s.dm.DB()
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, CodeDBUnavailable, "Database connection failed")
+		return
+	}
+
+	info, err := database.NewSchema(s.logger).GetSchemaInfo(db)
+	if err != nil {
+		s.logger.Printf("Failed to read schema: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to read schema")
+		return
+	}
+	s.sendJSON(w, http.StatusOK, info)
+}
+
+// getValueHistogram handles GET /api/whales/histogram, ?buckets=1,10,100 overrides the bucket boundaries
+func (s *Server) getValueHistogram(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	buckets := database.DefaultValueHistogramBuckets
+	if param := r.URL.Query().Get("buckets"); param != "" {
+		buckets = nil
+		for _, part := range strings.Split(param, ",") {
+			boundary, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || boundary < 0 {
+				s.sendError(w, http.StatusBadRequest, CodeInvalidParam, fmt.Sprintf("Invalid bucket boundary: %q", part))
+				return
+			}
+			buckets = append(buckets, boundary)
+		}
+	}
+
+	histogram, err := s.txRepo.ValueHistogram(ctx, buckets)
+	if errors.Is(err, database.ErrInvalidBuckets) {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, err.Error())
+		return
+	}
+	if err != nil {
+		s.logger.Printf("Failed to build value histogram: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to build value histogram")
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"labels": database.ValueHistogramLabels(buckets), // bucket order, JSON objects are unordered
+		"counts": histogram,
+	})
+}
+
+// DefaultTopTransactions is the leaderboard size of /api/transactions/top without ?n=
+const DefaultTopTransactions = 10
+
+// getTopTransactions handles GET /api/transactions/top?from=&to=&n=, the largest transactions by value
+func (s *Server) getTopTransactions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	fromBlock, toBlock := int64(0), int64(math.MaxInt64)
+	for param, target := range map[string]*int64{"from": &fromBlock, "to": &toBlock} {
+		str := r.URL.Query().Get(param)
+		if str == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(str, 10, 64)
+		if err != nil || value < 0 {
+			s.sendError(w, http.StatusBadRequest, CodeInvalidParam, fmt.Sprintf("Invalid %s block: %q", param, str))
+			return
+		}
+		*target = value
+	}
+	if fromBlock > toBlock {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "from block is after to block")
+		return
+	}
+	n, ok := s.getIntParam(w, r, "n", DefaultTopTransactions)
+	if !ok {
+		return
+	}
+	n = s.clampLimit(n)
+
+	transactions, err := s.txRepo.GetTopByValue(ctx, fromBlock, toBlock, n)
+	if err != nil {
+		s.logger.Printf("Failed to get top transactions: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to get top transactions")
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, transactions)
+}
+
+// handleTransaction dispatches /api/transactions/{hash} requests by method, and /api/transactions/{hash}/logs
+func (s *Server) handleTransaction(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/logs") {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			s.sendError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+			return
+		}
+		s.getTransactionLogs(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		s.getTransactionByHash(w, r)
+	case http.MethodDelete:
+		s.deleteTransaction(w, r)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		s.sendError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// getTransactionLogs handles GET /api/transactions/{hash}/logs, an empty array if no logs are stored
+func (s *Server) getTransactionLogs(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hash := strings.TrimSuffix(r.URL.Path[len("/api/transactions/"):], "/logs")
+	if hash == "" {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Transaction hash required")
+		return
+	}
+
+	logs, err := s.logRepo.GetByTxHash(ctx, hash)
+	if err != nil {
+		s.logger.Printf("Failed to fetch logs of transaction %s: %v", hash, err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch transaction logs")
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, logs)
+}
+
+// deleteTransaction handles DELETE /api/transactions/{hash}
+func (s *Server) deleteTransaction(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	hash := r.URL.Path[len("/api/transactions/"):]
+	if hash == "" {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Transaction hash required")
+		return
+	}
+
+	deleted, err := s.txRepo.DeleteByHash(ctx, hash)
+	if err != nil {
+		s.logger.Printf("Failed to delete transaction %s: %v", hash, err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to delete transaction")
+		return
+	}
+
+	if s.txCache != nil {
+		s.txCache.Invalidate(hash)
+	}
+	s.logger.Printf("Deleted %d transactions with hash %s", deleted, hash)
+	s.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"tx_hash": hash,
+		"deleted": deleted,
+	})
+}
+
+// getBlocks handles GET /api/blocks, newest first
+func (s *Server) getBlocks(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	page, limit, offset, ok := s.getPagination(w, r)
+	if !ok {
+		return
+	}
+
+	blocks, err := s.blockRepo.GetRecent(ctx, limit, offset)
+	if err != nil {
+		s.logger.Printf("Failed to fetch blocks: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch blocks")
+		return
+	}
+
+	total, err := s.blockRepo.Count(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to get block count: %v", err)
+		total = len(blocks) // Fallback
+	}
+
+	meta := PaginationMeta{
+		Page:    page,
+		Limit:   limit,
+		Total:   total,
+		HasNext: offset+limit < total,
+		HasPrev: page > 1,
+	}
+	s.sendPaginated(w, blocks, len(blocks), meta)
+}
+
+// deleteBlock handles DELETE /api/blocks/{number}
+func (s *Server) deleteBlock(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.Header().Set("Allow", http.MethodDelete)
+		s.sendError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	blockNumber, err := strconv.ParseInt(r.URL.Path[len("/api/blocks/"):], 10, 64)
+	if err != nil || blockNumber < 0 {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Invalid block number")
+		return
+	}
+
+	deleted, blockDeleted, err := s.blockRepo.Delete(ctx, blockNumber)
+	if err != nil {
+		s.logger.Printf("Failed to delete block %d: %v", blockNumber, err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to delete block")
+		return
+	}
+	if s.txCache != nil {
+		s.txCache.InvalidateBlock(blockNumber)
+	}
+
+	s.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"block_number":  blockNumber,
+		"deleted":       deleted,
+		"block_deleted": blockDeleted,
+	})
+}
+
+// handleSettings dispatches /api/settings requests by method
+func (s *Server) handleSettings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.getSettings(w, r)
+	case http.MethodPut:
+		s.putSettings(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		s.sendError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// getSettings handles GET /api/settings, only stored settings are returned, the rest use config defaults
+func (s *Server) getSettings(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	settings, err := s.settings.All(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to load settings: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to load settings")
+		return
+	}
+	s.sendJSON(w, http.StatusOK, settings)
+}
+
+// putSettings handles PUT /api/settings with a JSON object of settings, values may be strings or numbers.
+// An empty string resets a setting to the config default. Nothing is stored if any setting is invalid.
+func (s *Server) putSettings(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	var body map[string]interface{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if len(body) == 0 {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidBody, "No settings given")
+		return
+	}
+
+	values := make(map[string]string, len(body))
+	for key, raw := range body {
+		var value string
+		switch v := raw.(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		default:
+			s.sendError(w, http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("Setting %s must be a string or a number", key))
+			return
+		}
+		if err := parser.ValidateSetting(key, value); err != nil {
+			s.sendError(w, http.StatusBadRequest, CodeInvalidParam, err.Error())
+			return
+		}
+		values[key] = value
+	}
+
+	if err := s.settings.SetAll(ctx, values); err != nil {
+		s.logger.Printf("Failed to store settings: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to store settings")
+		return
+	}
+	s.logger.Printf("Updated settings: %v", values)
+	s.getSettings(w, r)
+}
+
+// ParseRequest is the body of POST /api/parse, both blocks inclusive
+type ParseRequest struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// ParseSummary is the result of POST /api/parse
+type ParseSummary struct {
+	Start             uint64 `json:"start"`
+	End               uint64 `json:"end"`
+	BlocksParsed      int    `json:"blocks_parsed"`
+	Transactions      int    `json:"transactions"`
+	WhaleTransactions int    `json:"whale_transactions"`
+	Complete          bool   `json:"complete"` // false if some blocks failed or the timeout hit, see BlocksParsed
+}
+
+// parseRange handles POST /api/parse: parses a block range with the injected client, like a parser run
+// it stores the blocks and their whale transactions (already stored ones are replaced)
+func (s *Server) parseRange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		s.sendError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.blockClient == nil {
+		s.sendError(w, http.StatusServiceUnavailable, CodeParseDisabled, "On-demand parsing is not enabled")
+		return
+	}
+
+	var req ParseRequest
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidBody, "Invalid JSON body: "+err.Error())
+		return
+	}
+	if req.End < req.Start {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("end %d is before start %d", req.End, req.Start))
+		return
+	}
+	// compared as distance, end-start+1 overflows for the full uint64 range
+	if req.End-req.Start >= s.config.MaxParseRange {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidBody, fmt.Sprintf("Range %d-%d exceeds the maximum of %d blocks", req.Start, req.End, s.config.MaxParseRange))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.ParseTimeout)
+	defer cancel()
+
+	// stored settings apply like in a parser run
+	config, err := parser.ApplySettings(ctx, s.parseConfig, s.settings)
+	if err != nil {
+		s.logger.Printf("Failed to apply settings: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to load settings")
+		return
+	}
+
+	blockParser := parser.NewParser(s.blockClient, config)
+	defer blockParser.Close()
+	blocks, err := blockParser.ParseBlockRange(ctx, req.Start, req.End)
+	if err != nil {
+		s.logger.Printf("Failed to parse blocks %d-%d: %v", req.Start, req.End, err)
+		s.sendError(w, http.StatusBadGateway, CodeParseFailed, "Failed to parse blocks")
+		return
+	}
+
+	whalesAddrToID, _, err := s.labels.Mappings(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to load whale addresses: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to load whale addresses")
+		return
+	}
+	whaleThresholds, err := s.labels.Thresholds(ctx)
+	if err != nil {
+		s.logger.Printf("Failed to load whale thresholds: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to load whale addresses")
+		return
+	}
+	filter := filtering.NewWhaleFilter(config).WithWhaleThresholds(whaleThresholds)
+	whaleTxs := filtering.ParseWhaleTransactionsWithFilter(blocks, whalesAddrToID, filter)
+
+	// stored even if the request timed out meanwhile, the blocks are already fetched
+	storeCtx := context.WithoutCancel(ctx)
+	storedBlocks := make([]*database.Block, len(blocks))
+	summary := ParseSummary{Start: req.Start, End: req.End, BlocksParsed: len(blocks), WhaleTransactions: len(whaleTxs)}
+	for i, block := range blocks {
+		storedBlocks[i] = database.MapParsedBlockToDatabaseBlock(block)
+		summary.Transactions += len(block.Transactions)
+	}
+	summary.Complete = uint64(len(blocks)) == req.End-req.Start+1
+	if err := s.blockRepo.BatchUpsert(storeCtx, storedBlocks); err != nil {
+		s.logger.Printf("Failed to store parsed blocks: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to store parsed blocks")
+		return
+	}
+	if err := s.txRepo.BatchInsert(storeCtx, whaleTxs); err != nil {
+		s.logger.Printf("Failed to store whale transactions: %v", err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to store whale transactions")
+		return
+	}
+
+	s.logger.Printf("Parsed blocks %d-%d on demand: %d blocks, %d whale txs", req.Start, req.End, summary.BlocksParsed, summary.WhaleTransactions)
+	s.sendJSON(w, http.StatusOK, summary)
+}
+
+// handleAddresses dispatches /api/addresses/{address}/... requests by suffix
+func (s *Server) handleAddresses(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, "/summary") {
+		s.getAddressSummary(w, r)
+		return
+	}
+	s.getTransactionsByAddress(w, r)
+}
+
+// getAddressSummary handles GET /api/addresses/{address}/summary
+func (s *Server) getAddressSummary(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	address := strings.TrimSuffix(r.URL.Path[len("/api/addresses/"):], "/summary")
+	if address == "" {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Address required")
+		return
+	}
+
+	summary, err := s.txRepo.GetAddressSummary(ctx, address)
+	if err != nil {
+		s.logger.Printf("Failed to fetch summary for address %s: %v", address, err)
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch address summary")
+		return
+	}
+
+	if label, ok, err := s.labels.Label(ctx, address); err != nil {
+		s.logger.Printf("Failed to resolve label for address %s: %v", address, err)
+	} else if ok {
+		summary.Label = label
+	}
+
+	if summary.TxCount == 0 {
+		s.sendError(w, http.StatusNotFound, CodeNotFound, "No transactions for address")
+		return
+	}
+
+	s.sendJSON(w, http.StatusOK, summary)
+}
+
 // getTransactionsByAddress handles GET /api/addresses/{address}/transactions
 func (s *Server) getTransactionsByAddress(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
@@ -236,26 +981,24 @@ func (s *Server) getTransactionsByAddress(w http.ResponseWriter, r *http.Request
 	}
 
 	if address == "" {
-		s.sendError(w, http.StatusBadRequest, "Address required")
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Address required")
 		return
 	}
 
 	// Parse pagination
-	page := s.getIntParam(r, "page", 1)
-	limit := s.getIntParam(r, "limit", 100)
-	if limit > 1000 {
-		limit = 1000
+	page, limit, offset, ok := s.getPagination(w, r)
+	if !ok {
+		return
 	}
-	offset := (page - 1) * limit
 
 	transactions, err := s.txRepo.GetByAddress(ctx, address, limit, offset)
 	if err != nil {
 		s.logger.Printf("Failed to fetch transactions for address %s: %v", address, err)
-		s.sendError(w, http.StatusInternalServerError, "Failed to fetch transactions")
+		s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to fetch transactions")
 		return
 	}
 
-	s.sendJSON(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"address":      address,
 		"transactions": transactions,
 		"count":        len(transactions),
@@ -263,14 +1006,114 @@ func (s *Server) getTransactionsByAddress(w http.ResponseWriter, r *http.Request
 			"page":  page,
 			"limit": limit,
 		},
-	})
+	}
+	if label, ok, err := s.labels.Label(ctx, address); err != nil {
+		s.logger.Printf("Failed to resolve label for address %s: %v", address, err)
+	} else if ok {
+		response["label"] = label
+	}
+	s.sendJSON(w, http.StatusOK, response)
+}
+
+// Search input types, detected from the shape of the query
+const (
+	SearchTypeTxHash  = "tx_hash" // 0x + 64 hex chars
+	SearchTypeAddress = "address" // 0x + 40 hex chars
+	SearchTypeLabel   = "label"   // anything else, matched as a substring of whale labels and addresses
+)
+
+// SearchResult is the tagged union returned by /api/search, Type tells the shape of Result:
+// a transaction for tx_hash, transactions of the address for address, whale addresses for label
+type SearchResult struct {
+	Query  string      `json:"query"`
+	Type   string      `json:"type"`
+	Result interface{} `json:"result"`
+}
+
+// detectSearchType classifies a search query by its length and hex content
+func detectSearchType(q string) string {
+	switch {
+	case isHexString(q, 64):
+		return SearchTypeTxHash
+	case isHexString(q, 40):
+		return SearchTypeAddress
+	}
+	return SearchTypeLabel
+}
+
+// isHexString reports whether s is 0x followed by exactly digits hex characters
+func isHexString(s string, digits int) bool {
+	if len(s) != digits+2 || !(strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X")) {
+		return false
+	}
+	_, err := hex.DecodeString(s[2:])
+	return err == nil
+}
+
+// search handles GET /api/search?q=, dispatching by the detected input type
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, "Search query required")
+		return
+	}
+	limit, ok := s.getIntParam(w, r, "limit", 100)
+	if !ok {
+		return
+	}
+	limit = s.clampLimit(limit)
+
+	result := SearchResult{Query: q, Type: detectSearchType(q)}
+	switch result.Type {
+	case SearchTypeTxHash:
+		transaction, err := s.txRepo.GetByHash(ctx, q)
+		if err != nil {
+			s.logger.Printf("Failed to search transaction %s: %v", q, err)
+			s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to search")
+			return
+		}
+		if transaction == nil {
+			s.sendError(w, http.StatusNotFound, CodeNotFound, "Transaction not found")
+			return
+		}
+		result.Result = transaction
+
+	case SearchTypeAddress:
+		transactions, err := s.txRepo.GetByAddress(ctx, q, limit, 0)
+		if err != nil {
+			s.logger.Printf("Failed to search transactions for address %s: %v", q, err)
+			s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to search")
+			return
+		}
+		if transactions == nil {
+			transactions = []*database.Transaction{}
+		}
+		result.Result = transactions
+
+	default:
+		whales, err := s.addrRepo.Search(ctx, q, limit)
+		if err != nil {
+			s.logger.Printf("Failed to search whale addresses for %q: %v", q, err)
+			s.sendError(w, http.StatusInternalServerError, CodeDBError, "Failed to search")
+			return
+		}
+		if whales == nil {
+			whales = []*database.WhaleAddress{}
+		}
+		result.Result = whales
+	}
+
+	s.sendJSON(w, http.StatusOK, result)
 }
 
 // healthCheck handles GET /health
 func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 	// Check database connection
 	if err := s.dm.Ping(); err != nil {
-		s.sendError(w, http.StatusServiceUnavailable, "Database unavailable")
+		s.sendError(w, http.StatusServiceUnavailable, CodeDBUnavailable, "Database unavailable")
 		return
 	}
 
@@ -281,84 +1124,395 @@ func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// getIntParam extracts integer parameter from query string with default value
-func (s *Server) getIntParam(r *http.Request, param string, defaultValue int) int {
+// getIntParam extracts a positive integer parameter from query string, absent means defaultValue.
+// A malformed value also falls back to defaultValue, unless StrictParams is set: then a 400 is sent
+// and ok is false.
+func (s *Server) getIntParam(w http.ResponseWriter, r *http.Request, param string, defaultValue int) (int, bool) {
 	str := r.URL.Query().Get(param)
 	if str == "" {
-		return defaultValue
+		return defaultValue, true
 	}
 
-	if val, err := strconv.Atoi(str); err == nil && val > 0 {
-		return val
+	val, err := strconv.Atoi(str)
+	if err == nil && val > 0 {
+		return val, true
 	}
+	if s.config.StrictParams {
+		s.sendError(w, http.StatusBadRequest, CodeInvalidParam, fmt.Sprintf("Invalid %s: %q, expected a positive integer", param, str))
+		return 0, false
+	}
+	return defaultValue, true
+}
+
+// getPagination parses ?page= and ?limit= (clamped to MaxPageLimit), ok is false when a 400 was sent
+func (s *Server) getPagination(w http.ResponseWriter, r *http.Request) (page, limit, offset int, ok bool) {
+	if page, ok = s.getIntParam(w, r, "page", 1); !ok {
+		return 0, 0, 0, false
+	}
+	if limit, ok = s.getIntParam(w, r, "limit", 100); !ok {
+		return 0, 0, 0, false
+	}
+	limit = s.clampLimit(limit)
+	return page, limit, (page - 1) * limit, true
+}
 
-	return defaultValue
+// clampLimit caps a requested page size at the configured MaxPageLimit
+func (s *Server) clampLimit(limit int) int {
+	if limit > s.config.MaxPageLimit {
+		return s.config.MaxPageLimit
+	}
+	return limit
+}
+
+// route describes an HTTP endpoint; the same table drives handler registration, /api docs and /openapi.json
+type route struct {
+	Pattern  string // ServeMux pattern the handler is registered on
+	Path     string // OpenAPI path with {params}
+	Method   string
+	Summary  string
+	Auth     bool
+	Params   []routeParam
+	Response interface{} // sample value of the "data" field, used to derive the response schema
+	Paged    bool        // response carries PaginationMeta in "meta"
+	Handler  http.HandlerFunc
+}
+
+// routeParam describes a path or query parameter of a route
+type routeParam struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string // OpenAPI primitive type
+	Description string
+	Required    bool
+}
+
+// paginationParams describes the page/limit query parameters
+func (s *Server) paginationParams() []routeParam {
+	return []routeParam{
+		{Name: "page", In: "query", Type: "integer", Description: "Page number, starts at 1"},
+		{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Page size, max %d", s.config.MaxPageLimit)},
+	}
+}
+
+// routes returns all HTTP endpoints served by the API
+func (s *Server) routes() []route {
+	return []route{
+		{
+			Pattern:  "/health",
+			Path:     "/health",
+			Method:   http.MethodGet,
+			Summary:  "Health check (no auth required)",
+			Response: map[string]interface{}{},
+			Handler:  s.healthCheck,
+		},
+		{
+			Pattern: "/api/transactions",
+			Path:    "/api/transactions",
+			Method:  http.MethodGet,
+			Summary: "Get all transactions with pagination (?page=1&limit=100), filter by whales with ?whale_ids=1,2,3 or by whale side with ?transfer_type=TO",
+			Auth:    true,
+			Params: append([]routeParam{
+				{Name: "whale_ids", In: "query", Type: "string", Description: fmt.Sprintf("Comma-separated whale address IDs, max %d", database.MaxWhaleIDsPerQuery)},
+				{Name: "transfer_type", In: "query", Type: "string", Description: "Whale side: FROM (withdrawals), TO (deposits), INT (whale to whale) or SELF (whale to itself)"},
+			}, s.paginationParams()...),
+			Response: []*database.Transaction{},
+			Paged:    true,
+			Handler:  s.getAllTransactions,
+		},
+		{
+			Pattern: "/api/transactions/top",
+			Path:    "/api/transactions/top",
+			Method:  http.MethodGet,
+			Summary: "Largest transactions by ETH value in a block range, largest first",
+			Auth:    true,
+			Params: []routeParam{
+				{Name: "from", In: "query", Type: "integer", Description: "First block, inclusive (default: all)"},
+				{Name: "to", In: "query", Type: "integer", Description: "Last block, inclusive (default: all)"},
+				{Name: "n", In: "query", Type: "integer", Description: fmt.Sprintf("Number of transactions, default %d, max %d", DefaultTopTransactions, s.config.MaxPageLimit)},
+			},
+			Response: []*database.Transaction{},
+			Handler:  s.getTopTransactions,
+		},
+		{
+			Pattern:  "/api/transactions/",
+			Path:     "/api/transactions/{hash}",
+			Method:   http.MethodGet,
+			Summary:  "Get transaction by hash",
+			Auth:     true,
+			Params:   []routeParam{{Name: "hash", In: "path", Type: "string", Description: "Transaction hash", Required: true}},
+			Response: &database.Transaction{},
+			Handler:  s.handleTransaction,
+		},
+		{
+			Pattern:  "/api/transactions/",
+			Path:     "/api/transactions/{hash}",
+			Method:   http.MethodDelete,
+			Summary:  "Delete transaction by hash, returns number of deleted rows",
+			Auth:     true,
+			Params:   []routeParam{{Name: "hash", In: "path", Type: "string", Description: "Transaction hash", Required: true}},
+			Response: map[string]interface{}{},
+			Handler:  s.handleTransaction,
+		},
+		{
+			Pattern:  "/api/transactions/",
+			Path:     "/api/transactions/{hash}/logs",
+			Method:   http.MethodGet,
+			Summary:  "Get event logs of a transaction with decoded event names and fields, empty if none are stored (logs are stored with include_logs)",
+			Auth:     true,
+			Params:   []routeParam{{Name: "hash", In: "path", Type: "string", Description: "Transaction hash", Required: true}},
+			Response: []*database.Log{},
+			Handler:  s.handleTransaction,
+		},
+		{
+			Pattern:  "/api/contracts/",
+			Path:     "/api/contracts/{address}",
+			Method:   http.MethodGet,
+			Summary:  "Get the transaction that created a contract (404 if the creation isn't stored)",
+			Auth:     true,
+			Params:   []routeParam{{Name: "address", In: "path", Type: "string", Description: "Contract address", Required: true}},
+			Response: &database.Transaction{},
+			Handler:  s.getContractCreation,
+		},
+		{
+			Pattern:  "/api/blocks",
+			Path:     "/api/blocks",
+			Method:   http.MethodGet,
+			Summary:  "Get stored blocks with gas used ratio and base fee (null before London), newest first",
+			Auth:     true,
+			Params:   s.paginationParams(),
+			Response: []*database.Block{},
+			Paged:    true,
+			Handler:  s.getBlocks,
+		},
+		{
+			Pattern:  "/api/blocks/",
+			Path:     "/api/blocks/{number}",
+			Method:   http.MethodDelete,
+			Summary:  "Delete all transactions of a block (e.g. reorged), returns number of deleted rows",
+			Auth:     true,
+			Params:   []routeParam{{Name: "number", In: "path", Type: "integer", Description: "Block number", Required: true}},
+			Response: map[string]interface{}{},
+			Handler:  s.deleteBlock,
+		},
+		{
+			Pattern: "/api/addresses/",
+			Path:    "/api/addresses/{address}/transactions",
+			Method:  http.MethodGet,
+			Summary: "Get transactions for specific address",
+			Auth:    true,
+			Params: append([]routeParam{
+				{Name: "address", In: "path", Type: "string", Description: "Ethereum address", Required: true},
+			}, s.paginationParams()...),
+			Response: map[string]interface{}{},
+			Handler:  s.handleAddresses,
+		},
+		{
+			Pattern:  "/api/addresses/",
+			Path:     "/api/addresses/{address}/summary",
+			Method:   http.MethodGet,
+			Summary:  "Get activity summary for address: first/last seen block, sent/received ETH, tx count (404 if no transactions)",
+			Auth:     true,
+			Params:   []routeParam{{Name: "address", In: "path", Type: "string", Description: "Ethereum address", Required: true}},
+			Response: &database.AddressSummary{},
+			Handler:  s.handleAddresses,
+		},
+		{
+			Pattern: "/api/search",
+			Path:    "/api/search",
+			Method:  http.MethodGet,
+			Summary: "Search by tx hash (0x + 64 hex), address (0x + 40 hex) or whale label substring, \"type\" tells the shape of \"result\"",
+			Auth:    true,
+			Params: []routeParam{
+				{Name: "q", In: "query", Type: "string", Description: "Tx hash, address or label", Required: true},
+				{Name: "limit", In: "query", Type: "integer", Description: fmt.Sprintf("Max results for address and label searches, max %d", s.config.MaxPageLimit)},
+			},
+			Response: &SearchResult{},
+			Handler:  s.search,
+		},
+		{
+			Pattern:  "/api/stats",
+			Path:     "/api/stats",
+			Method:   http.MethodGet,
+			Summary:  "Stored transaction stats: total and counts by tx type (0 legacy, 1 access list, 2 dynamic fee, 3 blob, 255 unknown)",
+			Auth:     true,
+			Response: map[string]interface{}{},
+			Handler:  s.getStats,
+		},
+		{
+			Pattern:  "/api/schema",
+			Path:     "/api/schema",
+			Method:   http.MethodGet,
+			Summary:  "Database schema for admin tools: tables with columns and row counts, indexes and DB size",
+			Auth:     true,
+			Response: &database.SchemaInfo{},
+			Handler:  s.getSchema,
+		},
+		{
+			Pattern: "/api/whales/histogram",
+			Path:    "/api/whales/histogram",
+			Method:  http.MethodGet,
+			Summary: "Whale transaction count by ETH value bucket (default <1, 1-10, 10-100, 100-1000, 1000+)",
+			Auth:    true,
+			Params: []routeParam{
+				{Name: "buckets", In: "query", Type: "string", Description: "Comma-separated ascending ETH bucket boundaries, e.g. 1,10,100,1000"},
+			},
+			Response: map[string]interface{}{},
+			Handler:  s.getValueHistogram,
+		},
+		{
+			Pattern:  "/api/settings",
+			Path:     "/api/settings",
+			Method:   http.MethodGet,
+			Summary:  "Stored runtime settings overriding the parser config: " + strings.Join(parser.SettingKeys(), ", "),
+			Auth:     true,
+			Response: map[string]string{},
+			Handler:  s.handleSettings,
+		},
+		{
+			Pattern:  "/api/settings",
+			Path:     "/api/settings",
+			Method:   http.MethodPut,
+			Summary:  "Update runtime settings from a JSON object, e.g. {\"min_eth_value\": \"5\"}, \"\" resets to the config default; the parser picks them up on its next run or -watch cycle",
+			Auth:     true,
+			Response: map[string]string{},
+			Handler:  s.handleSettings,
+		},
+		{
+			Pattern:  "/api/parse",
+			Path:     "/api/parse",
+			Method:   http.MethodPost,
+			Summary:  fmt.Sprintf("Parse a block range on demand from a JSON body {\"start\": 1, \"end\": 10}, max %d blocks; stores the blocks and whale transactions (503 unless the server runs with -enable-parse)", s.config.MaxParseRange),
+			Auth:     true,
+			Response: &ParseSummary{},
+			Handler:  s.parseRange,
+		},
+		{
+			Pattern:  "/api",
+			Path:     "/api",
+			Method:   http.MethodGet,
+			Summary:  "API documentation",
+			Auth:     true,
+			Response: map[string]interface{}{},
+			Handler:  s.apiDocs,
+		},
+		{
+			Pattern:  "/openapi.json",
+			Path:     "/openapi.json",
+			Method:   http.MethodGet,
+			Summary:  "OpenAPI 3 specification (no auth required)",
+			Response: map[string]interface{}{},
+			Handler:  s.openAPISpec,
+		},
+	}
 }
 
 // setupRoutes configures HTTP routes
 func (s *Server) setupRoutes() *http.ServeMux {
 	mux := http.NewServeMux()
 
-	// Public health check (no auth required)
-	mux.HandleFunc("/health", s.healthCheck)
-
-	// Protected API endpoints (require authentication)
-	mux.HandleFunc("/api/transactions", s.basicAuth(s.getAllTransactions))
-	mux.HandleFunc("/api/transactions/", s.basicAuth(s.getTransactionByHash))
-	mux.HandleFunc("/api/addresses/", s.basicAuth(s.getTransactionsByAddress))
+	registered := make(map[string]bool)
+	for _, rt := range s.routes() {
+		// several documented paths can share one prefix pattern and dispatcher
+		if registered[rt.Pattern] {
+			continue
+		}
+		registered[rt.Pattern] = true
 
-	// API documentation endpoint
-	mux.HandleFunc("/api", s.basicAuth(s.apiDocs))
+		handler := rt.Handler
+		// Protected API endpoints (require authentication)
+		if rt.Auth {
+			handler = s.basicAuth(handler)
+		}
+		mux.HandleFunc(rt.Pattern, handler)
+	}
 
 	return mux
 }
 
 // apiDocs provides API documentation
 func (s *Server) apiDocs(w http.ResponseWriter, r *http.Request) {
+	endpoints := make(map[string]interface{})
+	for _, rt := range s.routes() {
+		endpoints[rt.Method+" "+rt.Path] = rt.Summary
+	}
+
 	docs := map[string]interface{}{
-		"title":   "SQLite Blockchain API",
-		"version": "1.0.0",
-		"endpoints": map[string]interface{}{
-			"GET /health":                               "Health check (no auth required)",
-			"GET /api/transactions":                     "Get all transactions with pagination (?page=1&limit=100)",
-			"GET /api/transactions/{hash}":              "Get transaction by hash",
-			"GET /api/addresses/{address}/transactions": "Get transactions for specific address",
-		},
+		"title":          "SQLite Blockchain API",
+		"version":        "1.0.0",
+		"endpoints":      endpoints,
+		"openapi":        "/openapi.json",
 		"authentication": "Basic HTTP Authentication required for /api/* endpoints",
 		"pagination":     "Use ?page=X&limit=Y query parameters",
 		"limits": map[string]interface{}{
-			"transactions_max_limit": 1000,
+			"transactions_max_limit": s.config.MaxPageLimit,
+			"whale_ids_max":          database.MaxWhaleIDsPerQuery,
 		},
 	}
 
 	s.sendJSON(w, http.StatusOK, docs)
 }
 
-// Start starts the HTTP server
-func (s *Server) Start() error {
+// handler builds the routes wrapped in request ID and logging middleware
+func (s *Server) handler() http.Handler {
 	mux := s.setupRoutes()
 
-	// Add request logging middleware
-	handler := s.loggingMiddleware(mux)
+	// Add request logging middleware, request ID goes outside so the log line can include it
+	return s.requestIDMiddleware(s.loggingMiddleware(mux))
+}
+
+// httpServer builds the http.Server with the configured address and timeouts
+func (s *Server) httpServer() *http.Server {
+	return &http.Server{
+		Addr:         s.Addr(),
+		Handler:      s.handler(),
+		ReadTimeout:  s.config.ReadTimeout,
+		WriteTimeout: s.config.WriteTimeout,
+		IdleTimeout:  s.config.IdleTimeout,
+	}
+}
+
+// Addr returns the listen address, IPv6 hosts are bracketed
+func (s *Server) Addr() string {
+	host := strings.TrimSuffix(strings.TrimPrefix(s.config.Host, "["), "]")
+	return net.JoinHostPort(host, s.config.Port)
+}
 
-	server := &http.Server{
-		Addr:         fmt.Sprintf("%s:%s", s.config.Host, s.config.Port),
-		Handler:      handler,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+// bindsAllInterfaces reports whether the configured host listens on every interface
+func (s *Server) bindsAllInterfaces() bool {
+	switch strings.Trim(s.config.Host, "[]") {
+	case "", "0.0.0.0", "::":
+		return true
 	}
+	return false
+}
 
-	s.logger.Printf("Starting HTTP server on http://%s:%s", s.config.Host, s.config.Port)
+// Start starts the HTTP server
+func (s *Server) Start() error {
+	server := s.httpServer()
+
+	s.logger.Printf("Starting HTTP server on http://%s", s.Addr())
+	if s.bindsAllInterfaces() {
+		s.logger.Printf("Listening on all interfaces")
+	}
 	s.logger.Printf("API endpoints available at /api (Basic Auth required)")
 	s.logger.Printf("Health check available at /health (no auth required)")
 	s.logger.Printf("Username: %s, Password: %s", s.config.Username, s.config.Password)
 
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.labels.Start(ctx)
+
 	return server.ListenAndServe()
 }
 
-// loggingMiddleware logs HTTP requests
+// loggingMiddleware logs HTTP requests with the route pattern (/api/transactions/{hash}) next to the
+// raw path, so log lines can be grouped by endpoint
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
+	var paths []string
+	for _, rt := range s.routes() {
+		paths = append(paths, rt.Path)
+	}
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 
@@ -368,10 +1522,96 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wrapper, r)
 
 		duration := time.Since(start)
-		s.logger.Printf("%s %s %d %v %s", r.Method, r.URL.Path, wrapper.statusCode, duration, r.RemoteAddr)
+		s.logger.Printf("%s %s %d %v %s route=%s request_id=%s", r.Method, r.URL.Path, wrapper.statusCode, duration, r.RemoteAddr,
+			routePattern(paths, r.URL.Path), RequestIDFromContext(r.Context()))
+	})
+}
+
+// unmatchedRoute is logged for paths no route matches, keeping arbitrary paths out of the grouping
+const unmatchedRoute = "unmatched"
+
+// routePattern returns the route path (with {params}) matching a request path, a {param} matches any
+// non-empty segment; the path with most literal segments wins, so /api/transactions/top isn't a {hash}
+func routePattern(paths []string, path string) string {
+	segments := strings.Split(path, "/")
+	best, bestLiterals := unmatchedRoute, -1
+	for _, pattern := range paths {
+		parts := strings.Split(pattern, "/")
+		if len(parts) != len(segments) {
+			continue
+		}
+		literals := 0
+		for i, part := range parts {
+			if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+				if segments[i] == "" {
+					literals = -1
+					break
+				}
+				continue
+			}
+			if part != segments[i] {
+				literals = -1
+				break
+			}
+			literals++
+		}
+		if literals > bestLiterals {
+			best, bestLiterals = pattern, literals
+		}
+	}
+	return best
+}
+
+// RequestIDHeader is the header used to pass request IDs between clients and the server
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits client-supplied request IDs
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the request ID stored by requestIDMiddleware
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestIDMiddleware reads X-Request-ID or generates one, stores it in the context and echoes it back
+func (s *Server) requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		// Set before the handler runs so sendJSON/sendError can pick it up from the headers
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
 	})
 }
 
+// validRequestID accepts non-empty printable ASCII IDs of reasonable length
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 16-byte hex request ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
 // responseWriter wraps http.ResponseWriter to capture status code
 type responseWriter struct {
 	http.ResponseWriter
@@ -0,0 +1,1283 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"eth-blockchain-parser/internal/types"
+	"eth-blockchain-parser/pkg/database"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	gethTypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// newTestServer creates a server without a database, logging into buf
+func newTestServer(buf *bytes.Buffer) *Server {
+	return NewServer(nil, DefaultServerConfig(), log.New(buf, "", 0))
+}
+
+// TestAccessLogRoutePattern tests that access log lines carry the route pattern instead of only the raw path
+func TestAccessLogRoutePattern(t *testing.T) {
+	hash := "0x3bb4c67c987ae8e2b383370a19ba1f634f5c7535446d5074ddfc42018700b5c0"
+	tests := []struct {
+		path          string
+		expectedRoute string
+	}{
+		{"/api/transactions/" + hash, "/api/transactions/{hash}"},
+		{"/api/transactions/top", "/api/transactions/top"},
+		{"/api/transactions", "/api/transactions"},
+		{"/api/addresses/0xwhale/summary", "/api/addresses/{address}/summary"},
+		{"/api/blocks/123", "/api/blocks/{number}"},
+		{"/api/transactions/", "unmatched"},
+		{"/nope/" + hash, "unmatched"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			var logs bytes.Buffer
+			s := newTestServer(&logs)
+
+			// unauthenticated, the request is logged without touching the DB
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			expected := " route=" + tt.expectedRoute + " "
+			if !strings.Contains(logs.String(), expected) {
+				t.Errorf("Expected log line with %q, got %q", expected, logs.String())
+			}
+		})
+	}
+}
+
+// TestRequestIDRoundTrip tests that X-Request-ID is echoed in headers, body and logs
+func TestRequestIDRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestID  string
+		auth       bool
+		expectCode int
+		expectSame bool
+	}{
+		{name: "Client ID on success", requestID: "trace-abc-123", auth: true, expectCode: http.StatusOK, expectSame: true},
+		{name: "Client ID on error", requestID: "trace-err-456", auth: false, expectCode: http.StatusUnauthorized, expectSame: true},
+		{name: "Generated ID", requestID: "", auth: true, expectCode: http.StatusOK, expectSame: false},
+		{name: "Invalid ID replaced", requestID: "bad id\twith spaces", auth: true, expectCode: http.StatusOK, expectSame: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			s := newTestServer(&logs)
+
+			req := httptest.NewRequest(http.MethodGet, "/api", nil)
+			if tt.requestID != "" {
+				req.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			if tt.auth {
+				req.SetBasicAuth(s.config.Username, s.config.Password)
+			}
+
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectCode {
+				t.Fatalf("Expected status %d, got %d", tt.expectCode, rec.Code)
+			}
+
+			headerID := rec.Header().Get(RequestIDHeader)
+			if headerID == "" {
+				t.Fatal("Expected X-Request-ID response header")
+			}
+			if tt.expectSame && headerID != tt.requestID {
+				t.Errorf("Expected request ID %q, got %q", tt.requestID, headerID)
+			}
+			if !tt.expectSame && headerID == tt.requestID {
+				t.Errorf("Expected a generated request ID, got %q", headerID)
+			}
+
+			var response APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.RequestID != headerID {
+				t.Errorf("Expected body request_id %q, got %q", headerID, response.RequestID)
+			}
+
+			if !strings.Contains(logs.String(), "request_id="+headerID) {
+				t.Errorf("Expected access log to contain request ID, got: %s", logs.String())
+			}
+		})
+	}
+}
+
+// TestServerTimeouts tests that configured timeouts are applied to the http.Server
+func TestServerTimeouts(t *testing.T) {
+	config := DefaultServerConfig()
+	config.ReadTimeout = 5 * time.Second
+	config.WriteTimeout = 10 * time.Minute
+	config.IdleTimeout = 2 * time.Minute
+
+	srv := NewServer(nil, config, nil).httpServer()
+
+	if srv.ReadTimeout != 5*time.Second {
+		t.Errorf("Expected ReadTimeout 5s, got %v", srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != 10*time.Minute {
+		t.Errorf("Expected WriteTimeout 10m, got %v", srv.WriteTimeout)
+	}
+	if srv.IdleTimeout != 2*time.Minute {
+		t.Errorf("Expected IdleTimeout 2m, got %v", srv.IdleTimeout)
+	}
+}
+
+// TestServerTimeoutDefaults tests that zero timeouts fall back to the defaults
+func TestServerTimeoutDefaults(t *testing.T) {
+	srv := NewServer(nil, &ServerConfig{Host: "localhost", Port: "8015"}, nil).httpServer()
+
+	if srv.ReadTimeout != DefaultReadTimeout || srv.WriteTimeout != DefaultWriteTimeout || srv.IdleTimeout != DefaultIdleTimeout {
+		t.Errorf("Expected default timeouts, got read=%v write=%v idle=%v", srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout)
+	}
+}
+
+// newTestServerWithDB creates a server backed by an in-memory database with the schema and one whale
+func newTestServerWithDB(t *testing.T) *Server {
+	t.Helper()
+
+	dm, err := database.NewDatabaseManager(database.InMemoryConfig(), log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("Failed to create in-memory database: %v", err)
+	}
+	t.Cleanup(func() { dm.Close() })
+
+	db, err := dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if err := database.NewSchema(log.New(io.Discard, "", 0)).CreateAllTables(db); err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+
+	label := "Whale"
+	addrRepo := database.NewAddressRepository(dm, log.New(io.Discard, "", 0))
+	if err := addrRepo.BatchInsert(context.Background(), []*database.WhaleAddress{{Address: "0xwhale", Label: &label}}); err != nil {
+		t.Fatalf("Failed to insert whale: %v", err)
+	}
+
+	return NewServer(dm, DefaultServerConfig(), log.New(io.Discard, "", 0))
+}
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+// doRequest sends an authenticated GET request through the full handler chain
+func doRequest(t *testing.T, s *Server, path string) (*httptest.ResponseRecorder, APIResponse) {
+	t.Helper()
+	return doMethodRequest(t, s, http.MethodGet, path)
+}
+
+// doMethodRequest sends an authenticated request with the given method through the full handler chain
+func doMethodRequest(t *testing.T, s *Server, method, path string) (*httptest.ResponseRecorder, APIResponse) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	req.SetBasicAuth(s.config.Username, s.config.Password)
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, req)
+
+	var response APIResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec, response
+}
+
+// TestAddressSummaryEndpoint tests GET /api/addresses/{address}/summary
+func TestAddressSummaryEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+	to := "0xreceiver"
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: "0x1", BlockNumber: 5, FromAddress: "0xwhale", ToAddress: &to, WhaleAddressID: int64Ptr(1), Value: "4.5"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	rec, response := doRequest(t, s, "/api/addresses/0xwhale/summary")
+	if rec.Code != http.StatusOK || !response.Success {
+		t.Fatalf("Expected 200 success, got %d: %s", rec.Code, rec.Body.String())
+	}
+	data := response.Data.(map[string]interface{})
+	if data["total_sent_eth"] != "4.5" || data["tx_count"] != float64(1) || data["label"] != "Whale" {
+		t.Errorf("Unexpected summary: %v", data)
+	}
+
+	// a write through the server's address repository invalidates the cached label
+	label := "Renamed whale"
+	if err := s.addrRepo.BatchUpsert(context.Background(), []*database.WhaleAddress{{Address: "0xwhale", Label: &label}}); err != nil {
+		t.Fatalf("Failed to rename whale: %v", err)
+	}
+	_, response = doRequest(t, s, "/api/addresses/0xwhale/summary")
+	if data := response.Data.(map[string]interface{}); data["label"] != label {
+		t.Errorf("Expected label %s after rename, got %v", label, data["label"])
+	}
+
+	rec, response = doRequest(t, s, "/api/addresses/0xnobody/summary")
+	if rec.Code != http.StatusNotFound || response.Success {
+		t.Fatalf("Expected 404 for unknown address, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if response.Code != CodeNotFound || response.Error == "" {
+		t.Errorf("Expected code %s with a message, got %+v", CodeNotFound, response)
+	}
+}
+
+// TestPageLimitClamped tests that every paginated endpoint clamps ?limit= to MaxPageLimit
+func TestPageLimitClamped(t *testing.T) {
+	s := newTestServerWithDB(t)
+	s.config.MaxPageLimit = 5
+
+	var txs []*database.Transaction
+	for i := 0; i < 10; i++ {
+		txs = append(txs, &database.Transaction{
+			TxHash:         fmt.Sprintf("0x%d", i),
+			BlockNumber:    int64(i + 1),
+			FromAddress:    "0xwhale",
+			WhaleAddressID: int64Ptr(1),
+			Value:          "1",
+		})
+	}
+	if err := s.txRepo.BatchInsert(context.Background(), txs); err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		path  string
+		limit func(APIResponse) interface{}
+	}{
+		{
+			name:  "All transactions",
+			path:  "/api/transactions?limit=50",
+			limit: func(r APIResponse) interface{} { return r.Meta.(map[string]interface{})["limit"] },
+		},
+		{
+			name:  "By whale IDs",
+			path:  "/api/transactions?whale_ids=1&limit=50",
+			limit: func(r APIResponse) interface{} { return r.Meta.(map[string]interface{})["limit"] },
+		},
+		{
+			name: "By address",
+			path: "/api/addresses/0xwhale/transactions?limit=50",
+			limit: func(r APIResponse) interface{} {
+				return r.Data.(map[string]interface{})["pagination"].(map[string]interface{})["limit"]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := doRequest(t, s, tt.path)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if limit := tt.limit(response); limit != float64(5) {
+				t.Errorf("Expected limit 5, got %v", limit)
+			}
+		})
+	}
+}
+
+// TestPaginationParamsStrict tests malformed vs absent pagination params with and without StrictParams
+func TestPaginationParamsStrict(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		strict     bool
+		expectCode int
+		wantLimit  float64
+	}{
+		{name: "Absent, strict", path: "/api/transactions", strict: true, expectCode: http.StatusOK, wantLimit: 100},
+		{name: "Valid, strict", path: "/api/transactions?page=2&limit=20", strict: true, expectCode: http.StatusOK, wantLimit: 20},
+		{name: "Empty value, strict", path: "/api/transactions?limit=", strict: true, expectCode: http.StatusOK, wantLimit: 100},
+		{name: "Non-numeric limit, strict", path: "/api/transactions?limit=abc", strict: true, expectCode: http.StatusBadRequest},
+		{name: "Negative page, strict", path: "/api/transactions?page=-1", strict: true, expectCode: http.StatusBadRequest},
+		{name: "Zero limit, strict", path: "/api/blocks?limit=0", strict: true, expectCode: http.StatusBadRequest},
+		{name: "Malformed address page, strict", path: "/api/addresses/0xwhale/transactions?page=x", strict: true, expectCode: http.StatusBadRequest},
+		{name: "Malformed search limit, strict", path: "/api/search?q=whale&limit=1.5", strict: true, expectCode: http.StatusBadRequest},
+		{name: "Malformed top n, strict", path: "/api/transactions/top?n=ten", strict: true, expectCode: http.StatusBadRequest},
+		{name: "Non-numeric limit, lenient", path: "/api/transactions?limit=abc", expectCode: http.StatusOK, wantLimit: 100},
+		{name: "Negative page, lenient", path: "/api/transactions?page=-1&limit=7", expectCode: http.StatusOK, wantLimit: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServerWithDB(t)
+			s.config.StrictParams = tt.strict
+
+			rec, response := doRequest(t, s, tt.path)
+			if rec.Code != tt.expectCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.expectCode, rec.Code, rec.Body.String())
+			}
+			if tt.expectCode != http.StatusOK {
+				if response.Error == "" {
+					t.Error("Expected an error message naming the param")
+				}
+				return
+			}
+			if tt.wantLimit != 0 {
+				if limit := response.Meta.(map[string]interface{})["limit"]; limit != tt.wantLimit {
+					t.Errorf("Expected limit %v, got %v", tt.wantLimit, limit)
+				}
+			}
+		})
+	}
+}
+
+// TestMaxPageLimitDefault tests that a zero MaxPageLimit falls back to the default
+func TestMaxPageLimitDefault(t *testing.T) {
+	s := NewServer(nil, &ServerConfig{}, log.New(io.Discard, "", 0))
+	if s.config.MaxPageLimit != DefaultMaxPageLimit {
+		t.Errorf("Expected MaxPageLimit %d, got %d", DefaultMaxPageLimit, s.config.MaxPageLimit)
+	}
+	if got := s.clampLimit(5000); got != DefaultMaxPageLimit {
+		t.Errorf("Expected limit clamped to %d, got %d", DefaultMaxPageLimit, got)
+	}
+}
+
+// TestDeleteEndpoints tests DELETE /api/transactions/{hash} and DELETE /api/blocks/{number}
+func TestDeleteEndpoints(t *testing.T) {
+	s := newTestServerWithDB(t)
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: "0x1", BlockNumber: 5, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "1"},
+		{TxHash: "0x2", BlockNumber: 6, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "1"},
+		{TxHash: "0x3", BlockNumber: 6, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+	err = s.blockRepo.BatchUpsert(context.Background(), []*database.Block{
+		{Number: 6, Hash: "0xb6", ParentHash: "0xb5", Timestamp: time.Unix(1700000000, 0).UTC()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert blocks: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		wantStatus       int
+		wantDeleted      float64
+		wantBlockDeleted interface{} // nil for transaction deletes
+	}{
+		{"Delete by hash", http.MethodDelete, "/api/transactions/0x1", http.StatusOK, 1, nil},
+		{"Delete nonexistent hash", http.MethodDelete, "/api/transactions/0xmissing", http.StatusOK, 0, nil},
+		{"Delete block", http.MethodDelete, "/api/blocks/6", http.StatusOK, 2, true},
+		{"Delete empty block", http.MethodDelete, "/api/blocks/7", http.StatusOK, 0, false},
+		{"Invalid block number", http.MethodDelete, "/api/blocks/abc", http.StatusBadRequest, 0, nil},
+		{"GET on blocks", http.MethodGet, "/api/blocks/6", http.StatusMethodNotAllowed, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := doMethodRequest(t, s, tt.method, tt.path)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if deleted := response.Data.(map[string]interface{})["deleted"]; deleted != tt.wantDeleted {
+				t.Errorf("Expected %v deleted, got %v", tt.wantDeleted, deleted)
+			}
+			if blockDeleted := response.Data.(map[string]interface{})["block_deleted"]; blockDeleted != tt.wantBlockDeleted {
+				t.Errorf("Expected block_deleted %v, got %v", tt.wantBlockDeleted, blockDeleted)
+			}
+		})
+	}
+
+	// the deleted block is no longer listed
+	if count, err := s.blockRepo.Count(context.Background()); err != nil || count != 0 {
+		t.Errorf("Expected no stored blocks, got %d (%v)", count, err)
+	}
+
+	// GET still works on the shared transactions pattern
+	if rec, _ := doRequest(t, s, "/api/transactions/0x1"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected deleted transaction to be gone, got %d", rec.Code)
+	}
+}
+
+// TestDeleteRequiresAuth tests that the delete endpoints are protected
+func TestDeleteRequiresAuth(t *testing.T) {
+	s := newTestServerWithDB(t)
+
+	for _, path := range []string{"/api/transactions/0x1", "/api/blocks/1"} {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %s, got %d", path, rec.Code)
+		}
+	}
+}
+
+// TestServerAddr tests listen address construction for IPv4, IPv6 and all-interfaces hosts
+func TestServerAddr(t *testing.T) {
+	tests := []struct {
+		host    string
+		want    string
+		wantAll bool
+	}{
+		{"localhost", "localhost:8015", false},
+		{"127.0.0.1", "127.0.0.1:8015", false},
+		{"::1", "[::1]:8015", false},
+		{"[::1]", "[::1]:8015", false},
+		{"fe80::1", "[fe80::1]:8015", false},
+		{"", ":8015", true},
+		{"0.0.0.0", "0.0.0.0:8015", true},
+		{"::", "[::]:8015", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			config := DefaultServerConfig()
+			config.Host = tt.host
+			s := NewServer(nil, config, log.New(io.Discard, "", 0))
+
+			if got := s.Addr(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if got := s.httpServer().Addr; got != tt.want {
+				t.Errorf("Expected http.Server Addr %s, got %s", tt.want, got)
+			}
+			if got := s.bindsAllInterfaces(); got != tt.wantAll {
+				t.Errorf("Expected bindsAllInterfaces %v, got %v", tt.wantAll, got)
+			}
+		})
+	}
+}
+
+// TestStatsEndpoint tests GET /api/stats tx type counts
+func TestStatsEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: "0x1", BlockNumber: 1, FromAddress: "0xwhale", Value: "1", TxType: 0},
+		{TxHash: "0x2", BlockNumber: 1, FromAddress: "0xwhale", Value: "1", TxType: 2},
+		{TxHash: "0x3", BlockNumber: 2, FromAddress: "0xwhale", Value: "1", TxType: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	rec, response := doRequest(t, s, "/api/stats")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	data := response.Data.(map[string]interface{})
+	if data["transactions"] != float64(3) {
+		t.Errorf("Expected 3 transactions, got %v", data["transactions"])
+	}
+	counts := data["tx_type_counts"].(map[string]interface{})
+	if counts["0"] != float64(1) || counts["2"] != float64(2) {
+		t.Errorf("Unexpected tx type counts: %v", counts)
+	}
+}
+
+// TestValueHistogramEndpoint tests GET /api/whales/histogram with default and custom buckets
+func TestValueHistogramEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: "0x1", BlockNumber: 1, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "5"},
+		{TxHash: "0x2", BlockNumber: 1, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "50"},
+		{TxHash: "0x3", BlockNumber: 2, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "5000"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		expectCode int
+		wantCounts map[string]float64
+		wantLabels int
+	}{
+		{
+			name:       "Default buckets",
+			path:       "/api/whales/histogram",
+			expectCode: http.StatusOK,
+			wantCounts: map[string]float64{"<1": 0, "1-10": 1, "10-100": 1, "100-1000": 0, "1000+": 1},
+			wantLabels: 5,
+		},
+		{
+			name:       "Custom buckets",
+			path:       "/api/whales/histogram?buckets=10,1000",
+			expectCode: http.StatusOK,
+			wantCounts: map[string]float64{"<10": 1, "10-1000": 1, "1000+": 1},
+			wantLabels: 3,
+		},
+		{name: "Not a number", path: "/api/whales/histogram?buckets=1,ten", expectCode: http.StatusBadRequest},
+		{name: "Not ascending", path: "/api/whales/histogram?buckets=100,10", expectCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := doRequest(t, s, tt.path)
+			if rec.Code != tt.expectCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.expectCode, rec.Code, rec.Body.String())
+			}
+			if tt.expectCode != http.StatusOK {
+				return
+			}
+
+			data := response.Data.(map[string]interface{})
+			if labels := data["labels"].([]interface{}); len(labels) != tt.wantLabels {
+				t.Errorf("Expected %d labels, got %v", tt.wantLabels, labels)
+			}
+			counts := data["counts"].(map[string]interface{})
+			for label, want := range tt.wantCounts {
+				if counts[label] != want {
+					t.Errorf("Expected %v in bucket %s, got %v", want, label, counts[label])
+				}
+			}
+		})
+	}
+}
+
+// TestTopTransactionsEndpoint tests GET /api/transactions/top
+func TestTopTransactionsEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: "0x1", BlockNumber: 1, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "9.5"},
+		{TxHash: "0x2", BlockNumber: 2, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "120"},
+		{TxHash: "0x3", BlockNumber: 3, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "15"},
+		{TxHash: "0x4", BlockNumber: 4, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "1000"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		expectCode int
+		expected   []string
+	}{
+		{name: "Default", path: "/api/transactions/top", expectCode: http.StatusOK, expected: []string{"0x4", "0x2", "0x3", "0x1"}},
+		{name: "Range and n", path: "/api/transactions/top?from=1&to=3&n=2", expectCode: http.StatusOK, expected: []string{"0x2", "0x3"}},
+		{name: "From only", path: "/api/transactions/top?from=3", expectCode: http.StatusOK, expected: []string{"0x4", "0x3"}},
+		{name: "Invalid from", path: "/api/transactions/top?from=abc", expectCode: http.StatusBadRequest},
+		{name: "Negative to", path: "/api/transactions/top?to=-1", expectCode: http.StatusBadRequest},
+		{name: "From after to", path: "/api/transactions/top?from=5&to=1", expectCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := doRequest(t, s, tt.path)
+			if rec.Code != tt.expectCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.expectCode, rec.Code, rec.Body.String())
+			}
+			if tt.expectCode != http.StatusOK {
+				return
+			}
+
+			data := response.Data.([]interface{})
+			if len(data) != len(tt.expected) {
+				t.Fatalf("Expected %d transactions, got %d", len(tt.expected), len(data))
+			}
+			for i, hash := range tt.expected {
+				if got := data[i].(map[string]interface{})["tx_hash"]; got != hash {
+					t.Errorf("Expected %s at %d, got %v", hash, i, got)
+				}
+			}
+		})
+	}
+}
+
+// TestBlocksEndpoint tests GET /api/blocks
+func TestBlocksEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+	baseFee := "12000000000"
+	err := s.blockRepo.BatchUpsert(context.Background(), []*database.Block{
+		{Number: 12000000, Hash: "0xa", ParentHash: "0x9", Timestamp: time.Unix(1, 0), GasUsed: 7_500_000, GasLimit: 15_000_000, GasUsedRatio: 0.5},
+		{Number: 20000000, Hash: "0xb", ParentHash: "0xa", Timestamp: time.Unix(2, 0), GasUsed: 29_999_000, GasLimit: 30_000_000, GasUsedRatio: 0.99996667, BaseFeePerGas: &baseFee},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert blocks: %v", err)
+	}
+
+	rec, response := doRequest(t, s, "/api/blocks?limit=1")
+	if rec.Code != http.StatusOK || !response.Success {
+		t.Fatalf("Expected 200 success, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if total := response.Meta.(map[string]interface{})["total"]; total != float64(2) {
+		t.Errorf("Expected total 2, got %v", total)
+	}
+	block := response.Data.([]interface{})[0].(map[string]interface{})
+	if block["number"] != float64(20000000) || block["gas_used_ratio"] != 0.99996667 || block["base_fee_per_gas"] != baseFee {
+		t.Errorf("Unexpected newest block: %v", block)
+	}
+
+	_, response = doRequest(t, s, "/api/blocks?page=2&limit=1")
+	block = response.Data.([]interface{})[0].(map[string]interface{})
+	if block["number"] != float64(12000000) || block["base_fee_per_gas"] != nil {
+		t.Errorf("Expected pre-London block without base fee, got %v", block)
+	}
+}
+
+// TestDetectSearchType tests search input classification by shape
+func TestDetectSearchType(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"0x" + strings.Repeat("ab", 32), SearchTypeTxHash},
+		{"0X" + strings.Repeat("AB", 32), SearchTypeTxHash},
+		{"0x56Eddb7aa87536c09CCc2793473599fD21A8b17F", SearchTypeAddress},
+		{"0x56Eddb7aa87536c09CCc2793473599fD21A8b17Z", SearchTypeLabel}, // not hex
+		{"56Eddb7aa87536c09CCc2793473599fD21A8b17F00", SearchTypeLabel}, // no 0x prefix
+		{"0x56Eddb", SearchTypeLabel},
+		{"Binance", SearchTypeLabel},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := detectSearchType(tt.query); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestSearchEndpoint tests GET /api/search for each input shape
+func TestSearchEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+	txHash := "0x" + strings.Repeat("ab", 32)
+	from := "0x56Eddb7aa87536c09CCc2793473599fD21A8b17F"
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: txHash, BlockNumber: 5, FromAddress: from, WhaleAddressID: int64Ptr(1), Value: "4.5"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transaction: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		query        string
+		expectedCode int
+		expectedType string
+		check        func(t *testing.T, result interface{})
+	}{
+		{
+			name:         "Tx hash",
+			query:        txHash,
+			expectedCode: http.StatusOK,
+			expectedType: SearchTypeTxHash,
+			check: func(t *testing.T, result interface{}) {
+				if tx := result.(map[string]interface{}); tx["tx_hash"] != txHash {
+					t.Errorf("Expected transaction %s, got %v", txHash, tx)
+				}
+			},
+		},
+		{
+			name:         "Unknown tx hash",
+			query:        "0x" + strings.Repeat("cd", 32),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "Address",
+			query:        from,
+			expectedCode: http.StatusOK,
+			expectedType: SearchTypeAddress,
+			check: func(t *testing.T, result interface{}) {
+				if txs := result.([]interface{}); len(txs) != 1 {
+					t.Errorf("Expected 1 transaction, got %d", len(txs))
+				}
+			},
+		},
+		{
+			name:         "Address without transactions",
+			query:        "0x" + strings.Repeat("0", 40),
+			expectedCode: http.StatusOK,
+			expectedType: SearchTypeAddress,
+			check: func(t *testing.T, result interface{}) {
+				if txs := result.([]interface{}); len(txs) != 0 {
+					t.Errorf("Expected no transactions, got %d", len(txs))
+				}
+			},
+		},
+		{
+			name:         "Label substring",
+			query:        "hal",
+			expectedCode: http.StatusOK,
+			expectedType: SearchTypeLabel,
+			check: func(t *testing.T, result interface{}) {
+				whales := result.([]interface{})
+				if len(whales) != 1 || whales[0].(map[string]interface{})["label"] != "Whale" {
+					t.Errorf("Expected the Whale address, got %v", whales)
+				}
+			},
+		},
+		{
+			name:         "Empty query",
+			query:        "",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := doRequest(t, s, "/api/search?q="+url.QueryEscape(tt.query))
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.expectedCode, rec.Code, rec.Body.String())
+			}
+			if tt.check == nil {
+				return
+			}
+			data := response.Data.(map[string]interface{})
+			if data["type"] != tt.expectedType || data["query"] != tt.query {
+				t.Errorf("Expected type %s for %s, got %v", tt.expectedType, tt.query, data)
+			}
+			tt.check(t, data["result"])
+		})
+	}
+}
+
+// TestTransactionsTransferTypeFilter tests GET /api/transactions?transfer_type=
+func TestTransactionsTransferTypeFilter(t *testing.T) {
+	s := newTestServerWithDB(t)
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: "0x1", BlockNumber: 1, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), TransferType: "FROM", Value: "1"},
+		{TxHash: "0x2", BlockNumber: 2, FromAddress: "0xuser", WhaleAddressID: int64Ptr(1), TransferType: "TO", Value: "1"},
+		{TxHash: "0x3", BlockNumber: 3, FromAddress: "0xuser", WhaleAddressID: int64Ptr(1), TransferType: "TO", Value: "1"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to insert transactions: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedCode   int
+		expectedHashes []string
+	}{
+		{"Deposits", "TO", http.StatusOK, []string{"0x3", "0x2"}},
+		{"Lowercase", "from", http.StatusOK, []string{"0x1"}},
+		{"No matches", "INT", http.StatusOK, []string{}},
+		{"Unknown type", "SIDEWAYS", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := doRequest(t, s, "/api/transactions?transfer_type="+tt.query)
+			if rec.Code != tt.expectedCode {
+				t.Fatalf("Expected %d, got %d: %s", tt.expectedCode, rec.Code, rec.Body.String())
+			}
+			if tt.expectedHashes == nil {
+				return
+			}
+			hashes := []string{}
+			if data, ok := response.Data.([]interface{}); ok {
+				for _, tx := range data {
+					hashes = append(hashes, tx.(map[string]interface{})["tx_hash"].(string))
+				}
+			}
+			if strings.Join(hashes, ",") != strings.Join(tt.expectedHashes, ",") {
+				t.Errorf("Expected %v, got %v", tt.expectedHashes, hashes)
+			}
+			if total := response.Meta.(map[string]interface{})["total"]; total != float64(len(tt.expectedHashes)) {
+				t.Errorf("Expected total %d, got %v", len(tt.expectedHashes), total)
+			}
+		})
+	}
+}
+
+// TestSettingsEndpoint tests updating and reading runtime settings through PUT/GET /api/settings
+func TestSettingsEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+
+	put := func(body string, auth bool) (*httptest.ResponseRecorder, APIResponse) {
+		req := httptest.NewRequest(http.MethodPut, "/api/settings", strings.NewReader(body))
+		if auth {
+			req.SetBasicAuth(s.config.Username, s.config.Password)
+		}
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		var response APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+		}
+		return rec, response
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		auth       bool
+		expectCode int
+		expected   map[string]interface{}
+	}{
+		{name: "Requires auth", body: `{"min_eth_value": "5"}`, auth: false, expectCode: http.StatusUnauthorized},
+		{name: "String and number", body: `{"min_eth_value": "5", "retention_days": 7}`, auth: true, expectCode: http.StatusOK,
+			expected: map[string]interface{}{"min_eth_value": "5", "retention_days": "7"}},
+		{name: "Unknown key", body: `{"workers": 10}`, auth: true, expectCode: http.StatusBadRequest},
+		{name: "Invalid value", body: `{"whale_direction": "sideways", "retention_days": 1}`, auth: true, expectCode: http.StatusBadRequest},
+		{name: "Not an object", body: `[1, 2]`, auth: true, expectCode: http.StatusBadRequest},
+		{name: "Empty", body: `{}`, auth: true, expectCode: http.StatusBadRequest},
+		{name: "Reset", body: `{"min_eth_value": "", "whale_direction": "from"}`, auth: true, expectCode: http.StatusOK,
+			expected: map[string]interface{}{"retention_days": "7", "whale_direction": "from"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := put(tt.body, tt.auth)
+			if rec.Code != tt.expectCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectCode, rec.Code, rec.Body.String())
+			}
+			if tt.expected == nil {
+				return
+			}
+			if fmt.Sprint(response.Data) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected settings %v, got %v", tt.expected, response.Data)
+			}
+
+			// rejected requests above must not have stored anything
+			rec, response = doRequest(t, s, "/api/settings")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", rec.Code)
+			}
+			if fmt.Sprint(response.Data) != fmt.Sprint(tt.expected) {
+				t.Errorf("Expected stored settings %v, got %v", tt.expected, response.Data)
+			}
+		})
+	}
+
+	rec, _ := doMethodRequest(t, s, http.MethodPost, "/api/settings")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+// TestErrorCodes tests that every error path returns its HTTP status with the matching machine-readable code
+func TestErrorCodes(t *testing.T) {
+	s := newTestServerWithDB(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		noAuth     bool
+		expectCode int
+		expectErr  string
+	}{
+		{name: "Missing credentials", method: http.MethodGet, path: "/api/transactions", noAuth: true, expectCode: http.StatusUnauthorized, expectErr: CodeUnauthorized},
+		{name: "Unknown transaction", method: http.MethodGet, path: "/api/transactions/0xmissing", expectCode: http.StatusNotFound, expectErr: CodeNotFound},
+		{name: "Unknown address summary", method: http.MethodGet, path: "/api/addresses/0xnobody/summary", expectCode: http.StatusNotFound, expectErr: CodeNotFound},
+		{name: "Search miss", method: http.MethodGet, path: "/api/search?q=0x" + strings.Repeat("ab", 32), expectCode: http.StatusNotFound, expectErr: CodeNotFound},
+		{name: "Invalid whale_ids", method: http.MethodGet, path: "/api/transactions?whale_ids=abc", expectCode: http.StatusBadRequest, expectErr: CodeInvalidParam},
+		{name: "Invalid transfer_type", method: http.MethodGet, path: "/api/transactions?transfer_type=SIDEWAYS", expectCode: http.StatusBadRequest, expectErr: CodeInvalidParam},
+		{name: "Invalid buckets", method: http.MethodGet, path: "/api/whales/histogram?buckets=x", expectCode: http.StatusBadRequest, expectErr: CodeInvalidParam},
+		{name: "Inverted top range", method: http.MethodGet, path: "/api/transactions/top?from=5&to=1", expectCode: http.StatusBadRequest, expectErr: CodeInvalidParam},
+		{name: "Missing search query", method: http.MethodGet, path: "/api/search", expectCode: http.StatusBadRequest, expectErr: CodeInvalidParam},
+		{name: "Invalid block number", method: http.MethodDelete, path: "/api/blocks/abc", expectCode: http.StatusBadRequest, expectErr: CodeInvalidParam},
+		{name: "Unknown setting", method: http.MethodPut, path: "/api/settings", body: `{"workers": 1}`, expectCode: http.StatusBadRequest, expectErr: CodeInvalidParam},
+		{name: "Malformed body", method: http.MethodPut, path: "/api/settings", body: `{`, expectCode: http.StatusBadRequest, expectErr: CodeInvalidBody},
+		{name: "Wrong method on blocks", method: http.MethodGet, path: "/api/blocks/1", expectCode: http.StatusMethodNotAllowed, expectErr: CodeMethodNotAllowed},
+		{name: "Wrong method on settings", method: http.MethodPost, path: "/api/settings", expectCode: http.StatusMethodNotAllowed, expectErr: CodeMethodNotAllowed},
+		{name: "Wrong method on transaction", method: http.MethodPost, path: "/api/transactions/0x1", expectCode: http.StatusMethodNotAllowed, expectErr: CodeMethodNotAllowed},
+		{name: "Wrong method on transaction logs", method: http.MethodDelete, path: "/api/transactions/0x1/logs", expectCode: http.StatusMethodNotAllowed, expectErr: CodeMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if !tt.noAuth {
+				req.SetBasicAuth(s.config.Username, s.config.Password)
+			}
+			rec := httptest.NewRecorder()
+			s.handler().ServeHTTP(rec, req)
+
+			var response APIResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+			}
+			if rec.Code != tt.expectCode {
+				t.Errorf("Expected status %d, got %d", tt.expectCode, rec.Code)
+			}
+			if response.Code != tt.expectErr {
+				t.Errorf("Expected code %s, got %q", tt.expectErr, response.Code)
+			}
+			if response.Error == "" || response.Success {
+				t.Errorf("Expected a failed response with a message, got %+v", response)
+			}
+		})
+	}
+
+	// a broken query surfaces as DB_ERROR
+	db, err := s.dm.DB()
+	if err != nil {
+		t.Fatalf("Failed to get database connection: %v", err)
+	}
+	if _, err := db.Exec("DROP TABLE blocks"); err != nil {
+		t.Fatalf("Failed to drop table: %v", err)
+	}
+	rec, response := doRequest(t, s, "/api/blocks")
+	if rec.Code != http.StatusInternalServerError || response.Code != CodeDBError {
+		t.Errorf("Expected 500 %s, got %d %q", CodeDBError, rec.Code, response.Code)
+	}
+
+	// successful responses carry no code
+	_, response = doRequest(t, s, "/api/transactions")
+	if response.Code != "" {
+		t.Errorf("Expected no code on success, got %q", response.Code)
+	}
+}
+
+// mockBlockClient serves blocks with one 2 ETH transfer signed by key each, nonce = block number
+type mockBlockClient struct {
+	key *ecdsa.PrivateKey
+}
+
+func (m *mockBlockClient) GetBlockByNumber(ctx context.Context, blockNumber uint64) (*gethTypes.Block, error) {
+	chainID := big.NewInt(1)
+	to := common.HexToAddress("0x0000000000000000000000000000000000000002")
+	tx, err := gethTypes.SignNewTx(m.key, gethTypes.LatestSignerForChainID(chainID), &gethTypes.DynamicFeeTx{
+		ChainID: chainID, Nonce: blockNumber, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10),
+		To: &to, Value: new(big.Int).Mul(big.NewInt(2), big.NewInt(1e18)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := &gethTypes.Header{Number: new(big.Int).SetUint64(blockNumber), Difficulty: big.NewInt(0)}
+	return gethTypes.NewBlockWithHeader(header).WithBody(gethTypes.Body{Transactions: []*gethTypes.Transaction{tx}}), nil
+}
+
+func (m *mockBlockClient) GetBlockByHash(ctx context.Context, blockHash common.Hash) (*gethTypes.Block, error) {
+	return m.GetBlockByNumber(ctx, 1)
+}
+
+func (m *mockBlockClient) GetTransactionReceiptsBatch(ctx context.Context, txHashes []common.Hash) ([]*gethTypes.Receipt, error) {
+	return make([]*gethTypes.Receipt, len(txHashes)), nil
+}
+
+func (m *mockBlockClient) GetLogs(ctx context.Context, query ethereum.FilterQuery) ([]gethTypes.Log, error) {
+	return nil, nil
+}
+
+// TestParseEndpoint tests POST /api/parse range checks and storing the whale txs of the parsed range
+func TestParseEndpoint(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	whale := strings.ToLower(crypto.PubkeyToAddress(key.PublicKey).Hex())
+
+	s := newTestServerWithDB(t)
+	if _, err := s.addrRepo.Insert(context.Background(), &database.WhaleAddress{Address: whale}); err != nil {
+		t.Fatalf("Failed to insert whale: %v", err)
+	}
+
+	post := func(body string) (*httptest.ResponseRecorder, APIResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/api/parse", strings.NewReader(body))
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+		rec := httptest.NewRecorder()
+		s.handler().ServeHTTP(rec, req)
+		var response APIResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+		}
+		return rec, response
+	}
+
+	if rec, response := post(`{"start": 1, "end": 2}`); rec.Code != http.StatusServiceUnavailable || response.Code != CodeParseDisabled {
+		t.Fatalf("Expected 503 %s without a client, got %d %q", CodeParseDisabled, rec.Code, response.Code)
+	}
+	// receipts skipped like for real blocks, a single-tx block would otherwise fetch them
+	config := types.DefaultConfig()
+	config.MaxTransactionsForReceipts = 0
+	s.SetBlockClient(&mockBlockClient{key: key}, config)
+
+	tests := []struct {
+		name       string
+		body       string
+		expectCode int
+		expectErr  string
+		expectTxs  float64
+	}{
+		{name: "Range too large", body: fmt.Sprintf(`{"start": 1, "end": %d}`, DefaultMaxParseRange+1), expectCode: http.StatusBadRequest, expectErr: CodeInvalidBody},
+		{name: "Full uint64 range", body: `{"start": 0, "end": 18446744073709551615}`, expectCode: http.StatusBadRequest, expectErr: CodeInvalidBody},
+		{name: "End before start", body: `{"start": 5, "end": 4}`, expectCode: http.StatusBadRequest, expectErr: CodeInvalidBody},
+		{name: "Unknown field", body: `{"from": 1, "to": 2}`, expectCode: http.StatusBadRequest, expectErr: CodeInvalidBody},
+		{name: "Valid range", body: `{"start": 10, "end": 12}`, expectCode: http.StatusOK, expectTxs: 3},
+		{name: "Largest range", body: fmt.Sprintf(`{"start": 1, "end": %d}`, DefaultMaxParseRange), expectCode: http.StatusOK, expectTxs: DefaultMaxParseRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := post(tt.body)
+			if rec.Code != tt.expectCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectCode, rec.Code, rec.Body.String())
+			}
+			if response.Code != tt.expectErr {
+				t.Errorf("Expected code %q, got %q", tt.expectErr, response.Code)
+			}
+			if tt.expectCode != http.StatusOK {
+				return
+			}
+			summary := response.Data.(map[string]interface{})
+			if summary["whale_transactions"] != tt.expectTxs || summary["blocks_parsed"] != tt.expectTxs || summary["complete"] != true {
+				t.Errorf("Expected %v whale txs in %v complete blocks, got %v", tt.expectTxs, tt.expectTxs, summary)
+			}
+		})
+	}
+
+	// blocks 10-12 are inside 1-50, so the second run replaced their txs
+	stored, err := s.txRepo.GetRecent(context.Background(), 1000)
+	if err != nil {
+		t.Fatalf("Failed to get stored transactions: %v", err)
+	}
+	if len(stored) != DefaultMaxParseRange {
+		t.Errorf("Expected %d stored whale txs, got %d", DefaultMaxParseRange, len(stored))
+	}
+}
+
+// spyTxGetter counts the lookups that reach the repository
+type spyTxGetter struct {
+	txGetter
+	calls int
+}
+
+func (s *spyTxGetter) GetByHash(ctx context.Context, txHash string) (*database.Transaction, error) {
+	s.calls++
+	return s.txGetter.GetByHash(ctx, txHash)
+}
+
+// TestTransactionCache tests that repeated GET /api/transactions/{hash} of a confirmed row is served
+// from the cache, unconfirmed rows always hit the DB and deletes and the TTL invalidate entries
+func TestTransactionCache(t *testing.T) {
+	s := newTestServerWithDB(t)
+	status := 1
+	err := s.txRepo.BatchInsert(context.Background(), []*database.Transaction{
+		{TxHash: "0xconfirmed", BlockNumber: 7, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "5", ReceiptFetched: true, Status: &status},
+		{TxHash: "0xpending", BlockNumber: 7, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "5"},
+		{TxHash: "0xreorged", BlockNumber: 8, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "5", ReceiptFetched: true, Status: &status},
+		{TxHash: "0xexpiring", BlockNumber: 9, FromAddress: "0xwhale", WhaleAddressID: int64Ptr(1), Value: "5", ReceiptFetched: true, Status: &status},
+	})
+	if err != nil {
+		t.Fatalf("Failed to seed transactions: %v", err)
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	spy := &spyTxGetter{txGetter: s.txRepo}
+	s.txCache = newTxCache(spy, 10, time.Minute)
+	s.txCache.now = func() time.Time { return now }
+	s.txByHash = s.txCache
+
+	steps := []struct {
+		name           string
+		method         string
+		path           string
+		advance        time.Duration
+		expectedStatus int
+		expectedCalls  int
+	}{
+		{"Confirmed miss", http.MethodGet, "/api/transactions/0xconfirmed", 0, http.StatusOK, 1},
+		{"Confirmed hit", http.MethodGet, "/api/transactions/0xconfirmed", 0, http.StatusOK, 1},
+		{"Unconfirmed", http.MethodGet, "/api/transactions/0xpending", 0, http.StatusOK, 2},
+		{"Unconfirmed not cached", http.MethodGet, "/api/transactions/0xpending", 0, http.StatusOK, 3},
+		{"Delete", http.MethodDelete, "/api/transactions/0xconfirmed", 0, http.StatusOK, 3},
+		{"Deleted not served", http.MethodGet, "/api/transactions/0xconfirmed", 0, http.StatusNotFound, 4},
+		{"Block tx miss", http.MethodGet, "/api/transactions/0xreorged", 0, http.StatusOK, 5},
+		{"Delete block", http.MethodDelete, "/api/blocks/8", 0, http.StatusOK, 5},
+		{"Deleted block not served", http.MethodGet, "/api/transactions/0xreorged", 0, http.StatusNotFound, 6},
+		{"Expiring miss", http.MethodGet, "/api/transactions/0xexpiring", 0, http.StatusOK, 7},
+		{"Expiring hit", http.MethodGet, "/api/transactions/0xexpiring", 30 * time.Second, http.StatusOK, 7},
+		{"Expired", http.MethodGet, "/api/transactions/0xexpiring", time.Minute, http.StatusOK, 8},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		rec, _ := doMethodRequest(t, s, step.method, step.path)
+		if rec.Code != step.expectedStatus {
+			t.Errorf("%s: expected status %d, got %d", step.name, step.expectedStatus, rec.Code)
+		}
+		if spy.calls != step.expectedCalls {
+			t.Errorf("%s: expected %d DB lookups, got %d", step.name, step.expectedCalls, spy.calls)
+		}
+	}
+}
+
+// TestTxCacheEviction tests that the least recently used transaction is evicted over the size
+func TestTxCacheEviction(t *testing.T) {
+	getter := &confirmedTxGetter{}
+	cache := newTxCache(getter, 2, time.Minute)
+	ctx := context.Background()
+
+	for _, hash := range []string{"0x1", "0x2", "0x1", "0x3"} {
+		if _, err := cache.GetByHash(ctx, hash); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// 0x2 was the least recently used when 0x3 was added
+	for _, tt := range []struct {
+		hash   string
+		cached bool
+	}{{"0x1", true}, {"0x2", false}, {"0x3", true}} {
+		if _, ok := cache.get(tt.hash); ok != tt.cached {
+			t.Errorf("Expected %s cached %v, got %v", tt.hash, tt.cached, ok)
+		}
+	}
+}
+
+// confirmedTxGetter returns a confirmed transaction for any hash
+type confirmedTxGetter struct{}
+
+func (confirmedTxGetter) GetByHash(ctx context.Context, txHash string) (*database.Transaction, error) {
+	return &database.Transaction{TxHash: txHash, ReceiptFetched: true}, nil
+}
+
+// TestSchemaEndpoint tests GET /api/schema lists the tables with their columns
+func TestSchemaEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+
+	rec, response := doRequest(t, s, "/api/schema")
+	if rec.Code != http.StatusOK || !response.Success {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	data, _ := json.Marshal(response.Data)
+	var info database.SchemaInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		t.Fatalf("Failed to decode schema info: %v", err)
+	}
+	tables := make(map[string]int)
+	for _, table := range info.Tables {
+		tables[table.Name] = len(table.Columns)
+	}
+	for _, name := range []string{"transactions", "whale_addresses", "blocks", "parse_errors", "settings", "meta", "logs"} {
+		if tables[name] == 0 {
+			t.Errorf("Expected table %s with columns, got %v", name, tables)
+		}
+	}
+	if len(info.Indexes) == 0 {
+		t.Error("Expected indexes, got none")
+	}
+}
+
+// TestTransactionLogsEndpoint tests GET /api/transactions/{hash}/logs for a tx with several logs and one without
+func TestTransactionLogsEndpoint(t *testing.T) {
+	s := newTestServerWithDB(t)
+	ctx := context.Background()
+
+	withLogs := "0x" + strings.Repeat("1", 64)
+	var logs []*database.Log
+	for _, parsed := range []*types.ParsedLog{
+		{
+			Address: "0xdac17f958d2ee523a2206206994597c13d831ec7", TxHash: withLogs, LogIndex: 1, BlockNumber: 100,
+			Topics:           []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+			DecodedEventName: "Transfer", DecodedData: map[string]string{"value": "1000"},
+		},
+		{
+			Address: "0xc02aaa39b223fe8d0a0e5c4f27ead9083c756cc2", TxHash: withLogs, LogIndex: 0, BlockNumber: 100,
+			Topics:           []string{"0xe1fffcc4923d04b559f4d29a8bfc6cda04eb5b0d3c460751c2402c5c5cc9109c"},
+			DecodedEventName: "Deposit", DecodedData: map[string]string{"wad": "5"},
+		},
+		{Address: "0x0000000000000000000000000000000000000001", TxHash: withLogs, LogIndex: 2, BlockNumber: 100},
+	} {
+		l, err := database.MapParsedLog(parsed)
+		if err != nil {
+			t.Fatalf("Failed to map log: %v", err)
+		}
+		logs = append(logs, l)
+	}
+	if err := s.logRepo.BatchInsert(ctx, logs); err != nil {
+		t.Fatalf("Failed to insert logs: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		hash           string
+		expectedEvents []string // "" for an undecoded log
+	}{
+		{"tx with logs", withLogs, []string{"Deposit", "Transfer", ""}},
+		{"tx without logs", "0x" + strings.Repeat("2", 64), []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, response := doRequest(t, s, "/api/transactions/"+tt.hash+"/logs")
+			if rec.Code != http.StatusOK || !response.Success {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if strings.Contains(rec.Body.String(), `"data":null`) {
+				t.Fatalf("Expected an array, got %s", rec.Body.String())
+			}
+
+			data, _ := json.Marshal(response.Data)
+			var got []*database.Log
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Failed to decode logs: %v", err)
+			}
+			events := []string{}
+			for _, l := range got {
+				name := ""
+				if l.EventName != nil {
+					name = *l.EventName
+				}
+				events = append(events, name)
+			}
+			if !reflect.DeepEqual(events, tt.expectedEvents) {
+				t.Errorf("Expected events %v, got %v", tt.expectedEvents, events)
+			}
+			if len(got) > 1 && string(got[1].DecodedData) != `{"value":"1000"}` {
+				t.Errorf("Expected decoded Transfer data, got %s", got[1].DecodedData)
+			}
+		})
+	}
+}
+
+// TestTransactionMethods tests that only GET and DELETE reach a transaction and only GET its logs
+func TestTransactionMethods(t *testing.T) {
+	s := newTestServerWithDB(t)
+
+	tests := []struct {
+		method     string
+		path       string
+		expectCode int
+		allow      string
+	}{
+		{http.MethodPost, "/api/transactions/0x1", http.StatusMethodNotAllowed, "GET, DELETE"},
+		{http.MethodPut, "/api/transactions/0x1", http.StatusMethodNotAllowed, "GET, DELETE"},
+		{http.MethodPatch, "/api/transactions/0x1", http.StatusMethodNotAllowed, "GET, DELETE"},
+		{http.MethodDelete, "/api/transactions/0x1/logs", http.StatusMethodNotAllowed, "GET"},
+		{http.MethodPost, "/api/transactions/0x1/logs", http.StatusMethodNotAllowed, "GET"},
+		{http.MethodGet, "/api/transactions/0x1/logs", http.StatusOK, ""},
+		{http.MethodGet, "/api/transactions/0x1", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			rec, _ := doMethodRequest(t, s, tt.method, tt.path)
+			if rec.Code != tt.expectCode {
+				t.Errorf("Expected status %d, got %d", tt.expectCode, rec.Code)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tt.allow {
+				t.Errorf("Expected Allow %q, got %q", tt.allow, allow)
+			}
+		})
+	}
+}
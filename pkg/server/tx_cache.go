@@ -0,0 +1,131 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"eth-blockchain-parser/pkg/database"
+)
+
+// DefaultTxCacheTTL is how long a cached GET /api/transactions/{hash} result is served when
+// ServerConfig.TxCacheTTL is zero
+const DefaultTxCacheTTL = 30 * time.Second
+
+// txGetter looks up a transaction by hash, nil if it isn't stored.
+// Implemented by database.TransactionRepository and txCache.
+type txGetter interface {
+	GetByHash(ctx context.Context, txHash string) (*database.Transaction, error)
+}
+
+// txCache is a bounded LRU in front of a txGetter for hashes requested over and over (shared in alerts).
+// Only confirmed rows are cached: with receipt data, a row without it is still updated by the enrich pass.
+// Misses are not cached, deleted rows are invalidated by the server, rows removed by other processes
+// (retention) are served for at most ttl.
+type txCache struct {
+	getter txGetter
+	size   int
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+}
+
+type txCacheEntry struct {
+	hash    string
+	tx      *database.Transaction
+	expires time.Time
+}
+
+// newTxCache creates a cache of up to size transactions, ttl <= 0 falls back to DefaultTxCacheTTL
+func newTxCache(getter txGetter, size int, ttl time.Duration) *txCache {
+	if ttl <= 0 {
+		ttl = DefaultTxCacheTTL
+	}
+	return &txCache{
+		getter:  getter,
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// GetByHash serves a cached transaction or loads it and caches it if it is confirmed
+func (c *txCache) GetByHash(ctx context.Context, txHash string) (*database.Transaction, error) {
+	if tx, ok := c.get(txHash); ok {
+		return tx, nil
+	}
+
+	tx, err := c.getter.GetByHash(ctx, txHash)
+	if err != nil || tx == nil || !tx.ReceiptFetched {
+		return tx, err
+	}
+	c.put(txHash, tx)
+	return tx, nil
+}
+
+func (c *txCache) get(txHash string) (*database.Transaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[txHash]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*txCacheEntry)
+	if c.now().After(entry.expires) {
+		c.removeLocked(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.tx, true
+}
+
+func (c *txCache) put(txHash string, tx *database.Transaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.entries[txHash]; ok {
+		elem.Value = &txCacheEntry{hash: txHash, tx: tx, expires: expires}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[txHash] = c.order.PushFront(&txCacheEntry{hash: txHash, tx: tx, expires: expires})
+	for c.order.Len() > c.size {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+// Invalidate drops the cached transaction of a hash, under any spelling of the hex
+func (c *txCache) Invalidate(txHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.entries {
+		if strings.EqualFold(key, txHash) || strings.EqualFold(elem.Value.(*txCacheEntry).tx.TxHash, txHash) {
+			c.removeLocked(elem)
+		}
+	}
+}
+
+// InvalidateBlock drops the cached transactions of a block
+func (c *txCache) InvalidateBlock(blockNumber int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, elem := range c.entries {
+		if elem.Value.(*txCacheEntry).tx.BlockNumber == blockNumber {
+			c.removeLocked(elem)
+		}
+	}
+}
+
+func (c *txCache) removeLocked(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*txCacheEntry).hash)
+}
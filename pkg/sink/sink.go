@@ -0,0 +1,70 @@
+// Package sink defines where parsed whale transactions are stored. SQLite is the default,
+// other destinations (files, stdout, message queues) implement TransactionSink.
+package sink
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"eth-blockchain-parser/internal/filtering"
+	"eth-blockchain-parser/pkg/database"
+)
+
+// TransactionSink stores whale transactions, implemented by database.TransactionRepository
+type TransactionSink interface {
+	Store(ctx context.Context, txs []*database.Transaction) error
+}
+
+var _ TransactionSink = (*database.TransactionRepository)(nil)
+
+// Stdout is the FileSink path that writes to standard output
+const Stdout = "-"
+
+// FileSink appends transactions as JSON Lines to a file, or to stdout for the path "-"
+type FileSink struct {
+	path string
+}
+
+// NewFileSink creates a sink appending to path, the file is created on the first Store
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+// Store appends one JSON object per transaction
+func (s *FileSink) Store(ctx context.Context, txs []*database.Transaction) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	if s.path == Stdout {
+		return filtering.WriteTransactionsNDJSON(os.Stdout, txs)
+	}
+	if err := filtering.AppendNDJSON(s.path, txs); err != nil {
+		return fmt.Errorf("failed to store transactions in %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// Noop discards transactions, for dry runs that only need the CSV/NDJSON output
+type Noop struct{}
+
+// Store does nothing
+func (Noop) Store(ctx context.Context, txs []*database.Transaction) error {
+	return nil
+}
+
+// New returns the sink of the given kind: "sqlite" (txRepo), "file" (path) or "none"
+func New(kind, path string, txRepo *database.TransactionRepository) (TransactionSink, error) {
+	switch kind {
+	case "", "sqlite":
+		return txRepo, nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("file sink needs a path")
+		}
+		return NewFileSink(path), nil
+	case "none":
+		return Noop{}, nil
+	}
+	return nil, fmt.Errorf("unknown sink %q, expected sqlite, file or none", kind)
+}
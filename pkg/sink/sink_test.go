@@ -0,0 +1,119 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"eth-blockchain-parser/pkg/database"
+)
+
+func testTransactions(hashes ...string) []*database.Transaction {
+	txs := make([]*database.Transaction, len(hashes))
+	for i, hash := range hashes {
+		txs[i] = &database.Transaction{TxHash: hash, BlockNumber: 100, Value: "12.5", TransferType: "FROM"}
+	}
+	return txs
+}
+
+// TestFileSink tests that every Store appends one JSON object per transaction
+func TestFileSink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "whales.ndjson")
+	s := NewFileSink(path)
+
+	if err := s.Store(ctx, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected no file for an empty store, got %v", err)
+	}
+
+	if err := s.Store(ctx, testTransactions("0xa", "0xb")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := s.Store(ctx, testTransactions("0xc")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open sink file: %v", err)
+	}
+	defer file.Close()
+
+	var hashes []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var tx database.Transaction
+		if err := json.Unmarshal(scanner.Bytes(), &tx); err != nil {
+			t.Fatalf("Expected a JSON object per line, got %q: %v", scanner.Text(), err)
+		}
+		if tx.Value != "12.5" || tx.TransferType != "FROM" {
+			t.Errorf("Expected stored fields, got %+v", tx)
+		}
+		hashes = append(hashes, tx.TxHash)
+	}
+	expected := []string{"0xa", "0xb", "0xc"}
+	if len(hashes) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d", len(expected), len(hashes))
+	}
+	for i, hash := range expected {
+		if hashes[i] != hash {
+			t.Errorf("Expected %s at line %d, got %s", hash, i, hashes[i])
+		}
+	}
+}
+
+// TestNoop tests that the no-op sink accepts anything
+func TestNoop(t *testing.T) {
+	var s TransactionSink = Noop{}
+	if err := s.Store(context.Background(), testTransactions("0xa")); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+	if err := s.Store(context.Background(), nil); err != nil {
+		t.Errorf("Expected nil error, got %v", err)
+	}
+}
+
+// TestNew tests selecting a sink by name
+func TestNew(t *testing.T) {
+	txRepo := database.NewTransactionRepository(nil, nil)
+
+	tests := []struct {
+		name    string
+		kind    string
+		path    string
+		check   func(TransactionSink) bool
+		wantErr bool
+	}{
+		{name: "Default", kind: "", check: func(s TransactionSink) bool { return s == TransactionSink(txRepo) }},
+		{name: "SQLite", kind: "sqlite", check: func(s TransactionSink) bool { return s == TransactionSink(txRepo) }},
+		{name: "File", kind: "file", path: "out.ndjson", check: func(s TransactionSink) bool { _, ok := s.(*FileSink); return ok }},
+		{name: "Stdout", kind: "file", path: Stdout, check: func(s TransactionSink) bool { _, ok := s.(*FileSink); return ok }},
+		{name: "None", kind: "none", check: func(s TransactionSink) bool { _, ok := s.(Noop); return ok }},
+		{name: "File without path", kind: "file", wantErr: true},
+		{name: "Unknown", kind: "kafka", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.kind, tt.path, txRepo)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !tt.check(s) {
+				t.Errorf("Expected %s sink, got %T", tt.kind, s)
+			}
+		})
+	}
+}